@@ -51,6 +51,7 @@ func (t *authenticatedTransport) RoundTrip(req *http.Request) (*http.Response, e
 		return nil, err
 	}
 	auth.SetVersionHeader(req)
+	setTraceHeader(req)
 	return t.underlyingTransport.RoundTrip(req)
 }
 
@@ -60,6 +61,7 @@ type unauthenticatedTransport struct {
 
 func (t *unauthenticatedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	auth.SetVersionHeader(req)
+	setTraceHeader(req)
 	return t.underlyingTransport.RoundTrip(req)
 }
 
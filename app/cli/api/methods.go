@@ -9,6 +9,7 @@ import (
 	"log"
 	"net/http"
 	"plandex-cli/types"
+	"strconv"
 	"strings"
 
 	shared "plandex-shared"
@@ -502,7 +503,9 @@ func (a *Api) TellPlan(planId, branch string, req shared.TellPlanRequest, onStre
 
 	if req.ConnectStream {
 		log.Println("Connecting stream")
-		connectPlanRespStream(resp.Body, onStream)
+		// a new stream starts its event ids over
+		ClearLastStreamEventId(planId, branch)
+		connectPlanRespStream(resp.Body, planId, branch, onStream)
 	} else {
 		// log.Println("Background exec - not connecting stream")
 		resp.Body.Close()
@@ -555,7 +558,8 @@ func (a *Api) BuildPlan(planId, branch string, req shared.BuildPlanRequest, onSt
 
 	if req.ConnectStream {
 		log.Println("Connecting stream")
-		connectPlanRespStream(resp.Body, onStream)
+		ClearLastStreamEventId(planId, branch)
+		connectPlanRespStream(resp.Body, planId, branch, onStream)
 	} else {
 		// log.Println("Background exec - not connecting stream")
 		resp.Body.Close()
@@ -564,6 +568,215 @@ func (a *Api) BuildPlan(planId, branch string, req shared.BuildPlanRequest, onSt
 	return nil
 }
 
+func (a *Api) ResumePlan(planId, branch string, req shared.ResumePlanRequest, onStream types.OnStreamPlan) *shared.ApiError {
+
+	log.Println("Calling ResumePlan")
+
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/resume", GetApiHost(), planId, branch)
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return &shared.ApiError{Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	request, err := http.NewRequest(http.MethodPatch, serverUrl, bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return &shared.ApiError{Msg: fmt.Sprintf("error creating request: %v", err)}
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	var client *http.Client
+	if req.ConnectStream {
+		client = authenticatedStreamingClient
+	} else {
+		client = authenticatedFastClient
+	}
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+
+	if resp.StatusCode >= 400 {
+		log.Println("Error response from resume plan", resp.StatusCode)
+
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := HandleApiError(resp, errorBody)
+
+		didRefresh, apiErr := refreshAuthIfNeeded(apiErr)
+
+		if didRefresh {
+			return a.ResumePlan(planId, branch, req, onStream)
+		}
+		return apiErr
+	}
+
+	if req.ConnectStream {
+		log.Println("Connecting stream")
+		ClearLastStreamEventId(planId, branch)
+		connectPlanRespStream(resp.Body, planId, branch, onStream)
+	} else {
+		resp.Body.Close()
+	}
+
+	return nil
+}
+
+func (a *Api) QueuePrompt(planId, branch string, req shared.TellPlanRequest) (*shared.QueuePromptResponse, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/queue", GetApiHost(), planId, branch)
+
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	resp, err := authenticatedFastClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := HandleApiError(resp, errorBody)
+		authRefreshed, apiErr := refreshAuthIfNeeded(apiErr)
+		if authRefreshed {
+			return a.QueuePrompt(planId, branch, req)
+		}
+		return nil, apiErr
+	}
+
+	var res shared.QueuePromptResponse
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &res, nil
+}
+
+func (a *Api) ListQueuedPrompts(planId, branch string) ([]*shared.QueuedPrompt, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/queue", GetApiHost(), planId, branch)
+
+	resp, err := authenticatedFastClient.Get(serverUrl)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := HandleApiError(resp, errorBody)
+		authRefreshed, apiErr := refreshAuthIfNeeded(apiErr)
+		if authRefreshed {
+			return a.ListQueuedPrompts(planId, branch)
+		}
+		return nil, apiErr
+	}
+
+	var prompts []*shared.QueuedPrompt
+	err = json.NewDecoder(resp.Body).Decode(&prompts)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return prompts, nil
+}
+
+func (a *Api) DeleteQueuedPrompt(planId, branch, queuedPromptId string) *shared.ApiError {
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/queue/%s", GetApiHost(), planId, branch, queuedPromptId)
+
+	req, err := http.NewRequest(http.MethodDelete, serverUrl, nil)
+	if err != nil {
+		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error creating request: %s", err)}
+	}
+
+	resp, err := authenticatedFastClient.Do(req)
+	if err != nil {
+		return &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %s", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+
+		apiErr := HandleApiError(resp, errorBody)
+		authRefreshed, apiErr := refreshAuthIfNeeded(apiErr)
+		if authRefreshed {
+			return a.DeleteQueuedPrompt(planId, branch, queuedPromptId)
+		}
+		return apiErr
+	}
+
+	return nil
+}
+
+func (a *Api) ListRepoLocks() (*shared.ListRepoLocksResponse, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/admin/locks", GetApiHost())
+
+	resp, err := authenticatedFastClient.Get(serverUrl)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := HandleApiError(resp, errorBody)
+		authRefreshed, apiErr := refreshAuthIfNeeded(apiErr)
+		if authRefreshed {
+			return a.ListRepoLocks()
+		}
+		return nil, apiErr
+	}
+
+	var res shared.ListRepoLocksResponse
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &res, nil
+}
+
+func (a *Api) ForceReleaseRepoLock(lockId string, req shared.ForceReleaseRepoLockRequest) (*shared.RepoLock, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/admin/locks/%s", GetApiHost(), lockId)
+
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	request, err := http.NewRequest(http.MethodDelete, serverUrl, bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error creating request: %v", err)}
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := authenticatedFastClient.Do(request)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := HandleApiError(resp, errorBody)
+		authRefreshed, apiErr := refreshAuthIfNeeded(apiErr)
+		if authRefreshed {
+			return a.ForceReleaseRepoLock(lockId, req)
+		}
+		return nil, apiErr
+	}
+
+	var lock shared.RepoLock
+	err = json.NewDecoder(resp.Body).Decode(&lock)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &lock, nil
+}
+
 func (a *Api) RespondMissingFile(planId, branch string, req shared.RespondMissingFileRequest) *shared.ApiError {
 	serverUrl := fmt.Sprintf("%s/plans/%s/%s/respond_missing_file", GetApiHost(), planId, branch)
 
@@ -607,6 +820,11 @@ func (a *Api) ConnectPlan(planId, branch string, onStream types.OnStreamPlan) *s
 		return &shared.ApiError{Msg: fmt.Sprintf("error creating request: %v", err)}
 	}
 
+	// resume from the last message received if we're reconnecting to a dropped stream
+	if lastEventId := getLastStreamEventId(planId, branch); lastEventId > 0 {
+		req.Header.Set("Last-Event-Id", strconv.FormatInt(lastEventId, 10))
+	}
+
 	resp, err := authenticatedStreamingClient.Do(req)
 	if err != nil {
 		return &shared.ApiError{Msg: fmt.Sprintf("error sending request: %v", err)}
@@ -625,7 +843,7 @@ func (a *Api) ConnectPlan(planId, branch string, onStream types.OnStreamPlan) *s
 		return apiErr
 	}
 
-	connectPlanRespStream(resp.Body, onStream)
+	connectPlanRespStream(resp.Body, planId, branch, onStream)
 
 	return nil
 }
@@ -1042,6 +1260,44 @@ func (a *Api) DeleteContext(planId, branch string, req shared.DeleteContextReque
 	return &deleteContextResponse, nil
 }
 
+func (a *Api) PinContext(planId, branch string, req shared.PinContextRequest) (*shared.PinContextResponse, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/plans/%s/%s/context/pin", GetApiHost(), planId, branch)
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	request, err := http.NewRequest(http.MethodPatch, serverUrl, bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error creating request: %v", err)}
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := authenticatedFastClient.Do(request)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := HandleApiError(resp, errorBody)
+		authRefreshed, apiErr := refreshAuthIfNeeded(apiErr)
+		if authRefreshed {
+			return a.PinContext(planId, branch, req)
+		}
+		return nil, apiErr
+	}
+
+	var pinContextResponse shared.PinContextResponse
+	err = json.NewDecoder(resp.Body).Decode(&pinContextResponse)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return &pinContextResponse, nil
+}
+
 func (a *Api) ListContext(planId, branch string) ([]*shared.Context, *shared.ApiError) {
 	serverUrl := fmt.Sprintf("%s/plans/%s/%s/context", GetApiHost(), planId, branch)
 
@@ -2295,6 +2551,39 @@ func (a *Api) GetCreditsSummary(req shared.CreditsLogRequest) (*shared.CreditsSu
 	return res, nil
 }
 
+func (a *Api) GetUsageSummary(req shared.UsageSummaryRequest) (*shared.UsageSummaryResponse, *shared.ApiError) {
+	serverUrl := fmt.Sprintf("%s/usage/summary", GetApiHost())
+
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error marshalling request: %v", err)}
+	}
+
+	resp, err := authenticatedFastClient.Post(serverUrl, "application/json", bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error sending request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(resp.Body)
+		apiErr := HandleApiError(resp, errorBody)
+		authRefreshed, apiErr := refreshAuthIfNeeded(apiErr)
+		if authRefreshed {
+			return a.GetUsageSummary(req)
+		}
+		return nil, apiErr
+	}
+
+	var res *shared.UsageSummaryResponse
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return nil, &shared.ApiError{Type: shared.ApiErrorTypeOther, Msg: fmt.Sprintf("error decoding response: %v", err)}
+	}
+
+	return res, nil
+}
+
 func (a *Api) GetBalance() (decimal.Decimal, *shared.ApiError) {
 	serverUrl := fmt.Sprintf("%s/billing/balance", GetApiHost())
 
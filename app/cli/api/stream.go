@@ -8,6 +8,7 @@ import (
 	"io"
 	"log"
 	"plandex-cli/types"
+	"sync"
 	"time"
 
 	shared "plandex-shared"
@@ -16,7 +17,28 @@ import (
 // 3 heartbeat misses = timeout
 const HeartbeatTimeout = 16 * time.Second
 
-func connectPlanRespStream(body io.ReadCloser, onStream types.OnStreamPlan) {
+// id of the last stream message received for each plan and branch -- sent as Last-Event-Id when reconnecting so the server can replay anything missed
+var lastStreamEventIds sync.Map
+
+func streamKey(planId, branch string) string {
+	return planId + "|" + branch
+}
+
+func getLastStreamEventId(planId, branch string) int64 {
+	if id, ok := lastStreamEventIds.Load(streamKey(planId, branch)); ok {
+		return id.(int64)
+	}
+	return 0
+}
+
+// ClearLastStreamEventId makes the next connection to a plan stream start from the plan's current state rather than replaying missed messages -- used when there's no existing stream UI to resume
+func ClearLastStreamEventId(planId, branch string) {
+	lastStreamEventIds.Delete(streamKey(planId, branch))
+}
+
+func connectPlanRespStream(body io.ReadCloser, planId, branch string, onStream types.OnStreamPlan) {
+	key := streamKey(planId, branch)
+
 	reader := bufio.NewReader(body)
 	timer := time.NewTimer(HeartbeatTimeout)
 	defer timer.Stop()
@@ -58,9 +80,18 @@ func connectPlanRespStream(body io.ReadCloser, onStream types.OnStreamPlan) {
 
 			// log.Println("connectPlanRespStream: received message:", msg)
 
+			if msg.Id > 0 {
+				if last, ok := lastStreamEventIds.Load(key); ok && msg.Id <= last.(int64) {
+					// already received before reconnecting
+					continue
+				}
+				lastStreamEventIds.Store(key, msg.Id)
+			}
+
 			onStream(types.OnStreamPlanParams{Msg: &msg, Err: nil})
 
 			if msg.Type == shared.StreamMessageFinished || msg.Type == shared.StreamMessageError || msg.Type == shared.StreamMessageAborted {
+				lastStreamEventIds.Delete(key)
 				body.Close()
 				return
 			}
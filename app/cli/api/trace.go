@@ -0,0 +1,37 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"regexp"
+)
+
+// Every request a command makes is sent in the same trace, so a server with tracing enabled shows a whole 'plandex tell' -- the request that starts it, the stream, and any follow-up requests -- as one trace. Setting TRACEPARENT continues an existing trace instead, e.g. from a script that's traced itself.
+
+var traceparentRegex = regexp.MustCompile(`^00-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+var traceId = loadTraceId()
+
+func loadTraceId() string {
+	if m := traceparentRegex.FindStringSubmatch(os.Getenv("TRACEPARENT")); m != nil && m[1] != "00000000000000000000000000000000" {
+		return m[1]
+	}
+	return randomHex(16)
+}
+
+// setTraceHeader adds a W3C traceparent header, with a new parent span id for each request
+func setTraceHeader(req *http.Request) {
+	req.Header.Set("traceparent", "00-"+traceId+"-"+randomHex(8)+"-01")
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		// crypto/rand doesn't fail on supported platforms
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
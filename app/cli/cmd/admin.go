@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex-cli/api"
+	"plandex-cli/auth"
+	"plandex-cli/format"
+	"plandex-cli/term"
+	"strings"
+	"time"
+
+	shared "plandex-shared"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var releaseLockNote string
+
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Org admin tools",
+}
+
+var adminLocksCmd = &cobra.Command{
+	Use:   "locks",
+	Short: "List active repo locks and queued repo operations",
+	Long: `List active repo locks and queued repo operations for plans in your org.
+
+Useful for debugging plans that appear stuck. Locks are shown for all server instances; operations are shown for the instance that handled the request. Requires an org owner or admin.`,
+	Args: cobra.NoArgs,
+	Run:  listRepoLocks,
+}
+
+var adminReleaseLockCmd = &cobra.Command{
+	Use:   "release <lock-id>",
+	Short: "Force release a stuck repo lock",
+	Long: `Force release a repo lock by id or unique id prefix (see 'plandex admin locks').
+
+The release is recorded with your user and an optional note. The operation holding the lock isn't stopped, so only release locks whose holder is stuck or gone.`,
+	Args: cobra.ExactArgs(1),
+	Run:  releaseRepoLock,
+}
+
+func init() {
+	RootCmd.AddCommand(adminCmd)
+	adminCmd.AddCommand(adminLocksCmd)
+	adminLocksCmd.AddCommand(adminReleaseLockCmd)
+
+	adminReleaseLockCmd.Flags().StringVarP(&releaseLockNote, "note", "n", "", "Note recorded with the release")
+}
+
+func listRepoLocks(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+
+	res := mustListRepoLocks()
+
+	planName := func(planId string) string {
+		if name, ok := res.PlanNamesById[planId]; ok {
+			return name
+		}
+		return planId
+	}
+
+	color.New(color.Bold, term.ColorHiCyan).Println("🔒 Repo locks")
+	fmt.Println()
+
+	if len(res.Locks) == 0 {
+		fmt.Println("🤷‍♂️ No active repo locks")
+	} else {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Id", "Plan", "Branch", "Scope", "Reason", "Holder", "Heartbeat"})
+		table.SetAutoWrapText(false)
+
+		for _, lock := range res.Locks {
+			holder := "-"
+			if lock.InstanceId != "" {
+				holder = fmt.Sprintf("%s / goroutine %d", shortId(lock.InstanceId), lock.GoroutineId)
+			}
+
+			heartbeat := fmt.Sprintf("%s ago", lock.HeartbeatAge.Round(time.Second))
+			heartbeatColor := tablewriter.Colors{}
+			if lock.Expired {
+				heartbeat += " (expired)"
+				heartbeatColor = tablewriter.Colors{tablewriter.FgHiRedColor}
+			}
+
+			table.Rich([]string{
+				shortId(lock.Id),
+				planName(lock.PlanId),
+				lock.Branch,
+				string(lock.Scope),
+				lock.Reason,
+				holder,
+				heartbeat,
+			}, []tablewriter.Colors{
+				{tablewriter.Bold},
+				{},
+				{},
+				{},
+				{},
+				{},
+				heartbeatColor,
+			})
+		}
+
+		table.Render()
+	}
+
+	fmt.Println()
+	color.New(color.Bold, term.ColorHiCyan).Printf("⏳ Repo operations on instance %s\n", shortId(res.InstanceId))
+	fmt.Println()
+
+	if len(res.Operations) == 0 {
+		fmt.Println("🤷‍♂️ No running or queued repo operations")
+	} else {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Plan", "Branch", "Scope", "Reason", "Status", "Lock", "Queued"})
+		table.SetAutoWrapText(false)
+
+		for _, op := range res.Operations {
+			status := "Waiting"
+			if op.Running {
+				status = "Running"
+				if op.LockId == "" {
+					status = "Acquiring lock"
+				}
+			}
+
+			lockId := "-"
+			if op.LockId != "" {
+				lockId = shortId(op.LockId)
+			}
+
+			table.Append([]string{
+				planName(op.PlanId),
+				op.Branch,
+				string(op.Scope),
+				op.Reason,
+				status,
+				lockId,
+				format.Time(op.EnqueuedAt),
+			})
+		}
+
+		table.Render()
+	}
+
+	fmt.Println()
+	term.PrintCmds("", "admin locks release")
+}
+
+func releaseRepoLock(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+
+	prefix := strings.TrimSpace(args[0])
+
+	res := mustListRepoLocks()
+
+	var matches []*shared.RepoLock
+	for _, lock := range res.Locks {
+		if strings.HasPrefix(lock.Id, prefix) {
+			matches = append(matches, lock)
+		}
+	}
+
+	if len(matches) == 0 {
+		term.OutputErrorAndExit("No active repo lock matches %s", prefix)
+	}
+
+	if len(matches) > 1 {
+		term.OutputErrorAndExit("More than one repo lock matches %s -- use a longer id prefix", prefix)
+	}
+
+	term.StartSpinner("")
+	lock, apiErr := api.Client.ForceReleaseRepoLock(matches[0].Id, shared.ForceReleaseRepoLockRequest{
+		Note: releaseLockNote,
+	})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		if apiErr.Status == 404 {
+			fmt.Println("🤷‍♂️ Lock was already released")
+			return
+		}
+		term.OutputErrorAndExit("Error releasing repo lock: %v", apiErr.Msg)
+	}
+
+	planName := lock.PlanId
+	if name, ok := res.PlanNamesById[lock.PlanId]; ok {
+		planName = name
+	}
+
+	fmt.Printf("✅ Released %s lock %s on plan %s\n", lock.Scope, color.New(color.Bold).Sprint(shortId(lock.Id)), color.New(color.Bold, term.ColorHiCyan).Sprint(planName))
+}
+
+func mustListRepoLocks() *shared.ListRepoLocksResponse {
+	term.StartSpinner("")
+	res, apiErr := api.Client.ListRepoLocks()
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error getting repo locks: %v", apiErr.Msg)
+	}
+
+	return res
+}
+
+func shortId(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
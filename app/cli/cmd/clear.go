@@ -15,10 +15,12 @@ import (
 var clearCmd = &cobra.Command{
 	Use:   "clear",
 	Short: "Clear all context",
-	Long:  `Clear all context.`,
+	Long:  `Clear all context. Pinned context is kept unless --all is passed.`,
 	Run:   clearAllContext,
 }
 
+var clearPinned bool
+
 func clearAllContext(cmd *cobra.Command, args []string) {
 	auth.MustResolveAuthWithOrg()
 	lib.MustResolveProject()
@@ -36,8 +38,13 @@ func clearAllContext(cmd *cobra.Command, args []string) {
 	}
 
 	deleteIds := map[string]bool{}
+	numPinned := 0
 
 	for _, context := range contexts {
+		if context.Pinned && !clearPinned {
+			numPinned++
+			continue
+		}
 		deleteIds[context.Id] = true
 	}
 
@@ -55,8 +62,18 @@ func clearAllContext(cmd *cobra.Command, args []string) {
 		fmt.Println("🤷‍♂️ No context removed")
 	}
 
+	if numPinned > 0 {
+		label := "pieces"
+		if numPinned == 1 {
+			label = "piece"
+		}
+		fmt.Printf("📌 Kept %d pinned %s of context\n", numPinned, label)
+		fmt.Println()
+		term.PrintCmds("", "clear --all", "unpin")
+	}
 }
 
 func init() {
 	RootCmd.AddCommand(clearCmd)
+	clearCmd.Flags().BoolVarP(&clearPinned, "all", "a", false, "Also clear pinned context")
 }
@@ -41,6 +41,9 @@ func connect(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	// the stream UI starts fresh, so it needs the full state of the plan rather than a replay
+	api.ClearLastStreamEventId(planId, branch)
+
 	term.StartSpinner("")
 	apiErr := api.Client.ConnectPlan(planId, branch, stream.OnStreamPlan)
 	term.StopSpinner()
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex-cli/api"
+	"plandex-cli/auth"
+	"plandex-cli/format"
+	"plandex-cli/lib"
+	"plandex-cli/term"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var contextSetsCmd = &cobra.Command{
+	Use:   "context",
+	Short: "List saved context sets",
+	Long: `List saved context sets for the current project.
+
+Save the current context as a named set, then load it into any plan with 'plandex load @name'.`,
+	Run: listContextSets,
+}
+
+var saveContextSetCmd = &cobra.Command{
+	Use:   "save [name]",
+	Short: "Save the current context as a named set",
+	Args:  cobra.ExactArgs(1),
+	Run:   saveContextSet,
+}
+
+var deleteContextSetCmd = &cobra.Command{
+	Use:     "delete [name]",
+	Aliases: []string{"rm"},
+	Short:   "Delete a saved context set",
+	Args:    cobra.ExactArgs(1),
+	Run:     deleteContextSet,
+}
+
+func init() {
+	RootCmd.AddCommand(contextSetsCmd)
+	contextSetsCmd.AddCommand(saveContextSetCmd)
+	contextSetsCmd.AddCommand(deleteContextSetCmd)
+}
+
+func listContextSets(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	sets, err := lib.ListContextSets()
+	if err != nil {
+		term.OutputErrorAndExit("Error listing context sets: %v", err)
+	}
+
+	if len(sets) == 0 {
+		fmt.Println("🤷‍♂️ No context sets")
+		fmt.Println()
+		term.PrintCmds("", "context save")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"#", "Name", "Entries", "Updated"})
+	table.SetAutoWrapText(false)
+
+	for i, set := range sets {
+		table.Rich([]string{
+			strconv.Itoa(i + 1),
+			lib.ContextSetPrefix + set.Name,
+			strconv.Itoa(len(set.Entries)),
+			format.Time(set.UpdatedAt),
+		}, []tablewriter.Colors{
+			{tablewriter.Bold},
+			{tablewriter.FgHiGreenColor, tablewriter.Bold},
+		})
+	}
+
+	table.Render()
+
+	fmt.Println()
+	term.PrintCmds("", "load @name", "context save", "context delete")
+}
+
+func saveContextSet(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		term.OutputNoCurrentPlanErrorAndExit()
+	}
+
+	name := strings.TrimPrefix(strings.TrimSpace(args[0]), lib.ContextSetPrefix)
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		term.OutputErrorAndExit("Context set names can't be empty or contain whitespace")
+	}
+
+	sets, err := lib.ReadContextSets()
+	if err != nil {
+		term.OutputErrorAndExit("Error reading context sets: %v", err)
+	}
+
+	if _, ok := sets[name]; ok {
+		confirmed, err := term.ConfirmYesNo("Context set %s already exists. Overwrite it?", lib.ContextSetPrefix+name)
+		if err != nil {
+			term.OutputErrorAndExit("Error getting user input: %v", err)
+		}
+		if !confirmed {
+			return
+		}
+	}
+
+	term.StartSpinner("")
+	contexts, apiErr := api.Client.ListContext(lib.CurrentPlanId, lib.CurrentBranch)
+	if apiErr != nil {
+		term.StopSpinner()
+		term.OutputErrorAndExit("Error listing context: %v", apiErr.Msg)
+	}
+
+	if len(contexts) == 0 {
+		term.StopSpinner()
+		fmt.Println("🤷‍♂️ No context to save")
+		fmt.Println()
+		term.PrintCmds("", "load")
+		return
+	}
+
+	set, err := lib.SaveContextSet(name, contexts)
+	term.StopSpinner()
+	if err != nil {
+		term.OutputErrorAndExit("Error saving context set: %v", err)
+	}
+
+	fmt.Printf("✅ Saved %d pieces of context to %s\n", len(set.Entries), color.New(color.Bold, term.ColorHiCyan).Sprint(lib.ContextSetPrefix+name))
+	fmt.Println()
+	term.PrintCmds("", "load @name", "context")
+}
+
+func deleteContextSet(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	name := strings.TrimPrefix(strings.TrimSpace(args[0]), lib.ContextSetPrefix)
+
+	deleted, err := lib.DeleteContextSet(name)
+	if err != nil {
+		term.OutputErrorAndExit("Error deleting context set: %v", err)
+	}
+
+	if !deleted {
+		fmt.Printf("🤷‍♂️ No context set named %s\n", color.New(color.Bold, term.ColorHiCyan).Sprint(lib.ContextSetPrefix+name))
+		return
+	}
+
+	fmt.Printf("✅ Deleted context set %s\n", color.New(color.Bold, term.ColorHiCyan).Sprint(lib.ContextSetPrefix+name))
+}
@@ -7,6 +7,8 @@ import (
 	"plandex-cli/lib"
 	"plandex-cli/term"
 	"plandex-cli/types"
+	"plandex-cli/url"
+	"strings"
 
 	"github.com/sashabaranov/go-openai"
 	"github.com/spf13/cobra"
@@ -19,14 +21,19 @@ var (
 	forceSkipIgnore bool
 	imageDetail     string
 	defsOnly        bool
+	pinLoaded       bool
+	crawlDepth      int
+	crawlMaxPages   int
 )
 
 var contextLoadCmd = &cobra.Command{
-	Use:     "load [files-or-urls...]",
+	Use:     "load [files-or-urls-or-@sets...]",
 	Aliases: []string{"l", "add"},
 	Short:   "Load context from various inputs",
-	Long:    `Load context from a file path, a directory, a URL, an image, a note, or piped data.`,
-	Run:     contextLoad,
+	Long: `Load context from a file path, a directory, a URL, an image, a note, or piped data.
+
+Load a saved context set with @name (see 'plandex context save'). If no set has that name, an argument starting with @ that matches a path on disk, like @types/index.d.ts, is loaded as a path. To always load a path, prefix it with ./ (like ./@types).`,
+	Run: contextLoad,
 }
 
 func init() {
@@ -36,6 +43,9 @@ func init() {
 	contextLoadCmd.Flags().BoolVarP(&forceSkipIgnore, "force", "f", false, "Load files even when ignored by .gitignore or .plandexignore")
 	contextLoadCmd.Flags().StringVarP(&imageDetail, "detail", "d", "high", "Image detail level (high or low)")
 	contextLoadCmd.Flags().BoolVar(&defsOnly, "map", false, "Load file maps (function/method/class signatures, variable names, types, etc.)")
+	contextLoadCmd.Flags().BoolVar(&pinLoaded, "pin", false, "Pin loaded context so it survives 'clear' and smart context")
+	contextLoadCmd.Flags().IntVar(&crawlDepth, "crawl", 0, "Follow same-origin links from loaded urls up to this depth")
+	contextLoadCmd.Flags().IntVar(&crawlMaxPages, "max-pages", url.DefaultCrawlMaxPages, "Max pages to load per url when crawling")
 	RootCmd.AddCommand(contextLoadCmd)
 }
 
@@ -48,15 +58,54 @@ func contextLoad(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	lib.MustLoadContext(args, &types.LoadContextParams{
+	var sets types.ContextSetsByName
+	for _, arg := range args {
+		if strings.HasPrefix(arg, lib.ContextSetPrefix) {
+			var err error
+			sets, err = lib.ReadContextSets()
+			if err != nil {
+				term.OutputErrorAndExit("Error reading context sets: %v", err)
+			}
+			break
+		}
+	}
+
+	var resources []string
+	var contextSetNames []string
+	for _, arg := range args {
+		if name, ok := lib.ContextSetArgName(arg, sets); ok {
+			contextSetNames = append(contextSetNames, name)
+		} else {
+			resources = append(resources, arg)
+		}
+	}
+
+	params := &types.LoadContextParams{
 		Note:            note,
 		Recursive:       recursive,
 		NamesOnly:       namesOnly,
 		ForceSkipIgnore: forceSkipIgnore,
 		ImageDetail:     openai.ImageURLDetail(imageDetail),
 		DefsOnly:        defsOnly,
+		Pinned:          pinLoaded,
+		CrawlDepth:      crawlDepth,
+		CrawlMaxPages:   crawlMaxPages,
 		SessionId:       os.Getenv("PLANDEX_REPL_SESSION_ID"),
-	})
+	}
+
+	if len(contextSetNames) > 0 {
+		for _, name := range contextSetNames {
+			lib.MustLoadContextSet(name, params)
+		}
+
+		if len(resources) == 0 && note == "" {
+			fmt.Println()
+			term.PrintCmds("", "ls", "tell", "context")
+			return
+		}
+	}
+
+	lib.MustLoadContext(resources, params)
 
 	fmt.Println()
 	term.PrintCmds("", "ls", "tell", "debug")
@@ -73,6 +73,9 @@ func listContext(cmd *cobra.Command, args []string) {
 		if len(name) > 40 {
 			name = name[:20] + "⋯" + name[len(name)-20:]
 		}
+		if context.Pinned {
+			name = "📌 " + name
+		}
 
 		row := []string{
 			strconv.Itoa(i + 1),
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"plandex-cli/api"
+	"plandex-cli/auth"
+	"plandex-cli/lib"
+	"plandex-cli/term"
+
+	shared "plandex-shared"
+
+	"github.com/spf13/cobra"
+)
+
+var pinCmd = &cobra.Command{
+	Use:   "pin",
+	Short: "Pin context so it's always included",
+	Long: `Pin context by index, range, name, or glob.
+
+	Pinned context is kept by 'plandex clear', skipped by 'plandex rm' globs, and never dropped by smart context.
+
+	plandex pin 1 # Pin by index in the 'plandex ls' list
+	plandex pin 1-3
+	plandex pin STYLE_GUIDE.md
+	plandex pin docs/*.md
+	`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setContextPinned(args, true)
+	},
+}
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin",
+	Short: "Unpin context",
+	Long: `Unpin context by index, range, name, or glob.
+
+	plandex unpin 1
+	plandex unpin STYLE_GUIDE.md
+	`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setContextPinned(args, false)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(pinCmd)
+	RootCmd.AddCommand(unpinCmd)
+}
+
+func setContextPinned(args []string, pinned bool) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		term.OutputNoCurrentPlanErrorAndExit()
+	}
+
+	term.StartSpinner("")
+	contexts, apiErr := api.Client.ListContext(lib.CurrentPlanId, lib.CurrentBranch)
+
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error retrieving context: %v", apiErr)
+	}
+
+	ids := map[string]bool{}
+	indices := parseIndices(args)
+
+	for i, context := range contexts {
+		if context.Pinned == pinned {
+			continue
+		}
+		if indices[i+1] {
+			ids[context.Id] = true
+			continue
+		}
+		for _, id := range args {
+			if context.Name == id || context.FilePath == id || context.Url == id {
+				ids[context.Id] = true
+				break
+			} else if context.FilePath != "" {
+				matched, err := filepath.Match(id, context.FilePath)
+				if err != nil {
+					term.OutputErrorAndExit("Error matching glob pattern: %v", err)
+				}
+				if matched {
+					ids[context.Id] = true
+					break
+				}
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		term.StopSpinner()
+		if pinned {
+			fmt.Println("🤷‍♂️ No context pinned")
+		} else {
+			fmt.Println("🤷‍♂️ No context unpinned")
+		}
+		return
+	}
+
+	res, apiErr := api.Client.PinContext(lib.CurrentPlanId, lib.CurrentBranch, shared.PinContextRequest{
+		Ids:    ids,
+		Pinned: pinned,
+	})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error updating context: %v", apiErr)
+	}
+
+	fmt.Println("✅ " + res.Msg)
+	fmt.Println()
+	term.PrintCmds("", "ls")
+}
@@ -25,6 +25,7 @@ var autoConfirm bool
 
 var tellPromptFile string
 var tellBg bool
+var tellQueue bool
 var tellStop bool
 var tellNoBuild bool
 var tellAutoApply bool
@@ -137,6 +138,10 @@ func validatePlanExecFlags(isApply bool) {
 		term.OutputErrorAndExit("--auto-context/-c can't be used with --bg")
 	}
 
+	if tellQueue && tellBg {
+		term.OutputErrorAndExit("--queue can't be used with --bg")
+	}
+
 	if !isApply {
 		if autoDebug > 0 && !tellAutoApply {
 			term.OutputErrorAndExit("--debug can only be used with --apply")
@@ -8,6 +8,7 @@ import (
 	"plandex-cli/format"
 	"plandex-cli/lib"
 	"plandex-cli/term"
+	"strconv"
 
 	shared "plandex-shared"
 
@@ -49,7 +50,7 @@ func ps(cmd *cobra.Command, args []string) {
 
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetAutoWrapText(false)
-	table.SetHeader([]string{"Pid", "Plan", "Branch", "Started", "Status"})
+	table.SetHeader([]string{"Pid", "Plan", "Branch", "Started", "Status", "Queued"})
 
 	for _, b := range res.Branches {
 		id := res.StreamIdByBranchId[b.Id]
@@ -66,6 +67,13 @@ func ps(cmd *cobra.Command, args []string) {
 			status = "Stopped " + format.Time(finishedAt)
 		case shared.PlanStatusMissingFile:
 			status = "Missing file"
+		case shared.PlanStatusInterrupted:
+			status = "Interrupted " + format.Time(finishedAt)
+		}
+
+		queued := ""
+		if n := res.QueuedPromptsByBranchId[b.Id]; n > 0 {
+			queued = strconv.Itoa(n)
 		}
 
 		row := []string{
@@ -74,6 +82,7 @@ func ps(cmd *cobra.Command, args []string) {
 			b.Name,
 			format.Time(res.StreamStartedAtByBranchId[b.Id]),
 			status,
+			queued,
 		}
 
 		var style []tablewriter.Colors
@@ -93,6 +102,6 @@ func ps(cmd *cobra.Command, args []string) {
 	table.Render()
 
 	fmt.Println()
-	term.PrintCmds("", "connect", "stop")
+	term.PrintCmds("", "connect", "stop", "queue ls")
 
 }
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex-cli/api"
+	"plandex-cli/auth"
+	"plandex-cli/format"
+	"plandex-cli/lib"
+	"plandex-cli/term"
+	"strconv"
+	"strings"
+
+	shared "plandex-shared"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var removeAllQueued bool
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "List prompts queued for the current plan",
+	Long: `List prompts queued for the current plan and branch.
+
+Queue a prompt with 'plandex tell --queue'. Queued prompts start one after another in the background once the plan's current stream finishes.`,
+	Args: cobra.NoArgs,
+	Run:  listQueue,
+}
+
+var listQueueCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List prompts queued for the current plan",
+	Args:  cobra.NoArgs,
+	Run:   listQueue,
+}
+
+var removeQueuedCmd = &cobra.Command{
+	Use:   "rm [indexes...]",
+	Short: "Remove queued prompts by index",
+	Run:   removeQueued,
+}
+
+func init() {
+	RootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(listQueueCmd)
+	queueCmd.AddCommand(removeQueuedCmd)
+
+	removeQueuedCmd.Flags().BoolVarP(&removeAllQueued, "all", "a", false, "Remove all queued prompts")
+}
+
+func listQueue(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		term.OutputNoCurrentPlanErrorAndExit()
+	}
+
+	prompts := mustListQueuedPrompts()
+
+	if len(prompts) == 0 {
+		fmt.Println("🤷‍♂️ No queued prompts")
+		fmt.Println()
+		term.PrintCmds("", "tell --queue")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"#", "Prompt", "Queued", "Status"})
+	table.SetAutoWrapText(false)
+
+	for i, p := range prompts {
+		status := "Waiting"
+		if p.Interrupted {
+			status = "Interrupted"
+		}
+
+		table.Rich([]string{
+			strconv.Itoa(i + 1),
+			queuedPromptPreview(p.Prompt),
+			format.Time(p.CreatedAt),
+			status,
+		}, []tablewriter.Colors{
+			{tablewriter.Bold},
+			{},
+			{},
+			{},
+		})
+	}
+
+	table.Render()
+
+	for _, p := range prompts {
+		if p.Interrupted {
+			fmt.Println()
+			fmt.Println("⚠️  Interrupted prompts were queued on a server that has since restarted, so they won't start. Remove them and queue them again.")
+			break
+		}
+	}
+
+	fmt.Println()
+	term.PrintCmds("", "queue rm", "ps", "connect")
+}
+
+func removeQueued(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		term.OutputNoCurrentPlanErrorAndExit()
+	}
+
+	if !removeAllQueued && len(args) == 0 {
+		term.OutputErrorAndExit("Pass the index of a queued prompt (see 'plandex queue ls') or --all")
+	}
+
+	prompts := mustListQueuedPrompts()
+
+	var toRemove []*shared.QueuedPrompt
+	if removeAllQueued {
+		toRemove = prompts
+	} else {
+		for _, arg := range args {
+			i, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil || i < 1 || i > len(prompts) {
+				term.OutputErrorAndExit("Invalid queued prompt index: %s", arg)
+			}
+			toRemove = append(toRemove, prompts[i-1])
+		}
+	}
+
+	if len(toRemove) == 0 {
+		fmt.Println("🤷‍♂️ No queued prompts")
+		return
+	}
+
+	term.StartSpinner("")
+	numRemoved := 0
+	for _, p := range toRemove {
+		apiErr := api.Client.DeleteQueuedPrompt(lib.CurrentPlanId, lib.CurrentBranch, p.Id)
+		if apiErr != nil {
+			if apiErr.Status == 404 {
+				// already started
+				continue
+			}
+			term.StopSpinner()
+			term.OutputErrorAndExit("Error removing queued prompt: %v", apiErr.Msg)
+		}
+		numRemoved++
+	}
+	term.StopSpinner()
+
+	suffix := "s"
+	if numRemoved == 1 {
+		suffix = ""
+	}
+	fmt.Printf("✅ Removed %d queued prompt%s\n", numRemoved, suffix)
+	if numRemoved < len(toRemove) {
+		fmt.Println("Some prompts had already started")
+	}
+
+	fmt.Println()
+	term.PrintCmds("", "queue ls", "ps")
+}
+
+func queuedPromptPreview(prompt string) string {
+	preview := strings.Join(strings.Fields(prompt), " ")
+	runes := []rune(preview)
+	if len(runes) > 60 {
+		preview = string(runes[:57]) + "..."
+	}
+	return preview
+}
+
+func mustListQueuedPrompts() []*shared.QueuedPrompt {
+	term.StartSpinner("")
+	prompts, apiErr := api.Client.ListQueuedPrompts(lib.CurrentPlanId, lib.CurrentBranch)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error getting queued prompts: %v", apiErr.Msg)
+	}
+
+	return prompts
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex-cli/api"
+	"plandex-cli/auth"
+	"plandex-cli/lib"
+	"plandex-cli/stream"
+	streamtui "plandex-cli/stream_tui"
+	"plandex-cli/term"
+
+	shared "plandex-shared"
+
+	"github.com/spf13/cobra"
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume a plan interrupted by a server restart",
+	Args:  cobra.NoArgs,
+	Run:   resume,
+}
+
+func init() {
+	RootCmd.AddCommand(resumeCmd)
+}
+
+func resume(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+	lib.MustResolveProject()
+
+	if lib.CurrentPlanId == "" {
+		term.OutputNoCurrentPlanErrorAndExit()
+	}
+
+	term.StartSpinner("")
+	apiErr := api.Client.ResumePlan(lib.CurrentPlanId, lib.CurrentBranch, shared.ResumePlanRequest{
+		ConnectStream: true,
+		AuthVars:      lib.MustVerifyAuthVars(auth.Current.IntegratedModelsMode),
+		SessionId:     os.Getenv("PLANDEX_REPL_SESSION_ID"),
+	}, stream.OnStreamPlan)
+	term.StopSpinner()
+
+	if apiErr != nil {
+		if apiErr.Msg == shared.NothingToResumeErr {
+			fmt.Println("🤷‍♂️ Nothing to resume")
+			fmt.Println()
+			term.PrintCmds("", "log", "tell", "continue")
+			return
+		}
+		term.OutputErrorAndExit("Error resuming plan: %v", apiErr.Msg)
+	}
+
+	go func() {
+		err := streamtui.StartStreamUI("", false, true)
+
+		if err != nil {
+			term.OutputErrorAndExit("Error starting stream UI", err)
+		}
+
+		fmt.Println()
+		term.PrintCmds("", "diff", "diff --ui", "apply", "reject", "log")
+
+		os.Exit(0)
+	}()
+
+	// Wait for the stream to finish
+	select {}
+}
@@ -20,6 +20,8 @@ var contextRmCmd = &cobra.Command{
 	Aliases: []string{"remove", "unload"},
 	Short:   "Remove context",
 	Long: `Remove context by index, range, name, or glob.
+
+	Pinned context is only removed by index or exact name, never by glob or parent directory.
 	
 	plandex rm 1 # Remove by index in the 'plandex ls' list
 	plandex rm 1-3
@@ -57,7 +59,7 @@ func contextRm(cmd *cobra.Command, args []string) {
 			if context.Name == id || context.FilePath == id || context.Url == id {
 				deleteIds[context.Id] = true
 				break
-			} else if context.FilePath != "" {
+			} else if context.FilePath != "" && !context.Pinned {
 				// Check if id is a glob pattern
 				matched, err := filepath.Match(id, context.FilePath)
 				if err != nil {
@@ -243,6 +243,21 @@ func updateConfig(args []string, originalConfig *shared.PlanConfig) (string, *sh
 				return "", nil
 			}
 			cfgSetting.IntSetter(&config, n)
+		} else if cfgSetting.FloatSetter != nil {
+			value, err := term.GetRequiredUserStringInput(fmt.Sprintf("Set %s (number)", cfgSetting.Name))
+			if err != nil {
+				if err.Error() == "interrupt" {
+					return "", nil
+				}
+				term.OutputErrorAndExit("Error getting value: %v", err)
+				return "", nil
+			}
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				term.OutputErrorAndExit("Invalid number value for %s (%s)", cfgSetting.Name, value)
+				return "", nil
+			}
+			cfgSetting.FloatSetter(&config, n)
 		} else if cfgSetting.StringSetter != nil {
 			var selection string
 			var err error
@@ -301,6 +316,13 @@ func updateConfig(args []string, originalConfig *shared.PlanConfig) (string, *sh
 				return "", nil
 			}
 			cfgSetting.IntSetter(&config, n)
+		} else if cfgSetting.FloatSetter != nil {
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				term.OutputErrorAndExit("Invalid number value for %s (%s)", cfgSetting.Name, value)
+				return "", nil
+			}
+			cfgSetting.FloatSetter(&config, n)
 		} else if cfgSetting.StringSetter != nil {
 			cfgSetting.StringSetter(&config, value)
 		} else if cfgSetting.EditorSetter != nil {
@@ -359,7 +381,7 @@ func removeMapIfNeeded(originalConfig, updatedConfig *shared.PlanConfig) {
 
 		if err == nil {
 			for _, c := range context {
-				if c.ContextType == shared.ContextMapType && (c.AutoLoaded || c.FilePath == ".") {
+				if c.ContextType == shared.ContextMapType && !c.Pinned && (c.AutoLoaded || c.FilePath == ".") {
 					res, err := api.Client.DeleteContext(lib.CurrentPlanId, lib.CurrentBranch, shared.DeleteContextRequest{
 						Ids: map[string]bool{c.Id: true},
 					})
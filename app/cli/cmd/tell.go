@@ -36,6 +36,7 @@ func init() {
 	initExecFlags(tellCmd, initExecFlagsParams{})
 
 	tellCmd.Flags().BoolVar(&isImplementationOfChat, "from-chat", false, "Begin implementation based on conversation so far")
+	tellCmd.Flags().BoolVar(&tellQueue, "queue", false, "Queue the prompt to run in the background once the plan's current stream finishes")
 }
 
 func doTell(cmd *cobra.Command, args []string) {
@@ -59,6 +60,7 @@ func doTell(cmd *cobra.Command, args []string) {
 
 	tellFlags := types.TellFlags{
 		TellBg:                 tellBg,
+		TellQueue:              tellQueue,
 		TellStop:               tellStop,
 		TellNoBuild:            tellNoBuild,
 		AutoContext:            tellAutoContext,
@@ -35,6 +35,7 @@ var creditsSession bool
 var creditsToday bool
 var creditsMonth bool
 var creditsCurrentPlan bool
+var usageAllUsers bool
 
 var usageCmd = &cobra.Command{
 	Use:   "usage",
@@ -54,9 +55,21 @@ func init() {
 	usageCmd.Flags().BoolVar(&creditsToday, "today", false, "Show usage for today")
 	usageCmd.Flags().BoolVar(&creditsMonth, "month", false, "Show usage for current billing month")
 	usageCmd.Flags().BoolVar(&creditsCurrentPlan, "plan", false, "Show usage for the current plan")
+	usageCmd.Flags().BoolVar(&usageAllUsers, "org", false, "Show usage for everyone in the org (requires billing permission)")
 }
 
 func usage(cmd *cobra.Command, args []string) {
+	auth.MustResolveAuthWithOrg()
+
+	// credits only apply to Plandex Cloud in integrated models mode--otherwise the server tracks usage and cost itself
+	if !(auth.Current.IsCloud && auth.Current.IntegratedModelsMode) {
+		if showUsageLog {
+			term.OutputErrorAndExit("The usage log is only available with Plandex Cloud integrated models. Use 'plandex usage' for a breakdown of usage and cost.")
+		}
+		showModelUsage()
+		return
+	}
+
 	if showUsageLog {
 		showLog(cmd, args)
 	} else {
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"plandex-cli/api"
+	"plandex-cli/lib"
+	"plandex-cli/term"
+	shared "plandex-shared"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/shopspring/decimal"
+)
+
+// showModelUsage reports usage tracked by the server for each model request, with cost estimated from model pricing
+func showModelUsage() {
+	if !(creditsSession || creditsToday || creditsMonth || creditsCurrentPlan) {
+		if os.Getenv("PLANDEX_REPL_SESSION_ID") != "" {
+			creditsSession = true
+		} else {
+			creditsToday = true
+		}
+	}
+
+	var sessionId string
+	if creditsSession {
+		sessionId = os.Getenv("PLANDEX_REPL_SESSION_ID")
+		if sessionId == "" {
+			term.OutputErrorAndExit("Session ID is not set. The --session flag should be used in the Plandex REPL.")
+		}
+	}
+
+	now := time.Now()
+	var since *time.Time
+	if creditsToday {
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		since = &midnight
+	} else if creditsMonth {
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		since = &monthStart
+	}
+
+	var planId string
+	var currentPlanName string
+	if creditsCurrentPlan {
+		lib.MustResolveProject()
+		planId = lib.CurrentPlanId
+		plan, apiErr := api.Client.GetPlan(planId)
+		if apiErr != nil {
+			term.OutputErrorAndExit("Error getting plan: %v", apiErr)
+		}
+		currentPlanName = plan.Name
+	}
+
+	term.StartSpinner("")
+	res, apiErr := api.Client.GetUsageSummary(shared.UsageSummaryRequest{
+		PlanId:    planId,
+		SessionId: sessionId,
+		Since:     since,
+		AllUsers:  usageAllUsers,
+	})
+	term.StopSpinner()
+
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error getting usage summary: %v", apiErr.Msg)
+	}
+
+	lbl := "Usage"
+	if usageAllUsers {
+		lbl = "Org Usage"
+	}
+	if creditsSession {
+		lbl += " This Session"
+	} else if creditsToday {
+		lbl += " Today"
+	} else if creditsMonth {
+		lbl += fmt.Sprintf(" This Month (since %s)", since.Format("Jan 2"))
+	} else if creditsCurrentPlan {
+		lbl += fmt.Sprintf(" On Plan 📋 %s", currentPlanName)
+	}
+
+	if res.Total.NumRequests == 0 {
+		fmt.Println("🤷‍♂️ No " + strings.ToLower(lbl))
+		return
+	}
+
+	builder := strings.Builder{}
+
+	color.New(color.Bold, term.ColorHiCyan).Fprintln(&builder, "📊 "+lbl)
+	fmt.Fprintln(&builder)
+
+	table := tablewriter.NewWriter(&builder)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"💸 Spent", "📨 Requests", "🪙 Input", "🪙 Output", "🎯 Cached"})
+	table.Append([]string{
+		formatUsageCost(res.Total.Cost),
+		fmt.Sprintf("%d", res.Total.NumRequests),
+		fmt.Sprintf("%d", res.Total.InputTokens),
+		fmt.Sprintf("%d", res.Total.OutputTokens),
+		fmt.Sprintf("%d", res.Total.CachedTokens),
+	})
+	table.Render()
+	fmt.Fprintln(&builder)
+
+	if !creditsCurrentPlan {
+		renderUsageBreakdown(&builder, "📋 Plan", res.ByPlanId, func(key string) string {
+			if name, ok := res.PlanNamesById[key]; ok {
+				return name
+			}
+			return "(deleted plan)"
+		})
+	}
+	renderUsageBreakdown(&builder, "🎭 Role", res.ByRole, nil)
+	renderUsageBreakdown(&builder, "🤖 Model", res.ByModel, nil)
+	renderUsageBreakdown(&builder, "⚡️ Purpose", res.ByPurpose, nil)
+	if len(res.ByDay) > 1 {
+		renderUsageBreakdown(&builder, "📅 Day", res.ByDay, nil)
+	}
+
+	if len(res.UnpricedModels) > 0 {
+		fmt.Fprintf(&builder, "⚠️  No pricing for %s, so their cost isn't included. Add 'pricing' to custom models with 'plandex models custom'.\n\n", strings.Join(res.UnpricedModels, ", "))
+	}
+
+	term.PageOutput(builder.String())
+
+	term.PrintCmds("", "usage")
+}
+
+func renderUsageBreakdown(builder *strings.Builder, header string, rows []shared.UsageBreakdown, getLabel func(key string) string) {
+	if len(rows) == 0 {
+		return
+	}
+
+	table := tablewriter.NewWriter(builder)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{header, "💸 Spent", "📨 Requests", "🪙 Input", "🪙 Output"})
+
+	for _, row := range rows {
+		label := row.Key
+		if getLabel != nil {
+			label = getLabel(row.Key)
+		}
+		table.Append([]string{
+			label,
+			formatUsageCost(row.Cost),
+			fmt.Sprintf("%d", row.NumRequests),
+			fmt.Sprintf("%d", row.InputTokens),
+			fmt.Sprintf("%d", row.OutputTokens),
+		})
+	}
+
+	table.Render()
+	fmt.Fprintln(builder)
+}
+
+func formatUsageCost(cost float64) string {
+	return formatSpend(decimal.NewFromFloat(cost))
+}
@@ -0,0 +1,161 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func readZipFile(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, nil
+}
+
+func xmlAttr(el xml.StartElement, local string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// extractDocx reads paragraphs and tables from word/document.xml. Docx files don't store page numbers, so
+// page markers are based on explicit and last-rendered page breaks.
+func extractDocx(content []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open docx: %v", err)
+	}
+
+	doc, err := readZipFile(zr, "word/document.xml")
+	if err != nil {
+		return "", fmt.Errorf("failed to read document.xml: %v", err)
+	}
+	if doc == nil {
+		return "", fmt.Errorf("document.xml not found")
+	}
+
+	var b strings.Builder
+	page := 1
+	b.WriteString("[page 1]\n")
+
+	var para strings.Builder
+	var headingLevel string
+	var tableDepth int
+	var rows [][]string
+	var row []string
+	var cell []string
+
+	nextPage := func() {
+		page++
+		if tableDepth == 0 {
+			para.WriteString(fmt.Sprintf("\n\n[page %d]\n", page))
+		}
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse document.xml: %v", err)
+		}
+
+		switch el := token.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					rows = nil
+				}
+			case "tr":
+				if tableDepth == 1 {
+					row = nil
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell = nil
+				}
+			case "p":
+				para.Reset()
+				headingLevel = ""
+			case "pStyle":
+				style := xmlAttr(el, "val")
+				if strings.HasPrefix(style, "Heading") {
+					headingLevel = strings.TrimPrefix(style, "Heading")
+				} else if style == "Title" {
+					headingLevel = "1"
+				}
+			case "t":
+				var text string
+				if err := decoder.DecodeElement(&text, &el); err != nil {
+					return "", fmt.Errorf("failed to parse text: %v", err)
+				}
+				para.WriteString(text)
+			case "tab":
+				para.WriteString("\t")
+			case "br":
+				if xmlAttr(el, "type") == "page" {
+					nextPage()
+				} else {
+					para.WriteString("\n")
+				}
+			case "lastRenderedPageBreak":
+				nextPage()
+			}
+
+		case xml.EndElement:
+			switch el.Name.Local {
+			case "p":
+				text := para.String()
+				if tableDepth > 0 {
+					if strings.TrimSpace(text) != "" {
+						cell = append(cell, strings.TrimSpace(text))
+					}
+					continue
+				}
+				if headingLevel != "" {
+					level := 1
+					fmt.Sscanf(headingLevel, "%d", &level)
+					if level < 1 || level > 6 {
+						level = 1
+					}
+					text = strings.Repeat("#", level) + " " + text
+				}
+				b.WriteString(text + "\n\n")
+			case "tc":
+				if tableDepth == 1 {
+					row = append(row, strings.Join(cell, " "))
+				}
+			case "tr":
+				if tableDepth == 1 {
+					rows = append(rows, row)
+				}
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 && len(rows) > 0 {
+					b.WriteString(FormatTable(rows) + "\n")
+				}
+			}
+		}
+	}
+
+	return b.String(), nil
+}
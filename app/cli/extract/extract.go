@@ -0,0 +1,96 @@
+package extract
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Extracts readable text from documents that can't be loaded into context as-is.
+// Output includes [page N], [sheet Name] and [cell N] markers so the model can refer back to the source.
+
+var extractorsByExt = map[string]func(content []byte) (string, error){
+	".pdf":   extractPdf,
+	".docx":  extractDocx,
+	".xlsx":  extractXlsx,
+	".csv":   extractCsv,
+	".tsv":   extractTsv,
+	".ipynb": extractNotebook,
+}
+
+// binary formats that can't be edited as text -- local files in these formats are loaded as read-only documents. CSV, TSV and notebooks are text, so local files load as-is and stay editable, and their extractors are only used for pages loaded from urls.
+var documentExts = map[string]bool{
+	".pdf":  true,
+	".docx": true,
+	".xlsx": true,
+}
+
+func IsSupported(path string) bool {
+	_, ok := extractorsByExt[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+func IsDocument(path string) bool {
+	return documentExts[strings.ToLower(filepath.Ext(path))]
+}
+
+func File(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the file %s: %v", path, err)
+	}
+	return Bytes(path, content)
+}
+
+// Bytes extracts text using the extension of name to determine the format
+func Bytes(name string, content []byte) (string, error) {
+	extractor, ok := extractorsByExt[strings.ToLower(filepath.Ext(name))]
+	if !ok {
+		return "", fmt.Errorf("unsupported document type: %s", name)
+	}
+
+	text, err := extractor(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract text from %s: %v", name, err)
+	}
+
+	return text, nil
+}
+
+// FormatTable renders rows as a markdown table, using the first row as the header
+func FormatTable(rows [][]string) string {
+	numCols := 0
+	for _, row := range rows {
+		if len(row) > numCols {
+			numCols = len(row)
+		}
+	}
+	if numCols == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for i := 0; i < numCols; i++ {
+			var cell string
+			if i < len(row) {
+				cell = row[i]
+			}
+			cell = strings.ReplaceAll(cell, "|", "\\|")
+			cell = strings.ReplaceAll(cell, "\r\n", " ")
+			cell = strings.ReplaceAll(cell, "\n", " ")
+			b.WriteString(" " + strings.TrimSpace(cell) + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	writeRow(rows[0])
+	b.WriteString("|" + strings.Repeat(" --- |", numCols) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+
+	return b.String()
+}
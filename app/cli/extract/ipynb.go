@@ -0,0 +1,123 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// notebook sources and text outputs can be either a string or a list of lines
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = notebookText(s)
+		return nil
+	}
+
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return err
+	}
+	*t = notebookText(strings.Join(lines, ""))
+	return nil
+}
+
+type notebook struct {
+	Metadata struct {
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []struct {
+		CellType string           `json:"cell_type"`
+		Source   notebookText     `json:"source"`
+		Outputs  []notebookOutput `json:"outputs"`
+	} `json:"cells"`
+}
+
+type notebookOutput struct {
+	OutputType string                     `json:"output_type"`
+	Text       notebookText               `json:"text"`
+	Data       map[string]json.RawMessage `json:"data"`
+	Ename      string                     `json:"ename"`
+	Evalue     string                     `json:"evalue"`
+	Traceback  []string                   `json:"traceback"`
+}
+
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+
+// text mime types in order of preference - anything else (images, widgets, etc.) is omitted
+var notebookTextMimeTypes = []string{"text/markdown", "text/plain"}
+
+func extractNotebook(content []byte) (string, error) {
+	var nb notebook
+	if err := json.Unmarshal(content, &nb); err != nil {
+		return "", fmt.Errorf("failed to parse notebook: %v", err)
+	}
+
+	lang := nb.Metadata.LanguageInfo.Name
+
+	var b strings.Builder
+	for i, cell := range nb.Cells {
+		num := i + 1
+		source := strings.TrimRight(string(cell.Source), "\n")
+
+		switch cell.CellType {
+		case "code":
+			fmt.Fprintf(&b, "[cell %d: code]\n```%s\n%s\n```\n\n", num, lang, source)
+		default:
+			fmt.Fprintf(&b, "[cell %d: %s]\n%s\n\n", num, cell.CellType, source)
+		}
+
+		var outputs []string
+		for _, output := range cell.Outputs {
+			if text := formatNotebookOutput(output); text != "" {
+				outputs = append(outputs, text)
+			}
+		}
+		if len(outputs) > 0 {
+			fmt.Fprintf(&b, "[cell %d: output]\n```\n%s\n```\n\n", num, strings.Join(outputs, "\n"))
+		}
+	}
+
+	return b.String(), nil
+}
+
+func formatNotebookOutput(output notebookOutput) string {
+	switch output.OutputType {
+	case "stream":
+		return strings.TrimRight(string(output.Text), "\n")
+	case "error":
+		lines := []string{fmt.Sprintf("%s: %s", output.Ename, output.Evalue)}
+		for _, line := range output.Traceback {
+			lines = append(lines, ansiRegex.ReplaceAllString(line, ""))
+		}
+		return strings.Join(lines, "\n")
+	case "execute_result", "display_data":
+		for _, mimeType := range notebookTextMimeTypes {
+			raw, ok := output.Data[mimeType]
+			if !ok {
+				continue
+			}
+			var text notebookText
+			if err := json.Unmarshal(raw, &text); err == nil {
+				return strings.TrimRight(string(text), "\n")
+			}
+		}
+
+		var omitted []string
+		for mimeType := range output.Data {
+			omitted = append(omitted, mimeType)
+		}
+		if len(omitted) == 0 {
+			return ""
+		}
+		sort.Strings(omitted)
+		return fmt.Sprintf("[%s output omitted]", strings.Join(omitted, ", "))
+	}
+	return ""
+}
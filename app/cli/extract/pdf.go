@@ -0,0 +1,46 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+func extractPdf(content []byte) (res string, err error) {
+	// the pdf reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to parse pdf: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open pdf: %v", err)
+	}
+
+	var b strings.Builder
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		fonts := map[string]*pdf.Font{}
+		for _, name := range page.Fonts() {
+			font := page.Font(name)
+			fonts[name] = &font
+		}
+
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return "", fmt.Errorf("failed to read page %d: %v", i, err)
+		}
+
+		fmt.Fprintf(&b, "[page %d]\n%s\n\n", i, strings.TrimSpace(text))
+	}
+
+	return b.String(), nil
+}
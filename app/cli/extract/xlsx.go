@@ -0,0 +1,197 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		Id   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRels struct {
+	Rels []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// rich text strings are split into runs, plain strings only have t
+type xlsxRichString struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (s xlsxRichString) String() string {
+	if len(s.Runs) == 0 {
+		return s.T
+	}
+	var b strings.Builder
+	for _, r := range s.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichString `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref       string         `xml:"r,attr"`
+			Type      string         `xml:"t,attr"`
+			Value     string         `xml:"v"`
+			InlineStr xlsxRichString `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func extractXlsx(content []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open xlsx: %v", err)
+	}
+
+	var workbook xlsxWorkbook
+	if err := unmarshalZipXml(zr, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+
+	var rels xlsxRels
+	if err := unmarshalZipXml(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	targetsById := map[string]string{}
+	for _, rel := range rels.Rels {
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targetsById[rel.Id] = target
+	}
+
+	var sharedStrings xlsxSharedStrings
+	if err := unmarshalZipXml(zr, "xl/sharedStrings.xml", &sharedStrings); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, sheetRef := range workbook.Sheets {
+		target, ok := targetsById[sheetRef.Id]
+		if !ok {
+			continue
+		}
+
+		var sheet xlsxSheet
+		if err := unmarshalZipXml(zr, target, &sheet); err != nil {
+			return "", err
+		}
+
+		var rows [][]string
+		for _, r := range sheet.Rows {
+			var row []string
+			for i, c := range r.Cells {
+				col := i
+				if c.Ref != "" {
+					col = xlsxColumnIndex(c.Ref)
+				}
+				for len(row) <= col {
+					row = append(row, "")
+				}
+
+				switch c.Type {
+				case "s":
+					var idx int
+					if _, err := fmt.Sscanf(c.Value, "%d", &idx); err == nil && idx >= 0 && idx < len(sharedStrings.Items) {
+						row[col] = sharedStrings.Items[idx].String()
+					}
+				case "inlineStr":
+					row[col] = c.InlineStr.String()
+				case "b":
+					if c.Value == "1" {
+						row[col] = "TRUE"
+					} else {
+						row[col] = "FALSE"
+					}
+				default:
+					row[col] = c.Value
+				}
+			}
+			rows = append(rows, row)
+		}
+
+		fmt.Fprintf(&b, "[sheet %s]\n", sheetRef.Name)
+		if len(rows) == 0 {
+			b.WriteString("[empty]\n\n")
+			continue
+		}
+		b.WriteString(FormatTable(rows) + "\n")
+	}
+
+	return b.String(), nil
+}
+
+func unmarshalZipXml(zr *zip.Reader, name string, v any) error {
+	bytes, err := readZipFile(zr, name)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	if bytes == nil {
+		// missing optional parts (like sharedStrings.xml) are fine
+		return nil
+	}
+	if err := xml.Unmarshal(bytes, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", name, err)
+	}
+	return nil
+}
+
+// xlsxColumnIndex converts a cell reference like "AB12" to a zero-based column index
+func xlsxColumnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+func extractCsv(content []byte) (string, error) {
+	return extractDelimited(content, ',')
+}
+
+func extractTsv(content []byte) (string, error) {
+	return extractDelimited(content, '\t')
+}
+
+func extractDelimited(content []byte, delimiter rune) (string, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse rows: %v", err)
+	}
+
+	if len(rows) == 0 {
+		return "", nil
+	}
+
+	return FormatTable(rows), nil
+}
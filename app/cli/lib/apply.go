@@ -14,6 +14,7 @@ import (
 	"plandex-cli/fs"
 	"plandex-cli/term"
 	"plandex-cli/types"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -135,10 +136,15 @@ func MustApplyPlanAttempt(
 	currentPlanFiles := currentPlanState.CurrentPlanFiles
 	isRepo := fs.ProjectRootIsGitRepo()
 
-	toApply := currentPlanFiles.Files
-	toRemove := currentPlanFiles.Removed
+	toApply, toRemove, skippedDocuments := withoutDocumentPaths(currentPlanState, currentPlanFiles.Files, currentPlanFiles.Removed)
 	hasExec := currentPlanFiles.Files["_apply.sh"] != ""
 
+	if len(skippedDocuments) > 0 {
+		term.StopSpinner()
+		fmt.Println("📑 Skipping changes to documents loaded as extracted text: " + strings.Join(skippedDocuments, ", "))
+		term.ResumeSpinner()
+	}
+
 	log.Printf("Files to apply: %d, Has exec script: %v", len(toApply), hasExec)
 
 	if len(toApply) == 0 && !hasExec {
@@ -818,3 +824,35 @@ func Rollback(rollbackPlan *types.ApplyRollbackPlan, msg bool) error {
 	}
 	return nil
 }
+
+// withoutDocumentPaths drops documents from the files to apply or remove -- documents are in context as their extracted text, so writing a change would replace the original file with markdown
+func withoutDocumentPaths(currentPlanState *shared.CurrentPlanState, toApply map[string]string, toRemove map[string]bool) (map[string]string, map[string]bool, []string) {
+	var skipped []string
+
+	isDocument := func(path string) bool {
+		context := currentPlanState.ContextsByPath[path]
+		return context != nil && context.ContextType == shared.ContextDocumentType
+	}
+
+	filteredApply := make(map[string]string, len(toApply))
+	for path, content := range toApply {
+		if isDocument(path) {
+			skipped = append(skipped, path)
+			continue
+		}
+		filteredApply[path] = content
+	}
+
+	filteredRemove := make(map[string]bool, len(toRemove))
+	for path, remove := range toRemove {
+		if isDocument(path) {
+			skipped = append(skipped, path)
+			continue
+		}
+		filteredRemove[path] = remove
+	}
+
+	sort.Strings(skipped)
+
+	return filteredApply, filteredRemove, skipped
+}
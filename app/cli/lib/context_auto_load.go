@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"plandex-cli/api"
+	"plandex-cli/extract"
 	"plandex-cli/types"
 	shared "plandex-shared"
 	"sync"
@@ -86,6 +87,8 @@ func AutoLoadContextFiles(ctx context.Context, files []string) (string, error) {
 			isImage := shared.IsImageFile(path)
 			if isImage {
 				contextType = shared.ContextImageType
+			} else if extract.IsDocument(path) {
+				contextType = shared.ContextDocumentType
 			} else {
 				contextType = shared.ContextFileType
 			}
@@ -98,6 +101,12 @@ func AutoLoadContextFiles(ctx context.Context, files []string) (string, error) {
 			var body string
 			if isImage {
 				body = base64.StdEncoding.EncodeToString(b)
+			} else if extract.IsDocument(path) {
+				body, err = extract.Bytes(path, b)
+				if err != nil {
+					errCh <- err
+					return
+				}
 			} else {
 				body = string(shared.NormalizeEOL(b))
 			}
@@ -27,6 +27,9 @@ func GetContextLabelAndIcon(contextType shared.ContextType) (string, string) {
 	case shared.ContextMapType:
 		icon = "🗺️ "
 		lbl = "map"
+	case shared.ContextDocumentType:
+		icon = "📑"
+		lbl = "doc"
 	}
 
 	return lbl, icon
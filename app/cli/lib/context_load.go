@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"plandex-cli/api"
 	"plandex-cli/auth"
+	"plandex-cli/extract"
 	"plandex-cli/fs"
 	"plandex-cli/term"
 	"plandex-cli/types"
@@ -65,6 +66,7 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 			OpenAIOrgId: os.Getenv("OPENAI_ORG_ID"),
 			SessionId:   params.SessionId,
 			AutoLoaded:  params.AutoLoaded,
+			Pinned:      params.Pinned,
 		})
 	}
 
@@ -84,6 +86,7 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 				OpenAIOrgId: os.Getenv("OPENAI_ORG_ID"),
 				SessionId:   params.SessionId,
 				AutoLoaded:  params.AutoLoaded,
+				Pinned:      params.Pinned,
 			})
 		}
 	}
@@ -132,7 +135,7 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 	existsByComposite := make(map[string]*shared.Context)
 	for _, context := range existingContexts {
 		switch context.ContextType {
-		case shared.ContextFileType, shared.ContextDirectoryTreeType, shared.ContextMapType, shared.ContextImageType:
+		case shared.ContextFileType, shared.ContextDocumentType, shared.ContextDirectoryTreeType, shared.ContextMapType, shared.ContextImageType:
 			existsByComposite[strings.Join([]string{string(context.ContextType), context.FilePath}, "|")] = context
 		case shared.ContextURLType:
 			existsByComposite[strings.Join([]string{string(context.ContextType), context.Url}, "|")] = context
@@ -317,6 +320,7 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 							FilePath:        inputFilePath,
 							ForceSkipIgnore: params.ForceSkipIgnore,
 							AutoLoaded:      params.AutoLoaded,
+							Pinned:          params.Pinned,
 						})
 						contextMu.Unlock()
 
@@ -432,6 +436,8 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 						contextType = shared.ContextImageType
 					} else if params.DefsOnly {
 						contextType = shared.ContextMapType
+					} else if extract.IsDocument(path) {
+						contextType = shared.ContextDocumentType
 					} else {
 						contextType = shared.ContextFileType
 					}
@@ -459,6 +465,18 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 						}
 						size = fileInfo.Size()
 
+						// documents are loaded as their extracted text, so size limits apply to the text
+						var extracted string
+						isDocument := contextType == shared.ContextDocumentType
+						if isDocument {
+							extracted, err = extract.File(path)
+							if err != nil {
+								errCh <- err
+								return
+							}
+							size = int64(len(extracted))
+						}
+
 						if !params.DefsOnly && size > shared.MaxContextBodySize {
 							contextMu.Lock()
 							filesSkippedTooLarge = append(filesSkippedTooLarge, filePathWithSize{Path: path, Size: size})
@@ -525,6 +543,19 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 								FilePath:    path,
 								ImageDetail: params.ImageDetail,
 								AutoLoaded:  params.AutoLoaded,
+								Pinned:      params.Pinned,
+							})
+						} else if isDocument {
+							contextMu.Lock()
+							defer contextMu.Unlock()
+
+							loadContextReq = append(loadContextReq, &shared.LoadContextParams{
+								ContextType: shared.ContextDocumentType,
+								Name:        path,
+								Body:        extracted,
+								FilePath:    path,
+								AutoLoaded:  params.AutoLoaded,
+								Pinned:      params.Pinned,
 							})
 						} else {
 							fileContent, err := os.ReadFile(path)
@@ -543,6 +574,7 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 								Body:        string(fileContent),
 								FilePath:    path,
 								AutoLoaded:  params.AutoLoaded,
+								Pinned:      params.Pinned,
 							})
 						}
 
@@ -553,6 +585,30 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 		}
 	}
 
+	// crawled pages are already fetched, so keep their bodies rather than fetching them again below
+	crawledBodies := map[string]string{}
+	if params.CrawlDepth > 0 && len(inputUrls) > 0 {
+		var crawledUrls []string
+		for _, u := range inputUrls {
+			pages, err := url.Crawl(u, url.CrawlParams{
+				Depth:    params.CrawlDepth,
+				MaxPages: params.CrawlMaxPages,
+			})
+			if err != nil {
+				onErr(fmt.Errorf("failed to crawl %s: %v", u, err))
+			}
+
+			for _, page := range pages {
+				if _, ok := crawledBodies[page.Url]; ok {
+					continue
+				}
+				crawledBodies[page.Url] = page.Body
+				crawledUrls = append(crawledUrls, page.Url)
+			}
+		}
+		inputUrls = crawledUrls
+	}
+
 	if len(inputUrls) > 0 {
 		for _, u := range inputUrls {
 			composite := strings.Join([]string{string(shared.ContextURLType), u}, "|")
@@ -566,10 +622,14 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 				sem <- struct{}{}
 				defer func() { <-sem }()
 
-				body, err := url.FetchURLContent(u)
-				if err != nil {
-					errCh <- fmt.Errorf("failed to fetch content from URL %s: %v", u, err)
-					return
+				body, ok := crawledBodies[u]
+				if !ok {
+					var err error
+					body, err = url.FetchURLContent(u)
+					if err != nil {
+						errCh <- fmt.Errorf("failed to fetch content from URL %s: %v", u, err)
+						return
+					}
 				}
 
 				name := url.SanitizeURL(u)
@@ -602,6 +662,7 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 					Body:        body,
 					Url:         u,
 					AutoLoaded:  params.AutoLoaded,
+					Pinned:      params.Pinned,
 				})
 
 				errCh <- nil
@@ -657,6 +718,7 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 				InputSizes:  pathSizes,
 				FilePath:    inputPath,
 				AutoLoaded:  params.AutoLoaded,
+				Pinned:      params.Pinned,
 			})
 
 		}
@@ -689,6 +751,10 @@ func MustLoadContext(resources []string, params *types.LoadContextParams) {
 			didOutputReason = true
 		}
 
+		if params.SkipEmptyExit {
+			return
+		}
+
 		if !didOutputReason {
 			fmt.Println()
 			fmt.Printf("Use %s to load a file or URL:", color.New(color.BgCyan, color.FgHiWhite).Sprint(" plandex load [file-path|url] "))
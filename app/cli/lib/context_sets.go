@@ -0,0 +1,434 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plandex-cli/api"
+	"plandex-cli/fs"
+	"plandex-cli/term"
+	"plandex-cli/types"
+	"sort"
+	"strings"
+	"time"
+
+	shared "plandex-shared"
+
+	"github.com/fatih/color"
+	"github.com/sashabaranov/go-openai"
+)
+
+const ContextSetPrefix = "@"
+
+// ContextSetArgName returns the name of the context set a load argument refers to. An argument starting with @ names a set, unless no set has that name and the argument matches a path on disk (like @types/index.d.ts) -- then it's loaded as a path. Prefixing a path with ./ always loads it as a path.
+func ContextSetArgName(arg string, sets types.ContextSetsByName) (string, bool) {
+	if !strings.HasPrefix(arg, ContextSetPrefix) {
+		return "", false
+	}
+
+	name := strings.TrimPrefix(arg, ContextSetPrefix)
+	if _, ok := sets[name]; ok {
+		return name, true
+	}
+
+	if _, err := os.Stat(arg); err == nil {
+		return "", false
+	}
+
+	if matches, err := filepath.Glob(arg); err == nil && len(matches) > 0 {
+		return "", false
+	}
+
+	return name, true
+}
+
+func getContextSetsPath() (string, error) {
+	if fs.PlandexDir == "" {
+		return "", fmt.Errorf("no plandex project found")
+	}
+	return filepath.Join(fs.PlandexDir, "context-sets.json"), nil
+}
+
+func ReadContextSets() (types.ContextSetsByName, error) {
+	path, err := getContextSetsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	sets := types.ContextSetsByName{}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sets, nil
+		}
+		return nil, fmt.Errorf("error reading context-sets.json: %v", err)
+	}
+
+	err = json.Unmarshal(bytes, &sets)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling context-sets.json: %v", err)
+	}
+
+	return sets, nil
+}
+
+func writeContextSets(sets types.ContextSetsByName) error {
+	path, err := getContextSetsPath()
+	if err != nil {
+		return err
+	}
+
+	bytes, err := json.MarshalIndent(sets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling context sets: %v", err)
+	}
+
+	err = os.WriteFile(path, bytes, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing context-sets.json: %v", err)
+	}
+
+	return nil
+}
+
+// SaveContextSet snapshots the current plan's context under the given name.
+// Piped data is skipped since it has no source to re-read it from.
+func SaveContextSet(name string, contexts []*shared.Context) (*types.ContextSet, error) {
+	sets, err := ReadContextSets()
+	if err != nil {
+		return nil, err
+	}
+
+	ts := time.Now()
+	set := &types.ContextSet{
+		Name:      name,
+		CreatedAt: ts,
+		UpdatedAt: ts,
+	}
+	if existing, ok := sets[name]; ok {
+		set.CreatedAt = existing.CreatedAt
+	}
+
+	for _, context := range contexts {
+		if context.ContextType == shared.ContextPipedDataType {
+			continue
+		}
+
+		entry := &types.ContextSetEntry{
+			ContextType:     context.ContextType,
+			Name:            context.Name,
+			FilePath:        context.FilePath,
+			Url:             context.Url,
+			Sha:             context.Sha,
+			MapShas:         context.MapShas,
+			ForceSkipIgnore: context.ForceSkipIgnore,
+			ImageDetail:     context.ImageDetail,
+			Pinned:          context.Pinned,
+		}
+
+		if context.ContextType == shared.ContextNoteType {
+			res, apiErr := api.Client.GetContextBody(CurrentPlanId, CurrentBranch, context.Id)
+			if apiErr != nil {
+				return nil, fmt.Errorf("error getting note body: %v", apiErr.Msg)
+			}
+			entry.Body = res.Body
+		}
+
+		set.Entries = append(set.Entries, entry)
+	}
+
+	sets[name] = set
+
+	err = writeContextSets(sets)
+	if err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+func DeleteContextSet(name string) (bool, error) {
+	sets, err := ReadContextSets()
+	if err != nil {
+		return false, err
+	}
+
+	if _, ok := sets[name]; !ok {
+		return false, nil
+	}
+
+	delete(sets, name)
+
+	err = writeContextSets(sets)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func ListContextSets() ([]*types.ContextSet, error) {
+	sets, err := ReadContextSets()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*types.ContextSet
+	for _, set := range sets {
+		res = append(res, set)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+type contextSetLoadGroup struct {
+	namesOnly       bool
+	forceSkipIgnore bool
+	imageDetail     openai.ImageURLDetail
+	pinned          bool
+}
+
+// MustLoadContextSet loads every entry of a saved context set that isn't already in context, updates entries that are
+// already loaded but outdated, then reports what changed since the set was saved.
+func MustLoadContextSet(name string, params *types.LoadContextParams) {
+	sets, err := ReadContextSets()
+	if err != nil {
+		term.OutputErrorAndExit("Error reading context sets: %v", err)
+	}
+
+	set, ok := sets[name]
+	if !ok {
+		term.OutputErrorAndExit("No context set named %s", color.New(color.Bold, term.ColorHiCyan).Sprint(ContextSetPrefix+name))
+	}
+
+	term.StartSpinner("")
+	existingContexts, apiErr := api.Client.ListContext(CurrentPlanId, CurrentBranch)
+	term.StopSpinner()
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error listing context: %v", apiErr.Msg)
+	}
+
+	existingByKey := map[string]*shared.Context{}
+	for _, context := range existingContexts {
+		existingByKey[contextSetKey(context.ContextType, context.FilePath, context.Url, context.Sha)] = context
+	}
+
+	var alreadyLoaded []*shared.Context
+	var removed []*types.ContextSetEntry
+	var groups []contextSetLoadGroup
+	resourcesByGroup := map[contextSetLoadGroup][]string{}
+	var maps []*types.ContextSetEntry
+	var notes []*types.ContextSetEntry
+
+	for _, entry := range set.Entries {
+		key := entryContextSetKey(entry)
+		if context, ok := existingByKey[key]; ok {
+			alreadyLoaded = append(alreadyLoaded, context)
+			continue
+		}
+
+		switch entry.ContextType {
+		case shared.ContextNoteType:
+			notes = append(notes, entry)
+			continue
+		case shared.ContextURLType:
+		default:
+			if _, err := os.Stat(entry.FilePath); os.IsNotExist(err) {
+				removed = append(removed, entry)
+				continue
+			}
+		}
+
+		if entry.ContextType == shared.ContextMapType {
+			maps = append(maps, entry)
+			continue
+		}
+
+		group := contextSetLoadGroup{
+			namesOnly:       entry.ContextType == shared.ContextDirectoryTreeType,
+			forceSkipIgnore: entry.ForceSkipIgnore,
+			imageDetail:     entry.ImageDetail,
+			pinned:          entry.Pinned || params.Pinned,
+		}
+		if group.imageDetail == "" {
+			group.imageDetail = params.ImageDetail
+		}
+		if _, ok := resourcesByGroup[group]; !ok {
+			groups = append(groups, group)
+		}
+
+		resource := entry.FilePath
+		if entry.ContextType == shared.ContextURLType {
+			resource = entry.Url
+		}
+		resourcesByGroup[group] = append(resourcesByGroup[group], resource)
+	}
+
+	for _, group := range groups {
+		MustLoadContext(resourcesByGroup[group], &types.LoadContextParams{
+			NamesOnly:         group.namesOnly,
+			ForceSkipIgnore:   group.forceSkipIgnore,
+			ImageDetail:       group.imageDetail,
+			Pinned:            group.pinned,
+			SessionId:         params.SessionId,
+			SkipIgnoreWarning: true,
+			SkipEmptyExit:     true,
+		})
+	}
+
+	// maps can only be loaded one directory at a time
+	for _, entry := range maps {
+		MustLoadContext([]string{entry.FilePath}, &types.LoadContextParams{
+			DefsOnly:      true,
+			Pinned:        entry.Pinned || params.Pinned,
+			SessionId:     params.SessionId,
+			SkipEmptyExit: true,
+		})
+	}
+
+	for _, entry := range notes {
+		MustLoadContext(nil, &types.LoadContextParams{
+			Note:          entry.Body,
+			Pinned:        entry.Pinned || params.Pinned,
+			SessionId:     params.SessionId,
+			SkipEmptyExit: true,
+		})
+	}
+
+	if len(alreadyLoaded) > 0 {
+		paths, err := fs.GetProjectPaths(fs.GetBaseDirForContexts(alreadyLoaded))
+		if err != nil {
+			term.OutputErrorAndExit("Error getting project paths: %v", err)
+		}
+
+		term.StartSpinner("🔬 Checking context...")
+		outdatedRes, err := CheckOutdatedContext(alreadyLoaded, paths)
+		term.StopSpinner()
+		if err != nil {
+			term.OutputErrorAndExit("Failed to check outdated context: %v", err)
+		}
+
+		if len(outdatedRes.UpdatedContexts) > 0 || len(outdatedRes.RemovedContexts) > 0 {
+			_, err = UpdateContextWithOutput(UpdateContextParams{
+				Contexts:    alreadyLoaded,
+				OutdatedRes: *outdatedRes,
+				ReqFn:       outdatedRes.ReqFn,
+			})
+			if err != nil {
+				term.OutputErrorAndExit("Error updating context: %v", err)
+			}
+		}
+
+		for _, context := range outdatedRes.RemovedContexts {
+			for _, entry := range set.Entries {
+				if entryContextSetKey(entry) == contextSetKey(context.ContextType, context.FilePath, context.Url, context.Sha) {
+					removed = append(removed, entry)
+				}
+			}
+		}
+	}
+
+	term.StartSpinner("")
+	loadedContexts, apiErr := api.Client.ListContext(CurrentPlanId, CurrentBranch)
+	term.StopSpinner()
+	if apiErr != nil {
+		term.OutputErrorAndExit("Error listing context: %v", apiErr.Msg)
+	}
+
+	printContextSetChanges(set, loadedContexts, removed)
+}
+
+func printContextSetChanges(set *types.ContextSet, loadedContexts []*shared.Context, removed []*types.ContextSetEntry) {
+	loadedByKey := map[string]*shared.Context{}
+	for _, context := range loadedContexts {
+		loadedByKey[contextSetKey(context.ContextType, context.FilePath, context.Url, context.Sha)] = context
+	}
+
+	var modified []string
+	for _, entry := range set.Entries {
+		if entry.ContextType == shared.ContextNoteType {
+			continue
+		}
+		context, ok := loadedByKey[entryContextSetKey(entry)]
+		if !ok {
+			continue
+		}
+
+		if entry.ContextType == shared.ContextMapType {
+			numChanged := 0
+			for path, sha := range context.MapShas {
+				if entry.MapShas[path] != sha {
+					numChanged++
+				}
+			}
+			for path := range entry.MapShas {
+				if _, ok := context.MapShas[path]; !ok {
+					numChanged++
+				}
+			}
+			if numChanged > 0 {
+				label := "files"
+				if numChanged == 1 {
+					label = "file"
+				}
+				_, icon := context.TypeAndIcon()
+				modified = append(modified, fmt.Sprintf("%s %s (%d mapped %s changed)", icon, context.Name, numChanged, label))
+			}
+			continue
+		}
+
+		if entry.Sha != "" && context.Sha != entry.Sha {
+			_, icon := context.TypeAndIcon()
+			modified = append(modified, fmt.Sprintf("%s %s", icon, context.Name))
+		}
+	}
+
+	fmt.Println()
+
+	if len(modified) == 0 && len(removed) == 0 {
+		fmt.Printf("✅ No changes since %s was saved\n", color.New(color.Bold, term.ColorHiCyan).Sprint(ContextSetPrefix+set.Name))
+		return
+	}
+
+	color.New(term.ColorHiCyan, color.Bold).Printf("Changes since %s was saved 👇\n", ContextSetPrefix+set.Name)
+
+	for _, line := range modified {
+		fmt.Printf("  • %s %s\n", color.New(color.FgHiYellow).Sprint("modified"), line)
+	}
+	for _, entry := range removed {
+		name := entry.Name
+		if name == "" {
+			name = entry.FilePath
+		}
+		fmt.Printf("  • %s %s\n", color.New(color.FgHiRed).Sprint("removed"), name)
+	}
+}
+
+func entryContextSetKey(entry *types.ContextSetEntry) string {
+	sha := entry.Sha
+	if entry.ContextType == shared.ContextNoteType && sha == "" {
+		hash := sha256.Sum256([]byte(entry.Body))
+		sha = hex.EncodeToString(hash[:])
+	}
+	return contextSetKey(entry.ContextType, entry.FilePath, entry.Url, sha)
+}
+
+// notes have no path or url, so they're matched by the sha of their body
+func contextSetKey(contextType shared.ContextType, filePath, url, sha string) string {
+	switch contextType {
+	case shared.ContextURLType:
+		return strings.Join([]string{string(contextType), url}, "|")
+	case shared.ContextNoteType:
+		return strings.Join([]string{string(contextType), sha}, "|")
+	default:
+		return strings.Join([]string{string(contextType), filePath}, "|")
+	}
+}
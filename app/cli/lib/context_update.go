@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"plandex-cli/api"
+	"plandex-cli/extract"
 	"plandex-cli/fs"
 	"plandex-cli/term"
 	"plandex-cli/types"
@@ -364,7 +365,7 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 
 	for _, context := range contexts {
 		switch context.ContextType {
-		case shared.ContextFileType:
+		case shared.ContextFileType, shared.ContextDocumentType:
 			wg.Add(1)
 			go func(ctx *shared.Context) {
 				defer wg.Done()
@@ -381,23 +382,39 @@ func checkOutdatedAndMaybeUpdateContext(doUpdate bool, maybeContexts []*shared.C
 					return
 				}
 
-				fileContent, err := os.ReadFile(ctx.FilePath)
-				if err != nil {
-					mu.Lock()
-					defer mu.Unlock()
-					errs = append(errs, fmt.Errorf("failed to read the file %s: %v", ctx.FilePath, err))
-					return
-				}
-				fileContent = shared.NormalizeEOL(fileContent)
+				var fileContent []byte
+				var size int64
+				if ctx.ContextType == shared.ContextDocumentType {
+					// documents are stored as their extracted text, so compare against that
+					text, err := extract.File(ctx.FilePath)
+					if err != nil {
+						mu.Lock()
+						defer mu.Unlock()
+						errs = append(errs, err)
+						return
+					}
+					fileContent = []byte(text)
+					size = int64(len(fileContent))
+				} else {
+					var err error
+					fileContent, err = os.ReadFile(ctx.FilePath)
+					if err != nil {
+						mu.Lock()
+						defer mu.Unlock()
+						errs = append(errs, fmt.Errorf("failed to read the file %s: %v", ctx.FilePath, err))
+						return
+					}
+					fileContent = shared.NormalizeEOL(fileContent)
 
-				fileInfo, err := os.Stat(ctx.FilePath)
-				if err != nil {
-					mu.Lock()
-					defer mu.Unlock()
-					errs = append(errs, fmt.Errorf("failed to get file info for %s: %v", ctx.FilePath, err))
-					return
+					fileInfo, err := os.Stat(ctx.FilePath)
+					if err != nil {
+						mu.Lock()
+						defer mu.Unlock()
+						errs = append(errs, fmt.Errorf("failed to get file info for %s: %v", ctx.FilePath, err))
+						return
+					}
+					size = fileInfo.Size()
 				}
-				size := fileInfo.Size()
 
 				// Individual skip checks
 				if size > shared.MaxContextBodySize {
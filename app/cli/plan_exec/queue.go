@@ -0,0 +1,62 @@
+package plan_exec
+
+import (
+	"fmt"
+	"net/http"
+	"plandex-cli/api"
+	"plandex-cli/stream"
+	"time"
+
+	shared "plandex-shared"
+)
+
+const queuedPromptPollInterval = time.Second
+
+// the server claims a queued prompt just before it starts its stream, so a connect right after can briefly find nothing to attach to
+const queuedPromptConnectTries = 5
+
+// waitForQueuedPrompt waits for a queued prompt to leave the queue and attaches to the stream it started. Returns false with no error if the stream already finished (or the prompt was removed) before it could be attached to -- its changes are then pending like any other finished stream.
+func waitForQueuedPrompt(params ExecParams, queuedPromptId string) (bool, *shared.ApiError) {
+	for {
+		prompts, apiErr := api.Client.ListQueuedPrompts(params.CurrentPlanId, params.CurrentBranch)
+		if apiErr != nil {
+			return false, apiErr
+		}
+
+		var queued *shared.QueuedPrompt
+		for _, p := range prompts {
+			if p.Id == queuedPromptId {
+				queued = p
+				break
+			}
+		}
+
+		if queued == nil {
+			break
+		}
+
+		if queued.Interrupted {
+			return false, &shared.ApiError{Msg: "queued prompt was interrupted before it started"}
+		}
+
+		time.Sleep(queuedPromptPollInterval)
+	}
+
+	// the stream UI starts fresh, so it needs the full state of the plan rather than a replay
+	api.ClearLastStreamEventId(params.CurrentPlanId, params.CurrentBranch)
+
+	for i := 0; i < queuedPromptConnectTries; i++ {
+		apiErr := api.Client.ConnectPlan(params.CurrentPlanId, params.CurrentBranch, stream.OnStreamPlan)
+		if apiErr == nil {
+			return true, nil
+		}
+
+		if apiErr.Status != http.StatusNotFound {
+			return false, &shared.ApiError{Msg: fmt.Sprintf("error connecting to queued prompt's stream: %v", apiErr.Msg)}
+		}
+
+		time.Sleep(queuedPromptPollInterval)
+	}
+
+	return false, nil
+}
@@ -30,6 +30,7 @@ func TellPlan(
 ) {
 
 	tellBg := flags.TellBg
+	tellQueue := flags.TellQueue
 	tellStop := flags.TellStop
 	tellNoBuild := flags.TellNoBuild
 	isUserContinue := flags.IsUserContinue
@@ -131,7 +132,7 @@ func TellPlan(
 
 		isGitRepo := fs.ProjectRootIsGitRepo()
 
-		apiErr := api.Client.TellPlan(params.CurrentPlanId, params.CurrentBranch, shared.TellPlanRequest{
+		req := shared.TellPlanRequest{
 			Prompt:                 prompt,
 			ConnectStream:          !tellBg,
 			AutoContinue:           !tellStop,
@@ -148,7 +149,43 @@ func TellPlan(
 			IsImplementationOfChat: isImplementationOfChat,
 			IsGitRepo:              isGitRepo,
 			SessionId:              os.Getenv("PLANDEX_REPL_SESSION_ID"),
-		}, stream.OnStreamPlan)
+		}
+
+		// a queued prompt that loads context or applies changes needs this terminal, so it waits for the prompt to start and attaches to its stream like a normal tell
+		attached := true
+		var apiErr *shared.ApiError
+		if tellQueue {
+			res, queueErr := api.Client.QueuePrompt(params.CurrentPlanId, params.CurrentBranch, req)
+			term.StopSpinner()
+
+			if queueErr != nil {
+				outputPromptIfTell()
+				term.OutputErrorAndExit("Error queuing prompt: %v", queueErr.Msg)
+			}
+
+			if !(autoApply || autoContext) {
+				if res.Position <= 1 {
+					fmt.Println("✅ Prompt queued — it will start in the background as soon as the plan is idle")
+				} else {
+					fmt.Printf("✅ Prompt queued at position %d — it will start in the background when the prompts ahead of it finish\n", res.Position)
+				}
+				fmt.Println()
+				term.PrintCmds("", "queue ls", "ps", "connect")
+
+				return false
+			}
+
+			if res.Position <= 1 {
+				fmt.Println("✅ Prompt queued — waiting for the plan to be idle")
+			} else {
+				fmt.Printf("✅ Prompt queued at position %d — waiting for the prompts ahead of it to finish\n", res.Position)
+			}
+
+			term.StartSpinner("")
+			attached, apiErr = waitForQueuedPrompt(params, res.QueuedPrompt.Id)
+		} else {
+			apiErr = api.Client.TellPlan(params.CurrentPlanId, params.CurrentBranch, req, stream.OnStreamPlan)
+		}
 
 		term.StopSpinner()
 
@@ -182,7 +219,11 @@ func TellPlan(
 			os.Exit(0)
 		}
 
-		if !tellBg {
+		if !tellBg && !attached {
+			// the queued prompt's stream finished before it was attached to
+			term.StopSpinner()
+			close(done)
+		} else if !tellBg {
 			go func() {
 				err := streamtui.StartStreamUI(
 					prompt,
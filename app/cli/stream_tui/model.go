@@ -47,6 +47,8 @@ type streamUIModel struct {
 
 	processing   bool
 	starting     bool
+	modelStatus  string
+	warning      string
 	spinner      spinner.Model
 	buildSpinner spinner.Model
 	sharedTicker *time.Ticker
@@ -262,7 +262,17 @@ func (m *streamUIModel) getViewportDimensions() (int, int) {
 		processingHeight = lipgloss.Height(m.renderProcessing())
 	}
 
-	maxViewportHeight := h - (helpHeight + processingHeight + buildHeight)
+	var statusHeight int
+	if m.modelStatus != "" {
+		statusHeight = lipgloss.Height(m.renderModelStatus())
+	}
+
+	var warningHeight int
+	if m.warning != "" {
+		warningHeight = lipgloss.Height(m.renderWarning())
+	}
+
+	maxViewportHeight := h - (helpHeight + processingHeight + statusHeight + warningHeight + buildHeight)
 	if maxViewportHeight < 0 {
 		maxViewportHeight = 0
 	}
@@ -507,6 +517,24 @@ func (m *streamUIModel) streamUpdate(msg *shared.StreamMessage, deferUIUpdate bo
 			}),
 		)
 
+	case shared.StreamMessageModelStatus:
+		m.updateState(func() {
+			m.modelStatus = msg.ModelStatus
+		})
+		if !deferUIUpdate {
+			m.updateViewportDimensions()
+		}
+		return m, m.Tick()
+
+	case shared.StreamMessageWarning:
+		m.updateState(func() {
+			m.warning = msg.Warning
+		})
+		if !deferUIUpdate {
+			m.updateViewportDimensions()
+		}
+		return m, m.Tick()
+
 	case shared.StreamMessageError:
 		log.Println("Stream message error:", spew.Sdump(msg))
 
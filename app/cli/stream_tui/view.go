@@ -27,6 +27,12 @@ func (m streamUIModel) View() string {
 	if m.processing || m.starting {
 		views = append(views, m.renderProcessing())
 	}
+	if m.modelStatus != "" {
+		views = append(views, m.renderModelStatus())
+	}
+	if m.warning != "" {
+		views = append(views, m.renderWarning())
+	}
 	if m.building {
 		views = append(views, m.renderBuild())
 	}
@@ -66,6 +72,16 @@ func (m streamUIModel) renderProcessing() string {
 	}
 }
 
+func (m streamUIModel) renderModelStatus() string {
+	style := lipgloss.NewStyle().Width(m.width).Foreground(lipgloss.Color(helpTextColor))
+	return style.Render(" ⏳ " + m.modelStatus)
+}
+
+func (m streamUIModel) renderWarning() string {
+	style := lipgloss.NewStyle().Width(m.width).Foreground(lipgloss.Color(helpTextColor))
+	return style.Render(" ⚠️  " + m.warning)
+}
+
 func (m streamUIModel) renderBuild() string {
 	return m.doRenderBuild(false)
 }
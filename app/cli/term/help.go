@@ -75,10 +75,17 @@ var CliCommands = []CmdConfig{
 	{"load", "l", "load files/dirs/urls/notes/images or pipe data into context", true},
 	{"ls", "", "list everything in context", true},
 	{"rm", "", "remove context by index, range, name, or glob", true},
-	{"clear", "", "remove all context", true},
+	{"clear", "", "remove all context except pinned context", true},
+	{"pin", "", "pin context by index, range, name, or glob so it's always included", true},
+	{"unpin", "", "unpin context by index, range, name, or glob", true},
 	{"update", "u", "update outdated context", true},
 	{"show", "", "show current context by name or index", true},
 
+	{"context", "", "list saved context sets", true},
+	{"context save", "", "save current context as a named set", true},
+	{"context delete", "", "delete a saved context set", true},
+	{"load @name", "", "load a saved context set and show what changed since it was saved", true},
+
 	{"diff --ui", "", "review pending changes in a browser UI", true},
 	{"diff", "", "review pending changes in 'git diff' format", true},
 	{"diff --plain", "", "review pending changes in 'git diff' format with no color formatting", false},
@@ -140,11 +147,16 @@ var CliCommands = []CmdConfig{
 	{"ps", "", "list active and recently finished plan streams", true},
 	{"stop", "", "stop an active plan stream", true},
 	{"connect", "conn", "connect to an active plan stream", true},
+	{"tell --queue", "", "queue a task to start once the current stream finishes", false},
+	{"queue ls", "", "list prompts queued for the current plan", true},
+	{"queue rm", "", "remove queued prompts by index", true},
 
 	{"sign-in", "", "sign in, accept an invite, or create an account", true},
 	{"invite", "", "invite a user to join your org", true},
 	{"revoke", "", "revoke an invite or remove a user from your org", true},
 	{"users", "", "list users and pending invites in your org", true},
+	{"admin locks", "", "list active repo locks and queued repo operations (org owners and admins)", true},
+	{"admin locks release", "", "force release a stuck repo lock", true},
 
 	{"connect-claude", "", "connect your Claude Pro or Max subscription", true},
 	{"disconnect-claude", "", "disconnect your Claude Pro or Max subscription", true},
@@ -325,7 +337,7 @@ func PrintHelpAllCommands() {
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Streams ")
-	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "ps", "connect", "stop")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "ps", "connect", "stop", "tell --queue", "queue ls", "queue rm")
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Config ")
@@ -346,7 +358,7 @@ func PrintHelpAllCommands() {
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Accounts ")
-	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "sign-in", "invite", "revoke", "users")
+	printCmds(builder, " ", []color.Attribute{color.Bold, ColorHiCyan}, "sign-in", "invite", "revoke", "users", "admin locks", "admin locks release")
 	fmt.Fprintln(builder)
 
 	color.New(color.Bold, color.BgCyan, color.FgHiWhite).Fprintln(builder, " Integrations ")
@@ -39,6 +39,9 @@ type ApiClient interface {
 
 	ListOrgRoles() ([]*shared.OrgRole, *shared.ApiError)
 
+	ListRepoLocks() (*shared.ListRepoLocksResponse, *shared.ApiError)
+	ForceReleaseRepoLock(lockId string, req shared.ForceReleaseRepoLockRequest) (*shared.RepoLock, *shared.ApiError)
+
 	InviteUser(req shared.InviteRequest) *shared.ApiError
 	ListPendingInvites() ([]*shared.Invite, *shared.ApiError)
 	ListAcceptedInvites() ([]*shared.Invite, *shared.ApiError)
@@ -61,6 +64,10 @@ type ApiClient interface {
 
 	TellPlan(planId, branch string, req shared.TellPlanRequest, onStreamPlan OnStreamPlan) *shared.ApiError
 	BuildPlan(planId, branch string, req shared.BuildPlanRequest, onStreamPlan OnStreamPlan) *shared.ApiError
+	ResumePlan(planId, branch string, req shared.ResumePlanRequest, onStreamPlan OnStreamPlan) *shared.ApiError
+	QueuePrompt(planId, branch string, req shared.TellPlanRequest) (*shared.QueuePromptResponse, *shared.ApiError)
+	ListQueuedPrompts(planId, branch string) ([]*shared.QueuedPrompt, *shared.ApiError)
+	DeleteQueuedPrompt(planId, branch, queuedPromptId string) *shared.ApiError
 	RespondMissingFile(planId, branch string, req shared.RespondMissingFileRequest) *shared.ApiError
 
 	DeletePlan(planId string) *shared.ApiError
@@ -84,6 +91,7 @@ type ApiClient interface {
 	UpdateContext(planId, branch string, req shared.UpdateContextRequest) (*shared.UpdateContextResponse, *shared.ApiError)
 	DeleteContext(planId, branch string, req shared.DeleteContextRequest) (*shared.DeleteContextResponse, *shared.ApiError)
 	ListContext(planId, branch string) ([]*shared.Context, *shared.ApiError)
+	PinContext(planId, branch string, req shared.PinContextRequest) (*shared.PinContextResponse, *shared.ApiError)
 	LoadCachedFileMap(planId, branch string, req shared.LoadCachedFileMapRequest) (*shared.LoadCachedFileMapResponse, *shared.ApiError)
 
 	ListConvo(planId, branch string) ([]*shared.ConvoMessage, *shared.ApiError)
@@ -117,6 +125,8 @@ type ApiClient interface {
 	GetCreditsSummary(req shared.CreditsLogRequest) (*shared.CreditsSummaryResponse, *shared.ApiError)
 	GetBalance() (decimal.Decimal, *shared.ApiError)
 
+	GetUsageSummary(req shared.UsageSummaryRequest) (*shared.UsageSummaryResponse, *shared.ApiError)
+
 	GetFileMap(req shared.GetFileMapRequest) (*shared.GetFileMapResponse, *shared.ApiError)
 	GetContextBody(planId, branch, contextId string) (*shared.GetContextBodyResponse, *shared.ApiError)
 	AutoLoadContext(ctx context.Context, planId, branch string, req shared.LoadContextRequest) (*shared.LoadContextResponse, *shared.ApiError)
@@ -2,6 +2,7 @@ package types
 
 type TellFlags struct {
 	TellBg                 bool
+	TellQueue              bool
 	TellStop               bool
 	TellNoBuild            bool
 	IsUserContinue         bool
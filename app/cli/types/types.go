@@ -17,8 +17,34 @@ type LoadContextParams struct {
 	SkipIgnoreWarning bool
 	AutoLoaded        bool
 	SessionId         string
+	SkipEmptyExit     bool
+	Pinned            bool
+	CrawlDepth        int
+	CrawlMaxPages     int
 }
 
+type ContextSetEntry struct {
+	ContextType     shared.ContextType    `json:"contextType"`
+	Name            string                `json:"name"`
+	FilePath        string                `json:"filePath,omitempty"`
+	Url             string                `json:"url,omitempty"`
+	Body            string                `json:"body,omitempty"` // notes only - everything else is re-read from its source
+	Sha             string                `json:"sha,omitempty"`
+	MapShas         map[string]string     `json:"mapShas,omitempty"`
+	ForceSkipIgnore bool                  `json:"forceSkipIgnore,omitempty"`
+	ImageDetail     openai.ImageURLDetail `json:"imageDetail,omitempty"`
+	Pinned          bool                  `json:"pinned,omitempty"`
+}
+
+type ContextSet struct {
+	Name      string             `json:"name"`
+	Entries   []*ContextSetEntry `json:"entries"`
+	CreatedAt time.Time          `json:"createdAt"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+}
+
+type ContextSetsByName map[string]*ContextSet
+
 type ContextOutdatedResult struct {
 	Msg             string
 	UpdatedContexts []*shared.Context
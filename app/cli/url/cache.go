@@ -0,0 +1,182 @@
+package url
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"plandex-cli/fs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fetched pages are cached on disk so unchanged pages can be re-validated with ETag/Last-Modified instead of
+// downloaded and extracted again
+
+const (
+	// entries older than this are dropped rather than re-validated, so a page is fully re-fetched at least this often
+	cacheTTL = 7 * 24 * time.Hour
+
+	// when the cache grows past this, the least recently fetched entries are removed
+	maxCacheBytes = 100 * 1024 * 1024
+)
+
+type cacheEntry struct {
+	Url          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Body         string    `json:"body"`
+	Links        []string  `json:"links,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+}
+
+func getCacheDir() string {
+	if fs.CacheDir == "" {
+		return ""
+	}
+	return filepath.Join(fs.CacheDir, "urls")
+}
+
+func getCachePath(u string) string {
+	dir := getCacheDir()
+	if dir == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(u))
+	return filepath.Join(dir, hex.EncodeToString(hash[:])+".json")
+}
+
+// readCache returns nil on a miss - the cache is best-effort, so errors are treated as misses
+func readCache(u string) *cacheEntry {
+	path := getCachePath(u)
+	if path == "" {
+		return nil
+	}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	err = json.Unmarshal(bytes, &entry)
+	if err != nil || entry.Url != u {
+		return nil
+	}
+
+	if time.Since(entry.FetchedAt) > cacheTTL {
+		os.Remove(path)
+		return nil
+	}
+
+	return &entry
+}
+
+func writeCache(entry *cacheEntry) {
+	path := getCachePath(entry.Url)
+	if path == "" {
+		return
+	}
+
+	bytes, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return
+	}
+
+	// write to a temp file and rename so concurrent fetches of the same url never see a partial entry
+	tmpPath := path + ".tmp-" + time.Now().Format("150405.000000000")
+	err = os.WriteFile(tmpPath, bytes, 0644)
+	if err != nil {
+		return
+	}
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		os.Remove(tmpPath)
+		return
+	}
+
+	trackCacheWrite(int64(len(bytes)))
+}
+
+var (
+	cacheSizeMu sync.Mutex
+	cacheSize   int64 = -1 // unknown until the first write scans the cache dir
+)
+
+// trackCacheWrite keeps a running total of the cache's size so the dir only needs to be scanned on the first write and when the cap is reached
+func trackCacheWrite(n int64) {
+	cacheSizeMu.Lock()
+	defer cacheSizeMu.Unlock()
+
+	if cacheSize >= 0 && cacheSize+n <= maxCacheBytes {
+		cacheSize += n
+		return
+	}
+
+	cacheSize = pruneCache(getCacheDir(), maxCacheBytes, time.Now())
+}
+
+// pruneCache removes expired entries and leftover temp files, then the oldest entries until the cache fits in maxBytes. It returns the cache's remaining size.
+func pruneCache(dir string, maxBytes int64, now time.Time) int64 {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []cacheFile
+	var total int64
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, dirEntry.Name())
+
+		// entries are only written when fetched, so mod time is when the page was fetched
+		isTmp := strings.Contains(dirEntry.Name(), ".tmp-")
+		if (isTmp && now.Sub(info.ModTime()) > time.Hour) || (!isTmp && now.Sub(info.ModTime()) > cacheTTL) {
+			os.Remove(path)
+			continue
+		}
+
+		files = append(files, cacheFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+
+	if total <= maxBytes {
+		return total
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	for _, file := range files {
+		if total <= maxBytes {
+			break
+		}
+		if os.Remove(file.path) == nil {
+			total -= file.size
+		}
+	}
+
+	return total
+}
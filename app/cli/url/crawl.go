@@ -0,0 +1,128 @@
+package url
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultCrawlMaxPages = 50
+	crawlConcurrency     = 5
+)
+
+// links to these are never worth loading as context
+var crawlSkipExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".svg": true, ".ico": true,
+	".zip": true, ".tar": true, ".gz": true, ".tgz": true, ".dmg": true, ".exe": true, ".pkg": true, ".deb": true, ".rpm": true,
+	".mp3": true, ".mp4": true, ".mov": true, ".webm": true, ".woff": true, ".woff2": true, ".ttf": true,
+	".css": true, ".js": true, ".xml": true, ".rss": true,
+}
+
+type CrawlParams struct {
+	Depth    int
+	MaxPages int
+}
+
+// Crawl fetches root and follows same-origin links breadth-first up to the given depth, stopping once MaxPages
+// pages have been fetched. Linked pages that fail to load are skipped, but an error loading root is returned.
+func Crawl(root string, params CrawlParams) ([]*Page, error) {
+	rootUrl, err := url.Parse(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %v", root, err)
+	}
+
+	maxPages := params.MaxPages
+	if maxPages <= 0 {
+		maxPages = DefaultCrawlMaxPages
+	}
+
+	rootPage, err := FetchPage(root)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := []*Page{rootPage}
+	visited := map[string]bool{normalizeCrawlUrl(rootUrl): true}
+	level := []*Page{rootPage}
+
+	for depth := 1; depth <= params.Depth && len(pages) < maxPages; depth++ {
+		var frontier []string
+		for _, page := range level {
+			for _, link := range page.Links {
+				if len(pages)+len(frontier) >= maxPages {
+					break
+				}
+
+				u, err := url.Parse(link)
+				if err != nil || u.Scheme != rootUrl.Scheme || u.Host != rootUrl.Host {
+					continue
+				}
+				if crawlSkipExts[strings.ToLower(filepath.Ext(u.Path))] {
+					continue
+				}
+
+				key := normalizeCrawlUrl(u)
+				if visited[key] {
+					continue
+				}
+				visited[key] = true
+				frontier = append(frontier, key)
+			}
+		}
+
+		if len(frontier) == 0 {
+			break
+		}
+
+		level = fetchPages(frontier)
+		pages = append(pages, level...)
+	}
+
+	return pages, nil
+}
+
+// fetchPages fetches urls concurrently, preserving order and dropping any that fail
+func fetchPages(urls []string) []*Page {
+	results := make([]*Page, len(urls))
+	sem := make(chan struct{}, crawlConcurrency)
+	var wg sync.WaitGroup
+
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			page, err := FetchPage(u)
+			if err == nil {
+				results[i] = page
+			}
+		}(i, u)
+	}
+
+	wg.Wait()
+
+	var pages []*Page
+	for _, page := range results {
+		if page != nil {
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+func normalizeCrawlUrl(u *url.URL) string {
+	normalized := *u
+	normalized.Fragment = ""
+	if normalized.Path != "/" {
+		normalized.Path = strings.TrimSuffix(normalized.Path, "/")
+	}
+	if normalized.Path == "" {
+		normalized.Path = "/"
+	}
+	return normalized.String()
+}
@@ -0,0 +1,330 @@
+package url
+
+import (
+	"fmt"
+	"net/url"
+	"plandex-cli/extract"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// elements that never hold a page's main content
+const readabilityStripSelector = "script, style, noscript, svg, iframe, form, nav, header, footer, aside, [role=navigation], [aria-hidden=true]"
+
+// candidates for a page's main content, in order of preference
+var readabilityMainSelectors = []string{"article", "main", "[role=main]", "#content", ".content", ".markdown-body", ".docs-content"}
+
+// a main content candidate with less text than this is probably a teaser or a wrapper, so keep looking
+const minMainContentLength = 200
+
+var blankLinesRegex = regexp.MustCompile(`\n{3,}`)
+var whitespaceOnlyLinesRegex = regexp.MustCompile(`(?m)^[ \t]+$`)
+var languageClassRegex = regexp.MustCompile(`(?:language|lang)-([\w+#-]+)`)
+
+// ExtractReadableContent converts the main content of an HTML page to markdown and returns it along with every
+// link on the page (resolved against base) so linked pages can be crawled.
+func ExtractReadableContent(htmlContent string, base *url.URL) (string, []string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse HTML: %v", err)
+	}
+
+	// collect links before stripping navigation since that's where most of them are
+	links := collectLinks(doc, base)
+
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+
+	doc.Find(readabilityStripSelector).Remove()
+
+	main := findMainContent(doc)
+
+	var b strings.Builder
+	for _, node := range main.Nodes {
+		b.WriteString(renderMarkdown(node, base))
+	}
+
+	md := whitespaceOnlyLinesRegex.ReplaceAllString(b.String(), "")
+	md = strings.TrimSpace(blankLinesRegex.ReplaceAllString(md, "\n\n"))
+	if title != "" && !strings.HasPrefix(md, "# ") {
+		md = "# " + title + "\n\n" + md
+	}
+
+	return md + "\n", links, nil
+}
+
+func findMainContent(doc *goquery.Document) *goquery.Selection {
+	for _, selector := range readabilityMainSelectors {
+		sel := doc.Find(selector).First()
+		if sel.Length() > 0 && len(strings.TrimSpace(sel.Text())) >= minMainContentLength {
+			return sel
+		}
+	}
+
+	body := doc.Find("body").First()
+	if body.Length() > 0 {
+		return body
+	}
+
+	return doc.Selection
+}
+
+func collectLinks(doc *goquery.Document, base *url.URL) []string {
+	seen := map[string]bool{}
+	var links []string
+
+	doc.Find("a[href]").Each(func(_ int, sel *goquery.Selection) {
+		href, _ := sel.Attr("href")
+		resolved := resolveLink(href, base)
+		if resolved == "" {
+			return
+		}
+
+		u, err := url.Parse(resolved)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		u.Fragment = ""
+		resolved = u.String()
+
+		if !seen[resolved] {
+			seen[resolved] = true
+			links = append(links, resolved)
+		}
+	})
+
+	return links
+}
+
+func resolveLink(href string, base *url.URL) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "javascript:") || strings.HasPrefix(href, "mailto:") {
+		return ""
+	}
+
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+
+	if base == nil {
+		return u.String()
+	}
+
+	return base.ResolveReference(u).String()
+}
+
+func renderMarkdown(n *html.Node, base *url.URL) string {
+	switch n.Type {
+	case html.TextNode:
+		return collapseWhitespace(n.Data)
+	case html.DocumentNode:
+		return renderChildren(n, base)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.Data[1] - '0')
+		text := strings.TrimSpace(renderChildren(n, base))
+		if text == "" {
+			return ""
+		}
+		return "\n\n" + strings.Repeat("#", level) + " " + text + "\n\n"
+
+	case "p", "div", "section", "article", "main", "figure", "details", "dl":
+		return "\n\n" + strings.TrimSpace(renderChildren(n, base)) + "\n\n"
+
+	case "br":
+		return "\n"
+
+	case "hr":
+		return "\n\n---\n\n"
+
+	case "a":
+		text := strings.TrimSpace(renderChildren(n, base))
+		href := resolveLink(getAttr(n, "href"), base)
+		if text == "" || href == "" || strings.HasPrefix(getAttr(n, "href"), "#") {
+			return text
+		}
+		return "[" + text + "](" + href + ")"
+
+	case "strong", "b":
+		return wrapInline(renderChildren(n, base), "**")
+
+	case "em", "i":
+		return wrapInline(renderChildren(n, base), "_")
+
+	case "code":
+		return wrapInline(textContent(n), "`")
+
+	case "pre":
+		lang := codeLanguage(n)
+		return "\n\n```" + lang + "\n" + strings.Trim(textContent(n), "\n") + "\n```\n\n"
+
+	case "ul", "ol":
+		return "\n\n" + renderList(n, base) + "\n\n"
+
+	case "blockquote":
+		inner := strings.TrimSpace(blankLinesRegex.ReplaceAllString(renderChildren(n, base), "\n\n"))
+		lines := strings.Split(inner, "\n")
+		for i, line := range lines {
+			lines[i] = "> " + line
+		}
+		return "\n\n" + strings.Join(lines, "\n") + "\n\n"
+
+	case "img":
+		alt := strings.TrimSpace(getAttr(n, "alt"))
+		if alt == "" {
+			return ""
+		}
+		return "[image: " + alt + "]"
+
+	case "table":
+		return "\n\n" + renderTable(n, base) + "\n\n"
+
+	case "dt":
+		return "\n\n**" + strings.TrimSpace(renderChildren(n, base)) + "**\n"
+
+	case "dd":
+		return "\n" + strings.TrimSpace(renderChildren(n, base)) + "\n"
+	}
+
+	return renderChildren(n, base)
+}
+
+func renderChildren(n *html.Node, base *url.URL) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(renderMarkdown(c, base))
+	}
+	return b.String()
+}
+
+func renderList(n *html.Node, base *url.URL) string {
+	ordered := n.Data == "ol"
+	var items []string
+	num := 1
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data != "li" {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", num)
+			num++
+		}
+
+		inner := strings.TrimSpace(blankLinesRegex.ReplaceAllString(renderChildren(c, base), "\n\n"))
+		inner = strings.ReplaceAll(inner, "\n\n", "\n")
+		// indent continuation lines (including nested lists) under the marker
+		inner = strings.ReplaceAll(inner, "\n", "\n"+strings.Repeat(" ", len(marker)))
+		items = append(items, marker+inner)
+	}
+
+	return strings.Join(items, "\n")
+}
+
+func renderTable(n *html.Node, base *url.URL) string {
+	var rows [][]string
+
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "tr":
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						row = append(row, strings.TrimSpace(renderChildren(cell, base)))
+					}
+				}
+				rows = append(rows, row)
+			case "thead", "tbody", "tfoot":
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+
+	if len(rows) == 0 {
+		return ""
+	}
+
+	return strings.TrimSpace(extract.FormatTable(rows))
+}
+
+func codeLanguage(n *html.Node) string {
+	classes := getAttr(n, "class")
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "code" {
+			classes += " " + getAttr(c, "class")
+		}
+	}
+	if m := languageClassRegex.FindStringSubmatch(classes); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "br" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func collapseWhitespace(s string) string {
+	if strings.TrimSpace(s) == "" {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+
+	res := strings.Join(strings.Fields(s), " ")
+	if strings.IndexAny(s[:1], " \t\n\r") == 0 {
+		res = " " + res
+	}
+	if strings.IndexAny(s[len(s)-1:], " \t\n\r") == 0 {
+		res += " "
+	}
+	return res
+}
+
+// wrapInline keeps surrounding whitespace outside of markers so "**bold **text" doesn't happen
+func wrapInline(s, marker string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	leading := s[:strings.Index(s, trimmed)]
+	trailing := s[strings.Index(s, trimmed)+len(trimmed):]
+	return leading + marker + trimmed + marker + trailing
+}
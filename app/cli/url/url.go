@@ -5,12 +5,10 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"plandex-cli/term"
+	"plandex-cli/extract"
 	"regexp"
 	"strings"
 	"time"
-
-	"github.com/PuerkitoBio/goquery"
 )
 
 const (
@@ -20,7 +18,33 @@ const (
 	maxContentSizeInMB = 10
 )
 
+// documents served without a matching extension in the url path
+var documentExtByContentType = map[string]string{
+	"application/pdf": ".pdf",
+	"text/csv":        ".csv",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ".docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       ".xlsx",
+}
+
+// Page is a fetched page's readable content along with the links it contains
+type Page struct {
+	Url         string
+	Body        string
+	Links       []string
+	NotModified bool // served from the cache after the server confirmed it hasn't changed
+}
+
 func FetchURLContent(url string) (string, error) {
+	page, err := FetchPage(url)
+	if err != nil {
+		return "", err
+	}
+	return page.Body, nil
+}
+
+// FetchPage fetches a url, re-validating any cached copy with ETag/Last-Modified. HTML is converted to markdown
+// and documents like PDFs are converted to text.
+func FetchPage(u string) (*Page, error) {
 	client := &http.Client{
 		Timeout: httpTimeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -31,14 +55,37 @@ func FetchURLContent(url string) (string, error) {
 		},
 	}
 
-	resp, err := client.Get(url)
+	req, err := http.NewRequest(http.MethodGet, u, nil)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	cached := readCache(u)
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		// the server confirmed the page is current, so the entry's expiry starts over
+		cached.FetchedAt = time.Now()
+		writeCache(cached)
+
+		return &Page{Url: u, Body: cached.Body, Links: cached.Links, NotModified: true}, nil
+	}
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", errors.New("non-2xx HTTP response status: " + resp.Status)
+		return nil, errors.New("non-2xx HTTP response status: " + resp.Status)
 	}
 
 	// Limit the response reader to a maximum amount
@@ -46,25 +93,41 @@ func FetchURLContent(url string) (string, error) {
 
 	content, err := io.ReadAll(limitedReader)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	page := &Page{Url: u}
+
 	contentType := resp.Header.Get("Content-Type")
+	docName := resp.Request.URL.Path
+	if ext, ok := documentExtByContentType[strings.Split(contentType, ";")[0]]; ok {
+		docName += ext
+	}
+
 	if strings.Contains(contentType, "text/html") {
-		return ExtractTextualContent(string(content)), nil
+		page.Body, page.Links, err = ExtractReadableContent(string(content), resp.Request.URL)
+		if err != nil {
+			return nil, err
+		}
+	} else if extract.IsSupported(docName) {
+		page.Body, err = extract.Bytes(docName, content)
+		if err != nil {
+			return nil, err
+		}
 	} else {
-		return string(content), nil
+		page.Body = string(content)
 	}
-}
 
-func ExtractTextualContent(htmlContent string) string {
-	r := strings.NewReader(htmlContent)
-	doc, err := goquery.NewDocumentFromReader(r)
-	if err != nil {
-		term.OutputErrorAndExit("Failed to parse HTML: %v", err)
-	}
+	writeCache(&cacheEntry{
+		Url:          u,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Body:         page.Body,
+		Links:        page.Links,
+		FetchedAt:    time.Now(),
+	})
 
-	return doc.Text()
+	return page, nil
 }
 
 func SanitizeURL(url string) string {
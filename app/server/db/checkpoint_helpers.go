@@ -0,0 +1,101 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// checkpoints of running plans are saved at least this often, so an older checkpoint belongs to a plan whose instance is gone
+const PlanCheckpointInterval = 5 * time.Second
+const planCheckpointStaleTimeout = 6 * PlanCheckpointInterval
+
+func UpsertPlanCheckpoint(checkpoint *PlanCheckpoint) error {
+	query := `
+		INSERT INTO plan_checkpoints (plan_id, branch, org_id, user_id, instance_id, build_only, tell_request, session_id, iteration, current_reply, num_tokens, message_num, replies_finished, pending_build_paths)
+		VALUES (:plan_id, :branch, :org_id, :user_id, :instance_id, :build_only, :tell_request, :session_id, :iteration, :current_reply, :num_tokens, :message_num, :replies_finished, :pending_build_paths)
+		ON CONFLICT (plan_id, branch) DO UPDATE SET
+			org_id = EXCLUDED.org_id,
+			user_id = EXCLUDED.user_id,
+			instance_id = EXCLUDED.instance_id,
+			build_only = EXCLUDED.build_only,
+			tell_request = EXCLUDED.tell_request,
+			session_id = EXCLUDED.session_id,
+			iteration = EXCLUDED.iteration,
+			current_reply = EXCLUDED.current_reply,
+			num_tokens = EXCLUDED.num_tokens,
+			message_num = EXCLUDED.message_num,
+			replies_finished = EXCLUDED.replies_finished,
+			pending_build_paths = EXCLUDED.pending_build_paths,
+			interrupted_at = NULL
+	`
+
+	_, err := Conn.NamedExec(query, checkpoint)
+	if err != nil {
+		return fmt.Errorf("error upserting plan checkpoint: %v", err)
+	}
+
+	return nil
+}
+
+func GetPlanCheckpoint(planId, branch string) (*PlanCheckpoint, error) {
+	var checkpoint PlanCheckpoint
+	err := Conn.Get(&checkpoint, "SELECT * FROM plan_checkpoints WHERE plan_id = $1 AND branch = $2", planId, branch)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting plan checkpoint: %v", err)
+	}
+
+	return &checkpoint, nil
+}
+
+func DeletePlanCheckpoint(planId, branch string) error {
+	_, err := Conn.Exec("DELETE FROM plan_checkpoints WHERE plan_id = $1 AND branch = $2", planId, branch)
+	if err != nil {
+		return fmt.Errorf("error deleting plan checkpoint: %v", err)
+	}
+
+	return nil
+}
+
+// GetOrphanedPlanCheckpoints returns checkpoints that haven't been marked interrupted yet, have stopped being updated, and whose plan no longer has a live model stream, meaning the instance running it is gone
+func GetOrphanedPlanCheckpoints() ([]*PlanCheckpoint, error) {
+	var checkpoints []*PlanCheckpoint
+	err := Conn.Select(&checkpoints, `
+		SELECT c.* FROM plan_checkpoints c
+		WHERE c.interrupted_at IS NULL
+		AND c.updated_at < NOW() - $2 * INTERVAL '1 second'
+		AND NOT EXISTS (
+			SELECT 1 FROM model_streams ms
+			WHERE ms.plan_id = c.plan_id
+			AND ms.branch = c.branch
+			AND ms.finished_at IS NULL
+			AND ms.last_heartbeat_at > NOW() - $1 * INTERVAL '1 second'
+		)
+		ORDER BY c.created_at
+	`, int(modelStreamHeartbeatTimeout.Seconds()), int(planCheckpointStaleTimeout.Seconds()))
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting orphaned plan checkpoints: %v", err)
+	}
+
+	return checkpoints, nil
+}
+
+// ClaimInterruptedPlanCheckpoint marks a checkpoint interrupted, returning false if another instance already did
+func ClaimInterruptedPlanCheckpoint(planId, branch string) (bool, error) {
+	res, err := Conn.Exec("UPDATE plan_checkpoints SET interrupted_at = NOW() WHERE plan_id = $1 AND branch = $2 AND interrupted_at IS NULL", planId, branch)
+	if err != nil {
+		return false, fmt.Errorf("error claiming plan checkpoint: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error claiming plan checkpoint: %v", err)
+	}
+
+	return n > 0, nil
+}
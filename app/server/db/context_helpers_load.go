@@ -221,6 +221,7 @@ func LoadContexts(ctx Ctx, params LoadContextsParams) (*shared.LoadContextRespon
 				MapTokens:   mapTokens,
 				MapSizes:    mapSizes,
 				AutoLoaded:  autoLoaded || contextParams.AutoLoaded,
+				Pinned:      contextParams.Pinned,
 			}
 
 			mapContextsByFilePath[contextParams.FilePath] = newContext
@@ -324,6 +325,7 @@ func LoadContexts(ctx Ctx, params LoadContextsParams) (*shared.LoadContextRespon
 					ForceSkipIgnore: loadParams.ForceSkipIgnore,
 					ImageDetail:     loadParams.ImageDetail,
 					AutoLoaded:      autoLoaded || loadParams.AutoLoaded,
+					Pinned:          loadParams.Pinned,
 				}
 			}
 
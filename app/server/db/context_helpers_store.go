@@ -129,3 +129,46 @@ func StoreContext(context *Context, skipMapCache bool) error {
 
 	return nil
 }
+
+// StoreContextMeta rewrites only the meta file for a context, leaving its body and map parts untouched
+func StoreContextMeta(context *Context) error {
+	contextDir := getPlanContextDir(context.OrgId, context.PlanId)
+	metaPath := filepath.Join(contextDir, context.Id+".meta")
+
+	context.UpdatedAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(context.ToMeta(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal context meta: %v", err)
+	}
+
+	if err = os.WriteFile(metaPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write context meta to file %s: %v", metaPath, err)
+	}
+
+	return nil
+}
+
+func SetContextsPinned(orgId, planId string, ids map[string]bool, pinned bool) ([]*Context, error) {
+	contexts, err := GetPlanContexts(orgId, planId, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("error getting plan contexts: %v", err)
+	}
+
+	var updated []*Context
+	for _, context := range contexts {
+		if !ids[context.Id] || context.Pinned == pinned {
+			continue
+		}
+
+		context.Pinned = pinned
+		err = StoreContextMeta(context)
+		if err != nil {
+			return nil, fmt.Errorf("error storing context meta: %v", err)
+		}
+
+		updated = append(updated, context)
+	}
+
+	return updated, nil
+}
@@ -196,7 +196,7 @@ func UpdateContexts(params UpdateContextsParams) (*shared.UpdateContextResponse,
 			}
 
 			switch context.ContextType {
-			case shared.ContextFileType:
+			case shared.ContextFileType, shared.ContextDocumentType:
 				numFiles++
 			case shared.ContextURLType:
 				numUrls++
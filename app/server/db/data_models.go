@@ -264,6 +264,7 @@ type ModelStream struct {
 	OrgId           string     `db:"org_id"`
 	PlanId          string     `db:"plan_id"`
 	InternalIp      string     `db:"internal_ip"`
+	InstanceId      *string    `db:"instance_id"`
 	Branch          string     `db:"branch"`
 	LastHeartbeatAt time.Time  `db:"last_heartbeat_at"`
 	CreatedAt       time.Time  `db:"created_at"`
@@ -296,6 +297,9 @@ type repoLock struct {
 	Scope           LockScope `db:"scope"`
 	Branch          *string   `db:"branch"`
 	PlanBuildId     *string   `db:"plan_build_id"`
+	Reason          *string   `db:"reason"`
+	InstanceId      *string   `db:"instance_id"`
+	GoroutineId     *int64    `db:"goroutine_id"`
 	LastHeartbeatAt time.Time `db:"last_heartbeat_at"`
 	CreatedAt       time.Time `db:"created_at"`
 }
@@ -381,6 +385,8 @@ type CustomModel struct {
 
 	Providers CustomModelProviders `db:"providers"`
 
+	Pricing *CustomModelPricing `db:"pricing"`
+
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
 }
@@ -417,6 +423,7 @@ func CustomModelFromApi(apiModel *shared.CustomModel) *CustomModel {
 		SingleMessageNoSystemPrompt: apiModel.SingleMessageNoSystemPrompt,
 		TokenEstimatePaddingPct:     apiModel.TokenEstimatePaddingPct,
 		Providers:                   providers,
+		Pricing:                     (*CustomModelPricing)(apiModel.Pricing),
 	}
 
 	return &dbModel
@@ -449,6 +456,7 @@ func (model *CustomModel) ToApi() *shared.CustomModel {
 			SupportsCacheControl:        model.SupportsCacheControl,
 			SingleMessageNoSystemPrompt: model.SingleMessageNoSystemPrompt,
 			TokenEstimatePaddingPct:     model.TokenEstimatePaddingPct,
+			Pricing:                     (*shared.ModelPricing)(model.Pricing),
 
 			ModelCompatibility: shared.ModelCompatibility{
 				HasImageSupport: model.HasImageSupport,
@@ -481,16 +489,60 @@ func (e ExtraAuthVars) Value() (driver.Value, error) {
 	return json.Marshal(e)
 }
 
+type ProviderRateLimits shared.ModelProviderRateLimits
+
+func (l *ProviderRateLimits) Scan(src interface{}) error {
+	if src == nil {
+		return nil
+	}
+
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, l)
+	case string:
+		return json.Unmarshal([]byte(s), l)
+	default:
+		return fmt.Errorf("unsupported data type: %T", src)
+	}
+}
+
+func (l ProviderRateLimits) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+type CustomModelPricing shared.ModelPricing
+
+func (p *CustomModelPricing) Scan(src interface{}) error {
+	if src == nil {
+		return nil
+	}
+
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, p)
+	case string:
+		return json.Unmarshal([]byte(s), p)
+	default:
+		return fmt.Errorf("unsupported data type: %T", src)
+	}
+}
+
+func (p CustomModelPricing) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
 type CustomProvider struct {
-	Id            string        `db:"id"`
-	OrgId         string        `db:"org_id"`
-	Name          string        `db:"name"`
-	BaseUrl       string        `db:"base_url"`
-	SkipAuth      bool          `db:"skip_auth"`
-	ApiKeyEnvVar  string        `db:"api_key_env_var"`
-	ExtraAuthVars ExtraAuthVars `db:"extra_auth_vars"`
-	CreatedAt     time.Time     `db:"created_at"`
-	UpdatedAt     time.Time     `db:"updated_at"`
+	Id            string                      `db:"id"`
+	OrgId         string                      `db:"org_id"`
+	Name          string                      `db:"name"`
+	BaseUrl       string                      `db:"base_url"`
+	SkipAuth      bool                        `db:"skip_auth"`
+	ApiKeyEnvVar  string                      `db:"api_key_env_var"`
+	ExtraAuthVars ExtraAuthVars               `db:"extra_auth_vars"`
+	ApiType       shared.ModelProviderApiType `db:"api_type"`
+	RateLimits    *ProviderRateLimits         `db:"rate_limits"`
+	CreatedAt     time.Time                   `db:"created_at"`
+	UpdatedAt     time.Time                   `db:"updated_at"`
 }
 
 func CustomProviderFromApi(apiProvider *shared.CustomProvider) *CustomProvider {
@@ -501,6 +553,8 @@ func CustomProviderFromApi(apiProvider *shared.CustomProvider) *CustomProvider {
 		SkipAuth:      apiProvider.SkipAuth,
 		ApiKeyEnvVar:  apiProvider.ApiKeyEnvVar,
 		ExtraAuthVars: apiProvider.ExtraAuthVars,
+		ApiType:       apiProvider.ApiType,
+		RateLimits:    (*ProviderRateLimits)(apiProvider.RateLimits),
 	}
 }
 
@@ -512,6 +566,8 @@ func (provider *CustomProvider) ToApi() *shared.CustomProvider {
 		SkipAuth:      provider.SkipAuth,
 		ApiKeyEnvVar:  provider.ApiKeyEnvVar,
 		ExtraAuthVars: provider.ExtraAuthVars,
+		ApiType:       provider.ApiType,
+		RateLimits:    (*shared.ModelProviderRateLimits)(provider.RateLimits),
 	}
 }
 
@@ -582,6 +638,7 @@ type Context struct {
 	MapTokens       map[string]int        `json:"mapTokens,omitempty"`
 	MapSizes        map[string]int64      `json:"mapSizes,omitempty"`
 	AutoLoaded      bool                  `json:"autoLoaded"`
+	Pinned          bool                  `json:"pinned"`
 	CreatedAt       time.Time             `json:"createdAt"`
 	UpdatedAt       time.Time             `json:"updatedAt"`
 }
@@ -603,6 +660,7 @@ func (context *Context) ToMeta() *Context {
 		BodySize:        context.BodySize,
 		ForceSkipIgnore: context.ForceSkipIgnore,
 		AutoLoaded:      context.AutoLoaded,
+		Pinned:          context.Pinned,
 		ImageDetail:     context.ImageDetail,
 		MapShas:         context.MapShas,
 		MapTokens:       context.MapTokens,
@@ -626,6 +684,7 @@ func (context *Context) ToApi() *shared.Context {
 		BodySize:        context.BodySize,
 		ForceSkipIgnore: context.ForceSkipIgnore,
 		AutoLoaded:      context.AutoLoaded,
+		Pinned:          context.Pinned,
 		ImageDetail:     context.ImageDetail,
 		MapParts:        context.MapParts,
 		MapShas:         context.MapShas,
@@ -800,3 +859,127 @@ func (subtask *Subtask) ToApi() *shared.Subtask {
 		IsFinished:  subtask.IsFinished,
 	}
 }
+
+type ModelUsage struct {
+	Id            string               `db:"id"`
+	OrgId         string               `db:"org_id"`
+	UserId        string               `db:"user_id"`
+	PlanId        string               `db:"plan_id"`
+	SessionId     *string              `db:"session_id"`
+	ModelId       shared.ModelId       `db:"model_id"`
+	ModelName     shared.ModelName     `db:"model_name"`
+	ModelProvider shared.ModelProvider `db:"model_provider"`
+	ModelRole     shared.ModelRole     `db:"model_role"`
+	Purpose       string               `db:"purpose"`
+	InputTokens   int                  `db:"input_tokens"`
+	OutputTokens  int                  `db:"output_tokens"`
+	CachedTokens  int                  `db:"cached_tokens"`
+	Cost          float64              `db:"cost"`
+	Priced        bool                 `db:"priced"`
+	CacheHit      bool                 `db:"cache_hit"`
+	CreatedAt     time.Time            `db:"created_at"`
+}
+
+type PlanCheckpoint struct {
+	PlanId            string                  `db:"plan_id"`
+	Branch            string                  `db:"branch"`
+	OrgId             string                  `db:"org_id"`
+	UserId            string                  `db:"user_id"`
+	InstanceId        string                  `db:"instance_id"`
+	BuildOnly         bool                    `db:"build_only"`
+	TellRequest       *StoredTellRequest      `db:"tell_request"`
+	SessionId         *string                 `db:"session_id"`
+	Iteration         int                     `db:"iteration"`
+	CurrentReply      string                  `db:"current_reply"`
+	NumTokens         int                     `db:"num_tokens"`
+	MessageNum        int                     `db:"message_num"`
+	RepliesFinished   bool                    `db:"replies_finished"`
+	PendingBuildPaths CheckpointPendingBuilds `db:"pending_build_paths"`
+	InterruptedAt     *time.Time              `db:"interrupted_at"`
+	CreatedAt         time.Time               `db:"created_at"`
+	UpdatedAt         time.Time               `db:"updated_at"`
+}
+
+type StoredTellRequest shared.TellPlanRequest
+
+func (r *StoredTellRequest) Scan(src interface{}) error {
+	if src == nil {
+		return nil
+	}
+
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, r)
+	case string:
+		return json.Unmarshal([]byte(s), r)
+	default:
+		return fmt.Errorf("unsupported data type: %T", src)
+	}
+}
+
+func (r StoredTellRequest) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+type CheckpointPendingBuilds []string
+
+func (p *CheckpointPendingBuilds) Scan(src interface{}) error {
+	if src == nil {
+		return nil
+	}
+
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, p)
+	case string:
+		return json.Unmarshal([]byte(s), p)
+	default:
+		return fmt.Errorf("unsupported data type: %T", src)
+	}
+}
+
+func (p CheckpointPendingBuilds) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+type QueuedPrompt struct {
+	Id            string             `db:"id"`
+	OrgId         string             `db:"org_id"`
+	PlanId        string             `db:"plan_id"`
+	Branch        string             `db:"branch"`
+	UserId        string             `db:"user_id"`
+	InstanceId    string             `db:"instance_id"`
+	TellRequest   *StoredTellRequest `db:"tell_request"`
+	InterruptedAt *time.Time         `db:"interrupted_at"`
+	CreatedAt     time.Time          `db:"created_at"`
+	UpdatedAt     time.Time          `db:"updated_at"`
+}
+
+func (q *QueuedPrompt) ToApi() *shared.QueuedPrompt {
+	var prompt string
+	if q.TellRequest != nil {
+		prompt = q.TellRequest.Prompt
+	}
+
+	return &shared.QueuedPrompt{
+		Id:          q.Id,
+		PlanId:      q.PlanId,
+		Branch:      q.Branch,
+		UserId:      q.UserId,
+		Prompt:      prompt,
+		Interrupted: q.InterruptedAt != nil,
+		CreatedAt:   q.CreatedAt,
+	}
+}
+
+type PlanMaintenanceRun struct {
+	Id              string     `db:"id"`
+	InstanceId      string     `db:"instance_id"`
+	PlansCompacted  int        `db:"plans_compacted"`
+	CommitsSquashed int        `db:"commits_squashed"`
+	PlansPurged     int        `db:"plans_purged"`
+	BytesReclaimed  int64      `db:"bytes_reclaimed"`
+	Error           *string    `db:"error"`
+	StartedAt       time.Time  `db:"started_at"`
+	FinishedAt      *time.Time `db:"finished_at"`
+}
@@ -17,6 +17,9 @@ import (
 
 var Conn *sqlx.DB
 
+// connection string used by Conn -- pubsub listeners open their own connections with it
+var connStr string
+
 const LockTimeout = 4000
 const IdleInTransactionSessionTimeout = 90000
 const StatementTimeout = 30000
@@ -47,6 +50,8 @@ func Connect() error {
 		dbUrl += fmt.Sprintf("?statement_timeout=%d&lock_timeout=%d&timezone=UTC&idle_in_transaction_session_timeout=%d", StatementTimeout, LockTimeout, IdleInTransactionSessionTimeout)
 	}
 
+	connStr = dbUrl
+
 	Conn, err = sqlx.Connect("postgres", dbUrl)
 	if err != nil {
 		return err
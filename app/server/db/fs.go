@@ -61,6 +61,12 @@ func InitPlan(orgId, planId string) error {
 		return fmt.Errorf("error initializing git repo: %v", err)
 	}
 
+	err = planStorage.Sync(orgId, planId)
+
+	if err != nil {
+		return fmt.Errorf("error syncing new plan to storage: %v", err)
+	}
+
 	return nil
 }
 
@@ -72,6 +78,12 @@ func DeletePlanDir(orgId, planId string) error {
 		return fmt.Errorf("error deleting plan dir: %v", err)
 	}
 
+	err = planStorage.Delete(orgId, planId)
+
+	if err != nil {
+		return fmt.Errorf("error deleting plan from storage: %v", err)
+	}
+
 	return nil
 }
 
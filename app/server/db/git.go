@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -32,6 +33,9 @@ func init() {
 type GitRepo struct {
 	orgId  string
 	planId string
+
+	// set when an operation changes the repo's history, so the queue knows to sync it to plan storage
+	changed bool
 }
 
 func InitGitRepo(orgId, planId string) error {
@@ -85,6 +89,8 @@ func (repo *GitRepo) GitAddAndCommit(branch, message string) error {
 		return fmt.Errorf("error committing files to git repository for dir: %s, err: %v", dir, err)
 	}
 
+	repo.changed = true
+
 	// log.Println("[Git] GitAddAndCommit - finished, logging repo state")
 
 	// repo.LogGitRepoState()
@@ -105,6 +111,8 @@ func (repo *GitRepo) GitRewindToSha(branch, sha string) error {
 		return fmt.Errorf("error rewinding git repository for dir: %s, err: %v", dir, err)
 	}
 
+	repo.changed = true
+
 	return nil
 }
 
@@ -168,6 +176,8 @@ func (repo *GitRepo) GitResetToSha(sha string) error {
 		return fmt.Errorf("error resetting git repository to SHA for dir: %s, sha: %s, err: %v", dir, sha, err)
 	}
 
+	repo.changed = true
+
 	return nil
 }
 
@@ -305,6 +315,8 @@ func (repo *GitRepo) GitCreateBranch(newBranch string) error {
 		return err
 	}
 
+	repo.changed = true
+
 	return nil
 }
 
@@ -327,6 +339,8 @@ func (repo *GitRepo) GitDeleteBranch(branchName string) error {
 		return err
 	}
 
+	repo.changed = true
+
 	return nil
 }
 
@@ -399,6 +413,160 @@ func (repo *GitRepo) GitCheckoutBranch(branch string) error {
 	return nil
 }
 
+type RepoCompactResult struct {
+	CommitsSquashed int
+	BytesReclaimed  int64
+}
+
+// GitCompact garbage collects the repo. If historyCutoff is set, each branch's history before it is squashed into the branch's last commit from before the cutoff. That commit is marked as a shallow boundary rather than rewritten, so every sha from the cutoff on stays the same and can still be rewound to -- older commits drop out of 'plandex log' and are pruned.
+func (repo *GitRepo) GitCompact(historyCutoff time.Time) (RepoCompactResult, error) {
+	var res RepoCompactResult
+
+	orgId := repo.orgId
+	planId := repo.planId
+
+	dir := getPlanDir(orgId, planId)
+	gitDir := filepath.Join(dir, ".git")
+
+	sizeBefore, err := dirSize(gitDir)
+	if err != nil {
+		return res, fmt.Errorf("error getting repo size for dir: %s, err: %v", dir, err)
+	}
+
+	var commitsBefore int
+	if !historyCutoff.IsZero() {
+		commitsBefore, err = gitCountCommits(dir)
+		if err != nil {
+			return res, err
+		}
+
+		err = gitSetShallowBoundaries(dir, historyCutoff)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	err = gitWriteOperation(func() error {
+		// a commit-graph written before the history was cut still lists the pruned parents
+		err := os.RemoveAll(filepath.Join(gitDir, "objects", "info", "commit-graph"))
+		if err != nil {
+			return fmt.Errorf("error removing commit-graph: %v", err)
+		}
+		err = os.RemoveAll(filepath.Join(gitDir, "objects", "info", "commit-graphs"))
+		if err != nil {
+			return fmt.Errorf("error removing commit-graphs: %v", err)
+		}
+
+		// rewinds leave commits that are only reachable from the reflog
+		out, err := exec.Command("git", "-C", dir, "reflog", "expire", "--expire=now", "--all").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("error expiring reflog for dir: %s, err: %v, output: %s", dir, err, string(out))
+		}
+
+		out, err = exec.Command("git", "-C", dir, "gc", "--prune=now", "--quiet").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("error running git gc for dir: %s, err: %v, output: %s", dir, err, string(out))
+		}
+
+		return nil
+	}, dir, fmt.Sprintf("GitCompact > gitGc: plan=%s", planId))
+	if err != nil {
+		return res, err
+	}
+
+	if !historyCutoff.IsZero() {
+		commitsAfter, err := gitCountCommits(dir)
+		if err != nil {
+			return res, err
+		}
+		res.CommitsSquashed = commitsBefore - commitsAfter
+	}
+
+	sizeAfter, err := dirSize(gitDir)
+	if err != nil {
+		return res, fmt.Errorf("error getting repo size for dir: %s, err: %v", dir, err)
+	}
+
+	if sizeAfter < sizeBefore {
+		res.BytesReclaimed = sizeBefore - sizeAfter
+	}
+
+	if res.CommitsSquashed > 0 || res.BytesReclaimed > 0 {
+		repo.changed = true
+	}
+
+	return res, nil
+}
+
+func gitCountCommits(dir string) (int, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-list", "--count", "--all").CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("error counting commits for dir: %s, err: %v, output: %s", dir, err, string(out))
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, fmt.Errorf("error parsing commit count for dir: %s, err: %v", dir, err)
+	}
+
+	return n, nil
+}
+
+// gitSetShallowBoundaries adds each branch's last commit before the cutoff to .git/shallow, which makes git treat it as having no parents
+func gitSetShallowBoundaries(dir string, cutoff time.Time) error {
+	shallowPath := filepath.Join(dir, ".git", "shallow")
+
+	boundaries := map[string]bool{}
+	existing, err := os.ReadFile(shallowPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading shallow file for dir: %s, err: %v", dir, err)
+	}
+	for _, sha := range strings.Fields(string(existing)) {
+		boundaries[sha] = true
+	}
+
+	out, err := exec.Command("git", "-C", dir, "branch", "--format=%(refname:short)").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error listing git branches for dir: %s, err: %v, output: %s", dir, err, string(out))
+	}
+
+	gitFormattedTime := cutoff.UTC().Format("2006-01-02 15:04:05+0000")
+
+	added := false
+	for _, branch := range strings.Fields(string(out)) {
+		out, err := exec.Command("git", "-C", dir, "rev-list", "-n", "1", "--parents", "--before="+gitFormattedTime, branch).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("error finding history cutoff for branch %s in dir: %s, err: %v, output: %s", branch, dir, err, string(out))
+		}
+
+		// sha followed by its parents -- nothing to squash if it has none
+		fields := strings.Fields(string(out))
+		if len(fields) < 2 || boundaries[fields[0]] {
+			continue
+		}
+
+		boundaries[fields[0]] = true
+		added = true
+	}
+
+	if !added {
+		return nil
+	}
+
+	shas := make([]string, 0, len(boundaries))
+	for sha := range boundaries {
+		shas = append(shas, sha)
+	}
+	sort.Strings(shas)
+
+	err = os.WriteFile(shallowPath, []byte(strings.Join(shas, "\n")+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing shallow file for dir: %s, err: %v", dir, err)
+	}
+
+	return nil
+}
+
 func gitAdd(repoDir, path string) error {
 
 	if err := gitRemoveIndexLockFileIfExists(repoDir); err != nil {
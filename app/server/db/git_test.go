@@ -0,0 +1,97 @@
+package db
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitCompactSquashesHistoryBeforeCutoff(t *testing.T) {
+	origBaseDir := BaseDir
+	defer func() {
+		BaseDir = origBaseDir
+	}()
+	BaseDir = t.TempDir()
+
+	if err := InitPlan("org", "plan"); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := getGitRepo("org", "plan")
+	dir := getPlanDir("org", "plan")
+	contextFile := filepath.Join(getPlanContextDir("org", "plan"), "file.txt")
+
+	commitAt := func(content string, at time.Time) string {
+		t.Helper()
+		t.Setenv("GIT_COMMITTER_DATE", at.Format(time.RFC3339))
+		t.Setenv("GIT_AUTHOR_DATE", at.Format(time.RFC3339))
+		if err := os.WriteFile(contextFile, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.GitAddAndCommit("main", content); err != nil {
+			t.Fatal(err)
+		}
+		sha, err := repo.GetCurrentCommitSha()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sha
+	}
+
+	now := time.Now()
+	commitAt("old 1", now.Add(-72*time.Hour))
+	commitAt("old 2", now.Add(-48*time.Hour))
+	boundary := commitAt("old 3", now.Add(-36*time.Hour))
+	recent := []string{
+		commitAt("new 1", now.Add(-2*time.Hour)),
+		commitAt("new 2", now.Add(-time.Hour)),
+	}
+
+	res, err := repo.GitCompact(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.CommitsSquashed != 2 {
+		t.Errorf("expected 2 commits squashed, got %d", res.CommitsSquashed)
+	}
+
+	out, err := exec.Command("git", "-C", dir, "log", "--format=%H").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git log failed: %v, %s", err, out)
+	}
+	got := strings.Fields(string(out))
+	want := []string{recent[1], recent[0], boundary}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected retained shas to be unchanged\nwant %v\ngot  %v", want, got)
+	}
+
+	out, err = exec.Command("git", "-C", dir, "fsck").CombinedOutput()
+	if err != nil {
+		t.Errorf("git fsck failed after compaction: %v, %s", err, out)
+	}
+
+	// the boundary is still rewindable
+	if err := repo.GitRewindToSha("main", boundary); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(contextFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "old 3" {
+		t.Errorf("expected rewound content 'old 3', got %q", content)
+	}
+
+	// a second pass has nothing left to squash
+	res, err = repo.GitCompact(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.CommitsSquashed != 0 {
+		t.Errorf("expected nothing squashed on second pass, got %d", res.CommitsSquashed)
+	}
+}
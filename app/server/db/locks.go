@@ -8,8 +8,10 @@ import (
 	"log"
 	"math"
 	"math/rand"
+	"plandex-server/host"
 	"plandex-server/notify"
 	"plandex-server/shutdown"
+	"plandex-server/tracing"
 	"runtime"
 	"runtime/debug"
 	"strconv"
@@ -20,6 +22,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/lib/pq"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const locksVerboseLogging = false
@@ -323,7 +326,8 @@ func lockRepoDB(params LockRepoParams, numRetry int) (string, error) {
 
 	var insertedId sql.NullString
 
-	insertQuery := "INSERT INTO repo_locks (org_id, user_id, plan_id, plan_build_id, scope, branch) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (plan_id) WHERE scope = 'w' DO NOTHING RETURNING id"
+	// reason, instance and goroutine are stored so stuck locks can be traced back to their holder
+	insertQuery := "INSERT INTO repo_locks (org_id, user_id, plan_id, plan_build_id, scope, branch, reason, instance_id, goroutine_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (plan_id) WHERE scope = 'w' DO NOTHING RETURNING id"
 
 	if locksVerboseLogging {
 		log.Printf("Insert query: %s", insertQuery)
@@ -337,6 +341,9 @@ func lockRepoDB(params LockRepoParams, numRetry int) (string, error) {
 		newLock.PlanBuildId,
 		newLock.Scope,
 		newLock.Branch,
+		params.Reason,
+		host.InstanceId,
+		int64(goroutineID),
 	).Scan(&insertedId)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -456,6 +463,15 @@ func lockRepoDB(params LockRepoParams, numRetry int) (string, error) {
 		}
 	}()
 
+	// bring the local working copy up to date in case another instance changed the plan
+	_, fetchSpan := tracing.Start(ctx, "PlanStorage.Fetch", attribute.String("storage.backend", planStorage.Name()))
+	err = planStorage.Fetch(orgId, planId, scope)
+	tracing.End(fetchSpan, err)
+	if err != nil {
+		log.Printf("[Lock] %s | %s | Error fetching plan from storage: %v", planId, params.Reason, err)
+		return newLock.Id, fmt.Errorf("error fetching plan from storage: %v", err)
+	}
+
 	// check if git lock file exists
 	// remove it if so
 	err = gitRemoveIndexLockFileIfExists(getPlanDir(orgId, planId))
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	shared "plandex-shared"
+)
+
+// Introspection for debugging stuck plans: repo locks held in the db (across all instances) and repo operations queued on this instance.
+
+func ListOrgRepoLocks(orgId string) ([]*shared.RepoLock, error) {
+	var locks []*repoLock
+	err := Conn.Select(&locks, "SELECT * FROM repo_locks WHERE org_id = $1 ORDER BY created_at", orgId)
+	if err != nil {
+		return nil, fmt.Errorf("error listing repo locks: %v", err)
+	}
+
+	now := time.Now()
+	res := make([]*shared.RepoLock, len(locks))
+	for i, lock := range locks {
+		res[i] = lock.toApi(now)
+	}
+
+	return res, nil
+}
+
+// ListOrgRepoOperations returns the operations this instance is running or has queued for an org's plans
+func ListOrgRepoOperations(orgId string) []*shared.RepoOperation {
+	queuesMu.Lock()
+	queues := make([]*repoQueue, 0, len(repoQueues))
+	for _, q := range repoQueues {
+		queues = append(queues, q)
+	}
+	queuesMu.Unlock()
+
+	res := []*shared.RepoOperation{}
+
+	for _, q := range queues {
+		q.mu.Lock()
+		for _, op := range q.current {
+			if op.orgId != orgId {
+				continue
+			}
+			apiOp := op.toApi()
+			apiOp.Running = true
+			apiOp.LockId = q.currentLockId
+			startedAt := q.currentStarted
+			apiOp.StartedAt = &startedAt
+			res = append(res, apiOp)
+		}
+		for _, op := range q.ops {
+			if op.orgId != orgId {
+				continue
+			}
+			res = append(res, op.toApi())
+		}
+		q.mu.Unlock()
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].EnqueuedAt.Before(res[j].EnqueuedAt)
+	})
+
+	return res
+}
+
+// ForceReleaseRepoLock deletes a lock and records who released it. The holder's heartbeat stops once it sees the lock is gone, but the operation holding it isn't interrupted. Returns nil if the lock doesn't exist.
+func ForceReleaseRepoLock(orgId, lockId, userId, note string) (*shared.RepoLock, error) {
+	tx, err := Conn.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && rbErr != sql.ErrTxDone {
+			log.Printf("transaction rollback error: %v\n", rbErr)
+		}
+	}()
+
+	var lock repoLock
+	err = tx.Get(&lock, "SELECT * FROM repo_locks WHERE id = $1 AND org_id = $2 FOR UPDATE", lockId, orgId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting repo lock: %v", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM repo_locks WHERE id = $1", lockId)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting repo lock: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO repo_lock_releases (org_id, plan_id, lock_id, scope, branch, lock_user_id, lock_reason, lock_instance_id, lock_goroutine_id, lock_created_at, lock_last_heartbeat_at, released_by, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`, lock.OrgId, lock.PlanId, lock.Id, lock.Scope, lock.Branch, lock.UserId, lock.Reason, lock.InstanceId, lock.GoroutineId, lock.CreatedAt, lock.LastHeartbeatAt, userId, note)
+	if err != nil {
+		return nil, fmt.Errorf("error recording repo lock release: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	activeLockIdsMu.Lock()
+	delete(activeLockIds, lockId)
+	activeLockIdsMu.Unlock()
+
+	log.Printf("[Lock] Lock %s for plan %s force released by user %s | note: %s", lockId, lock.PlanId, userId, note)
+
+	return lock.toApi(time.Now()), nil
+}
+
+func (lock *repoLock) toApi(now time.Time) *shared.RepoLock {
+	res := &shared.RepoLock{
+		Id:              lock.Id,
+		PlanId:          lock.PlanId,
+		Scope:           lock.Scope.toApi(),
+		HeartbeatAge:    now.Sub(lock.LastHeartbeatAt),
+		Expired:         now.Sub(lock.LastHeartbeatAt) >= lockHeartbeatTimeout,
+		LastHeartbeatAt: lock.LastHeartbeatAt,
+		CreatedAt:       lock.CreatedAt,
+	}
+
+	if lock.Branch != nil {
+		res.Branch = *lock.Branch
+	}
+	if lock.UserId != nil {
+		res.UserId = *lock.UserId
+	}
+	if lock.Reason != nil {
+		res.Reason = *lock.Reason
+	}
+	if lock.InstanceId != nil {
+		res.InstanceId = *lock.InstanceId
+	}
+	if lock.GoroutineId != nil {
+		res.GoroutineId = *lock.GoroutineId
+	}
+
+	return res
+}
+
+func (op *repoOperation) toApi() *shared.RepoOperation {
+	return &shared.RepoOperation{
+		Id:         op.id,
+		PlanId:     op.planId,
+		Branch:     op.branch,
+		Scope:      op.scope.toApi(),
+		Reason:     op.reason,
+		UserId:     op.userId,
+		EnqueuedAt: op.enqueuedAt,
+	}
+}
+
+func (scope LockScope) toApi() shared.RepoLockScope {
+	if scope == LockScopeWrite {
+		return shared.RepoLockScopeWrite
+	}
+	return shared.RepoLockScopeRead
+}
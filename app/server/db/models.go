@@ -22,7 +22,7 @@ INSERT INTO custom_models (
     predicted_output_enabled, reasoning_effort_enabled, reasoning_effort,
     include_reasoning, reasoning_budget, supports_cache_control,
     single_message_no_system_prompt, token_estimate_padding_pct,
-    providers
+    providers, pricing
 )
 VALUES (
     $1,$2,
@@ -33,7 +33,7 @@ VALUES (
     $14,$15,$16,
     $17,$18,$19,
     $20,$21,
-    $22
+    $22,$23
 )
 ON CONFLICT (org_id, model_id)
 DO UPDATE SET
@@ -56,7 +56,8 @@ DO UPDATE SET
     supports_cache_control        = EXCLUDED.supports_cache_control,
     single_message_no_system_prompt = EXCLUDED.single_message_no_system_prompt,
     token_estimate_padding_pct    = EXCLUDED.token_estimate_padding_pct,
-    providers                     = EXCLUDED.providers
+    providers                     = EXCLUDED.providers,
+    pricing                       = EXCLUDED.pricing
 RETURNING id, created_at, updated_at;
 `
 
@@ -84,6 +85,7 @@ RETURNING id, created_at, updated_at;
 		model.SingleMessageNoSystemPrompt,
 		model.TokenEstimatePaddingPct,
 		model.Providers,
+		model.Pricing,
 	).Scan(&model.Id, &model.CreatedAt, &model.UpdatedAt)
 }
 
@@ -130,18 +132,22 @@ func UpsertCustomProvider(tx *sqlx.Tx, p *CustomProvider) error {
 	const q = `
 INSERT INTO custom_providers (
 	  org_id, name, base_url,
-	  skip_auth, api_key_env_var, extra_auth_vars
+	  skip_auth, api_key_env_var, extra_auth_vars,
+	  api_type, rate_limits
 )
 VALUES (
 	  $1,$2,$3,
-	  $4,$5,$6
+	  $4,$5,$6,
+	  $7,$8
 )
 ON CONFLICT (org_id, name)
 DO UPDATE SET
 	  base_url        = EXCLUDED.base_url,
 	  skip_auth       = EXCLUDED.skip_auth,
 	  api_key_env_var = EXCLUDED.api_key_env_var,
-	  extra_auth_vars = EXCLUDED.extra_auth_vars
+	  extra_auth_vars = EXCLUDED.extra_auth_vars,
+	  api_type        = EXCLUDED.api_type,
+	  rate_limits     = EXCLUDED.rate_limits
 RETURNING id, created_at, updated_at;
 `
 	return tx.QueryRow(
@@ -152,6 +158,8 @@ RETURNING id, created_at, updated_at;
 		p.SkipAuth,
 		p.ApiKeyEnvVar,
 		p.ExtraAuthVars,
+		p.ApiType,
+		p.RateLimits,
 	).Scan(&p.Id, &p.CreatedAt, &p.UpdatedAt)
 }
 
@@ -0,0 +1,488 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"plandex-server/host"
+	"plandex-server/shutdown"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Plan repos grow with every tell and build, and archived or deleted plans keep using disk. A maintenance run compacts the repos of plans that changed since the last run (see GitCompact) and purges plans that have been archived longer than the archived plan retention. Runs happen at most once per interval across all instances -- an advisory lock picks the instance that runs it, and each run is recorded in plan_maintenance_runs. Every instance also removes its own local dirs for plans that no longer exist, since with object storage each instance has its own working copies.
+//
+// Configured with:
+//   - PLANDEX_PLAN_MAINTENANCE_INTERVAL_HOURS: time between runs, defaults to 24 -- 0 disables maintenance
+//   - PLANDEX_PLAN_HISTORY_RETENTION_DAYS: squash plan history older than this, defaults to 0 which keeps all history
+//   - PLANDEX_ARCHIVED_PLAN_RETENTION_DAYS: delete plans this long after they're archived, defaults to 0 which keeps them
+
+const (
+	planMaintenanceIntervalDefault = 24 * time.Hour
+	planMaintenanceCheckInterval   = time.Hour
+	planMaintenanceStartDelay      = time.Minute
+	planMaintenanceOpTimeout       = 10 * time.Minute
+
+	// arbitrary key shared by all instances
+	planMaintenanceLockKey = 7305021
+
+	// a plan's dir is created before its row is committed, so recently modified dirs are never treated as orphaned
+	orphanedPlanDirMinAge = time.Hour
+)
+
+type PlanMaintenanceConfig struct {
+	Interval              time.Duration
+	HistoryRetention      time.Duration
+	ArchivedPlanRetention time.Duration
+}
+
+// PlanMaintenanceStats are totals for this instance since it started
+type PlanMaintenanceStats struct {
+	Runs                int64
+	PlansCompacted      int64
+	CommitsSquashed     int64
+	PlansPurged         int64
+	OrphanedDirsRemoved int64
+	BytesReclaimed      int64
+}
+
+var (
+	planMaintenanceStats   PlanMaintenanceStats
+	planMaintenanceStatsMu sync.Mutex
+	lastOrphanedDirSweep   time.Time
+)
+
+func GetPlanMaintenanceStats() PlanMaintenanceStats {
+	planMaintenanceStatsMu.Lock()
+	defer planMaintenanceStatsMu.Unlock()
+	return planMaintenanceStats
+}
+
+func addPlanMaintenanceStats(fn func(stats *PlanMaintenanceStats)) {
+	planMaintenanceStatsMu.Lock()
+	defer planMaintenanceStatsMu.Unlock()
+	fn(&planMaintenanceStats)
+}
+
+func loadPlanMaintenanceConfig() PlanMaintenanceConfig {
+	return PlanMaintenanceConfig{
+		Interval:              envDuration("PLANDEX_PLAN_MAINTENANCE_INTERVAL_HOURS", time.Hour, planMaintenanceIntervalDefault),
+		HistoryRetention:      envDuration("PLANDEX_PLAN_HISTORY_RETENTION_DAYS", 24*time.Hour, 0),
+		ArchivedPlanRetention: envDuration("PLANDEX_ARCHIVED_PLAN_RETENTION_DAYS", 24*time.Hour, 0),
+	}
+}
+
+func envDuration(key string, unit, defaultValue time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		log.Printf("Invalid %s %q, using default", key, raw)
+		return defaultValue
+	}
+	return time.Duration(n) * unit
+}
+
+func StartPlanMaintenance() {
+	config := loadPlanMaintenanceConfig()
+
+	if config.Interval == 0 {
+		log.Println("[Maintenance] Plan maintenance disabled")
+		return
+	}
+
+	log.Printf("[Maintenance] Plan maintenance every %v | history retention: %v | archived plan retention: %v", config.Interval, config.HistoryRetention, config.ArchivedPlanRetention)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic in plan maintenance: %v\n%s", r, debug.Stack())
+			}
+		}()
+
+		select {
+		case <-shutdown.ShutdownCtx.Done():
+			return
+		case <-time.After(planMaintenanceStartDelay):
+		}
+
+		checkPlanMaintenance(config)
+
+		ticker := time.NewTicker(planMaintenanceCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-shutdown.ShutdownCtx.Done():
+				return
+			case <-ticker.C:
+				checkPlanMaintenance(config)
+			}
+		}
+	}()
+}
+
+func checkPlanMaintenance(config PlanMaintenanceConfig) {
+	if time.Since(lastOrphanedDirSweep) >= config.Interval {
+		lastOrphanedDirSweep = time.Now()
+
+		removed, reclaimed, err := removeOrphanedPlanDirs()
+		if err != nil {
+			log.Printf("[Maintenance] Error removing orphaned plan dirs: %v", err)
+		}
+		if removed > 0 {
+			log.Printf("[Maintenance] Removed %d orphaned plan dirs | reclaimed %d bytes", removed, reclaimed)
+		}
+
+		addPlanMaintenanceStats(func(stats *PlanMaintenanceStats) {
+			stats.OrphanedDirsRemoved += int64(removed)
+			stats.BytesReclaimed += reclaimed
+		})
+	}
+
+	last, err := getLastPlanMaintenanceRun()
+	if err != nil {
+		log.Printf("[Maintenance] Error getting last plan maintenance run: %v", err)
+		return
+	}
+
+	if last != nil && time.Since(*last.FinishedAt) < config.Interval {
+		return
+	}
+
+	err = runPlanMaintenance(config)
+	if err != nil {
+		log.Printf("[Maintenance] Error running plan maintenance: %v", err)
+	}
+}
+
+func runPlanMaintenance(config PlanMaintenanceConfig) error {
+	ctx := shutdown.ShutdownCtx
+
+	// session-level advisory locks belong to a connection, so hold one for the whole run
+	conn, err := Conn.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting connection: %v", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	err = conn.GetContext(ctx, &locked, "SELECT pg_try_advisory_lock($1)", planMaintenanceLockKey)
+	if err != nil {
+		return fmt.Errorf("error acquiring plan maintenance lock: %v", err)
+	}
+	if !locked {
+		return nil
+	}
+	defer func() {
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", planMaintenanceLockKey)
+		if err != nil {
+			log.Printf("[Maintenance] Error releasing plan maintenance lock: %v", err)
+		}
+	}()
+
+	// another instance may have finished a run while we were waiting on the lock
+	last, err := getLastPlanMaintenanceRun()
+	if err != nil {
+		return fmt.Errorf("error getting last plan maintenance run: %v", err)
+	}
+	if last != nil && time.Since(*last.FinishedAt) < config.Interval {
+		return nil
+	}
+
+	var since time.Time
+	if last != nil {
+		since = last.StartedAt
+	}
+
+	run := &PlanMaintenanceRun{InstanceId: host.InstanceId}
+	err = Conn.QueryRow("INSERT INTO plan_maintenance_runs (instance_id) VALUES ($1) RETURNING id, started_at", run.InstanceId).Scan(&run.Id, &run.StartedAt)
+	if err != nil {
+		return fmt.Errorf("error recording plan maintenance run: %v", err)
+	}
+
+	log.Printf("[Maintenance] Starting plan maintenance run %s", run.Id)
+
+	var errs []string
+
+	if config.ArchivedPlanRetention > 0 {
+		purged, reclaimed, err := purgeArchivedPlans(time.Now().Add(-config.ArchivedPlanRetention))
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		run.PlansPurged = purged
+		run.BytesReclaimed += reclaimed
+	}
+
+	var historyCutoff time.Time
+	if config.HistoryRetention > 0 {
+		historyCutoff = time.Now().Add(-config.HistoryRetention)
+	}
+
+	compacted, squashed, reclaimed, err := compactPlanRepos(since, historyCutoff)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	run.PlansCompacted = compacted
+	run.CommitsSquashed = squashed
+	run.BytesReclaimed += reclaimed
+
+	if len(errs) > 0 {
+		runErr := strings.Join(errs, "; ")
+		run.Error = &runErr
+	}
+
+	_, err = Conn.Exec(
+		"UPDATE plan_maintenance_runs SET plans_compacted = $1, commits_squashed = $2, plans_purged = $3, bytes_reclaimed = $4, error = $5, finished_at = NOW() WHERE id = $6",
+		run.PlansCompacted, run.CommitsSquashed, run.PlansPurged, run.BytesReclaimed, run.Error, run.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("error recording plan maintenance run result: %v", err)
+	}
+
+	addPlanMaintenanceStats(func(stats *PlanMaintenanceStats) {
+		stats.Runs++
+		stats.PlansCompacted += int64(run.PlansCompacted)
+		stats.CommitsSquashed += int64(run.CommitsSquashed)
+		stats.PlansPurged += int64(run.PlansPurged)
+		stats.BytesReclaimed += run.BytesReclaimed
+	})
+
+	log.Printf("[Maintenance] Finished plan maintenance run %s in %v | compacted %d plans | squashed %d commits | purged %d archived plans | reclaimed %d bytes", run.Id, time.Since(run.StartedAt), run.PlansCompacted, run.CommitsSquashed, run.PlansPurged, run.BytesReclaimed)
+
+	return nil
+}
+
+func getLastPlanMaintenanceRun() (*PlanMaintenanceRun, error) {
+	var runs []*PlanMaintenanceRun
+	err := Conn.Select(&runs, "SELECT * FROM plan_maintenance_runs WHERE finished_at IS NOT NULL ORDER BY finished_at DESC LIMIT 1")
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, nil
+	}
+	return runs[0], nil
+}
+
+// compactPlanRepos compacts the repo of every plan that changed since the last run. Plans that have been idle since then were already compacted and haven't grown -- their history is squashed the next time they change.
+func compactPlanRepos(since, historyCutoff time.Time) (compacted, squashed int, reclaimed int64, err error) {
+	var plans []*Plan
+	err = Conn.Select(&plans, `
+		SELECT DISTINCT p.id, p.org_id FROM plans p
+		LEFT JOIN branches b ON b.plan_id = p.id
+		WHERE p.updated_at > $1 OR b.updated_at > $1
+	`, since)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("error listing plans to compact: %v", err)
+	}
+
+	numErrs := 0
+
+	for _, plan := range plans {
+		if shutdown.ShutdownCtx.Err() != nil {
+			break
+		}
+
+		var res RepoCompactResult
+
+		ctx, cancel := context.WithTimeout(shutdown.ShutdownCtx, planMaintenanceOpTimeout)
+		opErr := ExecRepoOperation(ExecRepoOperationParams{
+			OrgId:    plan.OrgId,
+			PlanId:   plan.Id,
+			Scope:    LockScopeWrite,
+			Reason:   "compact plan repo",
+			Ctx:      ctx,
+			CancelFn: cancel,
+		}, func(repo *GitRepo) error {
+			if _, err := os.Stat(getPlanDir(plan.OrgId, plan.Id)); os.IsNotExist(err) {
+				return nil
+			}
+
+			var err error
+			res, err = repo.GitCompact(historyCutoff)
+			return err
+		})
+		cancel()
+
+		if opErr != nil {
+			log.Printf("[Maintenance] Error compacting plan %s: %v", plan.Id, opErr)
+			numErrs++
+			continue
+		}
+
+		compacted++
+		squashed += res.CommitsSquashed
+		reclaimed += res.BytesReclaimed
+	}
+
+	if numErrs > 0 {
+		err = fmt.Errorf("error compacting %d plans", numErrs)
+	}
+
+	return compacted, squashed, reclaimed, err
+}
+
+func purgeArchivedPlans(cutoff time.Time) (purged int, reclaimed int64, err error) {
+	var plans []*Plan
+	err = Conn.Select(&plans, "SELECT id, org_id FROM plans WHERE archived_at IS NOT NULL AND archived_at < $1", cutoff)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error listing archived plans: %v", err)
+	}
+
+	numErrs := 0
+
+	for _, plan := range plans {
+		size, err := dirSize(getPlanDir(plan.OrgId, plan.Id))
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("[Maintenance] Error getting size of archived plan %s: %v", plan.Id, err)
+		}
+
+		// check again in case the plan was unarchived since it was listed
+		res, err := Conn.Exec("DELETE FROM plans WHERE id = $1 AND archived_at IS NOT NULL AND archived_at < $2", plan.Id, cutoff)
+		if err != nil {
+			log.Printf("[Maintenance] Error deleting archived plan %s: %v", plan.Id, err)
+			numErrs++
+			continue
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil || rowsAffected == 0 {
+			continue
+		}
+
+		err = DeletePlanDir(plan.OrgId, plan.Id)
+		if err != nil {
+			log.Printf("[Maintenance] Error deleting archived plan dir %s: %v", plan.Id, err)
+			numErrs++
+			continue
+		}
+
+		log.Printf("[Maintenance] Purged archived plan %s", plan.Id)
+
+		purged++
+		reclaimed += size
+	}
+
+	if numErrs > 0 {
+		err = fmt.Errorf("error purging %d archived plans", numErrs)
+	}
+
+	return purged, reclaimed, err
+}
+
+// removeOrphanedPlanDirs removes local plan dirs whose plans were deleted without their dir being removed, along with temp dirs left by interrupted fetches from plan storage
+func removeOrphanedPlanDirs() (removed int, reclaimed int64, err error) {
+	orgDirs, err := os.ReadDir(filepath.Join(BaseDir, "orgs"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, fmt.Errorf("error reading orgs dir: %v", err)
+	}
+
+	dirsByPlanId := map[string]string{}
+	var stale []string
+
+	for _, orgDir := range orgDirs {
+		if !orgDir.IsDir() {
+			continue
+		}
+
+		plansDir := filepath.Join(BaseDir, "orgs", orgDir.Name(), "plans")
+		entries, err := os.ReadDir(plansDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, 0, fmt.Errorf("error reading plans dir: %v", err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil || time.Since(info.ModTime()) < orphanedPlanDirMinAge {
+				continue
+			}
+
+			path := filepath.Join(plansDir, entry.Name())
+
+			if strings.HasPrefix(entry.Name(), ".") {
+				stale = append(stale, path)
+				continue
+			}
+
+			if _, err := uuid.Parse(entry.Name()); err != nil {
+				continue
+			}
+
+			dirsByPlanId[entry.Name()] = path
+		}
+	}
+
+	if len(dirsByPlanId) > 0 {
+		planIds := make([]string, 0, len(dirsByPlanId))
+		for planId := range dirsByPlanId {
+			planIds = append(planIds, planId)
+		}
+
+		var existing []string
+		err = Conn.Select(&existing, "SELECT id FROM plans WHERE id = ANY($1)", pq.Array(planIds))
+		if err != nil {
+			return 0, 0, fmt.Errorf("error checking plan ids: %v", err)
+		}
+
+		for _, planId := range existing {
+			delete(dirsByPlanId, planId)
+		}
+
+		for _, path := range dirsByPlanId {
+			stale = append(stale, path)
+		}
+	}
+
+	for _, path := range stale {
+		size, err := dirSize(path)
+		if err != nil {
+			log.Printf("[Maintenance] Error getting size of %s: %v", path, err)
+		}
+
+		err = os.RemoveAll(path)
+		if err != nil {
+			log.Printf("[Maintenance] Error removing %s: %v", path, err)
+			continue
+		}
+
+		removed++
+		reclaimed += size
+	}
+
+	return removed, reclaimed, nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
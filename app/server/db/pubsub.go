@@ -0,0 +1,289 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"plandex-server/notify"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NOTIFY payloads are limited to 8000 bytes -- larger payloads are stored in pubsub_payloads and the notification references them by id
+const maxNotifyPayloadSize = 7000
+const pubsubPayloadRetention = 10 * time.Minute
+const pubsubCleanupInterval = 1 * time.Minute
+
+const notifyInlinePrefix = "m:"
+const notifyRefPrefix = "r:"
+
+type PubSubHandler func(payload []byte)
+
+var (
+	pubsubListener *pq.Listener
+	pubsubQueues   = map[string]*pubsubQueue{}
+	pubsubMu       sync.Mutex
+)
+
+func PlanBusEnabled() bool {
+	return os.Getenv("PLANDEX_PLAN_BUS") == "postgres"
+}
+
+// StartPubSub opens a dedicated LISTEN connection and dispatches notifications to handlers registered with Listen until ctx is done
+func StartPubSub(ctx context.Context) error {
+	if connStr == "" {
+		return errors.New("db not initialized")
+	}
+
+	listener := pq.NewListener(connStr, 1*time.Second, 30*time.Second, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("PubSub listener error: %v\n", err)
+		}
+		if ev == pq.ListenerEventReconnected {
+			log.Println("PubSub listener reconnected -- notifications sent while disconnected were missed")
+		}
+	})
+
+	pubsubMu.Lock()
+	pubsubListener = listener
+	pubsubMu.Unlock()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic in pubsub dispatch: %v\n%s", r, debug.Stack())
+				go notify.NotifyErr(notify.SeverityError, fmt.Errorf("panic in pubsub dispatch: %v\n%s", r, debug.Stack()))
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				err := listener.Close()
+				if err != nil {
+					log.Printf("Error closing pubsub listener: %v\n", err)
+				}
+
+				pubsubMu.Lock()
+				for channel, queue := range pubsubQueues {
+					queue.stop()
+					delete(pubsubQueues, channel)
+				}
+				pubsubMu.Unlock()
+				return
+			case n := <-listener.Notify:
+				// nil notifications are sent after a reconnect
+				if n == nil {
+					continue
+				}
+
+				pubsubMu.Lock()
+				queue := pubsubQueues[n.Channel]
+				pubsubMu.Unlock()
+
+				if queue == nil {
+					continue
+				}
+
+				// decoding (which may fetch a stored payload) and handling happen on the channel's own goroutine so this loop never waits on them
+				queue.push(n.Extra)
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(pubsubCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, err := Conn.Exec("DELETE FROM pubsub_payloads WHERE created_at < $1", time.Now().Add(-pubsubPayloadRetention))
+				if err != nil {
+					log.Printf("Error cleaning up pubsub payloads: %v\n", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func Listen(channel string, handler PubSubHandler) error {
+	pubsubMu.Lock()
+	defer pubsubMu.Unlock()
+
+	if pubsubListener == nil {
+		return errors.New("pubsub not started")
+	}
+
+	if existing := pubsubQueues[channel]; existing != nil {
+		existing.stop()
+	}
+	queue := newPubSubQueue(channel, handler)
+	pubsubQueues[channel] = queue
+
+	err := pubsubListener.Listen(channel)
+	if err != nil && err != pq.ErrChannelAlreadyOpen {
+		queue.stop()
+		delete(pubsubQueues, channel)
+		return fmt.Errorf("error listening on channel %s: %v", channel, err)
+	}
+
+	return nil
+}
+
+func Unlisten(channel string) error {
+	pubsubMu.Lock()
+	defer pubsubMu.Unlock()
+
+	if pubsubListener == nil {
+		return nil
+	}
+
+	if queue := pubsubQueues[channel]; queue != nil {
+		queue.stop()
+		delete(pubsubQueues, channel)
+	}
+
+	err := pubsubListener.Unlisten(channel)
+	if err != nil && err != pq.ErrChannelNotOpen {
+		return fmt.Errorf("error unlistening on channel %s: %v", channel, err)
+	}
+
+	return nil
+}
+
+func Publish(channel string, payload []byte) error {
+	var notifyPayload string
+
+	if len(payload)+len(notifyInlinePrefix) <= maxNotifyPayloadSize {
+		notifyPayload = notifyInlinePrefix + string(payload)
+	} else {
+		var id string
+		err := Conn.QueryRow("INSERT INTO pubsub_payloads (payload) VALUES ($1) RETURNING id", payload).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("error storing pubsub payload: %v", err)
+		}
+		notifyPayload = notifyRefPrefix + id
+	}
+
+	_, err := Conn.Exec("SELECT pg_notify($1, $2)", channel, notifyPayload)
+	if err != nil {
+		return fmt.Errorf("error publishing to channel %s: %v", channel, err)
+	}
+
+	return nil
+}
+
+func decodeNotifyPayload(extra string) ([]byte, error) {
+	if strings.HasPrefix(extra, notifyInlinePrefix) {
+		return []byte(strings.TrimPrefix(extra, notifyInlinePrefix)), nil
+	}
+
+	if strings.HasPrefix(extra, notifyRefPrefix) {
+		var payload []byte
+		err := Conn.Get(&payload, "SELECT payload FROM pubsub_payloads WHERE id = $1", strings.TrimPrefix(extra, notifyRefPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("error getting pubsub payload: %v", err)
+		}
+		return payload, nil
+	}
+
+	return nil, fmt.Errorf("unknown payload format")
+}
+
+// pubsubQueue hands a channel's notifications to its handler one at a time, in the order they were published, on a goroutine of its own. Pushing never blocks, so the LISTEN connection keeps draining even while a handler or a stored payload fetch is slow.
+type pubsubQueue struct {
+	channel string
+	handler PubSubHandler
+
+	mu      sync.Mutex
+	pending []string
+
+	ready    chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newPubSubQueue(channel string, handler PubSubHandler) *pubsubQueue {
+	q := &pubsubQueue{
+		channel: channel,
+		handler: handler,
+		ready:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+func (q *pubsubQueue) push(extra string) {
+	q.mu.Lock()
+	q.pending = append(q.pending, extra)
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *pubsubQueue) stop() {
+	q.stopOnce.Do(func() {
+		close(q.done)
+	})
+}
+
+func (q *pubsubQueue) run() {
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-q.ready:
+		}
+
+		for {
+			q.mu.Lock()
+			if len(q.pending) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			extra := q.pending[0]
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+
+			select {
+			case <-q.done:
+				return
+			default:
+			}
+
+			q.handle(extra)
+		}
+	}
+}
+
+func (q *pubsubQueue) handle(extra string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic in pubsub handler for channel %s: %v\n%s", q.channel, r, debug.Stack())
+			go notify.NotifyErr(notify.SeverityError, fmt.Errorf("panic in pubsub handler for channel %s: %v\n%s", q.channel, r, debug.Stack()))
+		}
+	}()
+
+	payload, err := decodeNotifyPayload(extra)
+	if err != nil {
+		log.Printf("Error decoding pubsub payload on channel %s: %v\n", q.channel, err)
+		return
+	}
+
+	q.handler(payload)
+}
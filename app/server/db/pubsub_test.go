@@ -0,0 +1,66 @@
+package db
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPubSubQueue(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan string, 100)
+
+	queue := newPubSubQueue("test", func(payload []byte) {
+		if string(payload) == "0" {
+			<-release
+		}
+		received <- string(payload)
+	})
+	defer queue.stop()
+
+	// pushing doesn't wait on the handler, which is stuck on the first message
+	started := time.Now()
+	for i := 0; i < 100; i++ {
+		queue.push(notifyInlinePrefix + strconv.Itoa(i))
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("pushing to a busy queue took %s", elapsed)
+	}
+
+	close(release)
+
+	for i := 0; i < 100; i++ {
+		select {
+		case payload := <-received:
+			if payload != strconv.Itoa(i) {
+				t.Fatalf("expected payload %d, got %s", i, payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for payload %d", i)
+		}
+	}
+}
+
+func TestPubSubQueueSkipsBadPayloads(t *testing.T) {
+	received := make(chan string, 10)
+	queue := newPubSubQueue("test", func(payload []byte) {
+		if string(payload) == "panic" {
+			panic("handler panic")
+		}
+		received <- string(payload)
+	})
+	defer queue.stop()
+
+	queue.push("unknown format")
+	queue.push(notifyInlinePrefix + "panic")
+	queue.push(notifyInlinePrefix + "ok")
+
+	select {
+	case payload := <-received:
+		if payload != "ok" {
+			t.Errorf("expected ok, got %s", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("queue stopped handling messages after a bad payload")
+	}
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"plandex-server/metrics"
+	"plandex-server/tracing"
 	"runtime/debug"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type repoOpFn func(repo *GitRepo) error
@@ -26,12 +30,18 @@ type repoOperation struct {
 	cancelFn       context.CancelFunc
 	done           chan error
 	clearRepoOnErr bool
+	enqueuedAt     time.Time
 }
 
 type repoQueue struct {
 	ops          []*repoOperation
 	mu           sync.Mutex
 	isProcessing bool
+
+	// the batch being processed, kept for introspection
+	current        []*repoOperation
+	currentLockId  string
+	currentStarted time.Time
 }
 
 type repoQueueMap map[string]*repoQueue
@@ -58,6 +68,24 @@ func (m repoQueueMap) getQueue(planId string) *repoQueue {
 	return q
 }
 
+// RepoQueueDepth is the number of operations waiting in this instance's repo queues, not counting batches being processed
+func RepoQueueDepth() int {
+	queuesMu.Lock()
+	queues := make([]*repoQueue, 0, len(repoQueues))
+	for _, q := range repoQueues {
+		queues = append(queues, q)
+	}
+	queuesMu.Unlock()
+
+	depth := 0
+	for _, q := range queues {
+		q.mu.Lock()
+		depth += len(q.ops)
+		q.mu.Unlock()
+	}
+	return depth
+}
+
 func (m repoQueueMap) add(op *repoOperation) int {
 	if locksVerboseLogging {
 		log.Printf("[Queue] Adding operation %s (%s) to queue for plan %s", op.id, op.reason, op.planId)
@@ -168,13 +196,26 @@ func (q *repoQueue) runQueue() {
 
 		firstOp := ops[0]
 
+		q.mu.Lock()
+		q.current = ops
+		q.currentLockId = ""
+		q.currentStarted = time.Now()
+		q.mu.Unlock()
+
 		func() {
+			defer func() {
+				q.mu.Lock()
+				q.current = nil
+				q.currentLockId = ""
+				q.mu.Unlock()
+			}()
 
 			if locksVerboseLogging {
 				log.Printf("[Queue] Attempting to acquire DB lock for plan %s, branch %s, scope %s",
 					firstOp.planId, firstOp.branch, firstOp.scope)
 			}
 
+			lockCtx, lockSpan := tracing.Start(firstOp.ctx, "lockRepoDB")
 			lockId, err := lockRepoDB(LockRepoParams{
 				OrgId:       firstOp.orgId,
 				UserId:      firstOp.userId,
@@ -183,13 +224,23 @@ func (q *repoQueue) runQueue() {
 				Scope:       firstOp.scope,
 				PlanBuildId: firstOp.planBuildId,
 				Reason:      firstOp.reason,
-				Ctx:         firstOp.ctx,
+				Ctx:         lockCtx,
 				CancelFn:    firstOp.cancelFn,
 			}, 0)
+			tracing.End(lockSpan, err)
 
 			if lockId != "" {
 				log.Printf("[Queue] Acquired DB lock %s", lockId)
 
+				for _, op := range ops {
+					metrics.ObserveRepoLockWait(string(op.scope), time.Since(op.enqueuedAt))
+					tracing.AddEvent(op.ctx, "lock acquired", attribute.String("lock.id", lockId))
+				}
+
+				q.mu.Lock()
+				q.currentLockId = lockId
+				q.mu.Unlock()
+
 				defer func() {
 					log.Printf("[Queue] Releasing DB lock %s for plan %s", lockId, firstOp.planId)
 					releaseErr := deleteRepoLockDB(lockId, firstOp.planId, firstOp.reason, 0)
@@ -265,6 +316,16 @@ func (q *repoQueue) runQueue() {
 								log.Printf("[Queue] Executing operation %s (%s)", op.id, op.reason)
 							}
 							opErr = op.op(repo)
+
+							// writers run alone in their batch, so the sync can't race another op on this repo, and it finishes before the lock is released
+							if opErr == nil && op.scope == LockScopeWrite && repo.changed {
+								_, syncSpan := tracing.Start(op.ctx, "PlanStorage.Sync", attribute.String("storage.backend", planStorage.Name()))
+								opErr = planStorage.Sync(op.orgId, op.planId)
+								tracing.End(syncSpan, opErr)
+								if opErr != nil {
+									log.Printf("[Queue] Operation %s (%s) failed to sync plan storage: %v", op.id, op.reason, opErr)
+								}
+							}
 							if locksVerboseLogging {
 								if opErr != nil {
 									log.Printf("[Queue] Operation %s (%s) failed with error: %v", op.id, op.reason, opErr)
@@ -319,10 +380,19 @@ func ExecRepoOperation(
 	log.Printf("[Queue] ExecRepoOperation called for plan %s, branch %s, scope %s, reason %s",
 		params.PlanId, params.Branch, params.Scope, params.Reason)
 
+	// the span covers the wait for the lock as well as the operation itself
+	ctx, span := tracing.Start(params.Ctx, "ExecRepoOperation",
+		attribute.String("plan.id", params.PlanId),
+		attribute.String("plan.branch", params.Branch),
+		attribute.String("repo.scope", string(params.Scope)),
+		attribute.String("repo.reason", params.Reason),
+	)
+
 	done := make(chan error, 1)
 	numOps := repoQueues.add(&repoOperation{
 		id:             id,
 		orgId:          params.OrgId,
+		userId:         params.UserId,
 		planId:         params.PlanId,
 		branch:         params.Branch,
 		scope:          params.Scope,
@@ -330,11 +400,14 @@ func ExecRepoOperation(
 		planBuildId:    params.PlanBuildId,
 		op:             op,
 		done:           done,
-		ctx:            params.Ctx,
+		ctx:            ctx,
 		cancelFn:       params.CancelFn,
 		clearRepoOnErr: params.ClearRepoOnErr,
+		enqueuedAt:     time.Now(),
 	})
 
+	span.SetAttributes(attribute.Int("repo.queue_position", numOps))
+
 	if numOps > 1 {
 		if locksVerboseLogging {
 			log.Printf("[Queue] Operation %s (%s) queued behind %d operations", id, params.Reason, numOps-1)
@@ -344,8 +417,9 @@ func ExecRepoOperation(
 		}
 	}
 
+	var err error
 	select {
-	case err := <-done:
+	case err = <-done:
 		if locksVerboseLogging {
 			if err != nil {
 				log.Printf("[Queue] Operation %s (%s) completed with error: %v", id, params.Reason, err)
@@ -353,11 +427,13 @@ func ExecRepoOperation(
 				log.Printf("[Queue] Operation %s (%s) completed successfully", id, params.Reason)
 			}
 		}
-		return err
 	case <-params.Ctx.Done():
 		if locksVerboseLogging {
 			log.Printf("[Queue] Operation %s (%s) context canceled while waiting", id, params.Reason)
 		}
-		return params.Ctx.Err()
+		err = params.Ctx.Err()
 	}
+
+	tracing.End(span, err)
+	return err
 }
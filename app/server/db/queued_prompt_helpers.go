@@ -0,0 +1,139 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// instances touch the prompts they hold at least this often, so an older prompt belongs to an instance that's gone
+const QueuedPromptHeartbeatInterval = 5 * time.Second
+const queuedPromptStaleTimeout = 6 * QueuedPromptHeartbeatInterval
+
+// interrupted prompts stay listed for a while so users can see what needs to be queued again
+const QueuedPromptInterruptedRetention = 24 * time.Hour
+
+func EnqueuePrompt(prompt *QueuedPrompt) error {
+	query := `
+		INSERT INTO queued_prompts (org_id, plan_id, branch, user_id, instance_id, tell_request)
+		VALUES (:org_id, :plan_id, :branch, :user_id, :instance_id, :tell_request)
+		RETURNING id, created_at, updated_at
+	`
+
+	row, err := Conn.NamedQuery(query, prompt)
+	if err != nil {
+		return fmt.Errorf("error enqueuing prompt: %v", err)
+	}
+	defer row.Close()
+
+	if row.Next() {
+		err = row.Scan(&prompt.Id, &prompt.CreatedAt, &prompt.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("error enqueuing prompt: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func ListQueuedPrompts(planId, branch string) ([]*QueuedPrompt, error) {
+	var prompts []*QueuedPrompt
+	err := Conn.Select(&prompts, "SELECT * FROM queued_prompts WHERE plan_id = $1 AND branch = $2 ORDER BY created_at", planId, branch)
+	if err != nil {
+		return nil, fmt.Errorf("error listing queued prompts: %v", err)
+	}
+
+	return prompts, nil
+}
+
+// GetNextQueuedPrompt returns the oldest prompt for a branch that can still be started
+func GetNextQueuedPrompt(planId, branch string) (*QueuedPrompt, error) {
+	var prompts []*QueuedPrompt
+	err := Conn.Select(&prompts, "SELECT * FROM queued_prompts WHERE plan_id = $1 AND branch = $2 AND interrupted_at IS NULL ORDER BY created_at LIMIT 1", planId, branch)
+	if err != nil {
+		return nil, fmt.Errorf("error getting next queued prompt: %v", err)
+	}
+
+	if len(prompts) == 0 {
+		return nil, nil
+	}
+
+	return prompts[0], nil
+}
+
+// GetNumQueuedPromptsByBranch counts the prompts waiting to run on each branch -- interrupted prompts will never run, so they aren't counted
+func GetNumQueuedPromptsByBranch(planIds []string) (map[string]int, error) {
+	var rows []struct {
+		PlanId string `db:"plan_id"`
+		Branch string `db:"branch"`
+		Num    int    `db:"num"`
+	}
+	err := Conn.Select(&rows, "SELECT plan_id, branch, COUNT(*) AS num FROM queued_prompts WHERE plan_id = ANY($1) AND interrupted_at IS NULL GROUP BY plan_id, branch", pq.Array(planIds))
+	if err != nil {
+		return nil, fmt.Errorf("error counting queued prompts: %v", err)
+	}
+
+	res := map[string]int{}
+	for _, row := range rows {
+		res[row.PlanId+"|"+row.Branch] = row.Num
+	}
+
+	return res, nil
+}
+
+// DeleteQueuedPrompt removes a prompt from a branch's queue, returning false if it was already started or removed
+func DeleteQueuedPrompt(planId, branch, id string) (bool, error) {
+	res, err := Conn.Exec("DELETE FROM queued_prompts WHERE id = $1 AND plan_id = $2 AND branch = $3", id, planId, branch)
+	if err != nil {
+		return false, fmt.Errorf("error deleting queued prompt: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error deleting queued prompt: %v", err)
+	}
+
+	return n > 0, nil
+}
+
+// TouchQueuedPrompts marks the prompts held by an instance as still live, returning their ids
+func TouchQueuedPrompts(instanceId string) ([]string, error) {
+	var ids []string
+	err := Conn.Select(&ids, "UPDATE queued_prompts SET updated_at = NOW() WHERE instance_id = $1 AND interrupted_at IS NULL RETURNING id", instanceId)
+	if err != nil {
+		return nil, fmt.Errorf("error touching queued prompts: %v", err)
+	}
+
+	return ids, nil
+}
+
+// InterruptOrphanedQueuedPrompts marks prompts whose instance stopped touching them as interrupted so they no longer hold up the queue
+func InterruptOrphanedQueuedPrompts() (int64, error) {
+	res, err := Conn.Exec("UPDATE queued_prompts SET interrupted_at = NOW() WHERE interrupted_at IS NULL AND updated_at < NOW() - $1 * INTERVAL '1 second'", int(queuedPromptStaleTimeout.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("error interrupting orphaned queued prompts: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error interrupting orphaned queued prompts: %v", err)
+	}
+
+	return n, nil
+}
+
+// DeleteInterruptedQueuedPrompts removes prompts that were interrupted longer than the retention period ago
+func DeleteInterruptedQueuedPrompts() (int64, error) {
+	res, err := Conn.Exec("DELETE FROM queued_prompts WHERE interrupted_at < NOW() - $1 * INTERVAL '1 second'", int(QueuedPromptInterruptedRetention.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("error deleting interrupted queued prompts: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error deleting interrupted queued prompts: %v", err)
+	}
+
+	return n, nil
+}
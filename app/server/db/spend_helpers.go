@@ -0,0 +1,56 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// spend is tracked per UTC day so daily limits reset at midnight UTC
+func spendDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func AddModelSpend(orgId, userId, planId string, amount float64) error {
+	query := `
+		INSERT INTO model_spend (org_id, user_id, plan_id, day, amount)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (org_id, user_id, plan_id, day)
+		DO UPDATE SET amount = model_spend.amount + EXCLUDED.amount, updated_at = NOW()
+	`
+
+	_, err := Conn.Exec(query, orgId, userId, planId, spendDay(time.Now()), amount)
+
+	if err != nil {
+		return fmt.Errorf("error adding model spend: %v", err)
+	}
+
+	return nil
+}
+
+type ModelSpendTotals struct {
+	PlanTotal float64 `db:"plan_total"`
+	PlanDay   float64 `db:"plan_day"`
+	UserDay   float64 `db:"user_day"`
+	OrgDay    float64 `db:"org_day"`
+}
+
+func GetModelSpendTotals(orgId, userId, planId string) (*ModelSpendTotals, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(amount) FILTER (WHERE plan_id = $3), 0) AS plan_total,
+			COALESCE(SUM(amount) FILTER (WHERE plan_id = $3 AND day = $4), 0) AS plan_day,
+			COALESCE(SUM(amount) FILTER (WHERE user_id = $2 AND day = $4), 0) AS user_day,
+			COALESCE(SUM(amount) FILTER (WHERE day = $4), 0) AS org_day
+		FROM model_spend
+		WHERE org_id = $1 AND (plan_id = $3 OR day = $4)
+	`
+
+	var totals ModelSpendTotals
+	err := Conn.Get(&totals, query, orgId, userId, planId, spendDay(time.Now()))
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting model spend totals: %v", err)
+	}
+
+	return &totals, nil
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// PlanStorage decides where plan repos are persisted. Git needs a working tree, so plans are always read and written through a local working copy under BaseDir -- context, convo, results, and the git history itself. A backend keeps that working copy in step with a durable copy: Fetch brings the local copy up to date when a repo lock is acquired, and Sync persists it after a write operation commits, before the lock is released. Since repo locks are held in the db, this lets any server instance work on any plan without a shared volume.
+type PlanStorage interface {
+	// Name identifies the backend in logs
+	Name() string
+
+	// Fetch makes sure the plan's local working copy matches its latest synced version before an operation with the given lock scope
+	Fetch(orgId, planId string, scope LockScope) error
+
+	// Sync persists the plan's local working copy
+	Sync(orgId, planId string) error
+
+	// Delete removes the plan's persisted copy (the local working copy is removed by DeletePlanDir)
+	Delete(orgId, planId string) error
+}
+
+const (
+	PlanStorageLocal = "local"
+	PlanStorageS3    = "s3"
+)
+
+var planStorage PlanStorage = localPlanStorage{}
+
+func InitPlanStorage() error {
+	backend := os.Getenv("PLANDEX_PLAN_STORAGE")
+
+	switch backend {
+	case "", PlanStorageLocal:
+		planStorage = localPlanStorage{}
+
+	case PlanStorageS3:
+		storage, err := NewS3PlanStorage(S3PlanStorageConfig{
+			Bucket:   os.Getenv("PLANDEX_S3_BUCKET"),
+			Prefix:   os.Getenv("PLANDEX_S3_PREFIX"),
+			Endpoint: os.Getenv("PLANDEX_S3_ENDPOINT"),
+			Region:   os.Getenv("PLANDEX_S3_REGION"),
+		})
+		if err != nil {
+			return fmt.Errorf("error initializing s3 plan storage: %v", err)
+		}
+		planStorage = storage
+
+	default:
+		return fmt.Errorf("unknown PLANDEX_PLAN_STORAGE: %s -- must be '%s' or '%s'", backend, PlanStorageLocal, PlanStorageS3)
+	}
+
+	log.Printf("Plan storage: %s\n", planStorage.Name())
+
+	return nil
+}
+
+// localPlanStorage keeps plans only on this server's disk. Running more than one instance needs BaseDir on a shared volume.
+type localPlanStorage struct{}
+
+func (localPlanStorage) Name() string {
+	return PlanStorageLocal
+}
+
+func (localPlanStorage) Fetch(orgId, planId string, scope LockScope) error {
+	return nil
+}
+
+func (localPlanStorage) Sync(orgId, planId string) error {
+	return nil
+}
+
+func (localPlanStorage) Delete(orgId, planId string) error {
+	return nil
+}
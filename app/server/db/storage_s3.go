@@ -0,0 +1,448 @@
+package db
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Each plan is stored as a single gzipped tarball of its dir, including the .git dir. A plan's files change together on every commit and are always read together under a repo lock, so one object per plan keeps a sync to a single request and makes every synced version a consistent snapshot.
+//
+// The tradeoff is that every write operation that commits re-uploads the whole archive, so upload size grows with the plan's full history rather than with the change. Plan maintenance keeps this in check: compaction packs loose objects into a smaller archive, and the history retention setting squashes old commits so a long-running plan's archive stops growing. Compaction runs as a write operation, so a plan is uploaded once more after it's compacted.
+//
+// The ETag of each plan's latest synced version is recorded in the db. Write operations always check the store before they start, but a read can't overlap a write, so a read lock skips the store entirely when the working copy already matches the recorded version.
+
+type S3PlanStorageConfig struct {
+	Bucket string
+	Prefix string
+	// Endpoint is set for S3-compatible stores like MinIO -- it also switches to path-style bucket addressing
+	Endpoint string
+	Region   string
+}
+
+type s3PlanStorage struct {
+	client *s3.S3
+	bucket string
+	prefix string
+
+	// the latest synced version of each plan, shared by all instances
+	versions planStorageVersions
+
+	// ETag of the version of each plan that's in the local working copy
+	mu         sync.Mutex
+	localETags map[string]string
+}
+
+type planStorageVersions interface {
+	get(planId string) (string, error)
+	set(planId, etag string) error
+	delete(planId string) error
+}
+
+func NewS3PlanStorage(config S3PlanStorageConfig) (*s3PlanStorage, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("PLANDEX_S3_BUCKET is required for s3 plan storage")
+	}
+
+	region := config.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	awsConfig := &aws.Config{
+		Region: aws.String(region),
+	}
+
+	if config.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(config.Endpoint)
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+
+	// credentials come from the standard AWS env vars or shared config
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating AWS session: %v", err)
+	}
+
+	prefix := strings.Trim(config.Prefix, "/")
+
+	return &s3PlanStorage{
+		client:     s3.New(sess),
+		bucket:     config.Bucket,
+		prefix:     prefix,
+		versions:   dbPlanStorageVersions{},
+		localETags: map[string]string{},
+	}, nil
+}
+
+func (s *s3PlanStorage) Name() string {
+	if s.prefix == "" {
+		return fmt.Sprintf("s3://%s", s.bucket)
+	}
+	return fmt.Sprintf("s3://%s/%s", s.bucket, s.prefix)
+}
+
+func (s *s3PlanStorage) Fetch(orgId, planId string, scope LockScope) error {
+	key := s.planKey(orgId, planId)
+
+	if scope == LockScopeRead {
+		synced, err := s.versions.get(planId)
+		if err != nil {
+			// fall back to checking the store
+			log.Printf("[Storage] Error getting synced version of plan %s: %v", planId, err)
+		} else if synced != "" && synced == s.getLocalETag(orgId, planId) {
+			return nil
+		}
+	}
+
+	head, err := s.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			// never synced -- plans created before switching storage backends are uploaded on their next commit
+			return nil
+		}
+		return fmt.Errorf("error checking synced plan %s: %v", planId, err)
+	}
+
+	etag := aws.StringValue(head.ETag)
+
+	if etag == s.getLocalETag(orgId, planId) {
+		return nil
+	}
+
+	start := time.Now()
+
+	out, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("error downloading synced plan %s: %v", planId, err)
+	}
+	defer out.Body.Close()
+
+	err = replacePlanDirFromArchive(orgId, planId, out.Body)
+	if err != nil {
+		return fmt.Errorf("error restoring synced plan %s: %v", planId, err)
+	}
+
+	s.setLocalETag(orgId, planId, aws.StringValue(out.ETag))
+
+	log.Printf("[Storage] Fetched plan %s from %s in %v", planId, s.Name(), time.Since(start))
+
+	return nil
+}
+
+func (s *s3PlanStorage) Sync(orgId, planId string) error {
+	start := time.Now()
+
+	tmp, err := os.CreateTemp("", "plandex-plan-*.tar.gz")
+	if err != nil {
+		return fmt.Errorf("error creating temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	err = writePlanDirArchive(getPlanDir(orgId, planId), tmp)
+	if err != nil {
+		return fmt.Errorf("error archiving plan %s: %v", planId, err)
+	}
+
+	_, err = tmp.Seek(0, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("error rewinding plan archive: %v", err)
+	}
+
+	out, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.planKey(orgId, planId)),
+		Body:        tmp,
+		ContentType: aws.String("application/gzip"),
+	})
+	if err != nil {
+		return fmt.Errorf("error uploading plan %s: %v", planId, err)
+	}
+
+	etag := aws.StringValue(out.ETag)
+
+	// if this isn't recorded, other instances could keep reading their stale working copies
+	err = s.versions.set(planId, etag)
+	if err != nil {
+		return fmt.Errorf("error recording synced version of plan %s: %v", planId, err)
+	}
+
+	s.setLocalETag(orgId, planId, etag)
+
+	log.Printf("[Storage] Synced plan %s to %s in %v", planId, s.Name(), time.Since(start))
+
+	return nil
+}
+
+func (s *s3PlanStorage) Delete(orgId, planId string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.planKey(orgId, planId)),
+	})
+	if err != nil && !isS3NotFound(err) {
+		return fmt.Errorf("error deleting synced plan %s: %v", planId, err)
+	}
+
+	err = s.versions.delete(planId)
+	if err != nil {
+		return fmt.Errorf("error deleting synced version of plan %s: %v", planId, err)
+	}
+
+	s.mu.Lock()
+	delete(s.localETags, orgId+"/"+planId)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *s3PlanStorage) planKey(orgId, planId string) string {
+	return path.Join(s.prefix, "orgs", orgId, "plans", planId+".tar.gz")
+}
+
+func (s *s3PlanStorage) getLocalETag(orgId, planId string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// if the working copy is gone, the cached ETag is meaningless
+	if _, err := os.Stat(getPlanDir(orgId, planId)); err != nil {
+		return ""
+	}
+
+	return s.localETags[orgId+"/"+planId]
+}
+
+func (s *s3PlanStorage) setLocalETag(orgId, planId, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.localETags[orgId+"/"+planId] = etag
+}
+
+type dbPlanStorageVersions struct{}
+
+func (dbPlanStorageVersions) get(planId string) (string, error) {
+	var etags []string
+	err := Conn.Select(&etags, "SELECT etag FROM plan_storage_versions WHERE plan_id = $1", planId)
+	if err != nil {
+		return "", fmt.Errorf("error getting plan storage version: %v", err)
+	}
+
+	if len(etags) == 0 {
+		return "", nil
+	}
+
+	return etags[0], nil
+}
+
+func (dbPlanStorageVersions) set(planId, etag string) error {
+	_, err := Conn.Exec("INSERT INTO plan_storage_versions (plan_id, etag) VALUES ($1, $2) ON CONFLICT (plan_id) DO UPDATE SET etag = EXCLUDED.etag", planId, etag)
+	if err != nil {
+		return fmt.Errorf("error setting plan storage version: %v", err)
+	}
+	return nil
+}
+
+func (dbPlanStorageVersions) delete(planId string) error {
+	_, err := Conn.Exec("DELETE FROM plan_storage_versions WHERE plan_id = $1", planId)
+	if err != nil {
+		return fmt.Errorf("error deleting plan storage version: %v", err)
+	}
+	return nil
+}
+
+func isS3NotFound(err error) bool {
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		return reqErr.StatusCode() == http.StatusNotFound
+	}
+	return false
+}
+
+// writePlanDirArchive writes a gzipped tarball of a plan dir, skipping git lock files left by interrupted operations
+func writePlanDirArchive(dir string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".lock") {
+			return nil
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(p)
+			if err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+
+		err = tw.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// replacePlanDirFromArchive extracts a plan archive next to the plan dir, then swaps it in so a failed download never leaves a partial working copy
+func replacePlanDirFromArchive(orgId, planId string, r io.Reader) error {
+	planDir := getPlanDir(orgId, planId)
+	plansDir := filepath.Dir(planDir)
+
+	err := os.MkdirAll(plansDir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("error creating plans dir: %v", err)
+	}
+
+	tmpDir, err := os.MkdirTemp(plansDir, "."+planId+"-fetch-")
+	if err != nil {
+		return fmt.Errorf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = extractArchive(r, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	oldDir := tmpDir + "-old"
+	if _, err := os.Stat(planDir); err == nil {
+		err = os.Rename(planDir, oldDir)
+		if err != nil {
+			return fmt.Errorf("error moving stale plan dir: %v", err)
+		}
+		defer os.RemoveAll(oldDir)
+	}
+
+	err = os.Rename(tmpDir, planDir)
+	if err != nil {
+		return fmt.Errorf("error moving fetched plan dir into place: %v", err)
+	}
+
+	return nil
+}
+
+func extractArchive(r io.Reader, dest string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("error reading gzip: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading tar: %v", err)
+		}
+
+		target := filepath.Join(dest, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, os.FileMode(header.Mode)|0700)
+			if err != nil {
+				return err
+			}
+
+		case tar.TypeSymlink:
+			err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
+			if err != nil {
+				return err
+			}
+			err = os.Symlink(header.Linkname, target)
+			if err != nil {
+				return err
+			}
+
+		case tar.TypeReg:
+			err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
+			if err != nil {
+				return err
+			}
+
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(f, tr)
+			closeErr := f.Close()
+			if err != nil {
+				return err
+			}
+			if closeErr != nil {
+				return closeErr
+			}
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package db
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a minimal stand-in for an S3-compatible store like MinIO, serving path-style object requests
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	requests int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests++
+
+	key := r.URL.Path
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.objects[key] = body
+		w.Header().Set("ETag", etagFor(body))
+
+	case http.MethodHead, http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code></Error>`)
+			}
+			return
+		}
+		w.Header().Set("ETag", etagFor(body))
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func etagFor(body []byte) string {
+	sum := md5.Sum(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) numRequests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+// memPlanStorageVersions stands in for the plan_storage_versions table
+type memPlanStorageVersions struct {
+	mu    sync.Mutex
+	etags map[string]string
+}
+
+func (m *memPlanStorageVersions) get(planId string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.etags[planId], nil
+}
+
+func (m *memPlanStorageVersions) set(planId, etag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.etags[planId] = etag
+	return nil
+}
+
+func (m *memPlanStorageVersions) delete(planId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.etags, planId)
+	return nil
+}
+
+func newTestS3PlanStorage(t *testing.T, url string, versions planStorageVersions) *s3PlanStorage {
+	storage, err := NewS3PlanStorage(S3PlanStorageConfig{
+		Bucket:   "plandex",
+		Prefix:   "test",
+		Endpoint: url,
+		Region:   "us-east-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.versions = versions
+	return storage
+}
+
+func TestS3PlanStorageSyncAndFetch(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	fake := &fakeS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	origBaseDir, origStorage := BaseDir, planStorage
+	defer func() {
+		BaseDir, planStorage = origBaseDir, origStorage
+	}()
+
+	// two instances with their own disks sharing a bucket
+	dirA, dirB := t.TempDir(), t.TempDir()
+	versions := &memPlanStorageVersions{etags: map[string]string{}}
+	storageA := newTestS3PlanStorage(t, srv.URL, versions)
+	storageB := newTestS3PlanStorage(t, srv.URL, versions)
+
+	useInstance := func(dir string, storage *s3PlanStorage) {
+		BaseDir, planStorage = dir, storage
+	}
+
+	commit := func(file, content string) {
+		t.Helper()
+		path := filepath.Join(getPlanContextDir("org", "plan"), file)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		repo := getGitRepo("org", "plan")
+		if err := repo.GitAddAndCommit("main", "add "+file); err != nil {
+			t.Fatal(err)
+		}
+		if err := planStorage.Sync("org", "plan"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	readContext := func(file string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(getPlanContextDir("org", "plan"), file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	useInstance(dirA, storageA)
+	if err := InitPlan("org", "plan"); err != nil {
+		t.Fatal(err)
+	}
+	commit("a.txt", "from a")
+
+	if _, ok := fake.objects["/plandex/test/orgs/org/plans/plan.tar.gz"]; !ok {
+		t.Fatalf("expected plan to be synced, got keys %v", fake.objects)
+	}
+
+	useInstance(dirB, storageB)
+	if err := storageB.Fetch("org", "plan", LockScopeWrite); err != nil {
+		t.Fatal(err)
+	}
+	if got := readContext("a.txt"); got != "from a" {
+		t.Errorf("expected fetched context 'from a', got %q", got)
+	}
+	commit("b.txt", "from b")
+
+	out, err := exec.Command("git", "-C", getPlanDir("org", "plan"), "log", "--oneline").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git log failed on fetched repo: %v, %s", err, out)
+	}
+	if n := len(strings.Split(strings.TrimSpace(string(out)), "\n")); n != 2 {
+		t.Errorf("expected 2 commits after fetch and commit, got %d:\n%s", n, out)
+	}
+
+	// instance a picks up b's commit on its next fetch
+	useInstance(dirA, storageA)
+	if err := storageA.Fetch("org", "plan", LockScopeWrite); err != nil {
+		t.Fatal(err)
+	}
+	if got := readContext("b.txt"); got != "from b" {
+		t.Errorf("expected fetched context 'from b', got %q", got)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(getPlanDir("org", "plan")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected temp dirs to be cleaned up after fetch, got %d entries", len(entries))
+	}
+
+	if err := DeletePlanDir("org", "plan"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.objects) != 0 {
+		t.Errorf("expected synced plan to be deleted, got keys %v", fake.objects)
+	}
+	if len(versions.etags) != 0 {
+		t.Errorf("expected synced version to be deleted, got %v", versions.etags)
+	}
+
+	// nothing synced is a no-op
+	if err := storageA.Fetch("org", "plan", LockScopeWrite); err != nil {
+		t.Errorf("expected fetch of unsynced plan to succeed, got %v", err)
+	}
+}
+
+func TestS3PlanStorageReadFetchSkipsStoreWhenCurrent(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	fake := &fakeS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	origBaseDir, origStorage := BaseDir, planStorage
+	defer func() {
+		BaseDir, planStorage = origBaseDir, origStorage
+	}()
+
+	dirA, dirB := t.TempDir(), t.TempDir()
+	versions := &memPlanStorageVersions{etags: map[string]string{}}
+	storageA := newTestS3PlanStorage(t, srv.URL, versions)
+	storageB := newTestS3PlanStorage(t, srv.URL, versions)
+
+	BaseDir, planStorage = dirA, storageA
+	if err := InitPlan("org", "plan"); err != nil {
+		t.Fatal(err)
+	}
+
+	// b has no working copy yet, so even a read downloads it
+	BaseDir, planStorage = dirB, storageB
+	if err := storageB.Fetch("org", "plan", LockScopeRead); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(getPlanDir("org", "plan")); err != nil {
+		t.Fatalf("expected the plan to be fetched: %v", err)
+	}
+
+	before := fake.numRequests()
+	if err := storageB.Fetch("org", "plan", LockScopeRead); err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.numRequests() - before; n != 0 {
+		t.Errorf("expected a read with a current working copy to skip the store, got %d requests", n)
+	}
+
+	// writes always check the store
+	before = fake.numRequests()
+	if err := storageB.Fetch("org", "plan", LockScopeWrite); err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.numRequests() - before; n != 1 {
+		t.Errorf("expected a write to check the store once, got %d requests", n)
+	}
+
+	// once a's change is synced, b's next read fetches it
+	BaseDir, planStorage = dirA, storageA
+	path := filepath.Join(getPlanContextDir("org", "plan"), "a.txt")
+	if err := os.WriteFile(path, []byte("from a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := getGitRepo("org", "plan").GitAddAndCommit("main", "add a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := storageA.Sync("org", "plan"); err != nil {
+		t.Fatal(err)
+	}
+
+	BaseDir, planStorage = dirB, storageB
+	if err := storageB.Fetch("org", "plan", LockScopeRead); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(getPlanContextDir("org", "plan"), "a.txt"))
+	if err != nil || string(b) != "from a" {
+		t.Errorf("expected the read to fetch a's change, got %q (%v)", b, err)
+	}
+}
+
+func TestExtractArchiveRejectsPathTraversal(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "ok.txt"), []byte("ok"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writePlanDirArchive(src, pw))
+	}()
+
+	dest := t.TempDir()
+	if err := extractArchive(pr, dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "ok.txt")); err != nil {
+		t.Errorf("expected ok.txt to be extracted: %v", err)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.tar.gz")
+	writeTarGz(t, bad, "../escaped.txt", "nope")
+
+	f, err := os.Open(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := extractArchive(f, dest); err == nil {
+		t.Error("expected an error for an archive entry outside the destination")
+	}
+}
+
+func writeTarGz(t *testing.T, path, name, content string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
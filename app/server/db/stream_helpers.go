@@ -18,7 +18,7 @@ const modelStreamHeartbeatInterval = 1 * time.Second
 const modelStreamHeartbeatTimeout = 5 * time.Second
 
 func StoreModelStream(stream *ModelStream, ctx context.Context, cancelFn context.CancelFunc) error {
-	query := `INSERT INTO model_streams (org_id, plan_id, internal_ip, branch) VALUES (:org_id, :plan_id, :internal_ip, :branch) RETURNING id, created_at`
+	query := `INSERT INTO model_streams (org_id, plan_id, internal_ip, instance_id, branch) VALUES (:org_id, :plan_id, :internal_ip, :instance_id, :branch) RETURNING id, created_at`
 
 	row, err := Conn.NamedQuery(query, stream)
 
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	shared "plandex-shared"
+)
+
+func InsertModelUsage(usage *ModelUsage) error {
+	query := `
+		INSERT INTO model_usage (
+			org_id, user_id, plan_id, session_id,
+			model_id, model_name, model_provider, model_role, purpose,
+			input_tokens, output_tokens, cached_tokens,
+			cost, priced, cache_hit
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING id, created_at
+	`
+
+	err := Conn.QueryRow(query,
+		usage.OrgId, usage.UserId, usage.PlanId, usage.SessionId,
+		usage.ModelId, usage.ModelName, usage.ModelProvider, usage.ModelRole, usage.Purpose,
+		usage.InputTokens, usage.OutputTokens, usage.CachedTokens,
+		usage.Cost, usage.Priced, usage.CacheHit,
+	).Scan(&usage.Id, &usage.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("error inserting model usage: %v", err)
+	}
+
+	return nil
+}
+
+type ModelUsageFilter struct {
+	OrgId string
+	// empty for every user in the org
+	UserId    string
+	PlanId    string
+	SessionId string
+	Since     *time.Time
+}
+
+func (f ModelUsageFilter) where() (string, []interface{}) {
+	conds := []string{"org_id = $1"}
+	args := []interface{}{f.OrgId}
+
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.UserId != "" {
+		add("user_id = $%d", f.UserId)
+	}
+	if f.PlanId != "" {
+		add("plan_id = $%d", f.PlanId)
+	}
+	if f.SessionId != "" {
+		add("session_id = $%d", f.SessionId)
+	}
+	if f.Since != nil {
+		add("created_at >= $%d", *f.Since)
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// columns (or expressions) usage can be grouped by--only these are interpolated into queries
+var modelUsageGroupings = map[string]string{
+	"total":   "''",
+	"plan":    "plan_id::text",
+	"role":    "model_role",
+	"model":   "model_name",
+	"purpose": "purpose",
+	"day":     "to_char(created_at, 'YYYY-MM-DD')",
+}
+
+// GetModelUsageBreakdown sums usage matching the filter, grouped by one of "total", "plan", "role", "model", "purpose" or "day"
+func GetModelUsageBreakdown(filter ModelUsageFilter, groupBy string) ([]shared.UsageBreakdown, error) {
+	groupExpr, ok := modelUsageGroupings[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid usage grouping: %s", groupBy)
+	}
+
+	where, args := filter.where()
+
+	orderBy := "cost DESC, num_requests DESC"
+	if groupBy == "day" {
+		orderBy = "key"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			%s AS key,
+			COUNT(*) AS num_requests,
+			COALESCE(SUM(input_tokens), 0) AS input_tokens,
+			COALESCE(SUM(output_tokens), 0) AS output_tokens,
+			COALESCE(SUM(cached_tokens), 0) AS cached_tokens,
+			COALESCE(SUM(cost), 0) AS cost
+		FROM model_usage
+		WHERE %s
+		GROUP BY 1
+		ORDER BY %s
+	`, groupExpr, where, orderBy)
+
+	var rows []struct {
+		Key          string  `db:"key"`
+		NumRequests  int     `db:"num_requests"`
+		InputTokens  int     `db:"input_tokens"`
+		OutputTokens int     `db:"output_tokens"`
+		CachedTokens int     `db:"cached_tokens"`
+		Cost         float64 `db:"cost"`
+	}
+	err := Conn.Select(&rows, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting model usage by %s: %v", groupBy, err)
+	}
+
+	res := make([]shared.UsageBreakdown, len(rows))
+	for i, row := range rows {
+		res[i] = shared.UsageBreakdown(row)
+	}
+
+	return res, nil
+}
+
+// GetUnpricedUsageModels lists models used without pricing, whose cost isn't included in usage totals
+func GetUnpricedUsageModels(filter ModelUsageFilter) ([]string, error) {
+	where, args := filter.where()
+
+	var models []string
+	err := Conn.Select(&models, fmt.Sprintf("SELECT DISTINCT model_name FROM model_usage WHERE %s AND NOT priced ORDER BY model_name", where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unpriced usage models: %v", err)
+	}
+
+	return models, nil
+}
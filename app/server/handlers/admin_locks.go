@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"plandex-server/db"
+	"plandex-server/host"
+	"plandex-server/types"
+
+	shared "plandex-shared"
+
+	"github.com/gorilla/mux"
+)
+
+func ListRepoLocksHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for ListRepoLocksHandler")
+
+	auth := Authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	if !authorizeLockAdmin(w, auth) {
+		return
+	}
+
+	locks, err := db.ListOrgRepoLocks(auth.OrgId)
+	if err != nil {
+		log.Printf("Error listing repo locks: %v\n", err)
+		http.Error(w, "Error listing repo locks: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ops := db.ListOrgRepoOperations(auth.OrgId)
+
+	planIdsSet := map[string]bool{}
+	for _, lock := range locks {
+		planIdsSet[lock.PlanId] = true
+	}
+	for _, op := range ops {
+		planIdsSet[op.PlanId] = true
+	}
+
+	planIds := make([]string, 0, len(planIdsSet))
+	for planId := range planIdsSet {
+		planIds = append(planIds, planId)
+	}
+
+	planNamesById := map[string]string{}
+	if len(planIds) > 0 {
+		planNamesById, err = db.GetPlanNamesById(planIds)
+		if err != nil {
+			log.Printf("Error getting plan names: %v\n", err)
+			http.Error(w, "Error getting plan names: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	bytes, err := json.Marshal(shared.ListRepoLocksResponse{
+		Locks:         locks,
+		Operations:    ops,
+		InstanceId:    host.InstanceId,
+		PlanNamesById: planNamesById,
+	})
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+
+	log.Println("Successfully processed request for ListRepoLocksHandler")
+}
+
+func ForceReleaseRepoLockHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for ForceReleaseRepoLockHandler")
+
+	auth := Authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	if !authorizeLockAdmin(w, auth) {
+		return
+	}
+
+	lockId := mux.Vars(r)["lockId"]
+	log.Println("lockId: ", lockId)
+
+	var requestBody shared.ForceReleaseRepoLockRequest
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading request body: %v\n", err)
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &requestBody); err != nil {
+			log.Printf("Error parsing request body: %v\n", err)
+			http.Error(w, "Error parsing request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	lock, err := db.ForceReleaseRepoLock(auth.OrgId, lockId, auth.User.Id, requestBody.Note)
+	if err != nil {
+		log.Printf("Error releasing repo lock: %v\n", err)
+		http.Error(w, "Error releasing repo lock: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if lock == nil {
+		http.Error(w, "Lock not found", http.StatusNotFound)
+		return
+	}
+
+	bytes, err := json.Marshal(lock)
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+
+	log.Println("Successfully processed request for ForceReleaseRepoLockHandler")
+}
+
+// lock introspection is limited to org members who can update any plan (owners and admins), since it exposes and can release locks on plans they don't own
+func authorizeLockAdmin(w http.ResponseWriter, auth *types.ServerAuth) bool {
+	if !auth.HasPermission(shared.PermissionUpdateAnyPlan) {
+		log.Println("User does not have permission to manage repo locks")
+		http.Error(w, "User does not have permission to manage repo locks", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
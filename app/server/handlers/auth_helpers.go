@@ -463,6 +463,12 @@ func requireMinClientVersion(w http.ResponseWriter, r *http.Request, minVersion
 func execAuthenticate(w http.ResponseWriter, r *http.Request, requireOrg bool, raiseErr bool) *types.ServerAuth {
 	log.Println("authenticating request")
 
+	// requests relayed over the plan bus were authenticated by the instance that received them and don't carry credentials
+	if auth, ok := r.Context().Value(planBusAuthContextKey{}).(*types.ServerAuth); ok {
+		log.Printf("UserId: %s, OrgId: %s (relayed over plan bus)\n", auth.User.Id, auth.OrgId)
+		return auth
+	}
+
 	parsed, err := GetAuthHeader(r)
 
 	if err != nil {
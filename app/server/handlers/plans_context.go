@@ -445,3 +445,93 @@ func DeleteContextHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Write(bytes)
 }
+
+func PinContextHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for PinContextHandler")
+
+	auth := Authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	branchName := vars["branch"]
+	log.Println("planId: ", planId)
+
+	plan := authorizePlan(w, planId, auth)
+	if plan == nil {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading request body: %v\n", err)
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var requestBody shared.PinContextRequest
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		log.Printf("Error parsing request body: %v\n", err)
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+
+	var commitMsg string
+
+	err = db.ExecRepoOperation(db.ExecRepoOperationParams{
+		OrgId:          auth.OrgId,
+		UserId:         auth.User.Id,
+		PlanId:         planId,
+		Branch:         branchName,
+		Reason:         "pin contexts",
+		Scope:          db.LockScopeWrite,
+		Ctx:            ctx,
+		CancelFn:       cancel,
+		ClearRepoOnErr: true,
+	}, func(repo *db.GitRepo) error {
+		updated, err := db.SetContextsPinned(auth.OrgId, planId, requestBody.Ids, requestBody.Pinned)
+		if err != nil {
+			return fmt.Errorf("error pinning contexts: %v", err)
+		}
+
+		var apiContexts []*shared.Context
+		for _, dbContext := range updated {
+			apiContexts = append(apiContexts, dbContext.ToApi())
+		}
+
+		commitMsg = shared.SummaryForPinContext(apiContexts, requestBody.Pinned)
+
+		if len(updated) == 0 {
+			return nil
+		}
+
+		err = repo.GitAddAndCommit(branchName, commitMsg)
+		if err != nil {
+			return fmt.Errorf("error committing changes: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("Error pinning contexts: %v\n", err)
+		http.Error(w, "Error pinning contexts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(shared.PinContextResponse{Msg: commitMsg})
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Println("Successfully processed PinContextHandler request")
+
+	w.Write(bytes)
+}
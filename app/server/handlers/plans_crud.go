@@ -489,12 +489,20 @@ func ListPlansRunningHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	numQueuedByComposite, err := db.GetNumQueuedPromptsByBranch(planIds)
+	if err != nil {
+		log.Printf("Error counting queued prompts: %v\n", err)
+		http.Error(w, "Error counting queued prompts", http.StatusInternalServerError)
+		return
+	}
+
 	res := shared.ListPlansRunningResponse{
 		Branches:                   []*shared.Branch{},
 		StreamStartedAtByBranchId:  map[string]time.Time{},
 		StreamFinishedAtByBranchId: map[string]time.Time{},
 		PlansById:                  map[string]*shared.Plan{},
 		StreamIdByBranchId:         map[string]string{},
+		QueuedPromptsByBranchId:    map[string]int{},
 	}
 
 	var apiPlansById = make(map[string]*shared.Plan)
@@ -536,6 +544,7 @@ func ListPlansRunningHandler(w http.ResponseWriter, r *http.Request) {
 			res.StreamFinishedAtByBranchId[apiBranch.Id] = *stream.FinishedAt
 		}
 		res.StreamIdByBranchId[apiBranch.Id] = stream.Id
+		res.QueuedPromptsByBranchId[apiBranch.Id] = numQueuedByComposite[branchComposite]
 
 		res.PlansById[stream.PlanId] = apiPlan
 	}
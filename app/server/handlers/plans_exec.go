@@ -13,6 +13,7 @@ import (
 	modelPlan "plandex-server/model/plan"
 	"plandex-server/notify"
 	"plandex-server/types"
+	"strconv"
 	"time"
 
 	shared "plandex-shared"
@@ -102,6 +103,7 @@ func TellPlanHandler(w http.ResponseWriter, r *http.Request) {
 		Auth:     auth,
 		Req:      &requestBody,
 		AuthVars: res.authVars,
+		Ctx:      r.Context(),
 	})
 
 	if err != nil {
@@ -112,7 +114,7 @@ func TellPlanHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if requestBody.ConnectStream {
-		startResponseStream(r.Context(), w, auth, planId, branch, false)
+		startResponseStream(w, r, auth, planId, branch, false, 0)
 	}
 
 	log.Println("Successfully processed request for TellPlanHandler")
@@ -190,6 +192,7 @@ func BuildPlanHandler(w http.ResponseWriter, r *http.Request) {
 		SessionId:     requestBody.SessionId,
 		OrgUserConfig: orgUserConfig,
 		Settings:      settings,
+		Ctx:           r.Context(),
 	})
 
 	if err != nil {
@@ -207,12 +210,112 @@ func BuildPlanHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if requestBody.ConnectStream {
-		startResponseStream(r.Context(), w, auth, planId, branch, false)
+		startResponseStream(w, r, auth, planId, branch, false, 0)
 	}
 
 	log.Println("Successfully processed request for BuildPlanHandler")
 }
 
+func ResumePlanHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for ResumePlanHandler", "ip:", host.Ip)
+	auth := Authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	branch := vars["branch"]
+
+	log.Println("planId: ", planId)
+	plan := authorizePlanExecUpdate(w, planId, auth)
+	if plan == nil {
+		return
+	}
+
+	settings, err := db.GetPlanSettings(plan)
+	if err != nil {
+		log.Printf("Error getting plan settings: %v\n", err)
+		http.Error(w, "Error getting plan settings", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading request body: %v\n", err)
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var requestBody shared.ResumePlanRequest
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		log.Printf("Error parsing request body: %v\n", err)
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+
+	_, apiErr := hooks.ExecHook(hooks.WillTellPlan, hooks.HookParams{
+		Auth: auth,
+		Plan: plan,
+	})
+	if apiErr != nil {
+		writeApiError(w, *apiErr)
+		return
+	}
+
+	orgUserConfig, err := db.GetOrgUserConfig(auth.User.Id, auth.OrgId)
+	if err != nil {
+		log.Printf("Error getting org user config: %v\n", err)
+		http.Error(w, "Error getting org user config", http.StatusInternalServerError)
+		return
+	}
+
+	res := initClients(
+		initClientsParams{
+			w:             w,
+			auth:          auth,
+			authVars:      requestBody.AuthVars,
+			plan:          plan,
+			settings:      settings,
+			orgUserConfig: orgUserConfig,
+		},
+	)
+	if res.clients == nil {
+		return
+	}
+
+	resumed, err := modelPlan.Resume(modelPlan.ResumeParams{
+		Clients:       res.clients,
+		AuthVars:      res.authVars,
+		Plan:          plan,
+		Branch:        branch,
+		Auth:          auth,
+		SessionId:     requestBody.SessionId,
+		OrgUserConfig: orgUserConfig,
+		Settings:      settings,
+		Ctx:           r.Context(),
+	})
+
+	if err != nil {
+		log.Printf("Error resuming plan: %v\n", err)
+		go notify.NotifyErr(notify.SeverityError, fmt.Errorf("error resuming plan: %v", err))
+		http.Error(w, "Error resuming plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !resumed {
+		http.Error(w, shared.NothingToResumeErr, http.StatusNotFound)
+		return
+	}
+
+	if requestBody.ConnectStream {
+		startResponseStream(w, r, auth, planId, branch, false, 0)
+	}
+
+	log.Println("Successfully processed request for ResumePlanHandler")
+}
+
 func ConnectPlanHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Received request for ConnectPlanHandler", "ip:", host.Ip)
 
@@ -250,7 +353,18 @@ func ConnectPlanHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	startResponseStream(r.Context(), w, auth, planId, branch, true)
+	var lastEventId int64
+	if h := r.Header.Get("Last-Event-Id"); h != "" {
+		id, err := strconv.ParseInt(h, 10, 64)
+		if err != nil {
+			log.Printf("Invalid Last-Event-Id header: %s\n", h)
+			http.Error(w, "Invalid Last-Event-Id header", http.StatusBadRequest)
+			return
+		}
+		lastEventId = id
+	}
+
+	startResponseStream(w, r, auth, planId, branch, true, lastEventId)
 
 	log.Println("Successfully processed request for ConnectPlanHandler")
 }
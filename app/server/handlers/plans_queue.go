@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"plandex-server/db"
+	"plandex-server/hooks"
+	"plandex-server/host"
+	modelPlan "plandex-server/model/plan"
+	"plandex-server/notify"
+
+	shared "plandex-shared"
+
+	"github.com/gorilla/mux"
+)
+
+func QueuePromptHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for QueuePromptHandler", "ip:", host.Ip)
+
+	auth := Authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	branch := vars["branch"]
+
+	log.Println("planId: ", planId, "branch: ", branch)
+
+	plan := authorizePlanExecUpdate(w, planId, auth)
+	if plan == nil {
+		return
+	}
+
+	settings, err := db.GetPlanSettings(plan)
+	if err != nil {
+		log.Printf("Error getting plan settings: %v\n", err)
+		http.Error(w, "Error getting plan settings", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error reading request body: %v\n", err)
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var requestBody shared.TellPlanRequest
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		log.Printf("Error parsing request body: %v\n", err)
+		http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		return
+	}
+
+	if requestBody.Prompt == "" {
+		http.Error(w, "Prompt is required", http.StatusBadRequest)
+		return
+	}
+
+	_, apiErr := hooks.ExecHook(hooks.WillTellPlan, hooks.HookParams{
+		Auth: auth,
+		Plan: plan,
+	})
+	if apiErr != nil {
+		writeApiError(w, *apiErr)
+		return
+	}
+
+	orgUserConfig, err := db.GetOrgUserConfig(auth.User.Id, auth.OrgId)
+	if err != nil {
+		log.Printf("Error getting org user config: %v\n", err)
+		http.Error(w, "Error getting org user config", http.StatusInternalServerError)
+		return
+	}
+
+	res := initClients(
+		initClientsParams{
+			w:             w,
+			auth:          auth,
+			apiKeys:       requestBody.ApiKeys,
+			openAIOrgId:   requestBody.OpenAIOrgId,
+			authVars:      requestBody.AuthVars,
+			plan:          plan,
+			settings:      settings,
+			orgUserConfig: orgUserConfig,
+		},
+	)
+	if res.clients == nil {
+		return
+	}
+
+	queued, position, err := modelPlan.EnqueuePrompt(modelPlan.EnqueuePromptParams{
+		Clients:  res.clients,
+		AuthVars: res.authVars,
+		Plan:     plan,
+		Branch:   branch,
+		Auth:     auth,
+		Req:      &requestBody,
+	})
+
+	if err != nil {
+		log.Printf("Error queuing prompt: %v\n", err)
+		go notify.NotifyErr(notify.SeverityError, fmt.Errorf("error queuing prompt: %v", err))
+		http.Error(w, "Error queuing prompt: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(shared.QueuePromptResponse{
+		QueuedPrompt: queued.ToApi(),
+		Position:     position,
+	})
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+
+	log.Println("Successfully processed request for QueuePromptHandler")
+}
+
+func ListQueuedPromptsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for ListQueuedPromptsHandler")
+
+	auth := Authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	branch := vars["branch"]
+
+	log.Println("planId: ", planId, "branch: ", branch)
+
+	plan := authorizePlan(w, planId, auth)
+	if plan == nil {
+		return
+	}
+
+	prompts, err := db.ListQueuedPrompts(planId, branch)
+	if err != nil {
+		log.Printf("Error listing queued prompts: %v\n", err)
+		http.Error(w, "Error listing queued prompts", http.StatusInternalServerError)
+		return
+	}
+
+	apiPrompts := make([]*shared.QueuedPrompt, len(prompts))
+	for i, p := range prompts {
+		apiPrompts[i] = p.ToApi()
+	}
+
+	bytes, err := json.Marshal(apiPrompts)
+	if err != nil {
+		log.Printf("Error marshalling queued prompts: %v\n", err)
+		http.Error(w, "Error marshalling queued prompts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+
+	log.Println("Successfully processed request for ListQueuedPromptsHandler")
+}
+
+func DeleteQueuedPromptHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for DeleteQueuedPromptHandler")
+
+	auth := Authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	branch := vars["branch"]
+	queuedPromptId := vars["queuedPromptId"]
+
+	log.Println("planId: ", planId, "branch: ", branch, "queuedPromptId: ", queuedPromptId)
+
+	plan := authorizePlanExecUpdate(w, planId, auth)
+	if plan == nil {
+		return
+	}
+
+	removed, err := modelPlan.RemoveQueuedPrompt(planId, branch, queuedPromptId)
+	if err != nil {
+		log.Printf("Error removing queued prompt: %v\n", err)
+		http.Error(w, "Error removing queued prompt", http.StatusInternalServerError)
+		return
+	}
+
+	if !removed {
+		http.Error(w, "Queued prompt not found -- it may have already started", http.StatusNotFound)
+		return
+	}
+
+	log.Println("Successfully processed request for DeleteQueuedPromptHandler")
+}
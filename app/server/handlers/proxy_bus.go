@@ -0,0 +1,522 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"plandex-server/db"
+	"plandex-server/host"
+	"plandex-server/notify"
+	"plandex-server/shutdown"
+	"plandex-server/types"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	shared "plandex-shared"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// With PLANDEX_PLAN_BUS=postgres, requests for an active plan running on another instance are relayed over Postgres LISTEN/NOTIFY instead of being proxied to the instance's IP. Each instance listens on its own channel. The requesting instance authenticates the request and publishes it, without its credentials, to the owning instance's channel, which runs the same handler as a proxied request as the authenticated user and publishes the response back as it's written, so streams are relayed as they happen.
+
+const planBusResponseTimeout = 10 * time.Second
+
+// streams send heartbeats every HeartbeatInterval, so a longer silence means the owning instance is gone
+const planBusIdleTimeout = 4 * HeartbeatInterval
+
+const planBusPingInterval = HeartbeatInterval
+
+// response messages queued for a request before it's dropped because its client isn't keeping up
+const planBusInboxMaxMessages = 4096
+
+// request headers that carry credentials -- they aren't relayed, since the request is authenticated before it's published
+var planBusCredentialHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+type planBusMessageKind string
+
+const (
+	planBusMessageRequest planBusMessageKind = "request"
+	planBusMessageHeader  planBusMessageKind = "header"
+	planBusMessageChunk   planBusMessageKind = "chunk"
+	planBusMessageDone    planBusMessageKind = "done"
+	planBusMessagePing    planBusMessageKind = "ping"
+	planBusMessageCancel  planBusMessageKind = "cancel"
+)
+
+type planBusMessage struct {
+	Kind           planBusMessageKind `json:"kind"`
+	RequestId      string             `json:"requestId"`
+	FromInstanceId string             `json:"fromInstanceId"`
+
+	PlanId     string      `json:"planId,omitempty"`
+	Branch     string      `json:"branch,omitempty"`
+	PlanMethod string      `json:"planMethod,omitempty"`
+	HttpMethod string      `json:"httpMethod,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Status     int         `json:"status,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+
+	// who the requesting instance authenticated the request as
+	Identity *planBusIdentity `json:"identity,omitempty"`
+}
+
+type planBusIdentity struct {
+	UserId      string `json:"userId"`
+	OrgId       string `json:"orgId"`
+	AuthTokenId string `json:"authTokenId"`
+}
+
+// relayed requests carry the auth of the user the requesting instance authenticated in their context, and Authenticate uses it instead of the (stripped) credentials
+type planBusAuthContextKey struct{}
+
+// handlers that can run for an active plan on another instance -- the same methods proxyActivePlanMethod is called with
+var planBusHandlers = map[string]http.HandlerFunc{
+	"connect":              ConnectPlanHandler,
+	"stop":                 StopPlanHandler,
+	"respond_missing_file": RespondMissingFileHandler,
+	"auto_load_context":    AutoLoadContextHandler,
+	"build_status":         GetBuildStatusHandler,
+}
+
+var (
+	// responses this instance is waiting on, by request id
+	planBusPending   = map[string]*planBusInbox{}
+	planBusPendingMu sync.Mutex
+
+	// requests this instance is handling for other instances, by request id
+	planBusServing   = map[string]*planBusServedRequest{}
+	planBusServingMu sync.Mutex
+)
+
+type planBusServedRequest struct {
+	cancel   context.CancelFunc
+	lastPing time.Time
+}
+
+func StartPlanBus() error {
+	if !db.PlanBusEnabled() {
+		return nil
+	}
+
+	err := db.StartPubSub(shutdown.ShutdownCtx)
+	if err != nil {
+		return fmt.Errorf("error starting pubsub: %v", err)
+	}
+
+	err = db.Listen(planBusChannel(host.InstanceId), onPlanBusMessage)
+	if err != nil {
+		return fmt.Errorf("error listening for plan bus messages: %v", err)
+	}
+
+	go expirePlanBusRequests()
+
+	log.Printf("Plan bus started for instance %s\n", host.InstanceId)
+
+	return nil
+}
+
+func planBusChannel(instanceId string) string {
+	return "plandex_instance_" + strings.ReplaceAll(instanceId, "-", "")
+}
+
+func publishPlanBusMessage(instanceId string, msg *planBusMessage) error {
+	msg.FromInstanceId = host.InstanceId
+
+	bytes, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error marshalling plan bus message: %v", err)
+	}
+
+	return db.Publish(planBusChannel(instanceId), bytes)
+}
+
+func onPlanBusMessage(payload []byte) {
+	var msg planBusMessage
+	err := json.Unmarshal(payload, &msg)
+	if err != nil {
+		log.Printf("Error unmarshalling plan bus message: %v\n", err)
+		return
+	}
+
+	switch msg.Kind {
+	case planBusMessageRequest:
+		go servePlanBusRequest(&msg)
+
+	case planBusMessagePing, planBusMessageCancel:
+		planBusServingMu.Lock()
+		served, ok := planBusServing[msg.RequestId]
+		if ok {
+			if msg.Kind == planBusMessageCancel {
+				served.cancel()
+			} else {
+				served.lastPing = time.Now()
+			}
+		}
+		planBusServingMu.Unlock()
+
+	default:
+		planBusPendingMu.Lock()
+		inbox, ok := planBusPending[msg.RequestId]
+		planBusPendingMu.Unlock()
+
+		if !ok {
+			return
+		}
+
+		// never blocks -- this runs for every message on the instance's channel, so waiting on one slow client would hold up every other relayed request and their pings
+		inbox.push(&msg)
+	}
+}
+
+// planBusInbox queues response messages, in order, for a request this instance is waiting on
+type planBusInbox struct {
+	mu       sync.Mutex
+	msgs     []*planBusMessage
+	overflow bool
+
+	ready chan struct{}
+}
+
+func newPlanBusInbox() *planBusInbox {
+	return &planBusInbox{ready: make(chan struct{}, 1)}
+}
+
+func (in *planBusInbox) push(msg *planBusMessage) {
+	in.mu.Lock()
+	if len(in.msgs) >= planBusInboxMaxMessages {
+		in.overflow = true
+	} else {
+		in.msgs = append(in.msgs, msg)
+	}
+	in.mu.Unlock()
+
+	select {
+	case in.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the queued messages, and whether any were dropped because the inbox was full
+func (in *planBusInbox) take() ([]*planBusMessage, bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	msgs := in.msgs
+	in.msgs = nil
+	return msgs, in.overflow
+}
+
+// planBusRequestHeader is the header relayed with a request, minus its credentials
+func planBusRequestHeader(r *http.Request) http.Header {
+	header := r.Header.Clone()
+	for _, name := range planBusCredentialHeaders {
+		header.Del(name)
+	}
+	return header
+}
+
+// proxyViaPlanBus relays a request for an active plan to the instance running it, writing its response to w as it arrives
+func proxyViaPlanBus(w http.ResponseWriter, r *http.Request, instanceId, planId, branch, method string) {
+	log.Printf("Relaying %s request for plan %s on branch %s to instance %s over plan bus\n", method, planId, branch, instanceId)
+
+	// the owning instance trusts the identity sent with the request, so it's only sent for a request that authenticates here
+	auth := Authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	var body []byte
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			log.Printf("Error reading request body: %v\n", err)
+			http.Error(w, "Error reading request body", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	requestId := uuid.New().String()
+	inbox := newPlanBusInbox()
+
+	planBusPendingMu.Lock()
+	planBusPending[requestId] = inbox
+	planBusPendingMu.Unlock()
+
+	defer func() {
+		planBusPendingMu.Lock()
+		delete(planBusPending, requestId)
+		planBusPendingMu.Unlock()
+	}()
+
+	err := publishPlanBusMessage(instanceId, &planBusMessage{
+		Kind:       planBusMessageRequest,
+		RequestId:  requestId,
+		PlanId:     planId,
+		Branch:     branch,
+		PlanMethod: method,
+		HttpMethod: r.Method,
+		Header:     planBusRequestHeader(r),
+		Body:       body,
+		Identity: &planBusIdentity{
+			UserId:      auth.User.Id,
+			OrgId:       auth.OrgId,
+			AuthTokenId: auth.AuthToken.Id,
+		},
+	})
+	if err != nil {
+		log.Printf("Error publishing plan bus request: %v\n", err)
+		http.Error(w, "Error relaying request", http.StatusInternalServerError)
+		return
+	}
+
+	cancelRemote := func() {
+		err := publishPlanBusMessage(instanceId, &planBusMessage{Kind: planBusMessageCancel, RequestId: requestId})
+		if err != nil {
+			log.Printf("Error publishing plan bus cancel: %v\n", err)
+		}
+	}
+
+	pingTicker := time.NewTicker(planBusPingInterval)
+	defer pingTicker.Stop()
+
+	timeout := time.NewTimer(planBusResponseTimeout)
+	defer timeout.Stop()
+
+	wroteHeader := false
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Printf("Plan bus request %s: client disconnected\n", requestId)
+			cancelRemote()
+			return
+
+		case <-timeout.C:
+			log.Printf("Plan bus request %s: timed out waiting for instance %s\n", requestId, instanceId)
+			cancelRemote()
+			if !wroteHeader {
+				http.Error(w, "Timed out waiting for active plan", http.StatusGatewayTimeout)
+			}
+			return
+
+		case <-pingTicker.C:
+			err := publishPlanBusMessage(instanceId, &planBusMessage{Kind: planBusMessagePing, RequestId: requestId})
+			if err != nil {
+				log.Printf("Error publishing plan bus ping: %v\n", err)
+			}
+
+		case <-inbox.ready:
+			timeout.Reset(planBusIdleTimeout)
+
+			msgs, overflow := inbox.take()
+			if overflow {
+				log.Printf("Plan bus request %s: client fell too far behind -- dropping request\n", requestId)
+				cancelRemote()
+				return
+			}
+
+			for _, msg := range msgs {
+				switch msg.Kind {
+				case planBusMessageHeader:
+					for name, values := range msg.Header {
+						for _, v := range values {
+							w.Header().Add(name, v)
+						}
+					}
+					w.WriteHeader(msg.Status)
+					wroteHeader = true
+
+				case planBusMessageChunk:
+					_, err := w.Write(msg.Body)
+					if err != nil {
+						log.Printf("Plan bus request %s: error writing to client: %v\n", requestId, err)
+						cancelRemote()
+						return
+					}
+					if flusher, ok := w.(http.Flusher); ok {
+						flusher.Flush()
+					}
+
+				case planBusMessageDone:
+					log.Printf("Plan bus request %s: done\n", requestId)
+					return
+				}
+			}
+		}
+	}
+}
+
+func servePlanBusRequest(msg *planBusMessage) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic serving plan bus request: %v\n%s", r, debug.Stack())
+			go notify.NotifyErr(notify.SeverityError, fmt.Errorf("panic serving plan bus request: %v\n%s", r, debug.Stack()))
+		}
+	}()
+
+	handler, ok := planBusHandlers[msg.PlanMethod]
+	if !ok {
+		log.Printf("Plan bus: unknown plan method %s\n", msg.PlanMethod)
+		return
+	}
+
+	w := &planBusResponseWriter{
+		requestId:  msg.RequestId,
+		instanceId: msg.FromInstanceId,
+		header:     http.Header{},
+	}
+
+	defer func() {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+
+		err := publishPlanBusMessage(msg.FromInstanceId, &planBusMessage{Kind: planBusMessageDone, RequestId: msg.RequestId})
+		if err != nil {
+			log.Printf("Error publishing plan bus done message: %v\n", err)
+		}
+	}()
+
+	if msg.Identity == nil {
+		log.Printf("Plan bus: request %s has no identity\n", msg.RequestId)
+		http.Error(w, "Relayed request isn't authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	auth, err := planBusAuth(msg.Identity)
+	if err != nil {
+		log.Printf("Plan bus: error loading auth for request %s: %v\n", msg.RequestId, err)
+		http.Error(w, "Error authenticating relayed request", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Serving plan bus %s request %s for plan %s on branch %s from instance %s\n", msg.PlanMethod, msg.RequestId, msg.PlanId, msg.Branch, msg.FromInstanceId)
+
+	ctx, cancel := context.WithCancel(context.WithValue(shutdown.ShutdownCtx, planBusAuthContextKey{}, auth))
+	defer cancel()
+
+	planBusServingMu.Lock()
+	planBusServing[msg.RequestId] = &planBusServedRequest{cancel: cancel, lastPing: time.Now()}
+	planBusServingMu.Unlock()
+
+	defer func() {
+		planBusServingMu.Lock()
+		delete(planBusServing, msg.RequestId)
+		planBusServingMu.Unlock()
+	}()
+
+	url := fmt.Sprintf("/plans/%s/%s/%s?proxy=true", msg.PlanId, msg.Branch, msg.PlanMethod)
+	req, err := http.NewRequestWithContext(ctx, msg.HttpMethod, url, bytes.NewReader(msg.Body))
+	if err != nil {
+		log.Printf("Error creating plan bus request: %v\n", err)
+		http.Error(w, "Error creating relayed request", http.StatusInternalServerError)
+		return
+	}
+	req.Header = msg.Header
+	req = mux.SetURLVars(req, map[string]string{"planId": msg.PlanId, "branch": msg.Branch})
+
+	handler(w, req)
+}
+
+// planBusAuth loads the auth for a relayed request's identity. Org membership was checked by the instance that authenticated it, but permissions are loaded here so they're current.
+func planBusAuth(identity *planBusIdentity) (*types.ServerAuth, error) {
+	user, err := db.GetUser(identity.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %v", err)
+	}
+
+	permissions, err := db.GetUserPermissions(identity.UserId, identity.OrgId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user permissions: %v", err)
+	}
+
+	permissionsMap := make(shared.Permissions)
+	for _, permission := range permissions {
+		permissionsMap[permission] = true
+	}
+
+	return &types.ServerAuth{
+		AuthToken:   &db.AuthToken{Id: identity.AuthTokenId, UserId: identity.UserId},
+		User:        user,
+		OrgId:       identity.OrgId,
+		Permissions: permissionsMap,
+	}, nil
+}
+
+// expirePlanBusRequests cancels requests served for instances that stopped pinging, so streams to an instance that died don't run forever
+func expirePlanBusRequests() {
+	ticker := time.NewTicker(planBusPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdown.ShutdownCtx.Done():
+			return
+		case <-ticker.C:
+			planBusServingMu.Lock()
+			for requestId, served := range planBusServing {
+				if time.Since(served.lastPing) > planBusIdleTimeout {
+					log.Printf("Plan bus request %s: no ping from requesting instance -- canceling\n", requestId)
+					served.cancel()
+				}
+			}
+			planBusServingMu.Unlock()
+		}
+	}
+}
+
+// planBusResponseWriter publishes a handler's response back to the requesting instance as it's written
+type planBusResponseWriter struct {
+	requestId   string
+	instanceId  string
+	header      http.Header
+	wroteHeader bool
+}
+
+func (w *planBusResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *planBusResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	err := publishPlanBusMessage(w.instanceId, &planBusMessage{
+		Kind:      planBusMessageHeader,
+		RequestId: w.requestId,
+		Header:    w.header,
+		Status:    status,
+	})
+	if err != nil {
+		log.Printf("Error publishing plan bus response header: %v\n", err)
+	}
+}
+
+func (w *planBusResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	err := publishPlanBusMessage(w.instanceId, &planBusMessage{
+		Kind:      planBusMessageChunk,
+		RequestId: w.requestId,
+		Body:      p,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (w *planBusResponseWriter) Flush() {}
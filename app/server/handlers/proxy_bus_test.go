@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"plandex-server/db"
+	"plandex-server/types"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPlanBusDispatchDoesNotBlock(t *testing.T) {
+	requestId := "test-request"
+	inbox := newPlanBusInbox()
+
+	planBusPendingMu.Lock()
+	planBusPending[requestId] = inbox
+	planBusPendingMu.Unlock()
+	defer func() {
+		planBusPendingMu.Lock()
+		delete(planBusPending, requestId)
+		planBusPendingMu.Unlock()
+	}()
+
+	// nothing reads the inbox, like a request whose client has stalled
+	started := time.Now()
+	for i := 0; i < planBusInboxMaxMessages+10; i++ {
+		payload, err := json.Marshal(planBusMessage{Kind: planBusMessageChunk, RequestId: requestId, Body: []byte(strconv.Itoa(i))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		onPlanBusMessage(payload)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Fatalf("dispatching to a stalled request took %s", elapsed)
+	}
+
+	select {
+	case <-inbox.ready:
+	default:
+		t.Fatal("expected the inbox to be signaled")
+	}
+
+	msgs, overflow := inbox.take()
+	if !overflow {
+		t.Error("expected the inbox to overflow")
+	}
+	if len(msgs) != planBusInboxMaxMessages {
+		t.Fatalf("expected %d queued messages, got %d", planBusInboxMaxMessages, len(msgs))
+	}
+	for i, msg := range msgs {
+		if string(msg.Body) != strconv.Itoa(i) {
+			t.Fatalf("message %d out of order: %s", i, msg.Body)
+		}
+	}
+
+	// messages for requests that aren't pending are ignored
+	payload, _ := json.Marshal(planBusMessage{Kind: planBusMessageChunk, RequestId: "unknown"})
+	onPlanBusMessage(payload)
+}
+
+func TestPlanBusPingAndCancel(t *testing.T) {
+	requestId := "test-served-request"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	served := &planBusServedRequest{cancel: cancel, lastPing: time.Now().Add(-time.Minute)}
+	planBusServingMu.Lock()
+	planBusServing[requestId] = served
+	planBusServingMu.Unlock()
+	defer func() {
+		planBusServingMu.Lock()
+		delete(planBusServing, requestId)
+		planBusServingMu.Unlock()
+	}()
+
+	payload, _ := json.Marshal(planBusMessage{Kind: planBusMessagePing, RequestId: requestId})
+	onPlanBusMessage(payload)
+
+	planBusServingMu.Lock()
+	lastPing := served.lastPing
+	planBusServingMu.Unlock()
+	if time.Since(lastPing) > time.Second {
+		t.Error("expected a ping to update lastPing")
+	}
+
+	payload, _ = json.Marshal(planBusMessage{Kind: planBusMessageCancel, RequestId: requestId})
+	onPlanBusMessage(payload)
+	if ctx.Err() == nil {
+		t.Error("expected a cancel message to cancel the served request")
+	}
+}
+
+func TestPlanBusRequestHeaderStripsCredentials(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPatch, "/plans/p/main/connect", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Cookie", "authToken=secret")
+	req.Header.Set("Proxy-Authorization", "Basic secret")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Last-Event-Id", "12")
+
+	header := planBusRequestHeader(req)
+
+	for _, name := range planBusCredentialHeaders {
+		if header.Get(name) != "" {
+			t.Errorf("expected %s to be stripped", name)
+		}
+	}
+	if header.Get("Accept") != "text/event-stream" || header.Get("Last-Event-Id") != "12" {
+		t.Errorf("expected other headers to be kept, got %v", header)
+	}
+	if req.Header.Get("Authorization") == "" {
+		t.Error("the original request's headers shouldn't be modified")
+	}
+}
+
+func TestAuthenticateRelayedRequest(t *testing.T) {
+	auth := &types.ServerAuth{User: &db.User{Id: "user"}, OrgId: "org"}
+
+	req := httptest.NewRequest(http.MethodPatch, "/plans/p/main/connect?proxy=true", nil)
+	req = req.WithContext(context.WithValue(req.Context(), planBusAuthContextKey{}, auth))
+
+	rec := httptest.NewRecorder()
+	if got := Authenticate(rec, req, true); got != auth {
+		t.Errorf("expected the relayed auth, got %+v", got)
+	}
+	if rec.Code != http.StatusOK || rec.Body.Len() > 0 {
+		t.Errorf("expected no response to be written, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// without it, a request with no credentials isn't authenticated
+	rec = httptest.NewRecorder()
+	if got := Authenticate(rec, httptest.NewRequest(http.MethodPatch, "/plans/p/main/connect?proxy=true", nil), true); got != nil {
+		t.Errorf("expected no auth, got %+v", got)
+	}
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", rec.Code)
+	}
+}
@@ -28,7 +28,13 @@ func proxyActivePlanMethod(w http.ResponseWriter, r *http.Request, planId, branc
 		return
 	}
 
-	if modelStream.InternalIp == host.Ip {
+	isLocal := modelStream.InternalIp == host.Ip
+	useBus := db.PlanBusEnabled() && modelStream.InstanceId != nil
+	if useBus {
+		isLocal = *modelStream.InstanceId == host.InstanceId
+	}
+
+	if isLocal {
 		// No active plan for this plan or else we wouldn't be calling proxyActivePlanMethod -- set the model stream to finished because something went wrong
 		err := db.SetModelStreamFinished(modelStream.Id)
 		if err != nil {
@@ -43,6 +49,9 @@ func proxyActivePlanMethod(w http.ResponseWriter, r *http.Request, planId, branc
 		log.Printf("No active plan for plan %s\n", planId)
 		http.Error(w, "No active plan for plan", http.StatusNotFound)
 		return
+	} else if useBus {
+		proxyViaPlanBus(w, r, *modelStream.InstanceId, planId, branch, method)
+		return
 	} else {
 		log.Printf("Forwarding request to %s\n", modelStream.InternalIp)
 		proxyUrl := fmt.Sprintf("http://%s:%s/plans/%s/%s/%s", modelStream.InternalIp, os.Getenv("PORT"), planId, branch, method)
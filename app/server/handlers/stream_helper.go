@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -16,7 +15,7 @@ import (
 
 const HeartbeatInterval = 5 * time.Second
 
-func startResponseStream(reqCtx context.Context, w http.ResponseWriter, auth *types.ServerAuth, planId, branch string, isConnect bool) {
+func startResponseStream(w http.ResponseWriter, r *http.Request, auth *types.ServerAuth, planId, branch string, isConnect bool, lastEventId int64) {
 	log.Println("Response stream manager: starting plan stream")
 
 	active := modelPlan.GetActivePlan(planId, branch)
@@ -27,8 +26,8 @@ func startResponseStream(reqCtx context.Context, w http.ResponseWriter, auth *ty
 		return
 	}
 
-	w.Header().Set("Transfer-Encoding", "chunked")
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	reqCtx := r.Context()
+	transport := newStreamTransport(w, r)
 
 	// send initial message to client
 	msg := shared.StreamMessage{
@@ -43,23 +42,38 @@ func startResponseStream(reqCtx context.Context, w http.ResponseWriter, auth *ty
 	}
 
 	log.Println("Response stream manager: sending initial message")
-	err = sendStreamMessage(w, string(bytes))
+	err = transport.send(string(bytes))
 	if err != nil {
 		log.Println("Response stream manager: error sending initial message:", err)
 		return
 	}
 
-	if isConnect {
+	var subscriptionId string
+	var ch chan string
+	replayed := false
+
+	// a client resuming a dropped stream gets exactly the messages it missed if they're still buffered
+	if isConnect && lastEventId > 0 {
+		subscriptionId, ch, replayed = modelPlan.SubscribePlanFrom(reqCtx, planId, branch, lastEventId)
+	}
+
+	if isConnect && !replayed {
 		time.Sleep(100 * time.Millisecond)
-		err = initConnectActive(auth, planId, branch, w)
+		err = initConnectActive(auth, planId, branch, transport)
 
 		if err != nil {
 			log.Println("Response stream manager: error initializing connection to active plan:", err)
+			if ch != nil {
+				modelPlan.UnsubscribePlan(planId, branch, subscriptionId)
+			}
 			return
 		}
 	}
 
-	subscriptionId, ch := modelPlan.SubscribePlan(reqCtx, planId, branch)
+	if ch == nil {
+		subscriptionId, ch = modelPlan.SubscribePlan(reqCtx, planId, branch)
+	}
+
 	defer func() {
 		log.Println("Response stream manager: client stream closed")
 		modelPlan.UnsubscribePlan(planId, branch, subscriptionId)
@@ -94,13 +108,13 @@ func startResponseStream(reqCtx context.Context, w http.ResponseWriter, auth *ty
 			log.Println("Response stream manager: request context done")
 			return
 		case msg := <-chHeartbeat:
-			err = sendStreamMessage(w, msg)
+			err = transport.send(msg)
 			if err != nil {
 				return
 			}
 		case msg := <-ch:
 			// log.Println("Response stream manager: sending message:", msg)
-			err = sendStreamMessage(w, msg)
+			err = transport.send(msg)
 			if err != nil {
 				return
 			}
@@ -124,7 +138,7 @@ func sendStreamMessage(w http.ResponseWriter, msg string) error {
 	return nil
 }
 
-func initConnectActive(auth *types.ServerAuth, planId, branch string, w http.ResponseWriter) error {
+func initConnectActive(auth *types.ServerAuth, planId, branch string, transport streamTransport) error {
 	log.Println("Response stream manager: initializing connection to active plan")
 
 	active := modelPlan.GetActivePlan(planId, branch)
@@ -178,7 +192,7 @@ func initConnectActive(auth *types.ServerAuth, planId, branch string, w http.Res
 	}
 
 	log.Println("Response stream manager: sending connect message")
-	err = sendStreamMessage(w, string(bytes))
+	err = transport.send(string(bytes))
 
 	if err != nil {
 		return fmt.Errorf("error sending connect message: %v", err)
@@ -214,7 +228,7 @@ func initConnectActive(auth *types.ServerAuth, planId, branch string, w http.Res
 				return fmt.Errorf("error marshalling message: %v", err)
 			}
 
-			err = sendStreamMessage(w, string(bytes))
+			err = transport.send(string(bytes))
 
 			if err != nil {
 				return fmt.Errorf("error sending message: %v", err)
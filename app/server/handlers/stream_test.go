@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	modelPlan "plandex-server/model/plan"
+	"plandex-server/shutdown"
+	"plandex-server/types"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	shared "plandex-shared"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestStreamTransports(t *testing.T) {
+	msgs := []string{`{"id":3,"type":"reply","replyChunk":"hi"}`, string(shared.StreamMessageHeartbeat)}
+
+	sse := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	transport := newStreamTransport(sse, req)
+	for _, msg := range msgs {
+		if err := transport.send(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ct := sse.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("SSE content type = %q", ct)
+	}
+	want := "id: 3\ndata: " + msgs[0] + "\n\ndata: " + heartbeatMessageJson + "\n\n"
+	if sse.Body.String() != want {
+		t.Errorf("SSE body = %q, want %q", sse.Body.String(), want)
+	}
+
+	chunked := httptest.NewRecorder()
+	transport = newStreamTransport(chunked, httptest.NewRequest(http.MethodPatch, "/", nil))
+	for _, msg := range msgs {
+		if err := transport.send(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want = msgs[0] + shared.STREAM_MESSAGE_SEPARATOR + msgs[1] + shared.STREAM_MESSAGE_SEPARATOR
+	if chunked.Body.String() != want {
+		t.Errorf("chunked body = %q, want %q", chunked.Body.String(), want)
+	}
+}
+
+// The websocket and SSE transports carry the same StreamMessage JSON for the same stream
+func TestWsStreamMatchesSSE(t *testing.T) {
+	msgs := []string{
+		`{"type":"start"}`,
+		`{"id":1,"type":"reply","replyChunk":"hello"}`,
+		string(shared.StreamMessageHeartbeat),
+		`{"id":2,"type":"finished"}`,
+	}
+
+	streamHandler := func(w http.ResponseWriter, r *http.Request) {
+		transport := newStreamTransport(w, r)
+		for _, msg := range msgs {
+			if err := transport.send(msg); err != nil {
+				return
+			}
+		}
+	}
+
+	sse := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	streamHandler(sse, req)
+	sseMsgs := readSSEData(t, bufio.NewScanner(sse.Body), len(msgs))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveWsStream(w, r, http.MethodPatch, streamHandler, nil)
+	}))
+	defer srv.Close()
+
+	conn := dialWsStream(t, srv.URL, srv.URL, nil)
+	defer conn.Close()
+	wsMsgs := readWsMessages(t, conn, len(msgs))
+
+	if strings.Join(wsMsgs, "\n") != strings.Join(sseMsgs, "\n") {
+		t.Errorf("websocket messages %v don't match SSE messages %v", wsMsgs, sseMsgs)
+	}
+	if wsMsgs[2] != heartbeatMessageJson {
+		t.Errorf("expected heartbeat as JSON, got %s", wsMsgs[2])
+	}
+}
+
+func TestWsStreamErrorResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveWsStream(w, r, http.MethodPatch, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Active plan not found", http.StatusNotFound)
+		}, nil)
+	}))
+	defer srv.Close()
+
+	conn := dialWsStream(t, srv.URL, srv.URL, nil)
+	defer conn.Close()
+
+	var msg shared.StreamMessage
+	if err := json.Unmarshal([]byte(readWsMessages(t, conn, 1)[0]), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != shared.StreamMessageError || msg.Error == nil || msg.Error.Status != http.StatusNotFound || msg.Error.Msg != "Active plan not found" {
+		t.Errorf("expected a not found error message, got %+v", msg)
+	}
+}
+
+func TestWsCheckOrigin(t *testing.T) {
+	t.Setenv("PLANDEX_WS_ALLOWED_ORIGINS", "https://app.example.com, https://other.example.com/")
+	t.Setenv("API_HOST", "https://api.example.com")
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true},
+		{"http://plandex.internal:8099", true},
+		{"https://app.example.com", true},
+		{"https://other.example.com", true},
+		{"https://api.example.com", true},
+		{"https://evil.example.com", false},
+		{"http://app.example.com", false},
+		{"null", false},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://plandex.internal:8099/plans/p/main/connect/ws", nil)
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		err := wsCheckOrigin(&websocket.Config{}, req)
+		if (err == nil) != test.allowed {
+			t.Errorf("origin %q: allowed = %t, want %t (err: %v)", test.origin, err == nil, test.allowed, err)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveWsStream(w, r, http.MethodPatch, func(w http.ResponseWriter, r *http.Request) {
+			t.Error("handler ran for a cross-site handshake")
+		}, nil)
+	}))
+	defer srv.Close()
+
+	config, err := websocket.NewConfig(wsUrl(srv.URL), "https://evil.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = websocket.DialConfig(config)
+	if err == nil {
+		t.Error("expected a cross-site handshake to be rejected")
+	}
+}
+
+// A client resuming with Last-Event-Id gets only the messages it missed, the same way over SSE and websockets
+func TestStreamLastEventIdReplay(t *testing.T) {
+	planId := "stream-test-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	branch := "main"
+
+	// normally set up at server start
+	if shutdown.ShutdownCtx == nil {
+		shutdown.ShutdownCtx, shutdown.ShutdownCancel = context.WithCancel(context.Background())
+	}
+
+	active := modelPlan.CreateActivePlan(context.Background(), "org", "user", planId, branch, "", false, false, "")
+	for _, chunk := range []string{"one", "two", "three"} {
+		active.Stream(shared.StreamMessage{Type: shared.StreamMessageReply, ReplyChunk: chunk})
+		time.Sleep(types.MaxStreamRate + 10*time.Millisecond)
+	}
+
+	connectHandler := func(w http.ResponseWriter, r *http.Request) {
+		lastEventId, _ := strconv.ParseInt(r.Header.Get("Last-Event-Id"), 10, 64)
+		startResponseStream(w, r, nil, planId, branch, true, lastEventId)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(connectHandler))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Last-Event-Id", "1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	// the start message, then messages 2 and 3
+	sseMsgs := readSSEData(t, bufio.NewScanner(res.Body), 3)
+
+	wsSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveWsStream(w, r, http.MethodPatch, connectHandler, nil)
+	}))
+	defer wsSrv.Close()
+
+	conn := dialWsStream(t, wsSrv.URL, wsSrv.URL, http.Header{"Last-Event-Id": {"1"}})
+	defer conn.Close()
+	wsMsgs := readWsMessages(t, conn, 3)
+
+	if strings.Join(wsMsgs, "\n") != strings.Join(sseMsgs, "\n") {
+		t.Errorf("websocket replay %v doesn't match SSE replay %v", wsMsgs, sseMsgs)
+	}
+
+	var chunks []string
+	for i, raw := range sseMsgs {
+		var msg shared.StreamMessage
+		if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if msg.Type != shared.StreamMessageStart {
+				t.Errorf("expected a start message first, got %s", raw)
+			}
+			continue
+		}
+		if msg.Id != int64(i+1) {
+			t.Errorf("expected message id %d, got %d", i+1, msg.Id)
+		}
+		chunks = append(chunks, msg.ReplyChunk)
+	}
+	if strings.Join(chunks, ",") != "two,three" {
+		t.Errorf("expected the messages after event 1, got %v", chunks)
+	}
+}
+
+func readSSEData(t *testing.T, scanner *bufio.Scanner, n int) []string {
+	var res []string
+	for len(res) < n && scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			res = append(res, data)
+		}
+	}
+	if len(res) < n {
+		t.Fatalf("expected %d SSE messages, got %v (err: %v)", n, res, scanner.Err())
+	}
+	return res
+}
+
+func wsUrl(httpUrl string) string {
+	return "ws" + strings.TrimPrefix(httpUrl, "http")
+}
+
+func dialWsStream(t *testing.T, srvUrl, origin string, header http.Header) *websocket.Conn {
+	config, err := websocket.NewConfig(wsUrl(srvUrl), origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		config.Header[name] = values
+	}
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func readWsMessages(t *testing.T, conn *websocket.Conn, n int) []string {
+	var res []string
+	for len(res) < n {
+		var msg string
+		if err := websocket.Message.Receive(conn, &msg); err != nil {
+			t.Fatalf("expected %d websocket messages, got %v (err: %v)", n, res, err)
+		}
+		res = append(res, msg)
+	}
+	return res
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	shared "plandex-shared"
+)
+
+// streamTransport writes plan stream messages to a client. Messages are StreamMessage JSON, except for heartbeats, which are the bare heartbeat message type.
+type streamTransport interface {
+	send(msg string) error
+}
+
+// newStreamTransport picks Server-Sent Events for clients that accept them, falling back to Plandex's own chunked format
+func newStreamTransport(w http.ResponseWriter, r *http.Request) streamTransport {
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		return &sseStreamTransport{w: w}
+	}
+
+	w.Header().Set("Transfer-Encoding", "chunked")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	return &chunkedStreamTransport{w: w}
+}
+
+type chunkedStreamTransport struct {
+	w http.ResponseWriter
+}
+
+func (t *chunkedStreamTransport) send(msg string) error {
+	return sendStreamMessage(t.w, msg)
+}
+
+type sseStreamTransport struct {
+	w http.ResponseWriter
+}
+
+func (t *sseStreamTransport) send(msg string) error {
+	msg = standardStreamMessage(msg)
+
+	var event string
+	if id := streamMessageId(msg); id > 0 {
+		// lets EventSource clients resume with Last-Event-Id
+		event = fmt.Sprintf("id: %d\n", id)
+	}
+	event += "data: " + msg + "\n\n"
+
+	_, err := t.w.Write([]byte(event))
+	if err != nil {
+		log.Printf("Response stream manager: error writing event to client: %v\n", err)
+		return err
+	} else if flusher, ok := t.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+var heartbeatMessageJson = fmt.Sprintf(`{"type":%q}`, shared.StreamMessageHeartbeat)
+
+// standardStreamMessage converts heartbeats to StreamMessage JSON so that standard transports only carry JSON messages
+func standardStreamMessage(msg string) string {
+	if msg == string(shared.StreamMessageHeartbeat) {
+		return heartbeatMessageJson
+	}
+	return msg
+}
+
+func streamMessageId(msg string) int64 {
+	var withId struct {
+		Id int64 `json:"id"`
+	}
+	err := json.Unmarshal([]byte(msg), &withId)
+	if err != nil {
+		return 0
+	}
+	return withId.Id
+}
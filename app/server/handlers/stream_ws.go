@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	shared "plandex-shared"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/net/websocket"
+)
+
+// The websocket handlers run the regular plan stream handlers, translating the stream they write into one websocket text message per StreamMessage. Clients can also send a StreamClientMessage over the same connection to stop the plan or respond to prompts. For tell and build, the client's first message is the request body.
+
+func TellPlanWsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for TellPlanWsHandler")
+
+	serveWsStream(w, r, http.MethodPost, TellPlanHandler, func(body []byte) ([]byte, error) {
+		var req shared.TellPlanRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, fmt.Errorf("error parsing tell request: %v", err)
+		}
+		req.ConnectStream = true
+		return json.Marshal(req)
+	})
+}
+
+func BuildPlanWsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for BuildPlanWsHandler")
+
+	serveWsStream(w, r, http.MethodPatch, BuildPlanHandler, func(body []byte) ([]byte, error) {
+		var req shared.BuildPlanRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, fmt.Errorf("error parsing build request: %v", err)
+		}
+		req.ConnectStream = true
+		return json.Marshal(req)
+	})
+}
+
+func ConnectPlanWsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for ConnectPlanWsHandler")
+
+	serveWsStream(w, r, http.MethodPatch, ConnectPlanHandler, nil)
+}
+
+func serveWsStream(w http.ResponseWriter, r *http.Request, method string, handler http.HandlerFunc, getBody func(msg []byte) ([]byte, error)) {
+	websocket.Server{
+		Handshake: wsCheckOrigin,
+		Handler: func(conn *websocket.Conn) {
+			// the request context isn't canceled when a hijacked connection closes, so the client message reader cancels it instead
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+
+			ws := &wsStreamWriter{conn: conn, header: http.Header{}}
+
+			var body []byte
+			if getBody != nil {
+				var msg []byte
+				err := websocket.Message.Receive(conn, &msg)
+				if err != nil {
+					log.Printf("Error receiving websocket request: %v\n", err)
+					return
+				}
+
+				body, err = getBody(msg)
+				if err != nil {
+					log.Printf("Error reading websocket request: %v\n", err)
+					ws.sendError(shared.ApiError{
+						Type:   shared.ApiErrorTypeOther,
+						Status: http.StatusBadRequest,
+						Msg:    err.Error(),
+					})
+					return
+				}
+			}
+
+			go ws.receiveClientMessages(ctx, cancel, r)
+
+			handler(ws, wsInnerRequest(ctx, r, method, body))
+
+			ws.finish()
+		},
+	}.ServeHTTP(w, r)
+}
+
+// wsCheckOrigin rejects websocket handshakes from other sites. Browsers send the authToken cookie with a websocket handshake from any page and don't enforce CORS on it, so without this check a page on another site could open a plan stream as the signed-in user. Clients that aren't browsers can leave out the Origin header.
+func wsCheckOrigin(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+
+	originUrl, err := url.Parse(origin)
+	if err != nil || originUrl.Host == "" {
+		return fmt.Errorf("invalid origin: %s", origin)
+	}
+
+	config.Origin = originUrl
+
+	if strings.EqualFold(originUrl.Host, r.Host) {
+		return nil
+	}
+
+	allowed := strings.Split(os.Getenv("PLANDEX_WS_ALLOWED_ORIGINS"), ",")
+	if apiHost := os.Getenv("API_HOST"); apiHost != "" {
+		allowed = append(allowed, apiHost)
+	}
+	for _, allowedOrigin := range allowed {
+		allowedOrigin = strings.TrimSuffix(strings.TrimSpace(allowedOrigin), "/")
+		if allowedOrigin != "" && strings.EqualFold(allowedOrigin, originUrl.Scheme+"://"+originUrl.Host) {
+			return nil
+		}
+	}
+
+	log.Printf("Rejecting websocket handshake from origin %s\n", origin)
+	return fmt.Errorf("origin not allowed: %s", origin)
+}
+
+// wsInnerRequest turns the websocket handshake into a regular request for the wrapped handlers, keeping auth headers and route vars
+func wsInnerRequest(ctx context.Context, r *http.Request, method string, body []byte) *http.Request {
+	req := r.Clone(ctx)
+	req.Method = method
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	for _, name := range []string{"Accept", "Upgrade", "Connection", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions", "Sec-Websocket-Protocol"} {
+		req.Header.Del(name)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+func (ws *wsStreamWriter) receiveClientMessages(ctx context.Context, cancel context.CancelFunc, r *http.Request) {
+	defer cancel()
+
+	vars := mux.Vars(r)
+	planId := vars["planId"]
+	branch := vars["branch"]
+
+	for {
+		var msg shared.StreamClientMessage
+		err := websocket.JSON.Receive(ws.conn, &msg)
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				log.Printf("Error receiving websocket client message: %v\n", err)
+			}
+			return
+		}
+
+		log.Printf("Received websocket client message for plan %s on branch %s: %s\n", planId, branch, msg.Type)
+
+		var handler http.HandlerFunc
+		var method string
+		var body []byte
+
+		switch msg.Type {
+		case shared.StreamClientMessageStop:
+			handler = StopPlanHandler
+			method = http.MethodDelete
+		case shared.StreamClientMessageRespondMissingFile:
+			if msg.RespondMissingFile == nil {
+				ws.sendWarning("Missing file response is empty")
+				continue
+			}
+			handler = RespondMissingFileHandler
+			method = http.MethodPost
+			body, err = json.Marshal(msg.RespondMissingFile)
+		case shared.StreamClientMessageAutoLoadContext:
+			handler = AutoLoadContextHandler
+			method = http.MethodPost
+			body, err = json.Marshal(msg.LoadContext)
+		default:
+			ws.sendWarning(fmt.Sprintf("Unknown client message type: %s", msg.Type))
+			continue
+		}
+
+		if err != nil {
+			log.Printf("Error marshalling websocket client message: %v\n", err)
+			ws.sendWarning("Error handling client message: " + err.Error())
+			continue
+		}
+
+		// handled in the background so that a slow response (like loading a missing file) doesn't hold up a stop message
+		go func(msgType shared.StreamClientMessageType) {
+			rec := &wsResponseRecorder{header: http.Header{}}
+			handler(rec, wsInnerRequest(ctx, r, method, body))
+
+			if rec.status >= 400 {
+				log.Printf("Error handling websocket client message %s: %d %s\n", msgType, rec.status, rec.body.String())
+				ws.sendWarning(fmt.Sprintf("Error handling %s: %s", msgType, strings.TrimSpace(rec.body.String())))
+			}
+		}(msg.Type)
+	}
+}
+
+// wsStreamWriter is the http.ResponseWriter handed to the wrapped stream handlers. Stream messages, whether written locally or copied from a proxied host, are split on the stream separator and sent as websocket messages. Error responses are sent as a single error StreamMessage.
+type wsStreamWriter struct {
+	conn   *websocket.Conn
+	header http.Header
+	status int
+
+	buf     []byte
+	errBody bytes.Buffer
+
+	mu sync.Mutex
+}
+
+func (ws *wsStreamWriter) Header() http.Header {
+	return ws.header
+}
+
+func (ws *wsStreamWriter) WriteHeader(status int) {
+	if ws.status == 0 {
+		ws.status = status
+	}
+}
+
+func (ws *wsStreamWriter) Write(p []byte) (int, error) {
+	if ws.status == 0 {
+		ws.status = http.StatusOK
+	}
+
+	if ws.status >= 400 {
+		return ws.errBody.Write(p)
+	}
+
+	ws.buf = append(ws.buf, p...)
+
+	for {
+		i := bytes.Index(ws.buf, []byte(shared.STREAM_MESSAGE_SEPARATOR))
+		if i == -1 {
+			break
+		}
+		msg := string(ws.buf[:i])
+		ws.buf = ws.buf[i+len(shared.STREAM_MESSAGE_SEPARATOR):]
+
+		err := ws.sendText(standardStreamMessage(msg))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (ws *wsStreamWriter) Flush() {}
+
+func (ws *wsStreamWriter) finish() {
+	if ws.status < 400 {
+		return
+	}
+
+	var apiErr shared.ApiError
+	err := json.Unmarshal(ws.errBody.Bytes(), &apiErr)
+	if err != nil || apiErr.Msg == "" {
+		apiErr = shared.ApiError{
+			Type:   shared.ApiErrorTypeOther,
+			Status: ws.status,
+			Msg:    strings.TrimSpace(ws.errBody.String()),
+		}
+	}
+
+	ws.sendError(apiErr)
+}
+
+func (ws *wsStreamWriter) sendError(apiErr shared.ApiError) {
+	ws.sendMessage(shared.StreamMessage{
+		Type:  shared.StreamMessageError,
+		Error: &apiErr,
+	})
+}
+
+func (ws *wsStreamWriter) sendWarning(warning string) {
+	ws.sendMessage(shared.StreamMessage{
+		Type:    shared.StreamMessageWarning,
+		Warning: warning,
+	})
+}
+
+func (ws *wsStreamWriter) sendMessage(msg shared.StreamMessage) {
+	bytes, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error marshalling websocket message: %v\n", err)
+		return
+	}
+	err = ws.sendText(string(bytes))
+	if err != nil {
+		log.Printf("Error sending websocket message: %v\n", err)
+	}
+}
+
+func (ws *wsStreamWriter) sendText(msg string) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return websocket.Message.Send(ws.conn, msg)
+}
+
+// wsResponseRecorder collects the response of a handler run for a websocket client message
+type wsResponseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *wsResponseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *wsResponseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *wsResponseRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(p)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"plandex-server/db"
+	"runtime"
+	"runtime/debug"
+
+	shared "plandex-shared"
+)
+
+func GetUsageSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for GetUsageSummaryHandler")
+
+	auth := Authenticate(w, r, true)
+	if auth == nil {
+		return
+	}
+
+	var req shared.UsageSummaryRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Printf("Error decoding request: %v\n", err)
+		http.Error(w, "Error decoding request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := db.ModelUsageFilter{
+		OrgId:     auth.OrgId,
+		UserId:    auth.User.Id,
+		SessionId: req.SessionId,
+		Since:     req.Since,
+	}
+
+	if req.AllUsers {
+		if !auth.HasPermission(shared.PermissionManageBilling) {
+			log.Println("User doesn't have permission to view org usage")
+			http.Error(w, "User doesn't have permission to view org usage", http.StatusForbidden)
+			return
+		}
+		filter.UserId = ""
+	}
+
+	if req.PlanId != "" {
+		plan := authorizePlan(w, req.PlanId, auth)
+		if plan == nil {
+			return
+		}
+		filter.PlanId = req.PlanId
+	}
+
+	groupings := []string{"total", "plan", "role", "model", "purpose", "day"}
+	breakdowns := make([][]shared.UsageBreakdown, len(groupings))
+	var unpricedModels []string
+
+	errCh := make(chan error, len(groupings)+1)
+
+	for i, groupBy := range groupings {
+		go func(i int, groupBy string) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("panic in GetUsageSummaryHandler: %v\n%s", r, debug.Stack())
+					errCh <- fmt.Errorf("panic in GetUsageSummaryHandler: %v\n%s", r, debug.Stack())
+					runtime.Goexit() // don't allow outer function to continue and double-send to channel
+				}
+			}()
+
+			res, err := db.GetModelUsageBreakdown(filter, groupBy)
+			breakdowns[i] = res
+			errCh <- err
+		}(i, groupBy)
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic in GetUsageSummaryHandler: %v\n%s", r, debug.Stack())
+				errCh <- fmt.Errorf("panic in GetUsageSummaryHandler: %v\n%s", r, debug.Stack())
+				runtime.Goexit() // don't allow outer function to continue and double-send to channel
+			}
+		}()
+
+		var err error
+		unpricedModels, err = db.GetUnpricedUsageModels(filter)
+		errCh <- err
+	}()
+
+	for i := 0; i < len(groupings)+1; i++ {
+		err := <-errCh
+		if err != nil {
+			log.Printf("Error getting usage summary: %v\n", err)
+			http.Error(w, "Error getting usage summary: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	res := shared.UsageSummaryResponse{
+		ByPlanId:       breakdowns[1],
+		ByRole:         breakdowns[2],
+		ByModel:        breakdowns[3],
+		ByPurpose:      breakdowns[4],
+		ByDay:          breakdowns[5],
+		UnpricedModels: unpricedModels,
+	}
+	if len(breakdowns[0]) > 0 {
+		res.Total = breakdowns[0][0]
+	}
+
+	if len(res.ByPlanId) > 0 {
+		planIds := make([]string, len(res.ByPlanId))
+		for i, b := range res.ByPlanId {
+			planIds[i] = b.Key
+		}
+		res.PlanNamesById, err = db.GetPlanNamesById(planIds)
+		if err != nil {
+			log.Printf("Error getting plan names: %v\n", err)
+			http.Error(w, "Error getting plan names: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	bytes, err := json.Marshal(res)
+	if err != nil {
+		log.Printf("Error marshalling response: %v\n", err)
+		http.Error(w, "Error marshalling response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(bytes)
+
+	log.Println("Successfully processed GetUsageSummaryHandler request")
+}
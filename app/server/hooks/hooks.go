@@ -59,6 +59,8 @@ type DidSendModelRequestParams struct {
 	HadError        bool
 	NoReportedUsage bool
 	SessionId       string
+	// served from the server's response cache, so nothing was sent to the model and there's no cost
+	ResponseCacheHit bool
 
 	RequestStartedAt time.Time
 	Streaming        bool
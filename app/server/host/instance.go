@@ -0,0 +1,6 @@
+package host
+
+import "github.com/google/uuid"
+
+// InstanceId identifies this server process, so other instances can reach its active plans over the plan bus without knowing its IP
+var InstanceId = uuid.New().String()
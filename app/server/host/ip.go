@@ -23,6 +23,11 @@ func LoadIp() error {
 		Ip, err = getAwsIp()
 
 		if err != nil {
+			// the plan bus reaches other instances without their IPs
+			if os.Getenv("PLANDEX_PLAN_BUS") == "postgres" {
+				log.Printf("Error getting AWS ECS IP: %v -- continuing since the plan bus is enabled\n", err)
+				return nil
+			}
 			return fmt.Errorf("error getting AWS ECS IP: %v", err)
 		}
 
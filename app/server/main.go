@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"plandex-server/mockmodel"
 	"plandex-server/model"
 	"plandex-server/routes"
 	"plandex-server/setup"
+	"plandex-server/tracing"
 
 	"github.com/gorilla/mux"
 )
@@ -15,19 +17,32 @@ func main() {
 	// Configure the default logger to include milliseconds in timestamps
 	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
 
+	if len(os.Args) > 1 && os.Args[1] == "mock-model" {
+		err := mockmodel.Run(os.Args[2:])
+		if err != nil {
+			log.Fatalf("Mock model server error: %v", err)
+		}
+		os.Exit(0)
+	}
+
 	routes.RegisterHandlePlandex(func(router *mux.Router, path string, isStreaming bool, handler routes.PlandexHandler) *mux.Route {
 		return router.HandleFunc(path, handler)
 	})
 
-	err := model.EnsureLiteLLM(2)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to start LiteLLM proxy: %v", err))
+	if model.LiteLLMDisabled() {
+		log.Println("LiteLLM proxy is disabled")
+	} else {
+		err := model.EnsureLiteLLM(2)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to start LiteLLM proxy: %v", err))
+		}
+		setup.RegisterShutdownHook(func() {
+			model.ShutdownLiteLLMServer()
+		})
 	}
-	setup.RegisterShutdownHook(func() {
-		model.ShutdownLiteLLMServer()
-	})
 
 	r := mux.NewRouter()
+	r.Use(tracing.NameSpanByRoute)
 	routes.AddHealthRoutes(r)
 	routes.AddApiRoutes(r)
 	routes.AddProxyableApiRoutes(r)
@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus metrics served at /metrics. This package only depends on the prometheus client so that db, model, and plan code can all record to it without import cycles. Gauges that read state owned by other packages (active plans, repo queues) are registered as funcs during setup.
+
+const namespace = "plandex"
+
+// outcomes of a plan stream
+const (
+	StreamOutcomeFinished = "finished"
+	StreamOutcomeError    = "error"
+	StreamOutcomeStopped  = "stopped"
+)
+
+// results of a file build
+const (
+	BuildResultAutoApply   = "auto_apply"
+	BuildResultFastApply   = "fast_apply"
+	BuildResultReplacement = "replacement"
+	BuildResultWholeFile   = "whole_file"
+	BuildResultOther       = "other"
+	BuildResultError       = "error"
+)
+
+var (
+	registry *prometheus.Registry
+
+	streamDuration     *prometheus.HistogramVec
+	timeToFirstToken   *prometheus.HistogramVec
+	modelRequests      *prometheus.CounterVec
+	modelRequestErrors *prometheus.CounterVec
+	modelTokens        *prometheus.CounterVec
+	builds             *prometheus.CounterVec
+	repoLockWait       *prometheus.HistogramVec
+)
+
+func init() {
+	reset()
+}
+
+// reset creates the collectors and a fresh registry to serve them from -- tests call it so each run starts from zero
+func reset() {
+	streamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "plan_stream_duration_seconds",
+		Help:      "Duration of plan streams from start until they finish, error, or are stopped.",
+		Buckets:   []float64{5, 15, 30, 60, 120, 300, 600, 1200, 2400, 3600, 7200},
+	}, []string{"outcome"})
+
+	timeToFirstToken = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "model_time_to_first_token_seconds",
+		Help:      "Time from sending a model request until its first token is received.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 3, 5, 8, 13, 21, 34, 60},
+	}, []string{"provider", "role"})
+
+	modelRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "model_requests_total",
+		Help:      "Model requests sent, by provider and role.",
+	}, []string{"provider", "role"})
+
+	modelRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "model_request_errors_total",
+		Help:      "Model requests that failed, either when opening the stream or mid-stream, by provider and role.",
+	}, []string{"provider", "role"})
+
+	modelTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "model_tokens_total",
+		Help:      "Model tokens used, by provider, role, and type (input, output, or cached).",
+	}, []string{"provider", "role", "type"})
+
+	builds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "builds_total",
+		Help:      "File builds, by the strategy that produced the result (whole_file is the fallback), or error.",
+	}, []string{"result"})
+
+	repoLockWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repo_lock_wait_seconds",
+		Help:      "Time repo operations waited in the queue before acquiring their lock, by lock scope.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"scope"})
+
+	registry = prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		streamDuration,
+		timeToFirstToken,
+		modelRequests,
+		modelRequestErrors,
+		modelTokens,
+		builds,
+		repoLockWait,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterGaugeFunc exposes a gauge whose value is read from fn on each scrape
+func RegisterGaugeFunc(name, help string, fn func() float64) {
+	registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// RegisterCounterFunc exposes a counter whose value is read from fn on each scrape -- fn must never decrease
+func RegisterCounterFunc(name, help string, fn func() float64) {
+	registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+func ObserveStream(outcome string, duration time.Duration) {
+	streamDuration.WithLabelValues(outcome).Observe(duration.Seconds())
+}
+
+func ObserveModelRequest(provider, role string) {
+	modelRequests.WithLabelValues(provider, role).Inc()
+}
+
+func ObserveModelRequestError(provider, role string) {
+	modelRequestErrors.WithLabelValues(provider, role).Inc()
+}
+
+func ObserveTimeToFirstToken(provider, role string, duration time.Duration) {
+	timeToFirstToken.WithLabelValues(provider, role).Observe(duration.Seconds())
+}
+
+func ObserveModelTokens(provider, role string, input, output, cached int) {
+	if input > 0 {
+		modelTokens.WithLabelValues(provider, role, "input").Add(float64(input))
+	}
+	if output > 0 {
+		modelTokens.WithLabelValues(provider, role, "output").Add(float64(output))
+	}
+	if cached > 0 {
+		modelTokens.WithLabelValues(provider, role, "cached").Add(float64(cached))
+	}
+}
+
+func ObserveBuild(result string) {
+	builds.WithLabelValues(result).Inc()
+}
+
+func ObserveRepoLockWait(scope string, duration time.Duration) {
+	repoLockWait.WithLabelValues(scope).Observe(duration.Seconds())
+}
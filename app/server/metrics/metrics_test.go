@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerExposesRecordedMetrics(t *testing.T) {
+	reset()
+
+	RegisterGaugeFunc("test_gauge", "A test gauge.", func() float64 { return 3 })
+	RegisterCounterFunc("test_counter_total", "A test counter.", func() float64 { return 7 })
+
+	ObserveModelRequest("openai", "planner")
+	ObserveModelRequestError("openai", "planner")
+	ObserveModelTokens("openai", "planner", 100, 20, 0)
+	ObserveTimeToFirstToken("openai", "planner", 750*time.Millisecond)
+	ObserveBuild(BuildResultWholeFile)
+	ObserveRepoLockWait("w", 20*time.Millisecond)
+	ObserveStream(StreamOutcomeFinished, 90*time.Second)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(body)
+
+	for _, want := range []string{
+		`plandex_test_gauge 3`,
+		`plandex_test_counter_total 7`,
+		`plandex_model_requests_total{provider="openai",role="planner"} 1`,
+		`plandex_model_request_errors_total{provider="openai",role="planner"} 1`,
+		`plandex_model_tokens_total{provider="openai",role="planner",type="input"} 100`,
+		`plandex_model_tokens_total{provider="openai",role="planner",type="output"} 20`,
+		`plandex_model_time_to_first_token_seconds_count{provider="openai",role="planner"} 1`,
+		`plandex_builds_total{result="whole_file"} 1`,
+		`plandex_repo_lock_wait_seconds_count{scope="w"} 1`,
+		`plandex_plan_stream_duration_seconds_count{outcome="finished"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected metrics output to contain %q", want)
+		}
+	}
+
+	if strings.Contains(out, `type="cached"`) {
+		t.Error("expected no cached token series when no tokens were cached")
+	}
+}
@@ -0,0 +1,2 @@
+ALTER TABLE custom_providers
+  ADD COLUMN api_type VARCHAR(64) NOT NULL DEFAULT '';
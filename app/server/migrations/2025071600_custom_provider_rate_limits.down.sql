@@ -0,0 +1,2 @@
+ALTER TABLE custom_providers
+  DROP COLUMN IF EXISTS rate_limits;
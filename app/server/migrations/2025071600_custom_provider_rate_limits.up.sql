@@ -0,0 +1,2 @@
+ALTER TABLE custom_providers
+  ADD COLUMN rate_limits JSON;
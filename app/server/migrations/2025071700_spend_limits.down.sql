@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS model_spend;
+
+ALTER TABLE custom_models
+  DROP COLUMN IF EXISTS pricing;
@@ -0,0 +1,16 @@
+ALTER TABLE custom_models
+  ADD COLUMN pricing JSON;
+
+CREATE TABLE IF NOT EXISTS model_spend (
+  org_id UUID NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- not a foreign key so that deleting a plan doesn't remove its spend from the user and org totals
+  plan_id UUID NOT NULL,
+  day DATE NOT NULL,
+  amount NUMERIC(20, 8) NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (org_id, user_id, plan_id, day)
+);
+
+CREATE INDEX model_spend_org_day_idx ON model_spend(org_id, day);
+CREATE INDEX model_spend_plan_idx ON model_spend(plan_id, day);
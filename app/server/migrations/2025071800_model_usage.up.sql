@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS model_usage (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  org_id UUID NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- not a foreign key so usage is still reported after a plan is deleted
+  plan_id UUID NOT NULL,
+  session_id VARCHAR(255),
+  model_id VARCHAR(255) NOT NULL,
+  model_name VARCHAR(255) NOT NULL,
+  model_provider VARCHAR(255) NOT NULL,
+  model_role VARCHAR(255) NOT NULL,
+  purpose VARCHAR(255) NOT NULL,
+  input_tokens INTEGER NOT NULL,
+  output_tokens INTEGER NOT NULL,
+  cached_tokens INTEGER NOT NULL DEFAULT 0,
+  cost NUMERIC(20, 8) NOT NULL DEFAULT 0,
+  -- false if the model has no pricing, so its cost is unknown rather than zero
+  priced BOOLEAN NOT NULL DEFAULT TRUE,
+  cache_hit BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX model_usage_org_created_idx ON model_usage(org_id, created_at);
+CREATE INDEX model_usage_plan_idx ON model_usage(plan_id, created_at);
+CREATE INDEX model_usage_user_created_idx ON model_usage(user_id, created_at);
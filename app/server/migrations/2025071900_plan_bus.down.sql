@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pubsub_payloads;
+
+ALTER TABLE model_streams DROP COLUMN IF EXISTS instance_id;
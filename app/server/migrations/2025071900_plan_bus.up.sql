@@ -0,0 +1,10 @@
+ALTER TABLE model_streams ADD COLUMN instance_id VARCHAR(36);
+
+-- payloads too large for a NOTIFY message -- only needed until listeners have read them
+CREATE UNLOGGED TABLE IF NOT EXISTS pubsub_payloads (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  payload BYTEA NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX pubsub_payloads_created_idx ON pubsub_payloads(created_at);
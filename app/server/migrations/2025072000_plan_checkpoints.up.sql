@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS plan_checkpoints (
+  plan_id UUID NOT NULL REFERENCES plans(id) ON DELETE CASCADE,
+  branch VARCHAR(255) NOT NULL,
+  org_id UUID NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  instance_id VARCHAR(36) NOT NULL,
+  build_only BOOLEAN NOT NULL DEFAULT FALSE,
+  -- the tell request without credentials
+  tell_request JSON,
+  session_id VARCHAR(255),
+  iteration INTEGER NOT NULL DEFAULT 0,
+  current_reply TEXT NOT NULL DEFAULT '',
+  num_tokens INTEGER NOT NULL DEFAULT 0,
+  message_num INTEGER NOT NULL DEFAULT 0,
+  replies_finished BOOLEAN NOT NULL DEFAULT FALSE,
+  pending_build_paths JSON,
+  -- set once the instance running the plan is gone and any partial reply has been stored
+  interrupted_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (plan_id, branch)
+);
+
+CREATE TRIGGER update_plan_checkpoints_modtime BEFORE UPDATE ON plan_checkpoints FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
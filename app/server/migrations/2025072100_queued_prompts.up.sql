@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS queued_prompts (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  org_id UUID NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+  plan_id UUID NOT NULL REFERENCES plans(id) ON DELETE CASCADE,
+  branch VARCHAR(255) NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- the instance that accepted the prompt and holds its credentials
+  instance_id VARCHAR(36) NOT NULL,
+  -- the tell request without credentials
+  tell_request JSON NOT NULL,
+  -- set once the instance holding the prompt is gone, since it can no longer be started
+  interrupted_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX queued_prompts_plan_branch_idx ON queued_prompts(plan_id, branch, created_at);
+
+CREATE TRIGGER update_queued_prompts_modtime BEFORE UPDATE ON queued_prompts FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
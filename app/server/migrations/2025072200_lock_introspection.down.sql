@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS repo_lock_releases;
+
+ALTER TABLE repo_locks
+  DROP COLUMN IF EXISTS reason,
+  DROP COLUMN IF EXISTS instance_id,
+  DROP COLUMN IF EXISTS goroutine_id;
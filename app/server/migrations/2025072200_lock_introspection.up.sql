@@ -0,0 +1,25 @@
+ALTER TABLE repo_locks
+  ADD COLUMN reason TEXT,
+  ADD COLUMN instance_id VARCHAR(36),
+  ADD COLUMN goroutine_id BIGINT;
+
+CREATE TABLE IF NOT EXISTS repo_lock_releases (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  org_id UUID NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+  plan_id UUID NOT NULL REFERENCES plans(id) ON DELETE CASCADE,
+  -- not a reference since the lock is gone once it's released
+  lock_id UUID NOT NULL,
+  scope VARCHAR(1) NOT NULL,
+  branch VARCHAR(255),
+  lock_user_id UUID,
+  lock_reason TEXT,
+  lock_instance_id VARCHAR(36),
+  lock_goroutine_id BIGINT,
+  lock_created_at TIMESTAMP NOT NULL,
+  lock_last_heartbeat_at TIMESTAMP NOT NULL,
+  released_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  note TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX repo_lock_releases_org_idx ON repo_lock_releases(org_id, created_at);
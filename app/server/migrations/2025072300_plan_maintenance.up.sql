@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS plan_maintenance_runs (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  instance_id VARCHAR(36) NOT NULL,
+  plans_compacted INTEGER NOT NULL DEFAULT 0,
+  commits_squashed INTEGER NOT NULL DEFAULT 0,
+  plans_purged INTEGER NOT NULL DEFAULT 0,
+  bytes_reclaimed BIGINT NOT NULL DEFAULT 0,
+  error TEXT,
+  started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  finished_at TIMESTAMP
+);
+
+CREATE INDEX plan_maintenance_runs_finished_idx ON plan_maintenance_runs(finished_at);
@@ -0,0 +1,9 @@
+-- ETag of each plan's latest version synced to object storage, so instances can tell whether their working copy is current without checking the store
+CREATE TABLE IF NOT EXISTS plan_storage_versions (
+  plan_id UUID PRIMARY KEY,
+  etag TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_plan_storage_versions_modtime BEFORE UPDATE ON plan_storage_versions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,34 @@
+package mockmodel
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// Run starts the mock server in the foreground for 'plandex-server mock-model'
+func Run(args []string) error {
+	flags := flag.NewFlagSet("mock-model", flag.ContinueOnError)
+	port := flags.Int("port", 8199, "port to listen on")
+	scenariosPath := flags.String("scenarios", "", "path to a YAML scenarios file")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *scenariosPath == "" {
+		return fmt.Errorf("--scenarios is required")
+	}
+
+	scenarios, err := LoadScenarios(*scenariosPath)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[MockModel] Loaded %d scenarios from %s", len(scenarios.Scenarios), *scenariosPath)
+	log.Printf("[MockModel] Listening on port %d -- use base url http://localhost:%d/v1", *port, *port)
+
+	return http.ListenAndServe(fmt.Sprintf(":%d", *port), NewServer(scenarios).Handler())
+}
@@ -0,0 +1,206 @@
+package mockmodel
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenarios script the mock server's replies. Each request is matched against the scenarios in order and the first
+// one that matches (and hasn't used up its 'times') answers it. A request that matches nothing gets the fallback.
+type Scenarios struct {
+	ChunkSize int         `yaml:"chunkSize"`
+	DelayMs   int         `yaml:"delayMs"`
+	Scenarios []*Scenario `yaml:"scenarios"`
+	Fallback  *Scenario   `yaml:"fallback"`
+
+	mu sync.Mutex
+}
+
+type Scenario struct {
+	Name  string         `yaml:"name"`
+	Match ScenarioMatch  `yaml:"match"`
+	Times int            `yaml:"times"`
+	Error *ScenarioError `yaml:"error"`
+
+	// Response is the reply content. Responses are used in turn for successive matches, with the last one repeating.
+	Response  string   `yaml:"response"`
+	Responses []string `yaml:"responses"`
+
+	// ToolCall streams a function call instead of content
+	ToolCall *ScenarioToolCall `yaml:"toolCall"`
+
+	// override the top-level streaming settings
+	ChunkSize int `yaml:"chunkSize"`
+	DelayMs   int `yaml:"delayMs"`
+
+	numUsed int
+}
+
+// ScenarioMatch conditions are all substring matches, and all that are set must match
+type ScenarioMatch struct {
+	Model       string `yaml:"model"`
+	System      string `yaml:"system"`
+	Contains    string `yaml:"contains"`
+	LastMessage string `yaml:"lastMessage"`
+}
+
+type ScenarioError struct {
+	Status  int    `yaml:"status"`
+	Message string `yaml:"message"`
+}
+
+type ScenarioToolCall struct {
+	Name      string `yaml:"name"`
+	Arguments string `yaml:"arguments"`
+}
+
+// scriptedReply is a scenario's answer to a single request
+type scriptedReply struct {
+	scenario string
+	content  string
+	toolCall *ScenarioToolCall
+	err      *ScenarioError
+
+	chunkSize int
+	delayMs   int
+}
+
+func LoadScenarios(path string) (*Scenarios, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading scenarios file: %v", err)
+	}
+
+	return ParseScenarios(bytes)
+}
+
+func ParseScenarios(bytes []byte) (*Scenarios, error) {
+	var scenarios Scenarios
+	err := yaml.Unmarshal(bytes, &scenarios)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing scenarios: %v", err)
+	}
+
+	for i, scenario := range scenarios.Scenarios {
+		if scenario == nil {
+			return nil, fmt.Errorf("scenario %d is empty", i)
+		}
+		if scenario.Name == "" {
+			scenario.Name = fmt.Sprintf("scenario-%d", i+1)
+		}
+		if !scenario.hasReply() {
+			return nil, fmt.Errorf("scenario %q needs a response, responses, toolCall, or error", scenario.Name)
+		}
+	}
+
+	if scenarios.Fallback != nil {
+		if scenarios.Fallback.Name == "" {
+			scenarios.Fallback.Name = "fallback"
+		}
+		if !scenarios.Fallback.hasReply() {
+			return nil, fmt.Errorf("fallback needs a response, responses, toolCall, or error")
+		}
+	}
+
+	return &scenarios, nil
+}
+
+func (s *Scenario) hasReply() bool {
+	return s.Response != "" || len(s.Responses) > 0 || s.ToolCall != nil || s.Error != nil
+}
+
+func (s *Scenarios) reply(req *chatRequest) *scriptedReply {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, scenario := range s.Scenarios {
+		if scenario.Times > 0 && scenario.numUsed >= scenario.Times {
+			continue
+		}
+		if !scenario.Match.matches(req) {
+			continue
+		}
+		return s.use(scenario)
+	}
+
+	if s.Fallback != nil {
+		return s.use(s.Fallback)
+	}
+
+	return nil
+}
+
+func (s *Scenarios) use(scenario *Scenario) *scriptedReply {
+	reply := &scriptedReply{
+		scenario:  scenario.Name,
+		toolCall:  scenario.ToolCall,
+		err:       scenario.Error,
+		chunkSize: s.ChunkSize,
+		delayMs:   s.DelayMs,
+	}
+
+	if len(scenario.Responses) > 0 {
+		i := min(scenario.numUsed, len(scenario.Responses)-1)
+		reply.content = scenario.Responses[i]
+	} else {
+		reply.content = scenario.Response
+	}
+
+	if scenario.ChunkSize > 0 {
+		reply.chunkSize = scenario.ChunkSize
+	}
+	if scenario.DelayMs > 0 {
+		reply.delayMs = scenario.DelayMs
+	}
+	if reply.chunkSize <= 0 {
+		reply.chunkSize = defaultChunkSize
+	}
+
+	scenario.numUsed++
+
+	return reply
+}
+
+func (m *ScenarioMatch) matches(req *chatRequest) bool {
+	if m.Model != "" && !strings.Contains(req.Model, m.Model) {
+		return false
+	}
+
+	if m.System != "" {
+		found := false
+		for _, msg := range req.Messages {
+			if msg.Role == "system" && strings.Contains(msg.text, m.System) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if m.Contains != "" {
+		found := false
+		for _, msg := range req.Messages {
+			if strings.Contains(msg.text, m.Contains) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if m.LastMessage != "" {
+		if len(req.Messages) == 0 || !strings.Contains(req.Messages[len(req.Messages)-1].text, m.LastMessage) {
+			return false
+		}
+	}
+
+	return true
+}
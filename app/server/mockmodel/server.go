@@ -0,0 +1,267 @@
+package mockmodel
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The mock server speaks just enough of the OpenAI chat completions api for Plandex to run against it as a custom
+// provider (base url 'http://localhost:<port>/v1') with scripted replies, so plans can be exercised without api keys.
+
+const defaultChunkSize = 20
+
+type Server struct {
+	scenarios *Scenarios
+}
+
+func NewServer(scenarios *Scenarios) *Server {
+	return &Server{scenarios: scenarios}
+}
+
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+
+	text string
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", s.handleChatCompletions)
+	mux.HandleFunc("/chat/completions", s.handleChatCompletions)
+	mux.HandleFunc("/v1/models", s.handleModels)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "OK")
+	})
+	return mux
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"object": "list",
+		"data": []map[string]any{
+			{"id": "mock", "object": "model", "owned_by": "plandex"},
+		},
+	})
+}
+
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req chatRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("error decoding request: %v", err))
+		return
+	}
+
+	for i := range req.Messages {
+		req.Messages[i].text = messageText(req.Messages[i].Content)
+	}
+
+	reply := s.scenarios.reply(&req)
+	if reply == nil {
+		log.Printf("[MockModel] No scenario matched request for model %s", req.Model)
+		writeError(w, http.StatusNotFound, "no mock scenario matched the request")
+		return
+	}
+
+	log.Printf("[MockModel] Replying to %s with scenario %q", req.Model, reply.scenario)
+
+	if reply.err != nil {
+		status := reply.err.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		writeError(w, status, reply.err.Message)
+		return
+	}
+
+	promptTokens := estimateTokens(req.Messages)
+	completionTokens := len(reply.content)/4 + 1
+	usage := map[string]any{
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"total_tokens":      promptTokens + completionTokens,
+	}
+
+	finishReason := "stop"
+	if reply.toolCall != nil {
+		finishReason = "tool_calls"
+	}
+
+	if !req.Stream {
+		message := map[string]any{"role": "assistant", "content": reply.content}
+		if reply.toolCall != nil {
+			message["tool_calls"] = []map[string]any{toolCallJson(reply.toolCall, reply.toolCall.Arguments, true)}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":      "mock-" + reply.scenario,
+			"object":  "chat.completion",
+			"created": time.Now().Unix(),
+			"model":   req.Model,
+			"choices": []map[string]any{
+				{"index": 0, "message": message, "finish_reason": finishReason},
+			},
+			"usage": usage,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(choices []map[string]any, usage map[string]any) error {
+		chunk := map[string]any{
+			"id":      "mock-" + reply.scenario,
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   req.Model,
+			"choices": choices,
+		}
+		if usage != nil {
+			chunk["usage"] = usage
+		}
+		bytes, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", bytes)
+		if err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if reply.delayMs > 0 {
+			select {
+			case <-r.Context().Done():
+				return r.Context().Err()
+			case <-time.After(time.Duration(reply.delayMs) * time.Millisecond):
+			}
+		}
+		return nil
+	}
+
+	for i, piece := range splitChunks(reply.content, reply.chunkSize) {
+		delta := map[string]any{"content": piece}
+		if i == 0 {
+			delta["role"] = "assistant"
+		}
+		err = send([]map[string]any{{"index": 0, "delta": delta}}, nil)
+		if err != nil {
+			log.Printf("[MockModel] Stream ended early: %v", err)
+			return
+		}
+	}
+
+	if reply.toolCall != nil {
+		for i, piece := range splitChunks(reply.toolCall.Arguments, reply.chunkSize) {
+			delta := map[string]any{
+				"tool_calls": []map[string]any{toolCallJson(reply.toolCall, piece, i == 0)},
+			}
+			err = send([]map[string]any{{"index": 0, "delta": delta}}, nil)
+			if err != nil {
+				log.Printf("[MockModel] Stream ended early: %v", err)
+				return
+			}
+		}
+	}
+
+	err = send([]map[string]any{{"index": 0, "delta": map[string]any{}, "finish_reason": finishReason}}, nil)
+	if err == nil {
+		err = send([]map[string]any{}, usage)
+	}
+	if err != nil {
+		log.Printf("[MockModel] Stream ended early: %v", err)
+		return
+	}
+
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func toolCallJson(toolCall *ScenarioToolCall, arguments string, first bool) map[string]any {
+	res := map[string]any{
+		"index":    0,
+		"function": map[string]any{"arguments": arguments},
+	}
+	if first {
+		res["id"] = "call_mock"
+		res["type"] = "function"
+		res["function"].(map[string]any)["name"] = toolCall.Name
+	}
+	return res
+}
+
+// content is either a plain string or an array of parts--only text parts are matched against
+func messageText(content json.RawMessage) string {
+	var s string
+	if json.Unmarshal(content, &s) == nil {
+		return s
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(content, &parts) == nil {
+		var texts []string
+		for _, part := range parts {
+			if part.Type == "text" {
+				texts = append(texts, part.Text)
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+
+	return ""
+}
+
+func splitChunks(s string, size int) []string {
+	runes := []rune(s)
+	var chunks []string
+	for i := 0; i < len(runes); i += size {
+		chunks = append(chunks, string(runes[i:min(i+size, len(runes))]))
+	}
+	return chunks
+}
+
+// rough estimate so usage-based accounting has something plausible to work with
+func estimateTokens(messages []chatMessage) int {
+	n := 0
+	for _, msg := range messages {
+		n += len(msg.text)/4 + 4
+	}
+	return n
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{
+			"message": message,
+			"type":    "mock_error",
+			"code":    status,
+		},
+	})
+}
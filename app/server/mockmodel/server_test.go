@@ -0,0 +1,76 @@
+package mockmodel
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testScenarios = `
+chunkSize: 4
+scenarios:
+  - name: namer
+    match:
+      system: "AI namer"
+    response: "<planName>hello-world</planName>"
+  - name: planner
+    times: 2
+    match:
+      contains: "hello"
+    responses:
+      - "first"
+      - "second"
+fallback:
+  error:
+    status: 429
+    message: "rate limited"
+`
+
+func TestMockServer(t *testing.T) {
+	scenarios, err := ParseScenarios([]byte(testScenarios))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(NewServer(scenarios).Handler())
+	defer srv.Close()
+
+	post := func(body string) (int, string) {
+		res, err := http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		bytes, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, string(bytes)
+	}
+
+	status, body := post(`{"model":"mock","stream":true,"messages":[{"role":"system","content":[{"type":"text","text":"You are an AI namer"}]}]}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	for _, want := range []string{`"content":"\u003cpla"`, `"finish_reason":"stop"`, `"usage":{`, "data: [DONE]"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stream missing %q:\n%s", want, body)
+		}
+	}
+
+	prompt := `{"model":"mock","messages":[{"role":"user","content":"say hello"}]}`
+	for _, want := range []string{`"content":"first"`, `"content":"second"`} {
+		_, body = post(prompt)
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s, got %s", want, body)
+		}
+	}
+
+	// planner is used up, so the fallback answers
+	status, _ = post(prompt)
+	if status != http.StatusTooManyRequests {
+		t.Errorf("fallback status = %d", status)
+	}
+}
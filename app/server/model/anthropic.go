@@ -0,0 +1,535 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"plandex-server/types"
+	shared "plandex-shared"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Native client for the Anthropic Messages API. Requests are translated from the OpenAI-style
+// ExtendedChatCompletionRequest and streamed events are translated back into OpenAI-style chunks, so callers
+// of CreateChatCompletionStream don't need to know which api a provider speaks.
+
+const anthropicApiVersion = "2023-06-01"
+
+// anthropic requires a thinking budget of at least this many tokens
+const anthropicMinThinkingBudget = 1024
+
+type anthropicCacheControl struct {
+	Type types.CacheControlType `json:"type"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	Url       string `json:"url,omitempty"`
+}
+
+type anthropicContentBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text,omitempty"`
+	Source       *anthropicImageSource  `json:"source,omitempty"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type anthropicTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicRequest struct {
+	Model         string                  `json:"model"`
+	MaxTokens     int                     `json:"max_tokens"`
+	System        []anthropicContentBlock `json:"system,omitempty"`
+	Messages      []anthropicMessage      `json:"messages"`
+	Stream        bool                    `json:"stream"`
+	Temperature   *float32                `json:"temperature,omitempty"`
+	TopP          *float32                `json:"top_p,omitempty"`
+	StopSequences []string                `json:"stop_sequences,omitempty"`
+	Thinking      *anthropicThinking      `json:"thinking,omitempty"`
+	Tools         []anthropicTool         `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice    `json:"tool_choice,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+type anthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Index int    `json:"index"`
+
+	Message *struct {
+		Id    string         `json:"id"`
+		Model string         `json:"model"`
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message,omitempty"`
+
+	ContentBlock *struct {
+		Type string `json:"type"`
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block,omitempty"`
+
+	Delta *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		PartialJson string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta,omitempty"`
+
+	Usage *anthropicUsage `json:"usage,omitempty"`
+
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func createAnthropicChatCompletionStream(
+	ctx context.Context,
+	client ClientInfo,
+	baseModelConfig *shared.BaseModelConfig,
+	extendedReq types.ExtendedChatCompletionRequest,
+) (*ExtendedChatCompletionStream, error) {
+	anthropicReq := toAnthropicRequest(extendedReq, baseModelConfig)
+
+	jsonBody, err := json.Marshal(anthropicReq)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling anthropic request: %w", err)
+	}
+
+	url := strings.TrimSuffix(client.ProviderConfig.BaseUrl, "/") + "/messages"
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("anthropic-version", anthropicApiVersion)
+
+	// claude max auth passes its bearer token and beta headers through extra headers
+	for k, v := range extendedReq.ExtraHeaders {
+		req.Header.Set(k, v)
+	}
+	if client.ApiKey != "" && req.Header.Get("Authorization") == "" {
+		req.Header.Set("x-api-key", client.ApiKey)
+	}
+
+	resp, err := httpClient.Do(req) //nolint:bodyclose // body is closed in stream.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading error response: %w", err)
+		}
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			Header:     resp.Header.Clone(),
+		}
+	}
+
+	includeThinking := extendedReq.ReasoningConfig != nil && !extendedReq.ReasoningConfig.Exclude
+
+	return &ExtendedChatCompletionStream{
+		nativeReader: &anthropicStreamReader{
+			reader:          bufio.NewReader(resp.Body),
+			response:        resp,
+			includeThinking: includeThinking,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func toAnthropicRequest(req types.ExtendedChatCompletionRequest, baseModelConfig *shared.BaseModelConfig) *anthropicRequest {
+	res := &anthropicRequest{
+		Model:         strings.TrimPrefix(string(req.Model), "anthropic/"),
+		Stream:        true,
+		StopSequences: req.Stop,
+	}
+
+	for _, msg := range req.Messages {
+		blocks := toAnthropicContentBlocks(msg.Content)
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if msg.Role == openai.ChatMessageRoleSystem {
+			res.System = append(res.System, blocks...)
+			continue
+		}
+
+		role := "user"
+		if msg.Role == openai.ChatMessageRoleAssistant {
+			role = "assistant"
+		}
+
+		// anthropic requires alternating roles, so merge consecutive messages from the same role
+		if n := len(res.Messages); n > 0 && res.Messages[n-1].Role == role {
+			res.Messages[n-1].Content = append(res.Messages[n-1].Content, blocks...)
+		} else {
+			res.Messages = append(res.Messages, anthropicMessage{Role: role, Content: blocks})
+		}
+	}
+
+	res.MaxTokens = req.MaxCompletionTokens
+	if res.MaxTokens == 0 {
+		res.MaxTokens = req.MaxTokens
+	}
+	if res.MaxTokens == 0 {
+		res.MaxTokens = baseModelConfig.MaxOutputTokens
+
+		// input and output share the context window, so don't ask for more output than can fit
+		if baseModelConfig.MaxTokens > 0 {
+			available := baseModelConfig.MaxTokens - GetMessagesTokenEstimate(req.Messages...) - TokensPerRequest
+			if available > 0 && available < res.MaxTokens {
+				res.MaxTokens = available
+			}
+		}
+	}
+
+	for _, tool := range req.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		var inputSchema any = tool.Function.Parameters
+		if inputSchema == nil {
+			inputSchema = map[string]string{"type": "object"}
+		}
+		res.Tools = append(res.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: inputSchema,
+		})
+	}
+	res.ToolChoice = toAnthropicToolChoice(req.ToolChoice)
+
+	var thinkingBudget int
+	if req.ReasoningConfig != nil {
+		if req.ReasoningConfig.MaxTokens > 0 {
+			thinkingBudget = req.ReasoningConfig.MaxTokens
+		} else if req.ReasoningConfig.Effort != "" {
+			thinkingBudget = reasoningBudgetByEffort[req.ReasoningConfig.Effort]
+		}
+	}
+	if thinkingBudget > shared.AnthropicMaxReasoningBudget {
+		thinkingBudget = shared.AnthropicMaxReasoningBudget
+	}
+	// the budget must leave room for the response itself
+	if thinkingBudget >= res.MaxTokens {
+		thinkingBudget = res.MaxTokens / 2
+	}
+
+	// thinking can't be combined with a forced tool choice
+	forcedTool := res.ToolChoice != nil && (res.ToolChoice.Type == "any" || res.ToolChoice.Type == "tool")
+
+	if thinkingBudget >= anthropicMinThinkingBudget && !forcedTool {
+		res.Thinking = &anthropicThinking{
+			Type:         "enabled",
+			BudgetTokens: thinkingBudget,
+		}
+		// temperature and top_p aren't compatible with thinking
+	} else {
+		if req.Temperature != 0 {
+			temperature := req.Temperature
+			res.Temperature = &temperature
+		}
+		if req.TopP != 0 {
+			topP := req.TopP
+			res.TopP = &topP
+		}
+	}
+
+	return res
+}
+
+func toAnthropicContentBlocks(parts []types.ExtendedChatMessagePart) []anthropicContentBlock {
+	var blocks []anthropicContentBlock
+	for _, part := range parts {
+		var block anthropicContentBlock
+
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			// anthropic rejects empty text blocks
+			if part.Text == "" {
+				continue
+			}
+			block = anthropicContentBlock{Type: "text", Text: part.Text}
+		case openai.ChatMessagePartTypeImageURL:
+			if part.ImageURL == nil {
+				continue
+			}
+			block = anthropicContentBlock{Type: "image", Source: toAnthropicImageSource(part.ImageURL.URL)}
+		default:
+			continue
+		}
+
+		if part.CacheControl != nil {
+			block.CacheControl = &anthropicCacheControl{Type: part.CacheControl.Type}
+		}
+
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// images are sent as data urls by the client, which anthropic expects as a separate media type and base64 payload
+func toAnthropicImageSource(imageUrl string) *anthropicImageSource {
+	if strings.HasPrefix(imageUrl, "data:") {
+		meta, data, found := strings.Cut(strings.TrimPrefix(imageUrl, "data:"), ",")
+		if found {
+			return &anthropicImageSource{
+				Type:      "base64",
+				MediaType: strings.TrimSuffix(meta, ";base64"),
+				Data:      data,
+			}
+		}
+	}
+
+	return &anthropicImageSource{Type: "url", Url: imageUrl}
+}
+
+func toAnthropicToolChoice(toolChoice any) *anthropicToolChoice {
+	switch choice := toolChoice.(type) {
+	case nil:
+		return nil
+	case string:
+		switch choice {
+		case "auto":
+			return &anthropicToolChoice{Type: "auto"}
+		case "required":
+			return &anthropicToolChoice{Type: "any"}
+		case "none":
+			return &anthropicToolChoice{Type: "none"}
+		}
+	case *openai.ToolChoice:
+		if choice != nil && choice.Function.Name != "" {
+			return &anthropicToolChoice{Type: "tool", Name: choice.Function.Name}
+		}
+	case openai.ToolChoice:
+		if choice.Function.Name != "" {
+			return &anthropicToolChoice{Type: "tool", Name: choice.Function.Name}
+		}
+	}
+	return nil
+}
+
+// anthropicStreamReader translates anthropic stream events into OpenAI-style chunks, skipping events that
+// don't carry content
+type anthropicStreamReader struct {
+	reader          *bufio.Reader
+	response        *http.Response
+	includeThinking bool
+
+	id          string
+	model       string
+	usage       anthropicUsage
+	toolIndexes map[int]int
+	done        bool
+}
+
+func (stream *anthropicStreamReader) Recv() (*types.ExtendedChatCompletionStreamResponse, error) {
+	for {
+		if stream.done {
+			return nil, io.EOF
+		}
+
+		line, err := stream.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+		var event anthropicStreamEvent
+		err = json.Unmarshal([]byte(data), &event)
+		if err != nil {
+			log.Printf("anthropic stream - error unmarshalling event: %v", err)
+			continue
+		}
+
+		chunk, err := stream.handleEvent(&event)
+		if err != nil {
+			return nil, err
+		}
+		if chunk != nil {
+			return chunk, nil
+		}
+	}
+}
+
+func (stream *anthropicStreamReader) handleEvent(event *anthropicStreamEvent) (*types.ExtendedChatCompletionStreamResponse, error) {
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			stream.id = event.Message.Id
+			stream.model = event.Message.Model
+			stream.usage = event.Message.Usage
+		}
+
+	case "content_block_start":
+		if event.ContentBlock != nil && event.ContentBlock.Type == "tool_use" {
+			if stream.toolIndexes == nil {
+				stream.toolIndexes = map[int]int{}
+			}
+			toolIndex := len(stream.toolIndexes)
+			stream.toolIndexes[event.Index] = toolIndex
+			return stream.chunk(types.ExtendedChatCompletionStreamChoiceDelta{
+				ToolCalls: []openai.ToolCall{{
+					Index: &toolIndex,
+					ID:    event.ContentBlock.Id,
+					Type:  openai.ToolTypeFunction,
+					Function: openai.FunctionCall{
+						Name: event.ContentBlock.Name,
+					},
+				}},
+			}, ""), nil
+		}
+
+	case "content_block_delta":
+		if event.Delta == nil {
+			return nil, nil
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			return stream.chunk(types.ExtendedChatCompletionStreamChoiceDelta{Content: event.Delta.Text}, ""), nil
+		case "thinking_delta":
+			if stream.includeThinking {
+				return stream.chunk(types.ExtendedChatCompletionStreamChoiceDelta{Reasoning: event.Delta.Thinking}, ""), nil
+			}
+		case "input_json_delta":
+			toolIndex := stream.toolIndexes[event.Index]
+			return stream.chunk(types.ExtendedChatCompletionStreamChoiceDelta{
+				ToolCalls: []openai.ToolCall{{
+					Index:    &toolIndex,
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Arguments: event.Delta.PartialJson},
+				}},
+			}, ""), nil
+		}
+
+	case "message_delta":
+		if event.Usage != nil {
+			stream.mergeUsage(event.Usage)
+		}
+		if event.Delta != nil && event.Delta.StopReason != "" {
+			return stream.chunk(types.ExtendedChatCompletionStreamChoiceDelta{}, anthropicFinishReason(event.Delta.StopReason)), nil
+		}
+
+	case "message_stop":
+		stream.done = true
+		cachedTokens := stream.usage.CacheReadInputTokens
+		promptTokens := stream.usage.InputTokens + stream.usage.CacheCreationInputTokens + cachedTokens
+		return &types.ExtendedChatCompletionStreamResponse{
+			ID:    stream.id,
+			Model: stream.model,
+			Usage: &openai.Usage{
+				PromptTokens:     promptTokens,
+				CompletionTokens: stream.usage.OutputTokens,
+				TotalTokens:      promptTokens + stream.usage.OutputTokens,
+				PromptTokensDetails: &openai.PromptTokensDetails{
+					CachedTokens: cachedTokens,
+				},
+			},
+		}, nil
+
+	case "error":
+		// the error type (overloaded_error, rate_limit_error, etc.) is kept in the message so it can be classified
+		if event.Error != nil {
+			return nil, fmt.Errorf("anthropic stream error: %s: %s", event.Error.Type, event.Error.Message)
+		}
+		return nil, fmt.Errorf("anthropic stream error")
+	}
+
+	return nil, nil
+}
+
+// message_delta usage is cumulative, so non-zero values replace what came with message_start
+func (stream *anthropicStreamReader) mergeUsage(usage *anthropicUsage) {
+	if usage.InputTokens > 0 {
+		stream.usage.InputTokens = usage.InputTokens
+	}
+	if usage.OutputTokens > 0 {
+		stream.usage.OutputTokens = usage.OutputTokens
+	}
+	if usage.CacheCreationInputTokens > 0 {
+		stream.usage.CacheCreationInputTokens = usage.CacheCreationInputTokens
+	}
+	if usage.CacheReadInputTokens > 0 {
+		stream.usage.CacheReadInputTokens = usage.CacheReadInputTokens
+	}
+}
+
+func (stream *anthropicStreamReader) chunk(delta types.ExtendedChatCompletionStreamChoiceDelta, finishReason openai.FinishReason) *types.ExtendedChatCompletionStreamResponse {
+	return &types.ExtendedChatCompletionStreamResponse{
+		ID:     stream.id,
+		Object: "chat.completion.chunk",
+		Model:  stream.model,
+		Choices: []types.ExtendedChatCompletionStreamChoice{{
+			Delta:        delta,
+			FinishReason: finishReason,
+		}},
+	}
+}
+
+func (stream *anthropicStreamReader) Close() error {
+	if stream.response != nil {
+		return stream.response.Body.Close()
+	}
+	return nil
+}
+
+func anthropicFinishReason(stopReason string) openai.FinishReason {
+	switch stopReason {
+	case "max_tokens":
+		return openai.FinishReasonLength
+	case "tool_use":
+		return openai.FinishReasonToolCalls
+	case "refusal":
+		return openai.FinishReasonContentFilter
+	default:
+		return openai.FinishReasonStop
+	}
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"bufio"
+	"io"
+	"plandex-server/types"
+	shared "plandex-shared"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestToAnthropicRequest(t *testing.T) {
+	req := types.ExtendedChatCompletionRequest{
+		Model: "anthropic/claude-sonnet-4-20250514",
+		Messages: []types.ExtendedChatMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: []types.ExtendedChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "system", CacheControl: &types.CacheControlSpec{Type: types.CacheControlTypeEphemeral}},
+			}},
+			{Role: openai.ChatMessageRoleUser, Content: []types.ExtendedChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "first"},
+			}},
+			{Role: openai.ChatMessageRoleUser, Content: []types.ExtendedChatMessagePart{
+				{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "data:image/png;base64,abc"}},
+			}},
+			{Role: openai.ChatMessageRoleAssistant, Content: []types.ExtendedChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: ""},
+				{Type: openai.ChatMessagePartTypeText, Text: "reply"},
+			}},
+		},
+		Temperature:     0.5,
+		ReasoningConfig: &types.ReasoningConfig{MaxTokens: 50000},
+	}
+
+	res := toAnthropicRequest(req, &shared.BaseModelConfig{BaseModelShared: shared.BaseModelShared{MaxOutputTokens: 64000}})
+
+	if res.Model != "claude-sonnet-4-20250514" {
+		t.Errorf("model = %q", res.Model)
+	}
+	if len(res.System) != 1 || res.System[0].CacheControl == nil {
+		t.Errorf("expected a single cached system block, got %+v", res.System)
+	}
+	if len(res.Messages) != 2 {
+		t.Fatalf("expected consecutive user messages to be merged, got %d messages", len(res.Messages))
+	}
+	if len(res.Messages[0].Content) != 2 || res.Messages[0].Content[1].Source == nil || res.Messages[0].Content[1].Source.MediaType != "image/png" || res.Messages[0].Content[1].Source.Data != "abc" {
+		t.Errorf("unexpected user content: %+v", res.Messages[0].Content)
+	}
+	if len(res.Messages[1].Content) != 1 {
+		t.Errorf("expected empty text block to be dropped, got %+v", res.Messages[1].Content)
+	}
+	if res.Thinking == nil || res.Thinking.BudgetTokens != shared.AnthropicMaxReasoningBudget {
+		t.Errorf("expected thinking budget to be capped, got %+v", res.Thinking)
+	}
+	if res.Temperature != nil {
+		t.Errorf("temperature should be dropped when thinking is enabled")
+	}
+}
+
+func TestAnthropicStreamReader(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","model":"claude","usage":{"input_tokens":10,"cache_read_input_tokens":100,"cache_creation_input_tokens":5,"output_tokens":1}}}`,
+		`{"type":"ping"}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"hmm"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":20}}`,
+		`{"type":"message_stop"}`,
+	}
+
+	var body strings.Builder
+	for _, event := range events {
+		body.WriteString("event: x\ndata: " + event + "\n\n")
+	}
+
+	stream := &anthropicStreamReader{reader: bufio.NewReader(strings.NewReader(body.String()))}
+
+	chunk, err := stream.Recv()
+	if err != nil || chunk.Choices[0].Delta.Content != "Hello" || chunk.ID != "msg_1" {
+		t.Fatalf("expected text chunk with thinking skipped, got %+v, %v", chunk, err)
+	}
+
+	chunk, err = stream.Recv()
+	if err != nil || chunk.Choices[0].FinishReason != openai.FinishReasonStop {
+		t.Fatalf("expected finish chunk, got %+v, %v", chunk, err)
+	}
+
+	chunk, err = stream.Recv()
+	if err != nil || chunk.Usage == nil {
+		t.Fatalf("expected usage chunk, got %+v, %v", chunk, err)
+	}
+	if chunk.Usage.PromptTokens != 115 || chunk.Usage.CompletionTokens != 20 || chunk.Usage.PromptTokensDetails.CachedTokens != 100 {
+		t.Errorf("unexpected usage: %+v", chunk.Usage)
+	}
+
+	_, err = stream.Recv()
+	if err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"plandex-server/db"
+	"plandex-server/metrics"
 	"plandex-server/types"
 	"strings"
 	"sync"
@@ -55,6 +57,8 @@ func InitClients(authVars map[string]string, settings *shared.PlanSettings, orgU
 }
 
 func newClient(providerConfig shared.ModelProviderConfigSchema, authVars map[string]string) ClientInfo {
+	providerConfig = resolveNativeProvider(providerConfig)
+
 	var apiKey string
 	if providerConfig.ApiKeyEnvVar != "" {
 		apiKey = authVars[providerConfig.ApiKeyEnvVar]
@@ -79,11 +83,29 @@ func newClient(providerConfig shared.ModelProviderConfigSchema, authVars map[str
 	}
 }
 
-// ExtendedChatCompletionStream can wrap either a native OpenAI stream or our custom implementation
+// ExtendedChatCompletionStream can wrap either a native OpenAI stream, our custom implementation, or a native client for a provider with its own api
 type ExtendedChatCompletionStream struct {
 	openaiStream *openai.ChatCompletionStream
 	customReader *StreamReader[types.ExtendedChatCompletionStreamResponse]
+	nativeReader nativeStreamReader
 	ctx          context.Context
+
+	// frees the request's rate limiter slot on close, settling up with the usage chunk if one was received
+	release    func(usedTokens int)
+	usedTokens int
+
+	// errors partway through the stream count against the provider's health
+	providerConfig *shared.ModelProviderConfigSchema
+
+	// labels for request metrics
+	metricsProvider string
+	metricsRole     string
+}
+
+// nativeStreamReader translates a provider's own stream format into OpenAI-style chunks
+type nativeStreamReader interface {
+	Recv() (*types.ExtendedChatCompletionStreamResponse, error)
+	Close() error
 }
 
 // StreamReader handles the SSE stream reading
@@ -114,6 +136,7 @@ func CreateChatCompletionStream(
 	currentUserId string,
 	ctx context.Context,
 	req types.ExtendedChatCompletionRequest,
+	onStatus OnModelStatusFn,
 ) (*ExtendedChatCompletionStream, error) {
 	providerComposite := modelConfig.GetProviderComposite(authVars, settings, orgUserConfig)
 	_, ok := clients[providerComposite]
@@ -208,7 +231,7 @@ func CreateChatCompletionStream(
 			"modelConfig.ApiKeyEnvVar": baseModelConfig.ApiKeyEnvVar,
 		})
 
-		resp, err := createChatCompletionStreamExtended(resolvedModelConfig, opClient, authVars, settings, orgUserConfig, ctx, req)
+		resp, err := createChatCompletionStreamExtended(resolvedModelConfig, opClient, authVars, settings, orgUserConfig, ctx, req, onStatus)
 		return resp, fallbackRes, err
 	}, func(resp *ExtendedChatCompletionStream, err error) {})
 }
@@ -221,12 +244,65 @@ func createChatCompletionStreamExtended(
 	orgUserConfig *shared.OrgUserConfig,
 	ctx context.Context,
 	extendedReq types.ExtendedChatCompletionRequest,
+	onStatus OnModelStatusFn,
 ) (*ExtendedChatCompletionStream, error) {
 	baseModelConfig := modelConfig.GetBaseModelConfig(authVars, settings, orgUserConfig)
 
 	// ensure the model name is set correctly on fallbacks
 	extendedReq.Model = baseModelConfig.ModelName
 
+	createLive := func() (*ExtendedChatCompletionStream, error) {
+		var release func(usedTokens int)
+		limiter := getProviderLimiter(client.ProviderConfig, extendedReq.Model)
+		if limiter != nil {
+			var err error
+			release, err = limiter.acquire(ctx, GetMessagesTokenEstimate(extendedReq.Messages...), onStatus)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		metricsProvider, metricsRole := string(baseModelConfig.Provider), string(modelConfig.Role)
+		metrics.ObserveModelRequest(metricsProvider, metricsRole)
+
+		started := time.Now()
+		stream, err := createLiveChatCompletionStream(client, baseModelConfig, authVars, ctx, extendedReq)
+		recordProviderResult(client.ProviderConfig, time.Since(started), err)
+		if err != nil {
+			if ctx.Err() == nil {
+				metrics.ObserveModelRequestError(metricsProvider, metricsRole)
+			}
+			if limiter != nil {
+				release(0)
+				var httpErr *HTTPError
+				if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests {
+					limiter.pause(time.Duration(extractRetryAfter(httpErr.Header, httpErr.Body)) * time.Second)
+				}
+			}
+			return nil, err
+		}
+		stream.release = release
+		stream.providerConfig = &client.ProviderConfig
+		stream.metricsProvider = metricsProvider
+		stream.metricsRole = metricsRole
+		return stream, nil
+	}
+
+	if fixtures := getModelFixtures(); fixtures != nil {
+		return fixtures.createStream(ctx, extendedReq, createLive)
+	}
+
+	return createLive()
+}
+
+func createLiveChatCompletionStream(
+	client ClientInfo,
+	baseModelConfig *shared.BaseModelConfig,
+	authVars map[string]string,
+	ctx context.Context,
+	extendedReq types.ExtendedChatCompletionRequest,
+) (*ExtendedChatCompletionStream, error) {
+
 	var openaiReq *types.ExtendedOpenAIChatCompletionRequest
 	if baseModelConfig.Provider == shared.ModelProviderOpenAI {
 		openaiReq = extendedReq.ToOpenAI()
@@ -317,6 +393,16 @@ func createChatCompletionStreamExtended(
 
 	}
 
+	if client.ProviderConfig.ApiType == shared.ModelProviderApiTypeAnthropic {
+		log.Println("Creating chat completion stream with native Anthropic client")
+		return createAnthropicChatCompletionStream(ctx, client, baseModelConfig, extendedReq)
+	}
+
+	if usesGeminiApi(client, extendedReq.Model) {
+		log.Println("Creating chat completion stream with native Gemini client")
+		return createGeminiChatCompletionStream(ctx, client, baseModelConfig, extendedReq)
+	}
+
 	// Marshal the request body to JSON
 	var jsonBody []byte
 	var err error
@@ -470,6 +556,18 @@ func (stream *StreamReader[T]) Close() error {
 
 // Recv returns the next message in the stream
 func (stream *ExtendedChatCompletionStream) Recv() (*types.ExtendedChatCompletionStreamResponse, error) {
+	response, err := stream.recv()
+	if err == nil && response != nil && response.Usage != nil {
+		stream.usedTokens = response.Usage.TotalTokens
+	}
+	if err != nil && err != io.EOF && stream.providerConfig != nil && stream.ctx.Err() == nil {
+		recordProviderResult(*stream.providerConfig, 0, err)
+		metrics.ObserveModelRequestError(stream.metricsProvider, stream.metricsRole)
+	}
+	return response, err
+}
+
+func (stream *ExtendedChatCompletionStream) recv() (*types.ExtendedChatCompletionStreamResponse, error) {
 	select {
 	case <-stream.ctx.Done():
 		return nil, stream.ctx.Err()
@@ -487,15 +585,24 @@ func (stream *ExtendedChatCompletionStream) Recv() (*types.ExtendedChatCompletio
 			}
 			return &response, nil
 		}
+		if stream.nativeReader != nil {
+			return stream.nativeReader.Recv()
+		}
 		return stream.customReader.Recv()
 	}
 }
 
 // Close the response body
 func (stream *ExtendedChatCompletionStream) Close() error {
+	if stream.release != nil {
+		stream.release(stream.usedTokens)
+	}
 	if stream.openaiStream != nil {
 		return stream.openaiStream.Close()
 	}
+	if stream.nativeReader != nil {
+		return stream.nativeReader.Close()
+	}
 	return stream.customReader.Close()
 }
 
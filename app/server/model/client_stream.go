@@ -6,12 +6,14 @@ import (
 	"io"
 	"log"
 	"math/rand"
+	"plandex-server/tracing"
 	"plandex-server/types"
 	shared "plandex-shared"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type OnStreamFn func(chunk string, buffer string) (shouldStop bool)
@@ -27,6 +29,7 @@ func CreateChatCompletionWithInternalStream(
 	ctx context.Context,
 	req types.ExtendedChatCompletionRequest,
 	onStream OnStreamFn,
+	onStatus OnModelStatusFn,
 	reqStarted time.Time,
 ) (*types.ModelResponse, error) {
 	providerComposite := modelConfig.GetProviderComposite(authVars, settings, orgUserConfig)
@@ -52,7 +55,42 @@ func CreateChatCompletionWithInternalStream(
 		IncludeUsage: true,
 	}
 
-	return withStreamingRetries(ctx, func(numTotalRetry int, didProviderFallback bool, modelErr *shared.ModelError) (resp *types.ModelResponse, fallbackRes shared.FallbackResult, err error) {
+	var cacheKey string
+	cache := getResponseCache()
+	if cache != nil && cache.enabledForRole(modelConfig.Role) {
+		key, err := responseCacheKey(currentOrgId, modelConfig.ModelId, req)
+		if err != nil {
+			log.Printf("[ResponseCache] Error building cache key: %v", err)
+		} else if content, ok := cache.get(key); ok {
+			log.Printf("[ResponseCache] Hit for %s request to %s", modelConfig.Role, baseModelConfig.ModelName)
+			if onStream != nil {
+				onStream(content, content)
+			}
+			return &types.ModelResponse{
+				Content:      content,
+				Usage:        &openai.Usage{},
+				FirstTokenAt: time.Now(),
+				CacheHit:     true,
+			}, nil
+		} else {
+			cacheKey = key
+		}
+	}
+
+	// a response the caller cut off partway isn't cached, since the next caller may want all of it
+	var stoppedByCaller bool
+	if cacheKey != "" && onStream != nil {
+		callerOnStream := onStream
+		onStream = func(chunk string, buffer string) bool {
+			shouldStop := callerOnStream(chunk, buffer)
+			if shouldStop {
+				stoppedByCaller = true
+			}
+			return shouldStop
+		}
+	}
+
+	res, err := withStreamingRetries(ctx, func(numTotalRetry int, didProviderFallback bool, modelErr *shared.ModelError) (resp *types.ModelResponse, fallbackRes shared.FallbackResult, err error) {
 		handleClaudeMaxRateLimitedIfNeeded(modelErr, modelConfig, authVars, settings, orgUserConfig, currentOrgId, currentUserId)
 
 		fallbackRes = modelConfig.GetFallbackForModelError(numTotalRetry, didProviderFallback, modelErr, authVars, settings, orgUserConfig)
@@ -70,7 +108,7 @@ func CreateChatCompletionWithInternalStream(
 		}
 
 		modelConfig = resolvedModelConfig
-		resp, err = processChatCompletionStream(resolvedModelConfig, opClient, authVars, settings, orgUserConfig, ctx, req, onStream, reqStarted)
+		resp, err = processChatCompletionStream(resolvedModelConfig, opClient, authVars, settings, orgUserConfig, ctx, req, onStream, onStatus, reqStarted)
 		if err != nil {
 			return nil, fallbackRes, err
 		}
@@ -81,6 +119,12 @@ func CreateChatCompletionWithInternalStream(
 			resp.Error = err.Error()
 		}
 	})
+
+	if cacheKey != "" && err == nil && res != nil && !res.Stopped && res.Error == "" && res.Content != "" && !stoppedByCaller {
+		cache.set(cacheKey, res.Content)
+	}
+
+	return res, err
 }
 
 func processChatCompletionStream(
@@ -92,6 +136,7 @@ func processChatCompletionStream(
 	ctx context.Context,
 	req types.ExtendedChatCompletionRequest,
 	onStream OnStreamFn,
+	onStatus OnModelStatusFn,
 	reqStarted time.Time,
 ) (*types.ModelResponse, error) {
 	streamCtx, cancel := context.WithCancel(ctx)
@@ -100,7 +145,7 @@ func processChatCompletionStream(
 		"model": modelConfig.ModelId,
 	}))
 
-	stream, err := createChatCompletionStreamExtended(modelConfig, client, authVars, settings, orgUserConfig, streamCtx, req)
+	stream, err := createChatCompletionStreamExtended(modelConfig, client, authVars, settings, orgUserConfig, streamCtx, req, onStatus)
 
 	if err != nil {
 		cancel()
@@ -207,6 +252,9 @@ func processChatCompletionStream(
 					if choice.Delta.ToolCalls != nil {
 						toolCall := choice.Delta.ToolCalls[0]
 						content = toolCall.Function.Arguments
+					} else if choice.Delta.Content != "" {
+						// kept so callers can fall back to parsing text if the model doesn't call the function
+						accumulator.AddTextFallback(choice.Delta.Content)
 					}
 				} else {
 					if choice.Delta.Content != "" {
@@ -231,8 +279,12 @@ func withStreamingRetries[T any](
 	ctx context.Context,
 	operation func(numRetry int, didProviderFallback bool, modelErr *shared.ModelError) (resp *T, fallbackRes shared.FallbackResult, err error),
 	onContextDone func(resp *T, err error),
-) (*T, error) {
-	var resp *T
+) (resp *T, err error) {
+	ctx, span := tracing.Start(ctx, "withStreamingRetries")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	var numTotalRetry int
 	var numFallbackRetry int
 	var fallbackRes shared.FallbackResult
@@ -249,8 +301,6 @@ func withStreamingRetries[T any](
 			return nil, ctx.Err()
 		}
 
-		var err error
-
 		var numRetry int
 		if numFallbackRetry > 0 {
 			numRetry = numFallbackRetry
@@ -273,6 +323,12 @@ func withStreamingRetries[T any](
 
 		log.Printf("withStreamingRetries - operation returned error: %v", err)
 
+		tracing.AddEvent(ctx, "attempt failed",
+			attribute.Int("retry", numTotalRetry),
+			attribute.Bool("fallback", fallbackRes.IsFallback),
+			attribute.String("error", err.Error()),
+		)
+
 		isFallback := fallbackRes.IsFallback
 		maxRetries := MAX_RETRIES_WITHOUT_FALLBACK
 		if isFallback {
@@ -0,0 +1,450 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"plandex-server/types"
+	shared "plandex-shared"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Model fixtures record streamed model responses to disk and replay them later, so the tell/build pipeline can
+// run deterministically without a live model. Both CreateChatCompletionStream and
+// CreateChatCompletionWithInternalStream go through createChatCompletionStreamExtended, which is where requests
+// are intercepted.
+//
+// Enable with PLANDEX_MODEL_FIXTURES=record|replay and PLANDEX_MODEL_FIXTURES_DIR, or call SetModelFixtures from tests.
+
+type ModelFixtureMode string
+
+const (
+	ModelFixtureModeRecord ModelFixtureMode = "record"
+	ModelFixtureModeReplay ModelFixtureMode = "replay"
+)
+
+type ModelFixtureConfig struct {
+	Mode ModelFixtureMode
+	Dir  string
+
+	// scales the recorded delay between chunks on replay—1 replays with the original timing, 0 replays instantly
+	TimingScale float64
+
+	// on replay, if no fixture matches a request's fingerprint, use the next unused fixture in recorded order
+	// instead of failing—useful when prompts contain details that vary between runs
+	Sequential bool
+}
+
+type modelFixtureChunk struct {
+	DelayMs int64                                       `json:"delayMs"`
+	Chunk   *types.ExtendedChatCompletionStreamResponse `json:"chunk"`
+}
+
+type modelFixtureResponse struct {
+	Chunks []modelFixtureChunk `json:"chunks,omitempty"`
+
+	// set if the stream ended with an error rather than EOF
+	Error string `json:"error,omitempty"`
+
+	// set if the request itself failed
+	StatusCode int    `json:"statusCode,omitempty"`
+	Body       string `json:"body,omitempty"`
+}
+
+// the parts of a request that determine the response—provider auth and routing fields are left out
+type modelFixtureRequest struct {
+	Model           shared.ModelName                     `json:"model"`
+	Messages        []types.ExtendedChatMessage          `json:"messages"`
+	Tools           []openai.Tool                        `json:"tools,omitempty"`
+	ToolChoice      any                                  `json:"toolChoice,omitempty"`
+	Stop            []string                             `json:"stop,omitempty"`
+	Temperature     float32                              `json:"temperature,omitempty"`
+	TopP            float32                              `json:"topP,omitempty"`
+	ResponseFormat  *openai.ChatCompletionResponseFormat `json:"responseFormat,omitempty"`
+	Prediction      *types.OpenAIPrediction              `json:"prediction,omitempty"`
+	ReasoningConfig *types.ReasoningConfig               `json:"reasoning,omitempty"`
+}
+
+type modelFixture struct {
+	Fingerprint string              `json:"fingerprint"`
+	Seq         int                 `json:"seq"`
+	Request     modelFixtureRequest `json:"request"`
+
+	// a request made more than once (e.g. on retry) gets one response per attempt, replayed in order
+	Responses []modelFixtureResponse `json:"responses"`
+}
+
+type modelFixtures struct {
+	config ModelFixtureConfig
+
+	mu            sync.Mutex
+	byFingerprint map[string]*modelFixture
+	recorded      map[string]bool
+	numReplayed   map[string]int
+	nextSeq       int
+}
+
+var (
+	activeModelFixtures   *modelFixtures
+	activeModelFixturesMu sync.RWMutex
+)
+
+func init() {
+	mode := os.Getenv("PLANDEX_MODEL_FIXTURES")
+	if mode == "" {
+		return
+	}
+
+	config := ModelFixtureConfig{
+		Mode:        ModelFixtureMode(mode),
+		Dir:         os.Getenv("PLANDEX_MODEL_FIXTURES_DIR"),
+		TimingScale: 1,
+		Sequential:  os.Getenv("PLANDEX_MODEL_FIXTURES_SEQUENTIAL") != "",
+	}
+
+	if timing := os.Getenv("PLANDEX_MODEL_FIXTURES_TIMING"); timing != "" {
+		scale, err := strconv.ParseFloat(timing, 64)
+		if err != nil {
+			panic(fmt.Sprintf("invalid PLANDEX_MODEL_FIXTURES_TIMING: %v", err))
+		}
+		config.TimingScale = scale
+	}
+
+	err := SetModelFixtures(&config)
+	if err != nil {
+		panic(fmt.Sprintf("error setting up model fixtures: %v", err))
+	}
+}
+
+// SetModelFixtures enables recording or replaying model responses. Pass nil to go back to live requests.
+func SetModelFixtures(config *ModelFixtureConfig) error {
+	activeModelFixturesMu.Lock()
+	defer activeModelFixturesMu.Unlock()
+
+	if config == nil {
+		activeModelFixtures = nil
+		return nil
+	}
+
+	if config.Mode != ModelFixtureModeRecord && config.Mode != ModelFixtureModeReplay {
+		return fmt.Errorf("invalid model fixture mode: %s", config.Mode)
+	}
+	if config.Dir == "" {
+		return fmt.Errorf("model fixtures dir is required")
+	}
+
+	fixtures := &modelFixtures{
+		config:        *config,
+		byFingerprint: map[string]*modelFixture{},
+		recorded:      map[string]bool{},
+		numReplayed:   map[string]int{},
+	}
+
+	err := fixtures.load()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Model fixtures enabled - mode: %s, dir: %s, fixtures loaded: %d", config.Mode, config.Dir, len(fixtures.byFingerprint))
+
+	activeModelFixtures = fixtures
+	return nil
+}
+
+func getModelFixtures() *modelFixtures {
+	activeModelFixturesMu.RLock()
+	defer activeModelFixturesMu.RUnlock()
+	return activeModelFixtures
+}
+
+func (f *modelFixtures) load() error {
+	err := os.MkdirAll(f.config.Dir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("error creating model fixtures dir: %v", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(f.config.Dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("error listing model fixtures: %v", err)
+	}
+
+	for _, path := range paths {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading model fixture %s: %v", path, err)
+		}
+
+		var fixture modelFixture
+		err = json.Unmarshal(bytes, &fixture)
+		if err != nil {
+			return fmt.Errorf("error parsing model fixture %s: %v", path, err)
+		}
+
+		f.byFingerprint[fixture.Fingerprint] = &fixture
+		if fixture.Seq >= f.nextSeq {
+			f.nextSeq = fixture.Seq + 1
+		}
+	}
+
+	return nil
+}
+
+func (f *modelFixtures) createStream(
+	ctx context.Context,
+	req types.ExtendedChatCompletionRequest,
+	createLive func() (*ExtendedChatCompletionStream, error),
+) (*ExtendedChatCompletionStream, error) {
+	fixtureReq := toModelFixtureRequest(req)
+	fingerprint, err := getModelFixtureFingerprint(fixtureReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.config.Mode == ModelFixtureModeReplay {
+		response, err := f.nextResponse(fingerprint, req.Model)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.StatusCode != 0 {
+			return nil, &HTTPError{StatusCode: response.StatusCode, Body: response.Body}
+		}
+
+		return &ExtendedChatCompletionStream{
+			nativeReader: &replayStreamReader{
+				ctx:         ctx,
+				response:    response,
+				timingScale: f.config.TimingScale,
+			},
+			ctx: ctx,
+		}, nil
+	}
+
+	startedAt := time.Now()
+	stream, err := createLive()
+	if err != nil {
+		response := modelFixtureResponse{Error: err.Error()}
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			response.StatusCode = httpErr.StatusCode
+			response.Body = httpErr.Body
+		}
+		f.record(fingerprint, fixtureReq, response)
+		return nil, err
+	}
+
+	return &ExtendedChatCompletionStream{
+		nativeReader: &recordingStreamReader{
+			inner:       stream,
+			fixtures:    f,
+			fingerprint: fingerprint,
+			request:     fixtureReq,
+			lastChunkAt: startedAt,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func (f *modelFixtures) nextResponse(fingerprint string, model shared.ModelName) (*modelFixtureResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fixture, ok := f.byFingerprint[fingerprint]
+	if !ok && f.config.Sequential {
+		fixture = f.nextUnusedFixture()
+		if fixture != nil {
+			log.Printf("Model fixtures - no fixture for request %s, replaying fixture %s in sequence", fingerprint[:12], fixture.Fingerprint[:12])
+		}
+	}
+	if fixture == nil {
+		return nil, fmt.Errorf("no model fixture recorded for request %s (model %s) in %s", fingerprint[:12], model, f.config.Dir)
+	}
+	if len(fixture.Responses) == 0 {
+		return nil, fmt.Errorf("model fixture %s has no responses", fixture.Fingerprint[:12])
+	}
+
+	// once every recorded attempt has been replayed, keep replaying the last one
+	i := f.numReplayed[fixture.Fingerprint]
+	f.numReplayed[fixture.Fingerprint]++
+	if i >= len(fixture.Responses) {
+		i = len(fixture.Responses) - 1
+	}
+
+	return &fixture.Responses[i], nil
+}
+
+func (f *modelFixtures) nextUnusedFixture() *modelFixture {
+	var unused []*modelFixture
+	for fingerprint, fixture := range f.byFingerprint {
+		if f.numReplayed[fingerprint] == 0 {
+			unused = append(unused, fixture)
+		}
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+	sort.Slice(unused, func(i, j int) bool { return unused[i].Seq < unused[j].Seq })
+	return unused[0]
+}
+
+func (f *modelFixtures) record(fingerprint string, req modelFixtureRequest, response modelFixtureResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fixture, ok := f.byFingerprint[fingerprint]
+
+	// the first time a request is recorded in this session replaces any responses from a previous recording
+	if !ok || !f.recorded[fingerprint] {
+		fixture = &modelFixture{
+			Fingerprint: fingerprint,
+			Seq:         f.nextSeq,
+			Request:     req,
+		}
+		f.nextSeq++
+		f.byFingerprint[fingerprint] = fixture
+		f.recorded[fingerprint] = true
+	}
+
+	fixture.Responses = append(fixture.Responses, response)
+
+	bytes, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		log.Printf("Model fixtures - error marshalling fixture %s: %v", fingerprint[:12], err)
+		return
+	}
+
+	path := filepath.Join(f.config.Dir, fingerprint[:24]+".json")
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, bytes, 0644)
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		log.Printf("Model fixtures - error writing fixture %s: %v", path, err)
+	}
+}
+
+func toModelFixtureRequest(req types.ExtendedChatCompletionRequest) modelFixtureRequest {
+	return modelFixtureRequest{
+		Model:           req.Model,
+		Messages:        req.Messages,
+		Tools:           req.Tools,
+		ToolChoice:      req.ToolChoice,
+		Stop:            req.Stop,
+		Temperature:     req.Temperature,
+		TopP:            req.TopP,
+		ResponseFormat:  req.ResponseFormat,
+		Prediction:      req.Prediction,
+		ReasoningConfig: req.ReasoningConfig,
+	}
+}
+
+func getModelFixtureFingerprint(req modelFixtureRequest) (string, error) {
+	bytes, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling request for fingerprint: %v", err)
+	}
+	hash := sha256.Sum256(bytes)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// recordingStreamReader passes chunks through from a live stream while recording them along with the delay since
+// the previous chunk (or since the request started, for the first chunk)
+type recordingStreamReader struct {
+	inner       *ExtendedChatCompletionStream
+	fixtures    *modelFixtures
+	fingerprint string
+	request     modelFixtureRequest
+	response    modelFixtureResponse
+	lastChunkAt time.Time
+	saveOnce    sync.Once
+}
+
+func (r *recordingStreamReader) Recv() (*types.ExtendedChatCompletionStreamResponse, error) {
+	chunk, err := r.inner.Recv()
+	if err != nil {
+		if err != io.EOF {
+			r.response.Error = err.Error()
+		}
+		r.save()
+		return nil, err
+	}
+
+	now := time.Now()
+	r.response.Chunks = append(r.response.Chunks, modelFixtureChunk{
+		DelayMs: now.Sub(r.lastChunkAt).Milliseconds(),
+		Chunk:   chunk,
+	})
+	r.lastChunkAt = now
+
+	return chunk, nil
+}
+
+// the stream is often closed before EOF (once usage arrives or a stop sequence is hit), so save on close too
+func (r *recordingStreamReader) Close() error {
+	r.save()
+	return r.inner.Close()
+}
+
+func (r *recordingStreamReader) save() {
+	r.saveOnce.Do(func() {
+		r.fixtures.record(r.fingerprint, r.request, r.response)
+	})
+}
+
+type replayStreamReader struct {
+	ctx         context.Context
+	response    *modelFixtureResponse
+	timingScale float64
+	i           int
+}
+
+func (r *replayStreamReader) Recv() (*types.ExtendedChatCompletionStreamResponse, error) {
+	if r.i >= len(r.response.Chunks) {
+		if r.response.Error != "" {
+			return nil, errors.New(r.response.Error)
+		}
+		return nil, io.EOF
+	}
+
+	chunk := r.response.Chunks[r.i]
+	r.i++
+
+	delay := time.Duration(float64(chunk.DelayMs)*r.timingScale) * time.Millisecond
+	if delay > 0 {
+		select {
+		case <-r.ctx.Done():
+			return nil, r.ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	// callers may hold on to and modify chunks, so hand out a deep copy rather than the fixture's own (a struct copy
+	// would still share its choices, tool calls, and usage)
+	bytes, err := json.Marshal(chunk.Chunk)
+	if err != nil {
+		return nil, fmt.Errorf("error copying fixture chunk: %v", err)
+	}
+	var res types.ExtendedChatCompletionStreamResponse
+	err = json.Unmarshal(bytes, &res)
+	if err != nil {
+		return nil, fmt.Errorf("error copying fixture chunk: %v", err)
+	}
+	return &res, nil
+}
+
+func (r *replayStreamReader) Close() error {
+	return nil
+}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"plandex-server/types"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestModelFixturesRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	defer SetModelFixtures(nil)
+
+	req := types.ExtendedChatCompletionRequest{
+		Model: "openai/gpt-4.1",
+		Messages: []types.ExtendedChatMessage{
+			{Role: openai.ChatMessageRoleUser, Content: []types.ExtendedChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "hi"},
+			}},
+		},
+	}
+
+	body := strings.Join([]string{
+		`data: {"id":"1","choices":[{"delta":{"content":"Hel"}}]}`,
+		`data: {"id":"1","choices":[{"delta":{"content":"lo"}}]}`,
+		`data: {"id":"1","choices":[{"delta":{},"finish_reason":"stop"}]}`,
+		`data: [DONE]`,
+	}, "\n\n") + "\n"
+
+	createLive := func() (*ExtendedChatCompletionStream, error) {
+		return &ExtendedChatCompletionStream{
+			customReader: &StreamReader[types.ExtendedChatCompletionStreamResponse]{
+				reader:         bufio.NewReader(strings.NewReader(body)),
+				errAccumulator: NewErrorAccumulator(),
+				unmarshaler:    &JSONUnmarshaler{},
+			},
+			ctx: ctx,
+		}, nil
+	}
+
+	err := SetModelFixtures(&ModelFixtureConfig{Mode: ModelFixtureModeRecord, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := readAllContent(t, ctx, req, createLive)
+
+	err = SetModelFixtures(&ModelFixtureConfig{Mode: ModelFixtureModeReplay, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failLive := func() (*ExtendedChatCompletionStream, error) {
+		t.Fatal("live request made during replay")
+		return nil, nil
+	}
+
+	replayed := readAllContent(t, ctx, req, failLive)
+	if replayed != recorded || replayed != "Hello" {
+		t.Errorf("replayed %q, recorded %q", replayed, recorded)
+	}
+
+	// a different request has no fixture
+	other := req
+	other.Model = "openai/gpt-4.1-mini"
+	_, err = getModelFixtures().createStream(ctx, other, failLive)
+	if err == nil {
+		t.Errorf("expected missing fixture error")
+	}
+
+	// unless replaying in sequence
+	err = SetModelFixtures(&ModelFixtureConfig{Mode: ModelFixtureModeReplay, Dir: dir, Sequential: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed = readAllContent(t, ctx, other, failLive)
+	if replayed != "Hello" {
+		t.Errorf("sequential replay got %q", replayed)
+	}
+}
+
+func readAllContent(t *testing.T, ctx context.Context, req types.ExtendedChatCompletionRequest, createLive func() (*ExtendedChatCompletionStream, error)) string {
+	stream, err := getModelFixtures().createStream(ctx, req, createLive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var content string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return content
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chunk.Choices) > 0 {
+			content += chunk.Choices[0].Delta.Content
+		}
+	}
+}
+
+func TestReplayedChunksAreCopies(t *testing.T) {
+	response := &modelFixtureResponse{
+		Chunks: []modelFixtureChunk{
+			{Chunk: &types.ExtendedChatCompletionStreamResponse{
+				ID:      "1",
+				Choices: []types.ExtendedChatCompletionStreamChoice{{Delta: types.ExtendedChatCompletionStreamChoiceDelta{Content: "Hello"}}},
+			}},
+		},
+	}
+
+	reader := &replayStreamReader{ctx: context.Background(), response: response}
+	chunk, err := reader.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk.Choices[0].Delta.Content = "changed"
+
+	if got := response.Chunks[0].Chunk.Choices[0].Delta.Content; got != "Hello" {
+		t.Errorf("modifying a replayed chunk changed the fixture to %q", got)
+	}
+}
@@ -0,0 +1,591 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"plandex-server/types"
+	shared "plandex-shared"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Native client for the Gemini streamGenerateContent api, used for both Google AI Studio (api key auth) and
+// Vertex AI (service account auth). Like the anthropic client, requests and streamed responses are translated
+// to and from the OpenAI format.
+
+const vertexAuthScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// token sources refresh themselves, so keep one per set of credentials rather than fetching a token per request
+var vertexTokenSources sync.Map
+
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileUri  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiPart struct {
+	Text         string              `json:"text,omitempty"`
+	Thought      bool                `json:"thought,omitempty"`
+	InlineData   *geminiInlineData   `json:"inlineData,omitempty"`
+	FileData     *geminiFileData     `json:"fileData,omitempty"`
+	FunctionCall *geminiFunctionCall `json:"functionCall,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     *float32              `json:"temperature,omitempty"`
+	TopP            *float32              `json:"topP,omitempty"`
+	MaxOutputTokens int                   `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string              `json:"stopSequences,omitempty"`
+	ThinkingConfig  *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode                 string   `json:"mode"`
+		AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+	} `json:"functionCallingConfig"`
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+}
+
+type geminiResponse struct {
+	ResponseId   string `json:"responseId"`
+	ModelVersion string `json:"modelVersion"`
+	Candidates   []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *geminiUsageMetadata `json:"usageMetadata,omitempty"`
+	Error         *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error,omitempty"`
+}
+
+// usesGeminiApi is false for claude models on vertex, which aren't served by the gemini api and so keep going
+// through litellm
+func usesGeminiApi(client ClientInfo, modelName shared.ModelName) bool {
+	if client.ProviderConfig.ApiType != shared.ModelProviderApiTypeGemini {
+		return false
+	}
+	return client.ProviderConfig.Provider != shared.ModelProviderGoogleVertex || !strings.Contains(string(modelName), "claude")
+}
+
+func createGeminiChatCompletionStream(
+	ctx context.Context,
+	client ClientInfo,
+	baseModelConfig *shared.BaseModelConfig,
+	extendedReq types.ExtendedChatCompletionRequest,
+) (*ExtendedChatCompletionStream, error) {
+	geminiReq := toGeminiRequest(extendedReq, baseModelConfig)
+
+	jsonBody, err := json.Marshal(geminiReq)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling gemini request: %w", err)
+	}
+
+	url, err := getGeminiStreamUrl(client, extendedReq)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	if client.ProviderConfig.Provider == shared.ModelProviderGoogleVertex {
+		token, err := getVertexAccessToken(extendedReq.VertexCredentials)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if client.ApiKey != "" {
+		req.Header.Set("x-goog-api-key", client.ApiKey)
+	}
+
+	resp, err := httpClient.Do(req) //nolint:bodyclose // body is closed in stream.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading error response: %w", err)
+		}
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			Header:     resp.Header.Clone(),
+		}
+	}
+
+	includeThoughts := extendedReq.ReasoningConfig != nil && !extendedReq.ReasoningConfig.Exclude
+
+	return &ExtendedChatCompletionStream{
+		nativeReader: &geminiStreamReader{
+			reader:          bufio.NewReader(resp.Body),
+			response:        resp,
+			includeThoughts: includeThoughts,
+		},
+		ctx: ctx,
+	}, nil
+}
+
+func getGeminiModelName(modelName shared.ModelName) string {
+	name := string(modelName)
+	for _, prefix := range []string{"gemini/", "vertex_ai/", "google/"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	return name
+}
+
+func getGeminiStreamUrl(client ClientInfo, req types.ExtendedChatCompletionRequest) (string, error) {
+	model := getGeminiModelName(req.Model)
+
+	if client.ProviderConfig.Provider == shared.ModelProviderGoogleVertex {
+		if req.VertexProject == "" || req.VertexLocation == "" {
+			return "", fmt.Errorf("VERTEXAI_PROJECT and VERTEXAI_LOCATION are required for vertex models")
+		}
+		host := req.VertexLocation + "-aiplatform.googleapis.com"
+		if req.VertexLocation == "global" {
+			host = "aiplatform.googleapis.com"
+		}
+		return fmt.Sprintf("https://%s/v1/projects/%s/locations/%s/publishers/google/models/%s:streamGenerateContent?alt=sse", host, req.VertexProject, req.VertexLocation, model), nil
+	}
+
+	return fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", strings.TrimSuffix(client.ProviderConfig.BaseUrl, "/"), model), nil
+}
+
+// credentials are normally passed along by the client as the contents of the json file, but a path is accepted too
+// when the server is configured with the env var directly
+func getVertexAccessToken(credentials string) (string, error) {
+	if credentials == "" {
+		return "", fmt.Errorf("GOOGLE_APPLICATION_CREDENTIALS is required for vertex models")
+	}
+
+	credentialsJson := []byte(credentials)
+	if !strings.HasPrefix(strings.TrimSpace(credentials), "{") {
+		bytes, err := os.ReadFile(credentials)
+		if err != nil {
+			return "", fmt.Errorf("error reading vertex credentials file: %w", err)
+		}
+		credentialsJson = bytes
+	}
+
+	hash := sha256.Sum256(credentialsJson)
+	key := hex.EncodeToString(hash[:])
+
+	var tokenSource oauth2.TokenSource
+	if cached, ok := vertexTokenSources.Load(key); ok {
+		tokenSource = cached.(oauth2.TokenSource)
+	} else {
+		// the token source outlives this request, so it mustn't be tied to the request context
+		creds, err := google.CredentialsFromJSON(context.Background(), credentialsJson, vertexAuthScope)
+		if err != nil {
+			return "", fmt.Errorf("error parsing vertex credentials: %w", err)
+		}
+		tokenSource = oauth2.ReuseTokenSource(nil, creds.TokenSource)
+		vertexTokenSources.Store(key, tokenSource)
+	}
+
+	token, err := tokenSource.Token()
+	if err != nil {
+		return "", fmt.Errorf("error getting vertex access token: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+func toGeminiRequest(req types.ExtendedChatCompletionRequest, baseModelConfig *shared.BaseModelConfig) *geminiRequest {
+	res := &geminiRequest{}
+
+	for _, msg := range req.Messages {
+		parts := toGeminiParts(msg.Content)
+		if len(parts) == 0 {
+			continue
+		}
+
+		if msg.Role == openai.ChatMessageRoleSystem {
+			if res.SystemInstruction == nil {
+				res.SystemInstruction = &geminiContent{}
+			}
+			res.SystemInstruction.Parts = append(res.SystemInstruction.Parts, parts...)
+			continue
+		}
+
+		role := "user"
+		if msg.Role == openai.ChatMessageRoleAssistant {
+			role = "model"
+		}
+
+		if n := len(res.Contents); n > 0 && res.Contents[n-1].Role == role {
+			res.Contents[n-1].Parts = append(res.Contents[n-1].Parts, parts...)
+		} else {
+			res.Contents = append(res.Contents, geminiContent{Role: role, Parts: parts})
+		}
+	}
+
+	config := &geminiGenerationConfig{
+		StopSequences: req.Stop,
+	}
+	if req.Temperature != 0 {
+		temperature := req.Temperature
+		config.Temperature = &temperature
+	}
+	if req.TopP != 0 {
+		topP := req.TopP
+		config.TopP = &topP
+	}
+
+	config.MaxOutputTokens = req.MaxCompletionTokens
+	if config.MaxOutputTokens == 0 {
+		config.MaxOutputTokens = req.MaxTokens
+	}
+	if config.MaxOutputTokens == 0 {
+		config.MaxOutputTokens = baseModelConfig.MaxOutputTokens
+	}
+
+	if req.ReasoningConfig != nil {
+		var budget int
+		if req.ReasoningConfig.MaxTokens > 0 {
+			budget = req.ReasoningConfig.MaxTokens
+		} else if req.ReasoningConfig.Effort != "" {
+			budget = reasoningBudgetByEffort[req.ReasoningConfig.Effort]
+		}
+		if budget > shared.GoogleMaxReasoningBudget {
+			budget = shared.GoogleMaxReasoningBudget
+		}
+
+		thinkingConfig := &geminiThinkingConfig{
+			IncludeThoughts: !req.ReasoningConfig.Exclude,
+		}
+		// without an explicit budget, the model decides how much to think
+		if budget > 0 {
+			thinkingConfig.ThinkingBudget = &budget
+		}
+		config.ThinkingConfig = thinkingConfig
+	}
+
+	res.GenerationConfig = config
+
+	var declarations []geminiFunctionDeclaration
+	for _, tool := range req.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		declarations = append(declarations, geminiFunctionDeclaration{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  tool.Function.Parameters,
+		})
+	}
+	if len(declarations) > 0 {
+		res.Tools = []geminiTool{{FunctionDeclarations: declarations}}
+	}
+	res.ToolConfig = toGeminiToolConfig(req.ToolChoice)
+
+	return res
+}
+
+func toGeminiParts(content []types.ExtendedChatMessagePart) []geminiPart {
+	var parts []geminiPart
+	for _, part := range content {
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			if part.Text == "" {
+				continue
+			}
+			parts = append(parts, geminiPart{Text: part.Text})
+		case openai.ChatMessagePartTypeImageURL:
+			if part.ImageURL == nil {
+				continue
+			}
+			parts = append(parts, toGeminiImagePart(part.ImageURL.URL))
+		}
+	}
+	return parts
+}
+
+func toGeminiImagePart(imageUrl string) geminiPart {
+	if strings.HasPrefix(imageUrl, "data:") {
+		meta, data, found := strings.Cut(strings.TrimPrefix(imageUrl, "data:"), ",")
+		if found {
+			return geminiPart{InlineData: &geminiInlineData{
+				MimeType: strings.TrimSuffix(meta, ";base64"),
+				Data:     data,
+			}}
+		}
+	}
+
+	return geminiPart{FileData: &geminiFileData{
+		MimeType: mime.TypeByExtension(filepath.Ext(imageUrl)),
+		FileUri:  imageUrl,
+	}}
+}
+
+func toGeminiToolConfig(toolChoice any) *geminiToolConfig {
+	var mode string
+	var name string
+
+	switch choice := toolChoice.(type) {
+	case string:
+		switch choice {
+		case "auto":
+			mode = "AUTO"
+		case "required":
+			mode = "ANY"
+		case "none":
+			mode = "NONE"
+		}
+	case *openai.ToolChoice:
+		if choice != nil {
+			mode, name = "ANY", choice.Function.Name
+		}
+	case openai.ToolChoice:
+		mode, name = "ANY", choice.Function.Name
+	}
+
+	if mode == "" {
+		return nil
+	}
+
+	res := &geminiToolConfig{}
+	res.FunctionCallingConfig.Mode = mode
+	if name != "" {
+		res.FunctionCallingConfig.AllowedFunctionNames = []string{name}
+	}
+	return res
+}
+
+// geminiStreamReader translates gemini responses into OpenAI-style chunks. A single response can carry content
+// and a finish reason together, so translated chunks are queued. Gemini sends usage with each response rather than
+// in a final event, so the latest usage is sent as its own chunk once the stream ends.
+type geminiStreamReader struct {
+	reader          *bufio.Reader
+	response        *http.Response
+	includeThoughts bool
+
+	id           string
+	model        string
+	usage        *geminiUsageMetadata
+	numToolCalls int
+	pending      []*types.ExtendedChatCompletionStreamResponse
+	done         bool
+}
+
+func (stream *geminiStreamReader) Recv() (*types.ExtendedChatCompletionStreamResponse, error) {
+	for {
+		if len(stream.pending) > 0 {
+			chunk := stream.pending[0]
+			stream.pending = stream.pending[1:]
+			return chunk, nil
+		}
+
+		if stream.done {
+			return nil, io.EOF
+		}
+
+		line, err := stream.reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if err == io.EOF {
+			stream.done = true
+			// the last line may not end in a newline, so still process it below
+		}
+
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "data:") {
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+			var response geminiResponse
+			err = json.Unmarshal([]byte(data), &response)
+			if err != nil {
+				log.Printf("gemini stream - error unmarshalling response: %v", err)
+			} else if response.Error != nil {
+				return nil, &HTTPError{
+					StatusCode: response.Error.Code,
+					Body:       fmt.Sprintf("%s: %s", response.Error.Status, response.Error.Message),
+				}
+			} else {
+				stream.handleResponse(&response)
+			}
+		}
+
+		if stream.done && stream.usage != nil {
+			stream.pending = append(stream.pending, stream.usageChunk())
+		}
+	}
+}
+
+func (stream *geminiStreamReader) handleResponse(response *geminiResponse) {
+	if response.ResponseId != "" {
+		stream.id = response.ResponseId
+	}
+	if response.ModelVersion != "" {
+		stream.model = response.ModelVersion
+	}
+	if response.UsageMetadata != nil {
+		stream.usage = response.UsageMetadata
+	}
+
+	if len(response.Candidates) == 0 {
+		return
+	}
+	candidate := response.Candidates[0]
+
+	for _, part := range candidate.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			toolIndex := stream.numToolCalls
+			stream.numToolCalls++
+
+			args := string(part.FunctionCall.Args)
+			if args == "" {
+				args = "{}"
+			}
+			stream.pending = append(stream.pending, stream.chunk(types.ExtendedChatCompletionStreamChoiceDelta{
+				ToolCalls: []openai.ToolCall{{
+					Index: &toolIndex,
+					ID:    fmt.Sprintf("call_%d", toolIndex),
+					Type:  openai.ToolTypeFunction,
+					Function: openai.FunctionCall{
+						Name:      part.FunctionCall.Name,
+						Arguments: args,
+					},
+				}},
+			}, ""))
+		case part.Thought:
+			if stream.includeThoughts && part.Text != "" {
+				stream.pending = append(stream.pending, stream.chunk(types.ExtendedChatCompletionStreamChoiceDelta{Reasoning: part.Text}, ""))
+			}
+		case part.Text != "":
+			stream.pending = append(stream.pending, stream.chunk(types.ExtendedChatCompletionStreamChoiceDelta{Content: part.Text}, ""))
+		}
+	}
+
+	if candidate.FinishReason != "" && candidate.FinishReason != "FINISH_REASON_UNSPECIFIED" {
+		finishReason := geminiFinishReason(candidate.FinishReason)
+		if stream.numToolCalls > 0 && finishReason == openai.FinishReasonStop {
+			finishReason = openai.FinishReasonToolCalls
+		}
+		stream.pending = append(stream.pending, stream.chunk(types.ExtendedChatCompletionStreamChoiceDelta{}, finishReason))
+	}
+}
+
+func (stream *geminiStreamReader) usageChunk() *types.ExtendedChatCompletionStreamResponse {
+	// thinking tokens are billed as output
+	completionTokens := stream.usage.CandidatesTokenCount + stream.usage.ThoughtsTokenCount
+
+	return &types.ExtendedChatCompletionStreamResponse{
+		ID:    stream.id,
+		Model: stream.model,
+		Usage: &openai.Usage{
+			PromptTokens:     stream.usage.PromptTokenCount,
+			CompletionTokens: completionTokens,
+			TotalTokens:      stream.usage.PromptTokenCount + completionTokens,
+			PromptTokensDetails: &openai.PromptTokensDetails{
+				CachedTokens: stream.usage.CachedContentTokenCount,
+			},
+			CompletionTokensDetails: &openai.CompletionTokensDetails{
+				ReasoningTokens: stream.usage.ThoughtsTokenCount,
+			},
+		},
+	}
+}
+
+func (stream *geminiStreamReader) chunk(delta types.ExtendedChatCompletionStreamChoiceDelta, finishReason openai.FinishReason) *types.ExtendedChatCompletionStreamResponse {
+	return &types.ExtendedChatCompletionStreamResponse{
+		ID:     stream.id,
+		Object: "chat.completion.chunk",
+		Model:  stream.model,
+		Choices: []types.ExtendedChatCompletionStreamChoice{{
+			Delta:        delta,
+			FinishReason: finishReason,
+		}},
+	}
+}
+
+func (stream *geminiStreamReader) Close() error {
+	if stream.response != nil {
+		return stream.response.Body.Close()
+	}
+	return nil
+}
+
+func geminiFinishReason(finishReason string) openai.FinishReason {
+	switch finishReason {
+	case "STOP":
+		return openai.FinishReasonStop
+	case "MAX_TOKENS":
+		return openai.FinishReasonLength
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return openai.FinishReasonContentFilter
+	default:
+		return openai.FinishReasonStop
+	}
+}
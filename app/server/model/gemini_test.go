@@ -0,0 +1,95 @@
+package model
+
+import (
+	"bufio"
+	"io"
+	"plandex-server/types"
+	shared "plandex-shared"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestToGeminiRequest(t *testing.T) {
+	req := types.ExtendedChatCompletionRequest{
+		Model: "gemini/gemini-2.5-pro",
+		Messages: []types.ExtendedChatMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: []types.ExtendedChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "system"},
+			}},
+			{Role: openai.ChatMessageRoleUser, Content: []types.ExtendedChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "look"},
+				{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "data:image/jpeg;base64,xyz"}},
+			}},
+			{Role: openai.ChatMessageRoleAssistant, Content: []types.ExtendedChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "ok"},
+			}},
+		},
+		ReasoningConfig: &types.ReasoningConfig{MaxTokens: 64000, Exclude: true},
+	}
+
+	res := toGeminiRequest(req, &shared.BaseModelConfig{BaseModelShared: shared.BaseModelShared{MaxOutputTokens: 65535}})
+
+	if res.SystemInstruction == nil || res.SystemInstruction.Parts[0].Text != "system" {
+		t.Errorf("unexpected system instruction: %+v", res.SystemInstruction)
+	}
+	if len(res.Contents) != 2 || res.Contents[1].Role != "model" {
+		t.Fatalf("unexpected contents: %+v", res.Contents)
+	}
+	image := res.Contents[0].Parts[1].InlineData
+	if image == nil || image.MimeType != "image/jpeg" || image.Data != "xyz" {
+		t.Errorf("unexpected image part: %+v", res.Contents[0].Parts[1])
+	}
+	thinking := res.GenerationConfig.ThinkingConfig
+	if thinking == nil || *thinking.ThinkingBudget != shared.GoogleMaxReasoningBudget || thinking.IncludeThoughts {
+		t.Errorf("unexpected thinking config: %+v", thinking)
+	}
+	if res.GenerationConfig.MaxOutputTokens != 65535 {
+		t.Errorf("max output tokens = %d", res.GenerationConfig.MaxOutputTokens)
+	}
+}
+
+func TestGeminiStreamReader(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"responseId":"r1","candidates":[{"content":{"role":"model","parts":[{"text":"thinking...","thought":true}]}}],"usageMetadata":{"promptTokenCount":50}}`,
+		`data: {"responseId":"r1","candidates":[{"content":{"role":"model","parts":[{"text":"Hello"}]}}]}`,
+		`data: {"responseId":"r1","candidates":[{"content":{"role":"model","parts":[{"text":" world"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":50,"candidatesTokenCount":4,"thoughtsTokenCount":10,"cachedContentTokenCount":30}}`,
+	}, "\r\n\r\n")
+
+	stream := &geminiStreamReader{reader: bufio.NewReader(strings.NewReader(body))}
+
+	var content string
+	var finishReason openai.FinishReason
+	var usage *openai.Usage
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+			continue
+		}
+		if chunk.Choices[0].Delta.Reasoning != "" {
+			t.Errorf("thoughts should be excluded")
+		}
+		content += chunk.Choices[0].Delta.Content
+		if chunk.Choices[0].FinishReason != "" {
+			finishReason = chunk.Choices[0].FinishReason
+		}
+	}
+
+	if content != "Hello world" {
+		t.Errorf("content = %q", content)
+	}
+	if finishReason != openai.FinishReasonStop {
+		t.Errorf("finish reason = %q", finishReason)
+	}
+	if usage == nil || usage.PromptTokens != 50 || usage.CompletionTokens != 14 || usage.PromptTokensDetails.CachedTokens != 30 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
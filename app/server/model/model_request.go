@@ -44,7 +44,9 @@ type ModelRequestParams struct {
 	BeforeReq func()
 	AfterReq  func()
 
-	OnStream func(string, string) bool
+	OnStream  func(string, string) bool
+	OnStatus  OnModelStatusFn
+	OnWarning OnModelWarningFn
 
 	WillCacheNumTokens int
 }
@@ -120,6 +122,23 @@ func ModelRequest(
 		return nil, apiErr
 	}
 
+	spendOutputTokens := baseModelConfig.ReservedOutputTokens
+	if params.EstimatedOutputTokens != 0 {
+		spendOutputTokens = params.EstimatedOutputTokens
+	}
+	apiErr = CheckSpendLimits(CheckSpendLimitsParams{
+		OrgId:           currentOrgId,
+		UserId:          currentUserId,
+		Plan:            plan,
+		BaseModelConfig: baseModelConfig,
+		InputTokens:     inputTokensEstimate,
+		OutputTokens:    spendOutputTokens,
+		OnWarning:       params.OnWarning,
+	})
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
 	if params.BeforeReq != nil {
 		params.BeforeReq()
 	}
@@ -170,7 +189,7 @@ func ModelRequest(
 		}
 	}
 
-	res, err := CreateChatCompletionWithInternalStream(clients, authVars, modelConfig, settings, orgUserConfig, currentOrgId, currentUserId, ctx, req, onStream, reqStarted)
+	res, err := CreateChatCompletionWithInternalStream(clients, authVars, modelConfig, settings, orgUserConfig, currentOrgId, currentUserId, ctx, req, onStream, params.OnStatus, reqStarted)
 
 	if err != nil {
 		return nil, err
@@ -215,6 +234,35 @@ func ModelRequest(
 		}
 	}
 
+	didSendParams := &hooks.DidSendModelRequestParams{
+		InputTokens:    inputTokens,
+		OutputTokens:   outputTokens,
+		CachedTokens:   cachedTokens,
+		ModelId:        baseModelConfig.ModelId,
+		ModelTag:       baseModelConfig.ModelTag,
+		ModelName:      baseModelConfig.ModelName,
+		ModelProvider:  baseModelConfig.Provider,
+		ModelPackName:  modelPackName,
+		ModelRole:      modelConfig.Role,
+		Purpose:        purpose,
+		GenerationId:   res.GenerationId,
+		PlanId:         plan.Id,
+		ModelStreamId:  modelStreamId,
+		ConvoMessageId: convoMessageId,
+		BuildId:        buildId,
+
+		RequestStartedAt: reqStarted,
+		Streaming:        true,
+		Req:              &req,
+		StreamResult:     res.Content,
+		ModelConfig:      modelConfig,
+		FirstTokenAt:     res.FirstTokenAt,
+		SessionId:        sessionId,
+		ResponseCacheHit: res.CacheHit,
+	}
+
+	go RecordModelUsage(currentOrgId, currentUserId, baseModelConfig, didSendParams)
+
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -224,33 +272,9 @@ func ModelRequest(
 		}()
 
 		_, apiErr := hooks.ExecHook(hooks.DidSendModelRequest, hooks.HookParams{
-			Auth: auth,
-			Plan: plan,
-			DidSendModelRequestParams: &hooks.DidSendModelRequestParams{
-				InputTokens:    inputTokens,
-				OutputTokens:   outputTokens,
-				CachedTokens:   cachedTokens,
-				ModelId:        baseModelConfig.ModelId,
-				ModelTag:       baseModelConfig.ModelTag,
-				ModelName:      baseModelConfig.ModelName,
-				ModelProvider:  baseModelConfig.Provider,
-				ModelPackName:  modelPackName,
-				ModelRole:      modelConfig.Role,
-				Purpose:        purpose,
-				GenerationId:   res.GenerationId,
-				PlanId:         plan.Id,
-				ModelStreamId:  modelStreamId,
-				ConvoMessageId: convoMessageId,
-				BuildId:        buildId,
-
-				RequestStartedAt: reqStarted,
-				Streaming:        true,
-				Req:              &req,
-				StreamResult:     res.Content,
-				ModelConfig:      modelConfig,
-				FirstTokenAt:     res.FirstTokenAt,
-				SessionId:        sessionId,
-			},
+			Auth:                      auth,
+			Plan:                      plan,
+			DidSendModelRequestParams: didSendParams,
 		})
 
 		if apiErr != nil {
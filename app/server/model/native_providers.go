@@ -0,0 +1,72 @@
+package model
+
+import (
+	"os"
+	shared "plandex-shared"
+	"strings"
+)
+
+// Built-in providers route through the LiteLLM proxy by default. Listing them in PLANDEX_NATIVE_PROVIDERS
+// (comma-separated provider ids, e.g. 'anthropic,google-ai-studio') calls their apis directly with a native client
+// instead. Custom providers opt in with their own 'apiType'.
+
+type nativeProvider struct {
+	apiType shared.ModelProviderApiType
+	baseUrl string
+}
+
+var nativeBuiltInProviders = map[shared.ModelProvider]nativeProvider{
+	shared.ModelProviderAnthropic: {
+		apiType: shared.ModelProviderApiTypeAnthropic,
+		baseUrl: shared.AnthropicV1BaseUrl,
+	},
+	shared.ModelProviderAnthropicClaudeMax: {
+		apiType: shared.ModelProviderApiTypeAnthropic,
+		baseUrl: shared.AnthropicV1BaseUrl,
+	},
+	shared.ModelProviderGoogleAIStudio: {
+		apiType: shared.ModelProviderApiTypeGemini,
+		baseUrl: shared.GeminiV1BetaBaseUrl,
+	},
+	// the vertex url depends on the project and location auth vars, so it's built per request
+	shared.ModelProviderGoogleVertex: {
+		apiType: shared.ModelProviderApiTypeGemini,
+	},
+}
+
+// same budgets litellm uses when translating reasoning effort to a thinking budget
+var reasoningBudgetByEffort = map[shared.ReasoningEffort]int{
+	shared.ReasoningEffortLow:    1024,
+	shared.ReasoningEffortMedium: 2048,
+	shared.ReasoningEffortHigh:   4096,
+}
+
+func resolveNativeProvider(providerConfig shared.ModelProviderConfigSchema) shared.ModelProviderConfigSchema {
+	if providerConfig.ApiType != "" || providerConfig.CustomProvider != nil {
+		return providerConfig
+	}
+
+	native, ok := nativeBuiltInProviders[providerConfig.Provider]
+	if !ok || !nativeProviderEnabled(providerConfig.Provider) {
+		return providerConfig
+	}
+
+	providerConfig.ApiType = native.apiType
+	providerConfig.BaseUrl = native.baseUrl
+	return providerConfig
+}
+
+func nativeProviderEnabled(provider shared.ModelProvider) bool {
+	for _, id := range strings.Split(os.Getenv("PLANDEX_NATIVE_PROVIDERS"), ",") {
+		if strings.TrimSpace(id) == string(provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// LiteLLMDisabled is set for deployments that only use native or OpenAI-compatible providers and so don't need
+// the python proxy running
+func LiteLLMDisabled() bool {
+	return os.Getenv("PLANDEX_DISABLE_LITELLM") != ""
+}
@@ -1,6 +1,7 @@
 package plan
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"plandex-server/db"
@@ -13,6 +14,7 @@ import (
 )
 
 func activatePlan(
+	traceCtx context.Context,
 	clients map[string]model.ClientInfo,
 	plan *db.Plan,
 	branch string,
@@ -47,6 +49,7 @@ func activatePlan(
 	}
 
 	active = CreateActivePlan(
+		traceCtx,
 		auth.OrgId,
 		auth.User.Id,
 		plan.Id,
@@ -61,6 +64,7 @@ func activatePlan(
 		OrgId:      auth.OrgId,
 		PlanId:     plan.Id,
 		InternalIp: host.Ip,
+		InstanceId: &host.InstanceId,
 		Branch:     branch,
 	}
 	err = db.StoreModelStream(modelStream, active.Ctx, active.CancelFn)
@@ -79,5 +83,7 @@ func activatePlan(
 	log.Printf("Tell: Model stream stored with ID %s for plan ID %s on branch %s\n", modelStream.Id, plan.Id, branch) // Log successful storage of model stream
 	log.Println("Model stream id:", modelStream.Id)
 
+	startCheckpoints(active)
+
 	return active, nil
 }
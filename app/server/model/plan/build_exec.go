@@ -1,6 +1,7 @@
 package plan
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,11 +10,14 @@ import (
 	"plandex-server/hooks"
 	"plandex-server/model"
 	"plandex-server/notify"
+	"plandex-server/tracing"
 	"plandex-server/types"
 	"runtime/debug"
 	"time"
 
 	shared "plandex-shared"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type BuildParams struct {
@@ -25,6 +29,9 @@ type BuildParams struct {
 	SessionId     string
 	OrgUserConfig *shared.OrgUserConfig
 	Settings      *shared.PlanSettings
+
+	// the context of the request that started the plan -- only used as the parent of the plan's trace
+	Ctx context.Context
 }
 
 func Build(params BuildParams) (int, error) {
@@ -40,6 +47,12 @@ func Build(params BuildParams) (int, error) {
 	log.Printf("Build: Called with plan ID %s on branch %s\n", plan.Id, branch)
 	log.Println("Build: Starting Build operation")
 
+	traceCtx, span := tracing.Start(params.Ctx, "Build",
+		attribute.String("plan.id", plan.Id),
+		attribute.String("plan.branch", branch),
+	)
+	defer span.End()
+
 	state := activeBuildStreamState{
 		clients:       clients,
 		authVars:      authVars,
@@ -61,11 +74,12 @@ func Build(params BuildParams) (int, error) {
 
 	onErr := func(err error) (int, error) {
 		log.Printf("Build error: %v\n", err)
+		tracing.RecordError(traceCtx, err)
 		streamDone()
 		return 0, err
 	}
 
-	pendingBuildsByPath, err := state.loadPendingBuilds(sessionId)
+	pendingBuildsByPath, err := state.loadPendingBuilds(traceCtx, sessionId)
 	if err != nil {
 		return onErr(err)
 	}
@@ -84,6 +98,7 @@ func Build(params BuildParams) (int, error) {
 	}
 
 	log.Printf("Starting %d builds\n", len(pendingBuildsByPath))
+	span.SetAttributes(attribute.Int("build.num_files", len(pendingBuildsByPath)))
 
 	for _, pendingBuilds := range pendingBuildsByPath {
 		go state.queueBuilds(pendingBuilds)
@@ -171,6 +186,12 @@ func (buildState *activeBuildStreamState) execPlanBuild(activeBuild *types.Activ
 
 	filePath := activeBuild.Path
 
+	traceCtx, span := tracing.Start(activePlan.Ctx, "buildFile",
+		attribute.String("plan.id", planId),
+		attribute.String("build.path", filePath),
+	)
+	defer span.End()
+
 	if !activePlan.IsBuildingByPath[filePath] {
 		UpdateActivePlan(activePlan.Id, activePlan.Branch, func(ap *types.ActivePlan) {
 			ap.IsBuildingByPath[filePath] = true
@@ -181,6 +202,7 @@ func (buildState *activeBuildStreamState) execPlanBuild(activeBuild *types.Activ
 		activeBuildStreamState: buildState,
 		filePath:               filePath,
 		activeBuild:            activeBuild,
+		traceCtx:               traceCtx,
 		builderRun: hooks.DidFinishBuilderRunParams{
 			StartedAt: time.Now(),
 			PlanId:    activePlan.Id,
@@ -322,7 +344,7 @@ func (fileState *activeBuildStreamFileState) buildFile() {
 			PlanBuildId: build.Id,
 			Scope:       db.LockScopeWrite,
 			Reason:      "reset file op",
-			Ctx:         activePlan.Ctx,
+			Ctx:         tracing.ContextWithSpanFrom(activePlan.Ctx, fileState.traceCtx),
 			CancelFn:    activePlan.CancelFn,
 		}, func(repo *db.GitRepo) error {
 			now := time.Now()
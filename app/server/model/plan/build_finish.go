@@ -7,12 +7,17 @@ import (
 	"net/http"
 	"plandex-server/db"
 	"plandex-server/hooks"
+	"plandex-server/metrics"
 	"plandex-server/notify"
+	"plandex-server/tracing"
 	"plandex-server/types"
 	"strings"
 	"time"
 
 	shared "plandex-shared"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func (state *activeBuildStreamFileState) onFinishBuild() {
@@ -199,7 +204,7 @@ func (fileState *activeBuildStreamFileState) onFinishBuildFile(planRes *db.PlanF
 		Branch:      branch,
 		PlanBuildId: build.Id,
 		Scope:       db.LockScopeWrite,
-		Ctx:         activePlan.Ctx,
+		Ctx:         tracing.ContextWithSpanFrom(activePlan.Ctx, fileState.traceCtx),
 		CancelFn:    activePlan.CancelFn,
 		Reason:      "store plan result",
 	}, func(repo *db.GitRepo) error {
@@ -232,6 +237,9 @@ func (fileState *activeBuildStreamFileState) onFinishBuildFile(planRes *db.PlanF
 		Plan:                      fileState.plan,
 		DidFinishBuilderRunParams: &fileState.builderRun,
 	})
+	result := builderRunResult(&fileState.builderRun)
+	metrics.ObserveBuild(result)
+	trace.SpanFromContext(fileState.traceCtx).SetAttributes(attribute.String("build.result", result))
 
 	log.Printf("Finished building file %s - setting activeBuild.Success to true\n", filePath)
 	// log.Println(spew.Sdump(activeBuild))
@@ -292,6 +300,9 @@ func (fileState *activeBuildStreamFileState) onBuildFileError(err error) {
 	activeBuild.Success = false
 	activeBuild.Error = err
 
+	metrics.ObserveBuild(metrics.BuildResultError)
+	tracing.RecordError(fileState.traceCtx, err)
+
 	go notify.NotifyErr(notify.SeverityError, fmt.Errorf("error for file %s: %v", filePath, err))
 
 	activePlan.StreamDoneCh <- &shared.ApiError{
@@ -312,6 +323,22 @@ func (fileState *activeBuildStreamFileState) onBuildFileError(err error) {
 	}
 }
 
+// builderRunResult classifies a successful build by the strategy that produced the final file -- the whole file rebuild is the last resort, so it's checked first
+func builderRunResult(run *hooks.DidFinishBuilderRunParams) string {
+	switch {
+	case run.BuiltWholeFile:
+		return metrics.BuildResultWholeFile
+	case run.ReplacementSuccess:
+		return metrics.BuildResultReplacement
+	case run.FastApplySuccess:
+		return metrics.BuildResultFastApply
+	case run.AutoApplySuccess:
+		return metrics.BuildResultAutoApply
+	default:
+		return metrics.BuildResultOther
+	}
+}
+
 func (fileState *activeBuildStreamFileState) buildNextInQueue() bool {
 	filePath := fileState.filePath
 	activePlan := GetActivePlan(fileState.plan.Id, fileState.branch)
@@ -1,12 +1,14 @@
 package plan
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"plandex-server/db"
 	"plandex-server/notify"
 	"plandex-server/syntax"
+	"plandex-server/tracing"
 	"plandex-server/types"
 	"runtime"
 	"runtime/debug"
@@ -14,13 +16,13 @@ import (
 	shared "plandex-shared"
 )
 
-func (state *activeBuildStreamState) loadPendingBuilds(sessionId string) (map[string][]*types.ActiveBuild, error) {
+func (state *activeBuildStreamState) loadPendingBuilds(traceCtx context.Context, sessionId string) (map[string][]*types.ActiveBuild, error) {
 	clients := state.clients
 	plan := state.plan
 	branch := state.branch
 	auth := state.auth
 
-	active, err := activatePlan(clients, plan, branch, auth, "", true, false, sessionId)
+	active, err := activatePlan(traceCtx, clients, plan, branch, auth, "", true, false, sessionId)
 
 	if err != nil {
 		log.Printf("Error activating plan: %v\n", err)
@@ -40,7 +42,7 @@ func (state *activeBuildStreamState) loadPendingBuilds(sessionId string) (map[st
 		PlanId:   plan.Id,
 		Branch:   branch,
 		Scope:    db.LockScopeRead,
-		Ctx:      active.Ctx,
+		Ctx:      tracing.ContextWithSpanFrom(active.Ctx, traceCtx),
 		CancelFn: active.CancelFn,
 		Reason:   "load pending builds",
 	}, func(repo *db.GitRepo) error {
@@ -153,7 +155,7 @@ func (state *activeBuildStreamState) loadPendingBuilds(sessionId string) (map[st
 	state.settings = settings
 	state.orgUserConfig = orgUserConfig
 
-	return pendingBuildsByPath, nil
+	return withoutDocumentBuilds(pendingBuildsByPath, documentPaths(modelContext)), nil
 }
 
 func (state *activeBuildStreamFileState) loadBuildFile(activeBuild *types.ActiveBuild) error {
@@ -226,7 +228,7 @@ func (state *activeBuildStreamFileState) loadBuildFile(activeBuild *types.Active
 		Branch:      branch,
 		PlanBuildId: build.Id,
 		Scope:       db.LockScopeRead,
-		Ctx:         activePlan.Ctx,
+		Ctx:         tracing.ContextWithSpanFrom(activePlan.Ctx, state.traceCtx),
 		CancelFn:    activePlan.CancelFn,
 		Reason:      "load build file",
 	}, func(repo *db.GitRepo) error {
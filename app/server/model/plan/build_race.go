@@ -6,13 +6,20 @@ import (
 	"fmt"
 	"log"
 	"plandex-server/syntax"
+	"plandex-server/tracing"
 	"plandex-server/utils"
+	"regexp"
 	"runtime"
 	"runtime/debug"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// matches a streaming 'validateChanges' function call once it has reported the changes as incorrect
+var validateChangesIncorrectRegex = regexp.MustCompile(`"correct"\s*:\s*false`)
+
 type raceResult struct {
 	content string
 	valid   bool
@@ -54,6 +61,11 @@ func (fileState *activeBuildStreamFileState) buildRace(
 	log.Printf("buildRace - original file length: %d, updated length: %d", len(originalFile), len(updated))
 	log.Printf("buildRace - has %d syntax errors and %d verify reasons", len(syntaxErrors), len(reasons))
 
+	buildCtx, span := tracing.Start(buildCtx, "buildRace",
+		attribute.Int("build.num_syntax_errors", len(syntaxErrors)),
+		attribute.Int("build.num_verify_reasons", len(reasons)),
+	)
+
 	maxErrs := 3
 
 	resCh := make(chan raceResult, 1)
@@ -217,6 +229,11 @@ func (fileState *activeBuildStreamFileState) buildRace(
 			comments := utils.GetXMLContent(buffer, "PlandexComments")
 
 			startFallbacks(comments)
+		} else if !startedFallbacks && validateChangesIncorrectRegex.MatchString(buffer) {
+			log.Printf("buildRace - detected incorrect function call response, triggering whole file build")
+
+			// comments may not be complete yet, so let the whole file build classify them
+			startFallbacks("")
 		}
 		// keep streaming
 		return false
@@ -275,6 +292,7 @@ func (fileState *activeBuildStreamFileState) buildRace(
 		select {
 		case <-buildCtx.Done():
 			log.Printf("buildRace - context canceled")
+			span.End()
 			return raceResult{}, buildCtx.Err()
 		case err := <-errCh:
 			errChNumReceived++
@@ -286,7 +304,9 @@ func (fileState *activeBuildStreamFileState) buildRace(
 
 			if errChNumReceived >= maxErrs {
 				log.Printf("buildRace - all attempts failed with %d errors", len(errs))
-				return raceResult{}, fmt.Errorf("all build attempts failed: %v", errs)
+				err = fmt.Errorf("all build attempts failed: %v", errs)
+				tracing.End(span, err)
+				return raceResult{}, err
 			}
 
 			if !startedFallbacks {
@@ -295,6 +315,8 @@ func (fileState *activeBuildStreamFileState) buildRace(
 			}
 		case res := <-resCh:
 			log.Printf("buildRace - got successful result")
+			span.SetAttributes(attribute.Bool("build.started_fallbacks", startedFallbacks))
+			span.End()
 			return res, nil
 		}
 	}
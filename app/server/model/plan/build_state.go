@@ -1,6 +1,7 @@
 package plan
 
 import (
+	"context"
 	"plandex-server/db"
 	"plandex-server/hooks"
 	"plandex-server/model"
@@ -45,5 +46,8 @@ type activeBuildStreamFileState struct {
 	isNewFile                  bool
 	contextPart                *db.Context
 
+	// carries the file build's span
+	traceCtx context.Context
+
 	builderRun hooks.DidFinishBuilderRunParams
 }
@@ -8,6 +8,7 @@ import (
 	diff_pkg "plandex-server/diff"
 	"plandex-server/hooks"
 	"plandex-server/syntax"
+	"plandex-server/tracing"
 	"plandex-server/utils"
 	"runtime"
 	"runtime/debug"
@@ -36,7 +37,7 @@ func (fileState *activeBuildStreamFileState) buildStructuredEdits() {
 		return
 	}
 
-	buildCtx, cancelBuild := context.WithCancel(activePlan.Ctx)
+	buildCtx, cancelBuild := context.WithCancel(tracing.ContextWithSpanFrom(activePlan.Ctx, fileState.traceCtx))
 
 	proposedContent := activeBuild.FileContent
 	desc := activeBuild.FileDescription
@@ -2,6 +2,7 @@ package plan
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -171,6 +172,8 @@ type buildValidateParams struct {
 	validateOnly    bool
 	isInitial       bool
 	sessionId       string
+	// use the XML format even if the model prefers function calls, after a function call response couldn't be parsed
+	xmlFallback bool
 }
 
 type buildValidateResult struct {
@@ -215,9 +218,9 @@ func (fileState *activeBuildStreamFileState) buildValidate(
 	maxExpectedOutputTokens := shared.GetNumTokensEstimate(originalFile)/2 + shared.GetNumTokensEstimate(proposedContent)
 
 	// Choose prompt and tools based on preferred format
+	useFunctionCall := baseModelConfig.PreferredOutputFormat != shared.ModelOutputFormatXml && !params.xmlFallback
 
-	log.Printf("Building XML validation replacements prompt")
-	promptText, headNumTokens := prompts.GetValidationReplacementsXmlPrompt(prompts.ValidationPromptParams{
+	promptParams := prompts.ValidationPromptParams{
 		Path:                 filePath,
 		OriginalWithLineNums: originalWithLineNums,
 		Desc:                 desc,
@@ -225,7 +228,39 @@ func (fileState *activeBuildStreamFileState) buildValidate(
 		Diff:                 diff,
 		SyntaxErrors:         syntaxErrors,
 		Reasons:              reasons,
-	})
+		ValidateOnly:         params.validateOnly,
+	}
+
+	var promptText string
+	var headNumTokens int
+	var tools []openai.Tool
+	var toolChoice *openai.ToolChoice
+	var stop []string
+
+	if useFunctionCall {
+		log.Printf("Building JSON validation replacements prompt")
+		promptText, headNumTokens = prompts.GetValidationReplacementsJsonPrompt(promptParams)
+		tools = []openai.Tool{
+			{
+				Type:     "function",
+				Function: &prompts.ValidateChangesFn,
+			},
+		}
+		choice := openai.ToolChoice{
+			Type: "function",
+			Function: openai.ToolFunction{
+				Name: prompts.ValidateChangesFn.Name,
+			},
+		}
+		toolChoice = &choice
+	} else {
+		log.Printf("Building XML validation replacements prompt")
+		promptText, headNumTokens = prompts.GetValidationReplacementsXmlPrompt(promptParams)
+		stop = []string{"<PlandexFinish/>"}
+		if params.validateOnly {
+			stop = []string{"<PlandexComments>", "<PlandexReplacements>"}
+		}
+	}
 
 	// log.Printf("Prompt to LLM: %s", promptText)
 
@@ -251,11 +286,6 @@ func (fileState *activeBuildStreamFileState) buildValidate(
 	}
 	// log.Printf("Messages: %v", messages)
 
-	stop := []string{"<PlandexFinish/>"}
-	if params.validateOnly {
-		stop = []string{"<PlandexComments>", "<PlandexReplacements>"}
-	}
-
 	var willCacheNumTokens int
 	isFirstPass := params.isInitial && params.phase == 1
 	if !isFirstPass && baseModelConfig.Provider == shared.ModelProviderOpenAI {
@@ -274,6 +304,8 @@ func (fileState *activeBuildStreamFileState) buildValidate(
 		ModelConfig:    modelConfig,
 		Purpose:        "File edit",
 		Messages:       messages,
+		Tools:          tools,
+		ToolChoice:     toolChoice,
 		ModelStreamId:  fileState.modelStreamId,
 		ConvoMessageId: fileState.convoMessageId,
 		BuildId:        fileState.build.Id,
@@ -287,7 +319,9 @@ func (fileState *activeBuildStreamFileState) buildValidate(
 			log.Printf("Finished model request")
 			fileState.builderRun.ReplacementFinishedAt = time.Now()
 		},
-		OnStream: onStream,
+		OnStream:  onStream,
+		OnStatus:  streamModelStatus(fileState.plan.Id, fileState.branch),
+		OnWarning: streamWarning(fileState.plan.Id, fileState.branch),
 
 		WillCacheNumTokens:    willCacheNumTokens,
 		SessionId:             params.sessionId,
@@ -312,7 +346,18 @@ func (fileState *activeBuildStreamFileState) buildValidate(
 	log.Printf("Added generation ID: %s", res.GenerationId)
 
 	// Handle response based on format
-	parseRes, err := handleXMLResponse(fileState, res.Content, originalWithLineNums, updated, params.validateOnly)
+	var parseRes buildValidateResult
+	if useFunctionCall {
+		parseRes, err = handleFunctionCallResponse(fileState, res.Content, originalWithLineNums, updated, params.validateOnly)
+		if err != nil {
+			// retry with the XML format, which doesn't depend on the model's function call support
+			log.Printf("Error handling function call response, falling back to XML: %v", err)
+			params.xmlFallback = true
+			return fileState.validationRetryOrError(ctx, params, err)
+		}
+	} else {
+		parseRes, err = handleXMLResponse(fileState, res.Content, originalWithLineNums, updated, params.validateOnly)
+	}
 
 	if err != nil {
 		log.Printf("Error handling response: %v", err)
@@ -350,10 +395,6 @@ func handleXMLResponse(
 		}, nil
 	}
 
-	originalFileLines := strings.Split(string(originalWithLineNums), "\n")
-
-	incremental := originalWithLineNums
-
 	log.Printf("Processing XML replacement blocks")
 
 	replacementsOuter := utils.GetXMLContent(content, "PlandexReplacements")
@@ -362,22 +403,113 @@ func handleXMLResponse(
 		log.Printf("No replacements found in XML response")
 		return buildValidateResult{
 			valid:   false,
-			updated: shared.RemoveLineNums(incremental),
+			updated: shared.RemoveLineNums(originalWithLineNums),
 			problem: "No replacements found in XML response",
 		}, nil
 	}
 
-	replacements := utils.GetAllXMLContent(replacementsOuter, "Replacement")
+	replacements := []prompts.ValidationReplacement{}
+	for _, replacement := range utils.GetAllXMLContent(replacementsOuter, "Replacement") {
+		replacements = append(replacements, prompts.ValidationReplacement{
+			Old: utils.GetXMLContent(replacement, "Old"),
+			New: utils.GetXMLContent(replacement, "New"),
+		})
+	}
+
+	incremental, err := applyValidationReplacements(originalWithLineNums, replacements)
+	if err != nil {
+		return buildValidateResult{valid: false, updated: updated}, err
+	}
+
+	var problem string
+
+	if strings.Contains(content, "<PlandexIncorrect/>") {
+		split := strings.Split(content, "<PlandexIncorrect/>")
+		problem = split[0]
+	} else if strings.Contains(content, "<PlandexReplacements>") {
+		split := strings.Split(content, "<PlandexReplacements>")
+		problem = split[0]
+	}
+
+	final := shared.RemoveLineNums(incremental)
+
+	// log.Printf("Final content:\n\n%s", final)
+
+	return buildValidateResult{valid: false, updated: final, problem: problem}, nil
+}
+
+func handleFunctionCallResponse(
+	fileState *activeBuildStreamFileState,
+	content string,
+	originalWithLineNums shared.LineNumberedTextType,
+	updated string,
+	validateOnly bool,
+) (buildValidateResult, error) {
+	log.Printf("Handling function call response for file: %s", fileState.filePath)
+
+	var res prompts.ValidateChangesRes
+	err := json.Unmarshal([]byte(content), &res)
+	if err != nil {
+		// the model may have answered in the XML format instead of calling the function
+		if strings.Contains(content, "<PlandexCorrect/>") || strings.Contains(content, "<PlandexReplacements>") {
+			log.Printf("Function call response is XML, parsing as XML")
+			return handleXMLResponse(fileState, content, originalWithLineNums, updated, validateOnly)
+		}
+		return buildValidateResult{valid: false, updated: updated}, fmt.Errorf("error unmarshalling validateChanges response: %v", err)
+	}
+
+	if res.Correct {
+		log.Printf("Function call response indicates changes are correct")
+		fileState.builderRun.ReplacementSuccess = true
+		return buildValidateResult{
+			valid:   true,
+			updated: updated,
+		}, nil
+	}
+
+	if validateOnly {
+		log.Printf("Validation-only mode, skipping replacements")
+		return buildValidateResult{
+			valid:   false,
+			updated: updated,
+		}, nil
+	}
+
+	if len(res.Replacements) == 0 {
+		log.Printf("No replacements found in function call response")
+		return buildValidateResult{
+			valid:   false,
+			updated: shared.RemoveLineNums(originalWithLineNums),
+			problem: "No replacements found in function call response",
+		}, nil
+	}
+
+	incremental, err := applyValidationReplacements(originalWithLineNums, res.Replacements)
+	if err != nil {
+		return buildValidateResult{valid: false, updated: updated}, err
+	}
+
+	return buildValidateResult{valid: false, updated: shared.RemoveLineNums(incremental), problem: res.Reasoning}, nil
+}
+
+// applyValidationReplacements applies replacements whose old content is identified by 'pdx-' line numbers to the original file
+func applyValidationReplacements(
+	originalWithLineNums shared.LineNumberedTextType,
+	replacements []prompts.ValidationReplacement,
+) (shared.LineNumberedTextType, error) {
+	originalFileLines := strings.Split(string(originalWithLineNums), "\n")
+
+	incremental := originalWithLineNums
 
 	for i, replacement := range replacements {
 		log.Printf("Processing replacement: %d/%d", i+1, len(replacements))
 
-		old := utils.GetXMLContent(replacement, "Old")
-		new := utils.GetXMLContent(replacement, "New")
+		old := replacement.Old
+		new := replacement.New
 
 		if old == "" {
 			log.Printf("No old content found for replacement")
-			return buildValidateResult{valid: false, updated: updated}, fmt.Errorf("no old content found for replacement")
+			return "", fmt.Errorf("no old content found for replacement")
 		}
 
 		old = strings.TrimSpace(old)
@@ -388,7 +520,7 @@ func handleXMLResponse(
 
 		if !strings.HasPrefix(old, "pdx-") {
 			log.Printf("Old content does not have a line number prefix for first line")
-			return buildValidateResult{valid: false, updated: updated}, fmt.Errorf("old content does not have a line number prefix for first line")
+			return "", fmt.Errorf("old content does not have a line number prefix for first line")
 		}
 
 		oldLines := strings.Split(old, "\n")
@@ -403,27 +535,27 @@ func handleXMLResponse(
 		firstLineNum, err := shared.ExtractLineNumberWithPrefix(firstLine, "pdx-")
 		if err != nil {
 			log.Printf("Error extracting line number from first line: %v", err)
-			return buildValidateResult{valid: false, updated: updated}, fmt.Errorf("error extracting line number from first line: %v", err)
+			return "", fmt.Errorf("error extracting line number from first line: %v", err)
 		}
 
 		if lastLine != "" {
 			lastLineNum, err = shared.ExtractLineNumberWithPrefix(lastLine, "pdx-")
 			if err != nil {
 				log.Printf("Error extracting line number from last line: %v", err)
-				return buildValidateResult{valid: false, updated: updated}, fmt.Errorf("error extracting line number from last line: %v", err)
+				return "", fmt.Errorf("error extracting line number from last line: %v", err)
 			}
 		}
 
 		if lastLineNum == 0 {
 			if !(firstLineNum > 0 && firstLineNum <= len(originalFileLines)) {
 				log.Printf("Invalid line number for first line: %d", firstLineNum)
-				return buildValidateResult{valid: false, updated: updated}, fmt.Errorf("invalid line number for first line: %d", firstLineNum)
+				return "", fmt.Errorf("invalid line number for first line: %d", firstLineNum)
 			}
 			old = originalFileLines[firstLineNum-1]
 		} else {
 			if !(firstLineNum > 0 && firstLineNum <= len(originalFileLines) && lastLineNum > firstLineNum && lastLineNum <= len(originalFileLines)) {
 				log.Printf("Invalid line numbers for first and last lines: %d-%d", firstLineNum, lastLineNum)
-				return buildValidateResult{valid: false, updated: updated}, fmt.Errorf("invalid line numbers: %d-%d", firstLineNum, lastLineNum)
+				return "", fmt.Errorf("invalid line numbers: %d-%d", firstLineNum, lastLineNum)
 			}
 			old = strings.Join(originalFileLines[firstLineNum-1:lastLineNum], "\n")
 		}
@@ -435,21 +567,7 @@ func handleXMLResponse(
 		// log.Printf("Updated content:\n\n%s", string(incremental))
 	}
 
-	var problem string
-
-	if strings.Contains(content, "<PlandexIncorrect/>") {
-		split := strings.Split(content, "<PlandexIncorrect/>")
-		problem = split[0]
-	} else if strings.Contains(content, "<PlandexReplacements>") {
-		split := strings.Split(content, "<PlandexReplacements>")
-		problem = split[0]
-	}
-
-	final := shared.RemoveLineNums(incremental)
-
-	// log.Printf("Final content:\n\n%s", final)
-
-	return buildValidateResult{valid: false, updated: final, problem: problem}, nil
+	return incremental, nil
 }
 
 func (fileState *activeBuildStreamFileState) validationRetryOrError(buildCtx context.Context, validateParams buildValidateParams, err error) (buildValidateResult, error) {
@@ -0,0 +1,81 @@
+package plan
+
+import (
+	shared "plandex-shared"
+	"testing"
+)
+
+const validateTestOriginal = `func main() {
+	fmt.Println("hello")
+}`
+
+func TestHandleFunctionCallResponse(t *testing.T) {
+	originalWithLineNums := shared.AddLineNums(validateTestOriginal)
+
+	t.Run("correct", func(t *testing.T) {
+		fileState := &activeBuildStreamFileState{filePath: "main.go"}
+		res, err := handleFunctionCallResponse(fileState, `{"reasoning": "applied as described", "correct": true}`, originalWithLineNums, "updated", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.valid || res.updated != "updated" {
+			t.Errorf("expected valid result with unchanged content, got %+v", res)
+		}
+		if !fileState.builderRun.ReplacementSuccess {
+			t.Error("expected replacement success to be recorded")
+		}
+	})
+
+	t.Run("replacements", func(t *testing.T) {
+		fileState := &activeBuildStreamFileState{filePath: "main.go"}
+		content := `{"reasoning": "wrong message", "correct": false, "comments": "", "replacements": [{"old": "pdx-2: \tfmt.Println(\"hello\")", "new": "\tfmt.Println(\"goodbye\")"}]}`
+		res, err := handleFunctionCallResponse(fileState, content, originalWithLineNums, "updated", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "func main() {\n\tfmt.Println(\"goodbye\")\n}\n"
+		if res.valid || res.updated != want {
+			t.Errorf("expected replaced content %q, got %+v", want, res)
+		}
+		if res.problem != "wrong message" {
+			t.Errorf("expected reasoning as the problem, got %q", res.problem)
+		}
+	})
+
+	t.Run("invalid line number", func(t *testing.T) {
+		fileState := &activeBuildStreamFileState{filePath: "main.go"}
+		content := `{"reasoning": "", "correct": false, "replacements": [{"old": "pdx-9: x", "new": "y"}]}`
+		_, err := handleFunctionCallResponse(fileState, content, originalWithLineNums, "updated", false)
+		if err == nil {
+			t.Error("expected an error for an out of range line number")
+		}
+	})
+
+	t.Run("xml fallback", func(t *testing.T) {
+		fileState := &activeBuildStreamFileState{filePath: "main.go"}
+		res, err := handleFunctionCallResponse(fileState, "## Evaluate Diff\nLooks right.\n\n<PlandexCorrect/>", originalWithLineNums, "updated", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.valid {
+			t.Errorf("expected XML response to be parsed as valid, got %+v", res)
+		}
+	})
+
+	t.Run("unparseable", func(t *testing.T) {
+		fileState := &activeBuildStreamFileState{filePath: "main.go"}
+		_, err := handleFunctionCallResponse(fileState, "not json", originalWithLineNums, "updated", false)
+		if err == nil {
+			t.Error("expected an error for an unparseable response")
+		}
+	})
+}
+
+func TestValidateChangesIncorrectRegex(t *testing.T) {
+	if !validateChangesIncorrectRegex.MatchString(`{"reasoning": "the indentation is off", "correct" : false, "comm`) {
+		t.Error("expected partial incorrect response to match")
+	}
+	if validateChangesIncorrectRegex.MatchString(`{"reasoning": "looks right", "correct": true}`) {
+		t.Error("expected correct response not to match")
+	}
+}
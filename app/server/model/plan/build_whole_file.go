@@ -105,6 +105,8 @@ func (fileState *activeBuildStreamFileState) buildWholeFileFallback(buildCtx con
 		AfterReq: func() {
 			fileState.builderRun.BuildWholeFileFinishedAt = time.Now()
 		},
+		OnStatus:  streamModelStatus(fileState.plan.Id, fileState.branch),
+		OnWarning: streamWarning(fileState.plan.Id, fileState.branch),
 
 		WillCacheNumTokens:    willCacheNumTokens,
 		EstimatedOutputTokens: maxExpectedOutputTokens,
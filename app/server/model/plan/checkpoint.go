@@ -0,0 +1,266 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"plandex-server/db"
+	"plandex-server/host"
+	"plandex-server/notify"
+	"plandex-server/shutdown"
+	"plandex-server/types"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	shared "plandex-shared"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Active plans are checkpointed to the db while they run. If the instance running a plan crashes or restarts, another instance (or the same one on startup) stores the partial reply from the checkpoint and marks the plan interrupted, so it can be continued with Resume instead of the user re-running the whole tell.
+
+const checkpointRecoveryInterval = 30 * time.Second
+
+var checkpointers = types.NewSafeMap[*checkpointer]()
+
+// checkpointer calls save on an interval until it's stopped
+type checkpointer struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newCheckpointer(ctx context.Context, interval time.Duration, save func()) *checkpointer {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &checkpointer{cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(c.done)
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic in checkpointer: %v\n%s", r, debug.Stack())
+				go notify.NotifyErr(notify.SeverityError, fmt.Errorf("panic in checkpointer: %v\n%s", r, debug.Stack()))
+			}
+		}()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		save()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// stop may have been called while waiting on the last save
+				if ctx.Err() != nil {
+					return
+				}
+				save()
+			}
+		}
+	}()
+
+	return c
+}
+
+// stop cancels the checkpointer and waits for a save that's in progress to finish, so nothing is saved once it returns
+func (c *checkpointer) stop() {
+	c.cancel()
+	<-c.done
+}
+
+func startCheckpoints(active *types.ActivePlan) {
+	planId := active.Id
+	branch := active.Branch
+
+	c := newCheckpointer(active.Ctx, db.PlanCheckpointInterval, func() {
+		saveCheckpoint(planId, branch)
+	})
+	checkpointers.Set(strings.Join([]string{planId, branch}, "|"), c)
+}
+
+// stopCheckpoints stops an active plan's checkpointer before its checkpoint is deleted -- otherwise a save that's already running could write the checkpoint back after it's deleted, and recovery would later treat the finished plan as interrupted
+func stopCheckpoints(planId, branch string) {
+	key := strings.Join([]string{planId, branch}, "|")
+	c := checkpointers.Get(key)
+	if c == nil {
+		return
+	}
+	checkpointers.Delete(key)
+	c.stop()
+}
+
+func saveCheckpoint(planId, branch string) {
+	var checkpoint *db.PlanCheckpoint
+
+	UpdateActivePlan(planId, branch, func(ap *types.ActivePlan) {
+		checkpoint = newPlanCheckpoint(ap)
+	})
+
+	if checkpoint == nil {
+		return
+	}
+
+	err := db.UpsertPlanCheckpoint(checkpoint)
+	if err != nil {
+		log.Printf("Error saving checkpoint for plan %s on branch %s: %v\n", planId, branch, err)
+	}
+}
+
+func newPlanCheckpoint(ap *types.ActivePlan) *db.PlanCheckpoint {
+	checkpoint := &db.PlanCheckpoint{
+		PlanId:          ap.Id,
+		Branch:          ap.Branch,
+		OrgId:           ap.OrgId,
+		UserId:          ap.UserId,
+		InstanceId:      host.InstanceId,
+		BuildOnly:       ap.BuildOnly,
+		Iteration:       ap.Iteration,
+		CurrentReply:    ap.CurrentReplyContent,
+		NumTokens:       ap.NumTokens,
+		MessageNum:      ap.MessageNum,
+		RepliesFinished: ap.RepliesFinished,
+	}
+
+	if ap.SessionId != "" {
+		sessionId := ap.SessionId
+		checkpoint.SessionId = &sessionId
+	}
+
+	if ap.TellReq != nil {
+		// credentials are sent again by the client on resume rather than stored
+		req := db.StoredTellRequest(*ap.TellReq)
+		req.ApiKeys = nil
+		req.OpenAIOrgId = ""
+		req.AuthVars = nil
+		checkpoint.TellRequest = &req
+	}
+
+	for path := range ap.BuildQueuesByPath {
+		if ap.IsBuildingByPath[path] || !ap.PathQueueEmpty(path) {
+			checkpoint.PendingBuildPaths = append(checkpoint.PendingBuildPaths, path)
+		}
+	}
+
+	return checkpoint
+}
+
+func StartCheckpointRecovery() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic in checkpoint recovery: %v\n%s", r, debug.Stack())
+				go notify.NotifyErr(notify.SeverityError, fmt.Errorf("panic in checkpoint recovery: %v\n%s", r, debug.Stack()))
+			}
+		}()
+
+		ticker := time.NewTicker(checkpointRecoveryInterval)
+		defer ticker.Stop()
+
+		for {
+			recoverInterruptedPlans()
+
+			select {
+			case <-shutdown.ShutdownCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func recoverInterruptedPlans() {
+	checkpoints, err := db.GetOrphanedPlanCheckpoints()
+	if err != nil {
+		log.Printf("Error getting orphaned plan checkpoints: %v\n", err)
+		return
+	}
+
+	for _, checkpoint := range checkpoints {
+		err := recoverInterruptedPlan(checkpoint)
+		if err != nil {
+			log.Printf("Error recovering interrupted plan %s on branch %s: %v\n", checkpoint.PlanId, checkpoint.Branch, err)
+			go notify.NotifyErr(notify.SeverityError, fmt.Errorf("error recovering interrupted plan %s on branch %s: %v", checkpoint.PlanId, checkpoint.Branch, err))
+		}
+	}
+}
+
+func recoverInterruptedPlan(checkpoint *db.PlanCheckpoint) error {
+	planId := checkpoint.PlanId
+	branch := checkpoint.Branch
+
+	dbBranch, err := db.GetDbBranch(planId, branch)
+	if err != nil {
+		return err
+	}
+	if dbBranch == nil || !checkpointIsInterrupted(dbBranch.Status) {
+		log.Printf("Deleting stale checkpoint for plan %s on branch %s\n", planId, branch)
+		return db.DeletePlanCheckpoint(planId, branch)
+	}
+
+	// another instance may be recovering the same plan
+	claimed, err := db.ClaimInterruptedPlanCheckpoint(planId, branch)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	log.Printf("Recovering interrupted plan %s on branch %s (iteration %d, %d pending builds)\n", planId, branch, checkpoint.Iteration, len(checkpoint.PendingBuildPaths))
+
+	ctx, cancel := context.WithTimeout(shutdown.ShutdownCtx, 30*time.Second)
+	defer cancel()
+
+	err = db.ExecRepoOperation(db.ExecRepoOperationParams{
+		OrgId:    checkpoint.OrgId,
+		UserId:   checkpoint.UserId,
+		PlanId:   planId,
+		Branch:   branch,
+		Reason:   "recover interrupted plan",
+		Scope:    db.LockScopeWrite,
+		Ctx:      ctx,
+		CancelFn: cancel,
+	}, func(repo *db.GitRepo) error {
+		// anything the interrupted stream didn't commit is incomplete
+		err := repo.GitClearUncommittedChanges(branch)
+		if err != nil {
+			return fmt.Errorf("error clearing uncommitted changes: %v", err)
+		}
+
+		if checkpoint.BuildOnly || checkpoint.RepliesFinished || checkpoint.CurrentReply == "" {
+			return nil
+		}
+
+		msg := db.ConvoMessage{
+			OrgId:   checkpoint.OrgId,
+			PlanId:  planId,
+			UserId:  checkpoint.UserId,
+			Role:    openai.ChatMessageRoleAssistant,
+			Tokens:  checkpoint.NumTokens,
+			Num:     checkpoint.MessageNum + 1,
+			Stopped: true,
+			Message: checkpoint.CurrentReply,
+		}
+
+		_, err = db.StoreConvoMessage(repo, &msg, checkpoint.UserId, branch, true)
+		if err != nil {
+			return fmt.Errorf("error storing partial reply: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return db.SetPlanStatus(planId, branch, shared.PlanStatusInterrupted, "Interrupted by a server restart")
+}
+
+// checkpointIsInterrupted is false for an orphaned checkpoint whose plan finished or was stopped, since the plan's own stream set that status before deleting it -- the checkpoint is left over, and recovering it would clear the plan's changes
+func checkpointIsInterrupted(status shared.PlanStatus) bool {
+	return status != shared.PlanStatusFinished && status != shared.PlanStatusStopped
+}
@@ -0,0 +1,172 @@
+package plan
+
+import (
+	"context"
+	"plandex-server/shutdown"
+	"plandex-server/types"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	shared "plandex-shared"
+)
+
+func TestNewPlanCheckpoint(t *testing.T) {
+	ap := &types.ActivePlan{
+		Id:                  "plan",
+		Branch:              "main",
+		OrgId:               "org",
+		UserId:              "user",
+		Iteration:           2,
+		CurrentReplyContent: "partial reply",
+		SessionId:           "session",
+		TellReq: &shared.TellPlanRequest{
+			Prompt:   "do the thing",
+			ApiKeys:  map[string]string{"OPENAI_API_KEY": "secret"},
+			AuthVars: map[string]string{"OPENAI_API_KEY": "secret"},
+		},
+		BuildQueuesByPath: map[string][]*types.ActiveBuild{
+			"done.go":     {{Path: "done.go", Success: true}},
+			"pending.go":  {{Path: "pending.go"}},
+			"building.go": {{Path: "building.go", Success: true}},
+		},
+		IsBuildingByPath: map[string]bool{"building.go": true},
+	}
+
+	checkpoint := newPlanCheckpoint(ap)
+
+	if checkpoint.PlanId != "plan" || checkpoint.Branch != "main" || checkpoint.Iteration != 2 || checkpoint.CurrentReply != "partial reply" {
+		t.Errorf("unexpected checkpoint %+v", checkpoint)
+	}
+	if checkpoint.SessionId == nil || *checkpoint.SessionId != "session" {
+		t.Errorf("expected the session id to be saved")
+	}
+	if checkpoint.TellRequest == nil || checkpoint.TellRequest.Prompt != "do the thing" {
+		t.Fatalf("expected the tell request to be saved")
+	}
+	if checkpoint.TellRequest.ApiKeys != nil || checkpoint.TellRequest.AuthVars != nil {
+		t.Error("credentials shouldn't be saved in a checkpoint")
+	}
+	if ap.TellReq.AuthVars == nil {
+		t.Error("the active plan's tell request shouldn't be modified")
+	}
+
+	pending := []string(checkpoint.PendingBuildPaths)
+	sort.Strings(pending)
+	if strings.Join(pending, ",") != "building.go,pending.go" {
+		t.Errorf("expected building and queued paths to be pending, got %v", pending)
+	}
+}
+
+func TestCheckpointerStopWaitsForSave(t *testing.T) {
+	var numSaves atomic.Int32
+	saving := make(chan struct{}, 1)
+	release := make(chan struct{})
+
+	c := newCheckpointer(context.Background(), 5*time.Millisecond, func() {
+		// the second save is slow, like an upsert to a busy db
+		if numSaves.Add(1) == 2 {
+			saving <- struct{}{}
+			<-release
+		}
+	})
+
+	select {
+	case <-saving:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a save")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		c.stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("stop returned while a save was still running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop didn't return after the save finished")
+	}
+
+	n := numSaves.Load()
+	time.Sleep(30 * time.Millisecond)
+	if numSaves.Load() != n {
+		t.Error("saved after the checkpointer was stopped")
+	}
+}
+
+func TestCheckpointerStopsWithPlan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := newCheckpointer(ctx, time.Hour, func() {})
+
+	cancel()
+
+	select {
+	case <-c.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("checkpointer kept running after the plan's context was canceled")
+	}
+
+	// stopping one that already stopped doesn't block
+	c.stop()
+}
+
+func TestStopCheckpoints(t *testing.T) {
+	if shutdown.ShutdownCtx == nil {
+		shutdown.ShutdownCtx, shutdown.ShutdownCancel = context.WithCancel(context.Background())
+	}
+
+	// the plan isn't registered as active, so saves don't reach the db
+	active := types.NewActivePlan(context.Background(), "org", "user", "checkpoint-test-plan", "main", "", false, false, "")
+	defer active.CancelFn()
+
+	startCheckpoints(active)
+
+	key := "checkpoint-test-plan|main"
+	c := checkpointers.Get(key)
+	if c == nil {
+		t.Fatal("expected a checkpointer to be registered")
+	}
+
+	stopCheckpoints("checkpoint-test-plan", "main")
+
+	if checkpointers.Get(key) != nil {
+		t.Error("expected the checkpointer to be removed")
+	}
+	select {
+	case <-c.done:
+	default:
+		t.Error("expected the checkpointer to have stopped before stopCheckpoints returned")
+	}
+
+	// nothing to stop
+	stopCheckpoints("checkpoint-test-plan", "main")
+}
+
+func TestCheckpointIsInterrupted(t *testing.T) {
+	for status, want := range map[shared.PlanStatus]bool{
+		shared.PlanStatusReplying:    true,
+		shared.PlanStatusDescribing:  true,
+		shared.PlanStatusBuilding:    true,
+		shared.PlanStatusMissingFile: true,
+		// set by the heartbeat check when the instance running the plan is gone
+		shared.PlanStatusError:    true,
+		shared.PlanStatusFinished: false,
+		shared.PlanStatusStopped:  false,
+	} {
+		if got := checkpointIsInterrupted(status); got != want {
+			t.Errorf("checkpointIsInterrupted(%s) = %t, want %t", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package plan
+
+import (
+	"log"
+	"plandex-server/db"
+	"plandex-server/types"
+
+	shared "plandex-shared"
+)
+
+// Documents (PDFs, Word docs, Excel spreadsheets) are in context as their extracted text, so they're read-only. Building a change to one would replace the original file with that text when the plan is applied.
+
+func documentPaths(contexts []*db.Context) map[string]bool {
+	res := map[string]bool{}
+	for _, context := range contexts {
+		if context.ContextType == shared.ContextDocumentType && context.FilePath != "" {
+			res[context.FilePath] = true
+		}
+	}
+	return res
+}
+
+func isDocumentOp(op *shared.Operation, documentPaths map[string]bool) bool {
+	return documentPaths[op.Path] || (op.Destination != "" && documentPaths[op.Destination])
+}
+
+func withoutDocumentBuilds(pendingBuildsByPath map[string][]*types.ActiveBuild, documentPaths map[string]bool) map[string][]*types.ActiveBuild {
+	if len(documentPaths) == 0 {
+		return pendingBuildsByPath
+	}
+
+	res := make(map[string][]*types.ActiveBuild, len(pendingBuildsByPath))
+	for path, builds := range pendingBuildsByPath {
+		var kept []*types.ActiveBuild
+		for _, build := range builds {
+			if documentPaths[build.Path] || (build.MoveDestination != "" && documentPaths[build.MoveDestination]) {
+				log.Printf("Skipping pending build for document %s -- documents are read-only\n", build.Path)
+				continue
+			}
+			kept = append(kept, build)
+		}
+		if len(kept) > 0 {
+			res[path] = kept
+		}
+	}
+	return res
+}
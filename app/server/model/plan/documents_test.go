@@ -0,0 +1,92 @@
+package plan
+
+import (
+	"plandex-server/db"
+	"plandex-server/types"
+	shared "plandex-shared"
+	"testing"
+)
+
+func documentTestContext() []*db.Context {
+	return []*db.Context{
+		{ContextType: shared.ContextDocumentType, FilePath: "docs/report.pdf", Name: "docs/report.pdf"},
+		{ContextType: shared.ContextFileType, FilePath: "main.go", Name: "main.go"},
+	}
+}
+
+func TestDocumentOperationsAreNotBuilt(t *testing.T) {
+	state := &activeTellStreamState{
+		plan:           &db.Plan{Id: "plan"},
+		branch:         "main",
+		req:            &shared.TellPlanRequest{BuildMode: shared.BuildModeNone},
+		modelContext:   documentTestContext(),
+		chunkProcessor: &chunkProcessor{},
+	}
+
+	operations := []*shared.Operation{
+		{Type: shared.OperationTypeFile, Path: "docs/report.pdf", Content: "# Report"},
+		{Type: shared.OperationTypeFile, Path: "main.go", Content: "package main"},
+		{Type: shared.OperationTypeMove, Path: "notes.md", Destination: "docs/report.pdf"},
+		{Type: shared.OperationTypeRemove, Path: "docs/report.pdf"},
+	}
+
+	// operations are parsed incrementally, so later chunks include earlier operations again
+	state.handleNewOperations(&types.ReplyParserRes{Operations: operations[:2]})
+	state.handleNewOperations(&types.ReplyParserRes{Operations: operations})
+
+	processor := state.chunkProcessor
+	if processor.numOperationsSeen != len(operations) {
+		t.Errorf("expected %d operations seen, got %d", len(operations), processor.numOperationsSeen)
+	}
+	if len(processor.replyOperations) != 1 || processor.replyOperations[0].Path != "main.go" {
+		var paths []string
+		for _, op := range processor.replyOperations {
+			paths = append(paths, op.Name())
+		}
+		t.Errorf("expected only main.go to become a build target, got %v", paths)
+	}
+}
+
+func TestPendingDocumentBuildsAreSkipped(t *testing.T) {
+	pending := map[string][]*types.ActiveBuild{
+		"docs/report.pdf": {{Path: "docs/report.pdf", FileContent: "# Report"}},
+		"main.go":         {{Path: "main.go", FileContent: "package main"}},
+		"notes.md":        {{Path: "notes.md", IsMoveOp: true, MoveDestination: "docs/report.pdf"}},
+	}
+
+	res := withoutDocumentBuilds(pending, documentPaths(documentTestContext()))
+
+	if len(res) != 1 || len(res["main.go"]) != 1 {
+		t.Errorf("expected only the main.go build to remain, got %v", res)
+	}
+}
+
+// csv, tsv and notebook files are loaded as plain files rather than documents, so plans can still edit them
+func TestLoadedCsvFilesCanBeEdited(t *testing.T) {
+	contexts := append(documentTestContext(), &db.Context{ContextType: shared.ContextFileType, FilePath: "data.csv", Name: "data.csv", Body: "id,name\n1,a\n"})
+
+	state := &activeTellStreamState{
+		plan:           &db.Plan{Id: "plan"},
+		branch:         "main",
+		req:            &shared.TellPlanRequest{BuildMode: shared.BuildModeNone},
+		modelContext:   contexts,
+		chunkProcessor: &chunkProcessor{},
+	}
+
+	state.handleNewOperations(&types.ReplyParserRes{Operations: []*shared.Operation{
+		{Type: shared.OperationTypeFile, Path: "data.csv", Content: "id,name,email\n1,a,a@example.com\n"},
+	}})
+
+	processor := state.chunkProcessor
+	if len(processor.replyOperations) != 1 || processor.replyOperations[0].Path != "data.csv" {
+		t.Errorf("expected data.csv to become a build target, got %v", processor.replyOperations)
+	}
+
+	pending := map[string][]*types.ActiveBuild{
+		"data.csv": {{Path: "data.csv", FileContent: "id,name,email\n1,a,a@example.com\n"}},
+	}
+	res := withoutDocumentBuilds(pending, documentPaths(contexts))
+	if len(res["data.csv"]) != 1 {
+		t.Errorf("expected the data.csv build to be kept, got %v", res)
+	}
+}
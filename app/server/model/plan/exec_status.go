@@ -145,6 +145,24 @@ func (state *activeTellStreamState) execStatusShouldContinue(currentMessage stri
 		},
 	}
 
+	var tools []openai.Tool
+	var toolChoice *openai.ToolChoice
+	if baseModelConfig.PreferredOutputFormat != shared.ModelOutputFormatXml {
+		tools = []openai.Tool{
+			{
+				Type:     "function",
+				Function: &prompts.DidFinishSubtaskFn,
+			},
+		}
+		choice := openai.ToolChoice{
+			Type: "function",
+			Function: openai.ToolFunction{
+				Name: prompts.DidFinishSubtaskFn.Name,
+			},
+		}
+		toolChoice = &choice
+	}
+
 	modelRes, err := model.ModelRequest(ctx, model.ModelRequestParams{
 		Clients:        clients,
 		Auth:           auth,
@@ -153,11 +171,15 @@ func (state *activeTellStreamState) execStatusShouldContinue(currentMessage stri
 		ModelConfig:    &config,
 		Purpose:        "Task completion check",
 		Messages:       messages,
+		Tools:          tools,
+		ToolChoice:     toolChoice,
 		ModelStreamId:  state.modelStreamId,
 		ConvoMessageId: state.replyId,
 		SessionId:      sessionId,
 		Settings:       settings,
 		OrgUserConfig:  orgUserConfig,
+		OnStatus:       streamModelStatus(plan.Id, state.branch),
+		OnWarning:      streamWarning(plan.Id, state.branch),
 	})
 
 	if err != nil {
@@ -193,8 +215,15 @@ func (state *activeTellStreamState) execStatusShouldContinue(currentMessage stri
 
 		var res types.ExecStatusResponse
 		if err := json.Unmarshal([]byte(content), &res); err != nil {
-			log.Printf("[ExecStatus] Failed to parse response: %v", err)
-			return execStatusShouldContinueResult{}, nil
+			// fall back to the XML format in case the model answered in text rather than with the function call
+			subtaskFinishedStr := utils.GetXMLContent(content, "subtaskFinished")
+			if subtaskFinishedStr == "" {
+				log.Printf("[ExecStatus] Failed to parse response: %v", err)
+				return execStatusShouldContinueResult{}, nil
+			}
+			log.Printf("[ExecStatus] Parsed XML fallback from non-JSON response")
+			res.Reasoning = utils.GetXMLContent(content, "reasoning")
+			res.SubtaskFinished = subtaskFinishedStr == "true"
 		}
 
 		reasoning = res.Reasoning
@@ -0,0 +1,170 @@
+package plan
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"plandex-server/mockmodel"
+	"plandex-server/model"
+	"plandex-server/model/prompts"
+	"plandex-server/types"
+	shared "plandex-shared"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const fixtureTestScenarios = `
+chunkSize: 7
+scenarios:
+  - name: builder
+    match:
+      system: "validate"
+    toolCall:
+      name: validateChanges
+      arguments: '{"reasoning": "wrong message", "correct": false, "comments": "", "replacements": [{"old": "pdx-2: \tfmt.Println(\"hello\")", "new": "\tfmt.Println(\"goodbye\")"}]}'
+  - name: planner
+    match:
+      contains: "say goodbye"
+    response: |
+      I'll update the message.
+
+      - file: main.go
+
+      <PlandexBlock lang="go" path="main.go">
+      func main() {
+      	fmt.Println("goodbye")
+      }
+      </PlandexBlock>
+`
+
+type fixtureTestRun struct {
+	operations []string
+	validated  buildValidateResult
+}
+
+// Records a streamed planner-style reply and a builder-style validation tool call from the mock model server, then
+// replays both with the server shut down and checks the parsed results match. The requests are built here rather
+// than by plan.Tell or plan.Build (which need a db), so this covers fixture record/replay and parsing of replayed
+// output, not the pipeline's prompt assembly.
+func TestModelFixturesRecordAndReplayModelCalls(t *testing.T) {
+	scenarios, err := mockmodel.ParseScenarios([]byte(fixtureTestScenarios))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(mockmodel.NewServer(scenarios).Handler())
+	defer srv.Close()
+
+	settings := &shared.PlanSettings{}
+	settings.Configure(nil, []*shared.CustomModel{
+		{
+			ModelId:         "mock-model",
+			BaseModelShared: shared.BaseModelShared{MaxTokens: 100000, MaxOutputTokens: 8000, ReservedOutputTokens: 8000},
+			Providers: []shared.BaseModelUsesProvider{
+				{Provider: shared.ModelProviderCustom, CustomProvider: stringPtr("mock"), ModelName: "mock-model"},
+			},
+		},
+	}, []*shared.CustomProvider{
+		{Name: "mock", BaseUrl: srv.URL + "/v1", SkipAuth: true},
+	}, false)
+
+	authVars := map[string]string{}
+	clients := model.InitClients(authVars, settings, nil)
+
+	dir := t.TempDir()
+	defer model.SetModelFixtures(nil)
+
+	err = model.SetModelFixtures(&model.ModelFixtureConfig{Mode: model.ModelFixtureModeRecord, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := runFixtureTestModelCalls(t, clients, authVars, settings)
+
+	srv.Close()
+
+	err = model.SetModelFixtures(&model.ModelFixtureConfig{Mode: model.ModelFixtureModeReplay, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := runFixtureTestModelCalls(t, clients, authVars, settings)
+
+	if len(recorded.operations) != 1 || recorded.operations[0] != "main.go" {
+		t.Errorf("expected the planner to update main.go, got %v", recorded.operations)
+	}
+	want := "func main() {\n\tfmt.Println(\"goodbye\")\n}\n"
+	if recorded.validated.updated != want {
+		t.Errorf("expected the builder's replacement %q, got %q", want, recorded.validated.updated)
+	}
+
+	if len(replayed.operations) != len(recorded.operations) || replayed.operations[0] != recorded.operations[0] {
+		t.Errorf("replayed operations %v, recorded %v", replayed.operations, recorded.operations)
+	}
+	if replayed.validated != recorded.validated {
+		t.Errorf("replayed build %+v, recorded %+v", replayed.validated, recorded.validated)
+	}
+}
+
+func runFixtureTestModelCalls(t *testing.T, clients map[string]model.ClientInfo, authVars map[string]string, settings *shared.PlanSettings) fixtureTestRun {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var res fixtureTestRun
+
+	plannerConfig := &shared.ModelRoleConfig{Role: shared.ModelRolePlanner, ModelId: "mock-model"}
+	stream, err := model.CreateChatCompletionStream(clients, authVars, plannerConfig, settings, nil, "org", "user", ctx, types.ExtendedChatCompletionRequest{
+		Stream: true,
+		Messages: []types.ExtendedChatMessage{
+			{Role: openai.ChatMessageRoleUser, Content: []types.ExtendedChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "say goodbye instead of hello"},
+			}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := types.NewReplyParser()
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chunk.Choices) > 0 {
+			parser.AddChunk(chunk.Choices[0].Delta.Content, true)
+		}
+	}
+	stream.Close()
+
+	for _, op := range parser.FinishAndRead().Operations {
+		res.operations = append(res.operations, op.Path)
+	}
+
+	builderConfig := &shared.ModelRoleConfig{Role: shared.ModelRoleBuilder, ModelId: "mock-model"}
+	modelRes, err := model.CreateChatCompletionWithInternalStream(clients, authVars, builderConfig, settings, nil, "org", "user", ctx, types.ExtendedChatCompletionRequest{
+		Messages: []types.ExtendedChatMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: []types.ExtendedChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "validate the changes to main.go"},
+			}},
+		},
+		Tools: []openai.Tool{{Type: "function", Function: &prompts.ValidateChangesFn}},
+	}, nil, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileState := &activeBuildStreamFileState{filePath: "main.go"}
+	res.validated, err = handleFunctionCallResponse(fileState, modelRes.Content, shared.AddLineNums(validateTestOriginal), "updated", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
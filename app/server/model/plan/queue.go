@@ -0,0 +1,305 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"plandex-server/db"
+	"plandex-server/host"
+	"plandex-server/model"
+	"plandex-server/notify"
+	"plandex-server/shutdown"
+	"plandex-server/types"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	shared "plandex-shared"
+)
+
+// Prompts can be queued on a plan branch to run one after another in the background. A queued prompt's request is stored in the db without credentials -- the clients and credentials it runs with stay in memory on the instance that accepted it, which starts it once the branch is idle and its last stream finished. If a stream errors or is stopped, the queue waits until the next stream on the branch finishes. If the instance holding a prompt goes away, the prompt is marked interrupted and skipped, then deleted once the retention period passes.
+
+// gives a finished stream time to be marked finished before the next prompt starts
+const queuedPromptStartDelay = 2 * time.Second
+
+type queuedPromptRun struct {
+	planId    string
+	branch    string
+	clients   map[string]model.ClientInfo
+	authVars  map[string]string
+	auth      *types.ServerAuth
+	createdAt time.Time
+}
+
+var (
+	queuedPromptRuns types.SafeMap[*queuedPromptRun] = *types.NewSafeMap[*queuedPromptRun]()
+	dispatchMu       sync.Mutex
+)
+
+type EnqueuePromptParams struct {
+	Clients  map[string]model.ClientInfo
+	AuthVars map[string]string
+	Plan     *db.Plan
+	Branch   string
+	Auth     *types.ServerAuth
+	Req      *shared.TellPlanRequest
+}
+
+// EnqueuePrompt adds a prompt to the end of a branch's queue, starting it right away if the branch is idle. Returns the queued prompt and its position in the queue.
+func EnqueuePrompt(params EnqueuePromptParams) (*db.QueuedPrompt, int, error) {
+	plan := params.Plan
+	branch := params.Branch
+	auth := params.Auth
+
+	storedReq := newStoredTellRequest(params.Req)
+
+	queued := &db.QueuedPrompt{
+		OrgId:       auth.OrgId,
+		PlanId:      plan.Id,
+		Branch:      branch,
+		UserId:      auth.User.Id,
+		InstanceId:  host.InstanceId,
+		TellRequest: &storedReq,
+	}
+
+	err := db.EnqueuePrompt(queued)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	queuedPromptRuns.Set(queued.Id, &queuedPromptRun{
+		planId:    plan.Id,
+		branch:    branch,
+		clients:   params.Clients,
+		authVars:  params.AuthVars,
+		auth:      auth,
+		createdAt: time.Now(),
+	})
+
+	prompts, err := db.ListQueuedPrompts(plan.Id, branch)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	position := queuedPromptPosition(prompts, queued.Id)
+
+	log.Printf("Queued prompt %s for plan %s on branch %s at position %d\n", queued.Id, plan.Id, branch, position)
+
+	go DispatchQueuedPrompt(plan.Id, branch)
+
+	return queued, position, nil
+}
+
+// newStoredTellRequest strips credentials from a queued prompt's request. Everything else -- including auto-context, exec, and auto-continue settings -- is kept so the prompt runs the way it was sent.
+func newStoredTellRequest(req *shared.TellPlanRequest) db.StoredTellRequest {
+	stored := *req
+	stored.ApiKeys = nil
+	stored.OpenAIOrgId = ""
+	stored.AuthVars = nil
+	stored.ConnectStream = false
+	return db.StoredTellRequest(stored)
+}
+
+// queuedPromptPosition returns a prompt's 1-based position among the prompts in a queue that can still run
+func queuedPromptPosition(prompts []*db.QueuedPrompt, id string) int {
+	position := 0
+	for _, p := range prompts {
+		if p.InterruptedAt != nil {
+			continue
+		}
+		position++
+		if p.Id == id {
+			break
+		}
+	}
+	return position
+}
+
+func RemoveQueuedPrompt(planId, branch, id string) (bool, error) {
+	removed, err := db.DeleteQueuedPrompt(planId, branch, id)
+	if err != nil {
+		return false, err
+	}
+
+	// prompts held by other instances are dropped from memory on their next heartbeat
+	queuedPromptRuns.Delete(id)
+
+	return removed, nil
+}
+
+// DispatchQueuedPrompt starts the next prompt in a branch's queue if this instance holds it and the branch is ready for it
+func DispatchQueuedPrompt(planId, branch string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic dispatching queued prompt: %v\n%s", r, debug.Stack())
+			go notify.NotifyErr(notify.SeverityError, fmt.Errorf("panic dispatching queued prompt: %v\n%s", r, debug.Stack()))
+		}
+	}()
+
+	dispatchMu.Lock()
+	defer dispatchMu.Unlock()
+
+	if GetActivePlan(planId, branch) != nil {
+		return
+	}
+
+	next, err := db.GetNextQueuedPrompt(planId, branch)
+	if err != nil {
+		log.Printf("Error getting next queued prompt for plan %s: %v\n", planId, err)
+		return
+	}
+
+	if next == nil {
+		return
+	}
+
+	run := queuedPromptRuns.Get(next.Id)
+	if run == nil {
+		// held by another instance
+		return
+	}
+
+	dbBranch, err := db.GetDbBranch(planId, branch)
+	if err != nil {
+		log.Printf("Error getting branch for plan %s: %v\n", planId, err)
+		return
+	}
+
+	if dbBranch == nil {
+		log.Printf("Branch %s not found for plan %s -- dropping queued prompt %s\n", branch, planId, next.Id)
+		queuedPromptRuns.Delete(next.Id)
+		return
+	}
+
+	if dbBranch.Status != shared.PlanStatusFinished && dbBranch.Status != shared.PlanStatusDraft {
+		log.Printf("Queued prompt %s for plan %s on branch %s waiting -- branch status is %s\n", next.Id, planId, branch, dbBranch.Status)
+		return
+	}
+
+	modelStream, err := db.GetActiveModelStream(planId, branch)
+	if err != nil {
+		log.Printf("Error getting active model stream for plan %s: %v\n", planId, err)
+		return
+	}
+
+	if modelStream != nil {
+		return
+	}
+
+	claimed, err := db.DeleteQueuedPrompt(planId, branch, next.Id)
+	if err != nil {
+		log.Printf("Error claiming queued prompt %s: %v\n", next.Id, err)
+		return
+	}
+
+	queuedPromptRuns.Delete(next.Id)
+
+	if !claimed {
+		return
+	}
+
+	plan, err := db.GetPlan(planId)
+	if err != nil {
+		log.Printf("Error getting plan %s for queued prompt: %v\n", planId, err)
+		return
+	}
+
+	req := shared.TellPlanRequest(*next.TellRequest)
+	req.AuthVars = run.authVars
+
+	log.Printf("Starting queued prompt %s for plan %s on branch %s\n", next.Id, planId, branch)
+
+	err = Tell(TellParams{
+		Clients:  run.clients,
+		AuthVars: run.authVars,
+		Plan:     plan,
+		Branch:   branch,
+		Auth:     run.auth,
+		Req:      &req,
+		Ctx:      context.Background(),
+	})
+
+	if err != nil {
+		log.Printf("Error starting queued prompt %s: %v\n", next.Id, err)
+		go notify.NotifyErr(notify.SeverityError, fmt.Errorf("error starting queued prompt %s: %v", next.Id, err))
+
+		err = db.SetPlanStatus(planId, branch, shared.PlanStatusError, "Error starting queued prompt: "+err.Error())
+		if err != nil {
+			log.Printf("Error setting plan %s status to error: %v\n", planId, err)
+		}
+	}
+}
+
+func dispatchQueuedPromptAfterFinish(planId, branch string) {
+	time.Sleep(queuedPromptStartDelay)
+	DispatchQueuedPrompt(planId, branch)
+}
+
+// StartQueuedPromptDispatch keeps the prompts this instance holds live, marks prompts held by instances that are gone as interrupted, and starts queued prompts whose branch became idle on another instance
+func StartQueuedPromptDispatch() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic in queued prompt dispatch: %v\n%s", r, debug.Stack())
+				go notify.NotifyErr(notify.SeverityError, fmt.Errorf("panic in queued prompt dispatch: %v\n%s", r, debug.Stack()))
+			}
+		}()
+
+		ticker := time.NewTicker(db.QueuedPromptHeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-shutdown.ShutdownCtx.Done():
+				return
+			case <-ticker.C:
+				dispatchQueuedPrompts()
+			}
+		}
+	}()
+}
+
+func dispatchQueuedPrompts() {
+	ids, err := db.TouchQueuedPrompts(host.InstanceId)
+	if err != nil {
+		log.Printf("Error touching queued prompts: %v\n", err)
+		return
+	}
+
+	live := map[string]bool{}
+	for _, id := range ids {
+		live[id] = true
+	}
+
+	branches := map[string]bool{}
+	for id, run := range queuedPromptRuns.Items() {
+		// drop prompts that were removed or started elsewhere -- skipping any enqueued since the heartbeat query
+		if !live[id] {
+			if time.Since(run.createdAt) > db.QueuedPromptHeartbeatInterval {
+				queuedPromptRuns.Delete(id)
+			}
+			continue
+		}
+		branches[strings.Join([]string{run.planId, run.branch}, "|")] = true
+	}
+
+	n, err := db.InterruptOrphanedQueuedPrompts()
+	if err != nil {
+		log.Printf("Error interrupting orphaned queued prompts: %v\n", err)
+	} else if n > 0 {
+		log.Printf("Marked %d orphaned queued prompts as interrupted\n", n)
+	}
+
+	n, err = db.DeleteInterruptedQueuedPrompts()
+	if err != nil {
+		log.Printf("Error deleting interrupted queued prompts: %v\n", err)
+	} else if n > 0 {
+		log.Printf("Deleted %d interrupted queued prompts\n", n)
+	}
+
+	for key := range branches {
+		parts := strings.Split(key, "|")
+		DispatchQueuedPrompt(parts[0], parts[1])
+	}
+}
@@ -0,0 +1,58 @@
+package plan
+
+import (
+	"plandex-server/db"
+	"testing"
+	"time"
+
+	shared "plandex-shared"
+)
+
+func TestNewStoredTellRequest(t *testing.T) {
+	req := &shared.TellPlanRequest{
+		Prompt:        "do the thing",
+		BuildMode:     shared.BuildModeAuto,
+		ConnectStream: true,
+		AutoContinue:  true,
+		AutoContext:   true,
+		SmartContext:  true,
+		ExecEnabled:   true,
+		OsDetails:     "linux",
+		ApiKeys:       map[string]string{"OPENAI_API_KEY": "secret"},
+		OpenAIOrgId:   "org",
+		AuthVars:      map[string]string{"OPENAI_API_KEY": "secret"},
+	}
+
+	stored := newStoredTellRequest(req)
+
+	if stored.ApiKeys != nil || stored.OpenAIOrgId != "" || stored.AuthVars != nil {
+		t.Errorf("expected credentials to be stripped, got %+v", stored)
+	}
+	if stored.ConnectStream {
+		t.Error("expected a queued prompt's stream not to be connected")
+	}
+	if stored.Prompt != "do the thing" || stored.BuildMode != shared.BuildModeAuto || !stored.AutoContinue || !stored.AutoContext || !stored.SmartContext || !stored.ExecEnabled || stored.OsDetails != "linux" {
+		t.Errorf("expected the prompt's settings to be kept, got %+v", stored)
+	}
+
+	if req.ApiKeys == nil || req.AuthVars == nil || !req.ConnectStream {
+		t.Error("expected the original request to be left unchanged")
+	}
+}
+
+func TestQueuedPromptPosition(t *testing.T) {
+	interruptedAt := time.Now()
+	prompts := []*db.QueuedPrompt{
+		{Id: "interrupted", InterruptedAt: &interruptedAt},
+		{Id: "first"},
+		{Id: "interrupted-2", InterruptedAt: &interruptedAt},
+		{Id: "second"},
+	}
+
+	if pos := queuedPromptPosition(prompts, "first"); pos != 1 {
+		t.Errorf("expected position 1, got %d", pos)
+	}
+	if pos := queuedPromptPosition(prompts, "second"); pos != 2 {
+		t.Errorf("expected interrupted prompts to be skipped, got position %d", pos)
+	}
+}
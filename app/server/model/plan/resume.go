@@ -0,0 +1,112 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"plandex-server/db"
+	"plandex-server/model"
+	"plandex-server/types"
+
+	shared "plandex-shared"
+)
+
+type ResumeParams struct {
+	Clients       map[string]model.ClientInfo
+	AuthVars      map[string]string
+	Plan          *db.Plan
+	Branch        string
+	Auth          *types.ServerAuth
+	SessionId     string
+	OrgUserConfig *shared.OrgUserConfig
+	Settings      *shared.PlanSettings
+
+	// the context of the request that started the plan -- only used as the parent of the plan's trace
+	Ctx context.Context
+}
+
+// Resume continues a plan that was interrupted by a server restart from its last checkpoint -- auto-continuing the reply if the plan was still replying, then building anything pending. Returns false if there's nothing to resume.
+func Resume(params ResumeParams) (bool, error) {
+	plan := params.Plan
+	branch := params.Branch
+
+	checkpoint, err := db.GetPlanCheckpoint(plan.Id, branch)
+	if err != nil {
+		return false, err
+	}
+
+	if checkpoint == nil || checkpoint.InterruptedAt == nil {
+		log.Printf("Resume: no interrupted checkpoint for plan %s on branch %s\n", plan.Id, branch)
+		return false, nil
+	}
+
+	sessionId := params.SessionId
+	if sessionId == "" && checkpoint.SessionId != nil {
+		sessionId = *checkpoint.SessionId
+	}
+
+	if checkpoint.BuildOnly || checkpoint.TellRequest == nil || checkpoint.RepliesFinished {
+		log.Printf("Resume: building pending changes for plan %s on branch %s\n", plan.Id, branch)
+
+		numBuilds, err := Build(BuildParams{
+			Clients:       params.Clients,
+			AuthVars:      params.AuthVars,
+			Plan:          plan,
+			Branch:        branch,
+			Auth:          params.Auth,
+			SessionId:     sessionId,
+			OrgUserConfig: params.OrgUserConfig,
+			Settings:      params.Settings,
+			Ctx:           params.Ctx,
+		})
+		if err != nil {
+			return false, fmt.Errorf("error building plan: %v", err)
+		}
+
+		if numBuilds == 0 {
+			err = db.DeletePlanCheckpoint(plan.Id, branch)
+			if err != nil {
+				log.Printf("Error deleting checkpoint for plan %s: %v\n", plan.Id, err)
+			}
+			return false, nil
+		}
+
+		return true, nil
+	}
+
+	req := shared.TellPlanRequest(*checkpoint.TellRequest)
+	req.AuthVars = params.AuthVars
+	req.SessionId = sessionId
+	// the prompt was already stored before the interruption, so pick up from the last message like 'plandex continue' -- continuing the partial reply, or replying to the prompt if the interruption came first
+	req.IsUserContinue = true
+
+	log.Printf("Resume: continuing plan %s on branch %s from iteration %d\n", plan.Id, branch, checkpoint.Iteration)
+
+	_, err = activatePlan(
+		params.Ctx,
+		params.Clients,
+		plan,
+		branch,
+		params.Auth,
+		req.Prompt,
+		false,
+		req.AutoContext,
+		sessionId,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error activating plan: %v", err)
+	}
+
+	go execTellPlan(execTellPlanParams{
+		clients:            params.Clients,
+		plan:               plan,
+		branch:             branch,
+		auth:               params.Auth,
+		req:                &req,
+		iteration:          checkpoint.Iteration,
+		shouldBuildPending: !req.IsChatOnly && req.BuildMode == shared.BuildModeAuto,
+		authVars:           params.AuthVars,
+	})
+
+	return true, nil
+}
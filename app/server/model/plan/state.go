@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"log"
 	"plandex-server/db"
+	"plandex-server/metrics"
 	"plandex-server/notify"
 	"plandex-server/shutdown"
+	"plandex-server/tracing"
 	"plandex-server/types"
 	"strings"
 	"time"
 
 	shared "plandex-shared"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
@@ -22,8 +26,15 @@ func GetActivePlan(planId, branch string) *types.ActivePlan {
 	return activePlans.Get(strings.Join([]string{planId, branch}, "|"))
 }
 
-func CreateActivePlan(orgId, userId, planId, branch, prompt string, buildOnly, autoContext bool, sessionId string) *types.ActivePlan {
-	activePlan := types.NewActivePlan(orgId, userId, planId, branch, prompt, buildOnly, autoContext, sessionId)
+// CreateActivePlan starts a plan's stream. traceCtx is the context of whatever started it -- usually a request -- and is only used as the parent of the stream's span.
+func CreateActivePlan(traceCtx context.Context, orgId, userId, planId, branch, prompt string, buildOnly, autoContext bool, sessionId string) *types.ActivePlan {
+	traceCtx, span := tracing.Start(traceCtx, "activePlan",
+		attribute.String("plan.id", planId),
+		attribute.String("plan.branch", branch),
+		attribute.Bool("plan.build_only", buildOnly),
+	)
+
+	activePlan := types.NewActivePlan(traceCtx, orgId, userId, planId, branch, prompt, buildOnly, autoContext, sessionId)
 	key := strings.Join([]string{planId, branch}, "|")
 
 	activePlans.Set(key, activePlan)
@@ -34,6 +45,10 @@ func CreateActivePlan(orgId, userId, planId, branch, prompt string, buildOnly, a
 			case <-activePlan.Ctx.Done():
 				log.Printf("case <-activePlan.Ctx.Done(): %s\n", planId)
 
+				metrics.ObserveStream(metrics.StreamOutcomeStopped, time.Since(activePlan.StartedAt))
+				span.SetAttributes(attribute.String("plan.outcome", metrics.StreamOutcomeStopped))
+				span.End()
+
 				err := db.SetPlanStatus(planId, branch, shared.PlanStatusStopped, "")
 				if err != nil {
 					log.Printf("Error setting plan %s status to stopped: %v\n", planId, err)
@@ -49,6 +64,10 @@ func CreateActivePlan(orgId, userId, planId, branch, prompt string, buildOnly, a
 				if apiErr == nil {
 					log.Printf("Plan %s stream completed successfully", planId)
 
+					metrics.ObserveStream(metrics.StreamOutcomeFinished, time.Since(activePlan.StartedAt))
+					span.SetAttributes(attribute.String("plan.outcome", metrics.StreamOutcomeFinished))
+					span.End()
+
 					err := db.SetPlanStatus(planId, branch, shared.PlanStatusFinished, "")
 					if err != nil {
 						log.Printf("Error setting plan %s status to ready: %v\n", planId, err)
@@ -58,10 +77,16 @@ func CreateActivePlan(orgId, userId, planId, branch, prompt string, buildOnly, a
 					// allows queued operations to complete
 					DeleteActivePlan(orgId, userId, planId, branch)
 					activePlan.CancelFn()
+
+					go dispatchQueuedPromptAfterFinish(planId, branch)
 					return
 				} else {
 					log.Printf("Error streaming plan %s: %v\n", planId, apiErr)
 
+					metrics.ObserveStream(metrics.StreamOutcomeError, time.Since(activePlan.StartedAt))
+					span.SetAttributes(attribute.String("plan.outcome", metrics.StreamOutcomeError))
+					tracing.End(span, apiErr)
+
 					go notify.NotifyErr(notify.SeverityError, fmt.Errorf("error streaming plan %s: %v", planId, apiErr))
 
 					err := db.SetPlanStatus(planId, branch, shared.PlanStatusError, apiErr.Msg)
@@ -131,8 +156,19 @@ func DeleteActivePlan(orgId, userId, planId, branch string) {
 		log.Printf("Error clearing uncommitted changes for plan %s: %v\n", planId, err)
 	}
 
+	// the checkpointer is stopped first so it can't save the checkpoint again after it's deleted
+	stopCheckpoints(planId, branch)
+
 	activePlans.Delete(strings.Join([]string{planId, branch}, "|"))
 
+	// a plan that's still running when the server shuts down keeps its checkpoint so it can be resumed
+	if shutdown.ShutdownCtx.Err() == nil {
+		err = db.DeletePlanCheckpoint(planId, branch)
+		if err != nil {
+			log.Printf("Error deleting checkpoint for plan %s: %v\n", planId, err)
+		}
+	}
+
 	log.Printf("Deleted active plan %s - %s - %s\n", planId, branch, orgId)
 }
 
@@ -157,6 +193,24 @@ func SubscribePlan(ctx context.Context, planId, branch string) (string, chan str
 	return id, ch
 }
 
+func SubscribePlanFrom(ctx context.Context, planId, branch string, lastEventId int64) (string, chan string, bool) {
+	log.Printf("Subscribing to plan %s from event %d\n", planId, lastEventId)
+	var id string
+	var ch chan string
+	var replayed bool
+
+	activePlan := GetActivePlan(planId, branch)
+	if activePlan == nil {
+		log.Printf("SubscribePlanFrom - No active plan found for plan ID %s on branch %s\n", planId, branch)
+		return "", nil, false
+	}
+
+	UpdateActivePlan(planId, branch, func(activePlan *types.ActivePlan) {
+		id, ch, replayed = activePlan.SubscribeFrom(ctx, lastEventId)
+	})
+	return id, ch, replayed
+}
+
 func UnsubscribePlan(planId, branch, subscriptionId string) {
 	log.Printf("UnsubscribePlan %s - %s - %s\n", planId, branch, subscriptionId)
 
@@ -24,6 +24,20 @@ type formatModelContextParams struct {
 	activatePaths        map[string]bool
 	activatePathsOrdered []string
 	maxTokens            int
+	contextBudget        int
+}
+
+type contextToLoad struct {
+	FilePath    string
+	Name        string
+	Url         string
+	NumTokens   int
+	Body        string
+	ContextType shared.ContextType
+	ImageDetail openai.ImageURLDetail
+	IsPending   bool
+	Pinned      bool
+	IsReduced   bool // full file swapped for its map to fit the context budget
 }
 
 func (state *activeTellStreamState) formatModelContext(params formatModelContextParams) []*types.ExtendedChatMessagePart {
@@ -46,8 +60,8 @@ func (state *activeTellStreamState) formatModelContext(params formatModelContext
 	maxTokens := params.maxTokens
 
 	// log all the flags
-	log.Printf("Tell plan - formatModelContext - basicOnly: %t, activeOnly: %t, autoOnly: %t, smartContextEnabled: %t, execEnabled: %t, includeMaps: %t, activatePaths: %v, activatePathsOrdered: %v, maxTokens: %d\n",
-		basicOnly, activeOnly, autoOnly, smartContextEnabled, includeApplyScript, includeMaps, activatePaths, activatePathsOrdered, params.maxTokens)
+	log.Printf("Tell plan - formatModelContext - basicOnly: %t, activeOnly: %t, autoOnly: %t, smartContextEnabled: %t, execEnabled: %t, includeMaps: %t, activatePaths: %v, activatePathsOrdered: %v, maxTokens: %d, contextBudget: %d\n",
+		basicOnly, activeOnly, autoOnly, smartContextEnabled, includeApplyScript, includeMaps, activatePaths, activatePathsOrdered, params.maxTokens, params.contextBudget)
 
 	var contextBodies []string = []string{
 		"### LATEST PLAN CONTEXT ###",
@@ -77,17 +91,7 @@ func (state *activeTellStreamState) formatModelContext(params formatModelContext
 
 	totalTokens := 0
 
-	type toLoad struct {
-		FilePath    string
-		Name        string
-		Url         string
-		NumTokens   int
-		Body        string
-		ContextType shared.ContextType
-		ImageDetail openai.ImageURLDetail
-		IsPending   bool
-	}
-	var toLoadAll []toLoad
+	var toLoadAll []contextToLoad
 
 	for _, part := range state.modelContext {
 		if verboseLogging {
@@ -109,9 +113,9 @@ func (state *activeTellStreamState) formatModelContext(params formatModelContext
 			}
 		}
 
-		if currentStage.TellStage == shared.TellStageImplementation && smartContextEnabled && state.currentSubtask != nil && part.ContextType == shared.ContextFileType && !uses[part.FilePath] {
+		if currentStage.TellStage == shared.TellStageImplementation && smartContextEnabled && state.currentSubtask != nil && part.ContextType == shared.ContextFileType && !uses[part.FilePath] && !part.Pinned {
 			if verboseLogging {
-				log.Println("Tell plan - formatModelContext - skipping part -- currentStage.TellStage == shared.TellStageImplementation && smartContextEnabled && state.currentSubtask != nil && part.ContextType == shared.ContextFileType && !uses[part.FilePath]")
+				log.Println("Tell plan - formatModelContext - skipping part -- currentStage.TellStage == shared.TellStageImplementation && smartContextEnabled && state.currentSubtask != nil && part.ContextType == shared.ContextFileType && !uses[part.FilePath] && !part.Pinned")
 			}
 			continue
 		}
@@ -130,7 +134,7 @@ func (state *activeTellStreamState) formatModelContext(params formatModelContext
 			continue
 		}
 
-		toLoadAll = append(toLoadAll, toLoad{
+		toLoadAll = append(toLoadAll, contextToLoad{
 			FilePath:    part.FilePath,
 			NumTokens:   part.NumTokens,
 			Body:        part.Body,
@@ -138,6 +142,7 @@ func (state *activeTellStreamState) formatModelContext(params formatModelContext
 			Name:        part.Name,
 			Url:         part.Url,
 			ImageDetail: part.ImageDetail,
+			Pinned:      part.Pinned,
 		})
 
 		if part.ContextType == shared.ContextFileType {
@@ -170,7 +175,7 @@ func (state *activeTellStreamState) formatModelContext(params formatModelContext
 
 			numTokens := shared.GetNumTokensEstimate(body)
 
-			toLoadAll = append(toLoadAll, toLoad{
+			toLoadAll = append(toLoadAll, contextToLoad{
 				FilePath:    filePath,
 				NumTokens:   numTokens,
 				Body:        body,
@@ -215,6 +220,14 @@ func (state *activeTellStreamState) formatModelContext(params formatModelContext
 		})
 	}
 
+	toLoadAll = state.reduceContextToBudget(reduceContextToBudgetParams{
+		toLoad:        toLoadAll,
+		budget:        params.contextBudget,
+		uses:          uses,
+		activatePaths: activatePaths,
+		pendingFiles:  pendingFiles,
+	})
+
 	for _, part := range toLoadAll {
 		totalTokens += part.NumTokens
 
@@ -232,6 +245,9 @@ func (state *activeTellStreamState) formatModelContext(params formatModelContext
 		if part.ContextType == shared.ContextDirectoryTreeType {
 			fmtStr = "\n\n- %s | directory tree:\n\n```\n%s\n```"
 			args = append(args, part.FilePath, part.Body)
+		} else if part.IsReduced {
+			fmtStr = "\n\n- %s | map (full file omitted to fit context budget):\n\n```\n%s\n```"
+			args = append(args, part.FilePath, part.Body)
 		} else if part.ContextType == shared.ContextFileType {
 			// if we're in the context phase and the file is pending, just include that the file is pending, not the full content
 			// there is generally enough related context from the conversation and summary to decide on whether to load the file or not
@@ -262,6 +278,9 @@ func (state *activeTellStreamState) formatModelContext(params formatModelContext
 		} else if part.ContextType == shared.ContextMapType {
 			fmtStr = "\n\n- %s | map:\n\n```\n%s\n```"
 			args = append(args, part.FilePath, part.Body)
+		} else if part.ContextType == shared.ContextDocumentType {
+			fmtStr = "\n\n- %s | document (extracted text -- read-only, never update, move, or remove it):\n\n```\n%s\n```"
+			args = append(args, part.FilePath, part.Body)
 		} else if part.Url != "" {
 			fmtStr = "\n\n- %s:\n\n```\n%s\n```"
 			args = append(args, part.Url, part.Body)
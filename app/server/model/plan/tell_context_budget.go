@@ -0,0 +1,221 @@
+package plan
+
+import (
+	"log"
+	"plandex-server/syntax/file_map"
+	"sort"
+	"strings"
+
+	shared "plandex-shared"
+)
+
+// how far back in the conversation to look for file references when ranking context
+const numRecentConvoMessagesForPriority = 6
+
+// the budget never goes below this, so reduction still kicks in (as hard as it can) when the rest of the request leaves little or no room for context
+const minContextBudget = 1000
+
+const (
+	contextPriorityDefault        = 0
+	contextPriorityMapRelevant    = 10
+	contextPriorityConvoReference = 20
+)
+
+type reduceContextToBudgetParams struct {
+	toLoad        []contextToLoad
+	budget        int
+	uses          map[string]bool
+	activatePaths map[string]bool
+	pendingFiles  map[string]string
+}
+
+// getContextBudget returns the tokens available for loaded context. The large context fallback is chosen first,
+// based on the request with all context loaded in full, so files are only reduced when even the fallback can't fit them,
+// or when the plan's own budget is lower.
+func (state *activeTellStreamState) getContextBudget(modelConfig shared.ModelRoleConfig, tentativeMaxTokens, tokensWithoutContext int) int {
+	fullContextTokens := 0
+	for _, context := range state.modelContext {
+		fullContextTokens += context.NumTokens
+	}
+
+	maxTokens := tentativeMaxTokens
+	roleConfig := modelConfig.GetRoleForInputTokens(tokensWithoutContext+fullContextTokens, state.settings)
+	baseConfig := roleConfig.GetSharedBaseConfig(state.settings)
+	if baseConfig != nil {
+		maxTokens = baseConfig.MaxTokens - roleConfig.GetReservedOutputTokens(state.settings.CustomModelsById)
+	}
+
+	budget := maxTokens - tokensWithoutContext
+	if state.plan.PlanConfig != nil && state.plan.PlanConfig.ContextBudget > 0 && state.plan.PlanConfig.ContextBudget < budget {
+		budget = state.plan.PlanConfig.ContextBudget
+	}
+
+	return budget
+}
+
+// reduceContextToBudget swaps the lowest priority files for their maps until the context fits in the budget.
+// Pinned files, files used by the current subtask, and anything that isn't a file are never reduced.
+// Order is preserved so the result stays cache-friendly.
+func (state *activeTellStreamState) reduceContextToBudget(params reduceContextToBudgetParams) []contextToLoad {
+	budget := params.budget
+	if budget < minContextBudget {
+		budget = minContextBudget
+	}
+
+	totalTokens := 0
+	for _, part := range params.toLoad {
+		totalTokens += part.NumTokens
+	}
+
+	if totalTokens <= budget {
+		return params.toLoad
+	}
+
+	log.Printf("Tell plan - reduceContextToBudget - context tokens %d exceed budget %d\n", totalTokens, budget)
+
+	priorities := state.getContextPriorities(params.toLoad, params.activatePaths)
+	candidates := rankContextForReduction(params.toLoad, params.uses, priorities)
+
+	res := make([]contextToLoad, len(params.toLoad))
+	copy(res, params.toLoad)
+
+	numReduced := 0
+	for _, i := range candidates {
+		if totalTokens <= budget {
+			break
+		}
+
+		part := res[i]
+		body := part.Body
+		if pending, ok := params.pendingFiles[part.FilePath]; ok {
+			body = pending
+		}
+
+		mapBody, ok := state.getFileMapBody(part.FilePath, body)
+		if !ok {
+			continue
+		}
+
+		numTokens := shared.GetNumTokensEstimate(mapBody)
+		if numTokens >= part.NumTokens {
+			continue
+		}
+
+		totalTokens -= part.NumTokens - numTokens
+		res[i].Body = mapBody
+		res[i].NumTokens = numTokens
+		res[i].IsReduced = true
+		numReduced++
+
+		if verboseLogging {
+			log.Printf("Tell plan - reduceContextToBudget - reduced %s to map - %d -> %d tokens\n", part.FilePath, part.NumTokens, numTokens)
+		}
+	}
+
+	log.Printf("Tell plan - reduceContextToBudget - reduced %d files to maps - context tokens now %d\n", numReduced, totalTokens)
+
+	return res
+}
+
+// getContextPriorities scores files by relevance - files referenced recently in the conversation rank highest,
+// followed by files selected from the project map during the context phase
+func (state *activeTellStreamState) getContextPriorities(toLoad []contextToLoad, activatePaths map[string]bool) map[string]int {
+	priorities := map[string]int{}
+
+	for path := range activatePaths {
+		priorities[path] = contextPriorityMapRelevant
+	}
+
+	var recentMessages []string
+	start := len(state.convo) - numRecentConvoMessagesForPriority
+	if start < 0 {
+		start = 0
+	}
+	for _, msg := range state.convo[start:] {
+		recentMessages = append(recentMessages, msg.Message)
+	}
+	if state.userPrompt != "" {
+		recentMessages = append(recentMessages, state.userPrompt)
+	}
+
+	for _, part := range toLoad {
+		if part.ContextType != shared.ContextFileType {
+			continue
+		}
+		for i, msg := range recentMessages {
+			if !strings.Contains(msg, part.FilePath) {
+				continue
+			}
+			// later messages are more recent, so they get a higher score
+			priority := contextPriorityConvoReference + i
+			if priority > priorities[part.FilePath] {
+				priorities[part.FilePath] = priority
+			}
+		}
+	}
+
+	return priorities
+}
+
+// rankContextForReduction returns the indices of files that can be reduced to maps, lowest priority first.
+// Within a priority level, larger files go first so fewer files need to be reduced.
+func rankContextForReduction(toLoad []contextToLoad, uses map[string]bool, priorities map[string]int) []int {
+	var res []int
+	for i, part := range toLoad {
+		if part.ContextType != shared.ContextFileType || part.Pinned || uses[part.FilePath] {
+			continue
+		}
+		res = append(res, i)
+	}
+
+	getPriority := func(path string) int {
+		if priority, ok := priorities[path]; ok {
+			return priority
+		}
+		return contextPriorityDefault
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := toLoad[res[i]], toLoad[res[j]]
+		aPriority, bPriority := getPriority(a.FilePath), getPriority(b.FilePath)
+		if aPriority != bPriority {
+			return aPriority < bPriority
+		}
+		if a.NumTokens != b.NumTokens {
+			return a.NumTokens > b.NumTokens
+		}
+		return a.FilePath < b.FilePath
+	})
+
+	return res
+}
+
+// getFileMapBody uses the file's part of a loaded map if there is one, otherwise it maps the file directly
+func (state *activeTellStreamState) getFileMapBody(path, body string) (string, bool) {
+	for _, part := range state.modelContext {
+		if part.ContextType != shared.ContextMapType {
+			continue
+		}
+		mapBody, ok := part.MapParts[path]
+		if ok && mapBody != "" && !strings.HasPrefix(mapBody, "[NO MAP") {
+			return mapBody, true
+		}
+	}
+
+	if !shared.HasFileMapSupport(path) || len(body) > shared.MaxContextMapSingleInputSize {
+		return "", false
+	}
+
+	fileMap, err := file_map.MapFile(state.activePlan.Ctx, path, []byte(body))
+	if err != nil {
+		log.Printf("Tell plan - getFileMapBody - error mapping file %s: %v\n", path, err)
+		return "", false
+	}
+
+	mapBody := fileMap.String()
+	if strings.TrimSpace(mapBody) == "" {
+		return "", false
+	}
+
+	return mapBody, true
+}
@@ -0,0 +1,180 @@
+package plan
+
+import (
+	"plandex-server/db"
+	shared "plandex-shared"
+	"reflect"
+	"testing"
+)
+
+func TestRankContextForReduction(t *testing.T) {
+	toLoad := []contextToLoad{
+		{FilePath: "pinned.go", ContextType: shared.ContextFileType, NumTokens: 5000, Pinned: true},
+		{FilePath: "used.go", ContextType: shared.ContextFileType, NumTokens: 4000},
+		{Name: "note", ContextType: shared.ContextNoteType, NumTokens: 3000},
+		{FilePath: "small.go", ContextType: shared.ContextFileType, NumTokens: 100},
+		{FilePath: "large.go", ContextType: shared.ContextFileType, NumTokens: 2000},
+		{FilePath: "referenced.go", ContextType: shared.ContextFileType, NumTokens: 3000},
+		{FilePath: "mapped.go", ContextType: shared.ContextFileType, NumTokens: 3000},
+	}
+
+	uses := map[string]bool{"used.go": true}
+	priorities := map[string]int{
+		"referenced.go": contextPriorityConvoReference,
+		"mapped.go":     contextPriorityMapRelevant,
+	}
+
+	got := rankContextForReduction(toLoad, uses, priorities)
+	want := []int{4, 3, 6, 5}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rankContextForReduction() = %v, want %v", got, want)
+	}
+}
+
+func newBudgetTestState() *activeTellStreamState {
+	return &activeTellStreamState{
+		plan: &db.Plan{},
+		modelContext: []*db.Context{
+			{
+				ContextType: shared.ContextMapType,
+				MapParts: shared.FileMapBodies{
+					"pinned.go":     "func Pinned()",
+					"used.go":       "func Used()",
+					"large.go":      "func Large()",
+					"small.go":      "func Small()",
+					"referenced.go": "func Referenced()",
+				},
+			},
+		},
+	}
+}
+
+func budgetTestContext() []contextToLoad {
+	return []contextToLoad{
+		{FilePath: "pinned.go", ContextType: shared.ContextFileType, NumTokens: 5000, Body: "pinned", Pinned: true},
+		{FilePath: "used.go", ContextType: shared.ContextFileType, NumTokens: 4000, Body: "used"},
+		{Name: "note", ContextType: shared.ContextNoteType, NumTokens: 3000, Body: "note"},
+		{FilePath: "large.go", ContextType: shared.ContextFileType, NumTokens: 6000, Body: "large"},
+		{FilePath: "small.go", ContextType: shared.ContextFileType, NumTokens: 2000, Body: "small"},
+		{FilePath: "referenced.go", ContextType: shared.ContextFileType, NumTokens: 6000, Body: "referenced"},
+		{FilePath: "notes.txt", ContextType: shared.ContextFileType, NumTokens: 2000, Body: "no map support"},
+	}
+}
+
+func reducedPaths(parts []contextToLoad) []string {
+	var res []string
+	for _, part := range parts {
+		if part.IsReduced {
+			res = append(res, part.FilePath)
+		}
+	}
+	return res
+}
+
+func TestReduceContextToBudget(t *testing.T) {
+	uses := map[string]bool{"used.go": true}
+	activatePaths := map[string]bool{"referenced.go": true}
+
+	tests := []struct {
+		name   string
+		budget int
+		want   []string
+	}{
+		{name: "fits", budget: 28000, want: nil},
+		{name: "lowest priority largest first", budget: 22500, want: []string{"large.go"}},
+		{name: "higher priority only when needed", budget: 16000, want: []string{"large.go", "small.go", "referenced.go"}},
+		{name: "zero budget reduces everything it can", budget: 0, want: []string{"large.go", "small.go", "referenced.go"}},
+		{name: "negative budget reduces everything it can", budget: -5000, want: []string{"large.go", "small.go", "referenced.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newBudgetTestState()
+			toLoad := budgetTestContext()
+
+			got := state.reduceContextToBudget(reduceContextToBudgetParams{
+				toLoad:        toLoad,
+				budget:        tt.budget,
+				uses:          uses,
+				activatePaths: activatePaths,
+			})
+
+			if !reflect.DeepEqual(reducedPaths(got), tt.want) {
+				t.Errorf("reduced %v, want %v", reducedPaths(got), tt.want)
+			}
+
+			if len(got) != len(toLoad) {
+				t.Fatalf("expected %d parts, got %d", len(toLoad), len(got))
+			}
+			for i, part := range got {
+				if part.FilePath != toLoad[i].FilePath || part.Name != toLoad[i].Name {
+					t.Errorf("expected order to be preserved at %d, got %q", i, part.FilePath)
+				}
+				if part.IsReduced {
+					if part.Body == toLoad[i].Body || part.NumTokens >= toLoad[i].NumTokens {
+						t.Errorf("expected %s to be swapped for its smaller map", part.FilePath)
+					}
+				} else if part.Body != toLoad[i].Body {
+					t.Errorf("expected %s to be unchanged", part.FilePath)
+				}
+			}
+
+			if toLoad[3].IsReduced {
+				t.Error("expected the input to be left unmodified")
+			}
+		})
+	}
+}
+
+func TestReduceContextToBudgetSkipsFilesWithoutMaps(t *testing.T) {
+	state := newBudgetTestState()
+	state.modelContext = nil
+
+	toLoad := []contextToLoad{
+		{FilePath: "notes.txt", ContextType: shared.ContextFileType, NumTokens: 5000, Body: "no map support"},
+	}
+
+	got := state.reduceContextToBudget(reduceContextToBudgetParams{
+		toLoad: toLoad,
+		budget: 0,
+	})
+
+	if got[0].IsReduced || got[0].Body != toLoad[0].Body {
+		t.Error("expected a file without map support to stay in full")
+	}
+}
+
+func TestGetContextBudget(t *testing.T) {
+	fallback := &shared.ModelRoleConfig{
+		BaseModelConfig: &shared.BaseModelConfig{BaseModelShared: shared.BaseModelShared{MaxTokens: 100000, ReservedOutputTokens: 10000}},
+	}
+	modelConfig := shared.ModelRoleConfig{
+		BaseModelConfig:      &shared.BaseModelConfig{BaseModelShared: shared.BaseModelShared{MaxTokens: 20000, ReservedOutputTokens: 5000}},
+		LargeContextFallback: fallback,
+	}
+
+	state := newBudgetTestState()
+	state.settings = &shared.PlanSettings{}
+
+	state.modelContext = []*db.Context{{NumTokens: 5000}}
+	if got := state.getContextBudget(modelConfig, 90000, 2000); got != 13000 {
+		t.Errorf("expected the default model's room when context fits it, got %d", got)
+	}
+
+	state.modelContext = []*db.Context{{NumTokens: 50000}}
+	if got := state.getContextBudget(modelConfig, 90000, 2000); got != 88000 {
+		t.Errorf("expected the large context fallback's room when context doesn't fit the default model, got %d", got)
+	}
+
+	state.plan.PlanConfig = &shared.PlanConfig{ContextBudget: 30000}
+	if got := state.getContextBudget(modelConfig, 90000, 2000); got != 30000 {
+		t.Errorf("expected the plan's budget when it's lower, got %d", got)
+	}
+
+	state.modelContext = []*db.Context{{NumTokens: 500000}}
+	state.plan.PlanConfig = nil
+	if got := state.getContextBudget(modelConfig, 90000, 95000); got >= 0 {
+		t.Errorf("expected a negative budget when the request overflows without context, got %d", got)
+	}
+}
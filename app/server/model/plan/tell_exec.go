@@ -1,9 +1,11 @@
 package plan
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"plandex-server/tracing"
 	"runtime/debug"
 	"time"
 
@@ -18,6 +20,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/google/uuid"
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type TellParams struct {
@@ -27,6 +30,9 @@ type TellParams struct {
 	Branch   string
 	Auth     *types.ServerAuth
 	Req      *shared.TellPlanRequest
+
+	// the context of the request that started the plan -- only used as the parent of the plan's trace
+	Ctx context.Context
 }
 
 func Tell(params TellParams) error {
@@ -40,6 +46,7 @@ func Tell(params TellParams) error {
 	log.Printf("Tell: Called with plan ID %s on branch %s\n", plan.Id, branch)
 
 	_, err := activatePlan(
+		params.Ctx,
 		clients,
 		plan,
 		branch,
@@ -107,6 +114,13 @@ func execTellPlan(params execTellPlanParams) {
 		return
 	}
 
+	traceCtx, span := tracing.Start(active.Ctx, "execTellPlan",
+		attribute.String("plan.id", plan.Id),
+		attribute.String("plan.branch", branch),
+		attribute.Int("tell.iteration", iteration),
+	)
+	defer span.End()
+
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("execTellPlan: Panic: %v\n%s\n", r, string(debug.Stack()))
@@ -153,6 +167,12 @@ func execTellPlan(params execTellPlanParams) {
 	}
 	log.Println("execTellPlan - Plan status set to replying")
 
+	UpdateActivePlan(planId, branch, func(ap *types.ActivePlan) {
+		ap.TellReq = req
+		ap.Iteration = iteration
+	})
+	saveCheckpoint(planId, branch)
+
 	state := &activeTellStreamState{
 		modelStreamId:       active.ModelStreamId,
 		clients:             clients,
@@ -165,6 +185,7 @@ func execTellPlan(params execTellPlanParams) {
 		branch:              branch,
 		iteration:           iteration,
 		missingFileResponse: missingFileResponse,
+		traceCtx:            traceCtx,
 	}
 
 	log.Println("execTellPlan - Loading tell plan")
@@ -176,6 +197,8 @@ func execTellPlan(params execTellPlanParams) {
 
 	activatePaths, activatePathsOrdered := state.resolveCurrentStage()
 
+	span.SetAttributes(attribute.String("tell.stage", string(state.currentStage.TellStage)))
+
 	var tentativeModelConfig shared.ModelRoleConfig
 	var tentativeMaxTokens int
 	if state.currentStage.TellStage == shared.TellStagePlanning {
@@ -208,6 +231,9 @@ func execTellPlan(params execTellPlanParams) {
 		return
 	}
 
+	// if context won't fit, low priority files are reduced to maps rather than failing the request
+	contextBudget := state.getContextBudget(tentativeModelConfig, tentativeMaxTokens, tokensWithoutContext)
+
 	var planStageSharedMsgs []*types.ExtendedChatMessagePart
 	var planningPhaseOnlyMsgs []*types.ExtendedChatMessagePart
 	var implementationMsgs []*types.ExtendedChatMessagePart
@@ -217,6 +243,7 @@ func execTellPlan(params execTellPlanParams) {
 			includeMaps:         false,
 			smartContextEnabled: req.SmartContext,
 			includeApplyScript:  req.ExecEnabled,
+			contextBudget:       contextBudget,
 		})
 	} else if state.currentStage.TellStage == shared.TellStagePlanning {
 		// add the shared context between planning and context phases first so it can be cached
@@ -227,18 +254,20 @@ func execTellPlan(params execTellPlanParams) {
 			includeApplyScript:  req.ExecEnabled,
 			baseOnly:            true,
 			cacheControl:        true,
+			contextBudget:       contextBudget,
 		})
 
 		if state.currentStage.PlanningPhase == shared.PlanningPhaseTasks {
+			msg := types.ExtendedChatMessage{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: []types.ExtendedChatMessagePart{},
+			}
+			for _, part := range planStageSharedMsgs {
+				msg.Content = append(msg.Content, *part)
+			}
+			sharedMsgsTokens := model.GetMessagesTokenEstimate(msg)
+
 			if req.AutoContext {
-				msg := types.ExtendedChatMessage{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: []types.ExtendedChatMessagePart{},
-				}
-				for _, part := range planStageSharedMsgs {
-					msg.Content = append(msg.Content, *part)
-				}
-				sharedMsgsTokens := model.GetMessagesTokenEstimate(msg)
 
 				tokensRemaining := tentativeMaxTokens - (sharedMsgsTokens + tokensWithoutContext)
 
@@ -262,6 +291,7 @@ func execTellPlan(params execTellPlanParams) {
 					activatePaths:        activatePaths,
 					activatePathsOrdered: activatePathsOrdered,
 					maxTokens:            int(float64(tokensRemaining) * 0.95), // leave a little extra room
+					contextBudget:        int(float64(contextBudget-sharedMsgsTokens) * 0.95),
 				})
 			} else {
 				// if auto context is disabled, just dump in any remaining auto contexts, since all basic contexts have already been added in planStageSharedMsgs
@@ -270,6 +300,7 @@ func execTellPlan(params execTellPlanParams) {
 					smartContextEnabled: req.SmartContext,
 					includeApplyScript:  false, // already included in planStageSharedMsgs
 					autoOnly:            true,
+					contextBudget:       contextBudget - sharedMsgsTokens,
 				})
 			}
 		}
@@ -478,6 +509,21 @@ func execTellPlan(params execTellPlanParams) {
 		return
 	}
 
+	// a request over a spending limit stops the plan here rather than continuing, so it can be resumed once the limit is raised
+	apiErr = model.CheckSpendLimits(model.CheckSpendLimitsParams{
+		OrgId:           currentOrgId,
+		UserId:          currentUserId,
+		Plan:            plan,
+		BaseModelConfig: baseModelConfig,
+		InputTokens:     requestTokens,
+		OutputTokens:    baseModelConfig.ReservedOutputTokens,
+		OnWarning:       streamWarning(planId, branch),
+	})
+	if apiErr != nil {
+		active.StreamDoneCh <- apiErr
+		return
+	}
+
 	state.doTellRequest()
 
 	if shouldBuildPending {
@@ -564,9 +610,16 @@ func (state *activeTellStreamState) doTellRequest() {
 	log.Printf("[Tell] doTellRequest retry=%d fallbackRetry=%d using model=%s",
 		state.numErrorRetry, state.numFallbackRetry, baseModelConfig.ModelName)
 
+	// the span covers the whole reply, until listenStream returns
+	traceCtx, span := tracing.Start(state.traceCtx, "doTellRequest",
+		attribute.String("model.name", string(baseModelConfig.ModelName)),
+		attribute.Int("tell.num_retry", state.numErrorRetry),
+	)
+
 	// start the stream
-	stream, err := model.CreateChatCompletionStream(clients, authVars, modelConfig, state.settings, state.orgUserConfig, state.currentOrgId, state.currentUserId, active.ModelStreamCtx, modelReq)
+	stream, err := model.CreateChatCompletionStream(clients, authVars, modelConfig, state.settings, state.orgUserConfig, state.currentOrgId, state.currentUserId, tracing.ContextWithSpanFrom(active.ModelStreamCtx, traceCtx), modelReq, streamModelStatus(state.plan.Id, state.branch))
 	if err != nil {
+		tracing.End(span, err)
 		log.Printf("Error starting reply stream: %v\n", err)
 		go notify.NotifyErr(notify.SeverityError, fmt.Errorf("error starting reply stream: %v", err))
 		active.StreamDoneCh <- &shared.ApiError{
@@ -578,7 +631,10 @@ func (state *activeTellStreamState) doTellRequest() {
 	}
 
 	// handle stream chunks
-	go state.listenStream(stream)
+	go func() {
+		defer span.End()
+		state.listenStream(stream)
+	}()
 }
 
 func (state *activeTellStreamState) dryRunCalculateTokensWithoutContext(tentativeMaxTokens int, unfinishedSubtaskReasoning string) (bool, int) {
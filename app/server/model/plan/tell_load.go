@@ -7,6 +7,7 @@ import (
 	"plandex-server/db"
 	"plandex-server/model"
 	"plandex-server/notify"
+	"plandex-server/tracing"
 	"plandex-server/types"
 	"runtime"
 	"runtime/debug"
@@ -59,7 +60,7 @@ func (state *activeTellStreamState) loadTellPlan() error {
 		PlanId:   planId,
 		Branch:   branch,
 		Scope:    lockScope,
-		Ctx:      active.Ctx,
+		Ctx:      tracing.ContextWithSpanFrom(active.Ctx, state.traceCtx),
 		CancelFn: active.CancelFn,
 		Reason:   "load tell plan",
 	}, func(repo *db.GitRepo) error {
@@ -82,6 +82,27 @@ func (state *activeTellStreamState) resolveCurrentStage() (activatePaths map[str
 		}
 	}
 
+	// pinned auto-loaded contexts are always active, and go first so they survive the context token limit
+	// (pinned basic contexts are already included with the rest of the basic context)
+	var pinnedPaths []string
+	for _, context := range state.modelContext {
+		if context.Pinned && context.AutoLoaded && context.FilePath != "" && !activatePaths[context.FilePath] {
+			pinnedPaths = append(pinnedPaths, context.FilePath)
+		}
+	}
+	if len(pinnedPaths) > 0 {
+		withPinned := make(map[string]bool, len(activatePaths)+len(pinnedPaths))
+		for path := range activatePaths {
+			withPinned[path] = true
+		}
+		for _, path := range pinnedPaths {
+			withPinned[path] = true
+		}
+		activatePaths = withPinned
+		activatePathsOrdered = append(pinnedPaths, activatePathsOrdered...)
+		log.Printf("[resolveCurrentStage] Activated pinned paths: %v", pinnedPaths)
+	}
+
 	if tellStage == shared.TellStagePlanning {
 		if req.AutoContext && hasContextMap && !contextMapEmpty && !wasContextStage {
 			planningPhase = shared.PlanningPhaseContext
@@ -1,6 +1,7 @@
 package plan
 
 import (
+	"context"
 	"plandex-server/db"
 	"plandex-server/model"
 	"plandex-server/types"
@@ -50,6 +51,9 @@ type activeTellStreamState struct {
 	chunkProcessor        *chunkProcessor
 	generationId          string
 
+	// carries the current iteration's span
+	traceCtx context.Context
+
 	requestStartedAt time.Time
 	firstTokenAt     time.Time
 	originalReq      *types.ExtendedChatCompletionRequest
@@ -70,6 +74,7 @@ type activeTellStreamState struct {
 
 type chunkProcessor struct {
 	replyOperations                 []*shared.Operation
+	numOperationsSeen               int // includes operations on documents, which are skipped
 	chunksReceived                  int
 	maybeRedundantOpeningTagContent string
 	fileOpen                        bool
@@ -146,7 +146,7 @@ func (state *activeTellStreamState) processChunk(choice types.ExtendedChatComple
 		spew.Dump(processor)
 	}
 
-	if !req.IsChatOnly && len(operations) > len(processor.replyOperations) {
+	if !req.IsChatOnly && len(operations) > processor.numOperationsSeen {
 		state.handleNewOperations(&parserRes)
 	}
 
@@ -535,15 +535,23 @@ func (state *activeTellStreamState) handleNewOperations(parserRes *types.ReplyPa
 
 	operations := parserRes.Operations
 
-	log.Printf("%d new operations\n", len(operations)-len(processor.replyOperations))
+	log.Printf("%d new operations\n", len(operations)-processor.numOperationsSeen)
+
+	documentPaths := documentPaths(state.modelContext)
 
 	for i, op := range operations {
-		if i < len(processor.replyOperations) {
+		if i < processor.numOperationsSeen {
 			continue
 		}
+		processor.numOperationsSeen++
 
 		log.Printf("Detected operation: %s\n", op.Name())
 
+		if isDocumentOp(op, documentPaths) {
+			log.Printf("Skipping operation on document %s -- documents are read-only\n", op.Path)
+			continue
+		}
+
 		if req.BuildMode == shared.BuildModeAuto {
 			log.Printf("Queuing build for %s\n", op.Name())
 			// log.Println("Content:")
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"plandex-server/db"
 	"plandex-server/notify"
+	"plandex-server/tracing"
 	"plandex-server/types"
 	shared "plandex-shared"
 
@@ -51,7 +52,7 @@ func (state *activeTellStreamState) storeOnFinished(params storeOnFinishedParams
 		PlanId:   planId,
 		Branch:   branch,
 		Scope:    db.LockScopeWrite,
-		Ctx:      active.Ctx,
+		Ctx:      tracing.ContextWithSpanFrom(active.Ctx, state.traceCtx),
 		CancelFn: active.CancelFn,
 		Reason:   "store on finished",
 	}, func(repo *db.GitRepo) error {
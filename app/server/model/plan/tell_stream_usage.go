@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"plandex-server/hooks"
+	"plandex-server/model"
 	"plandex-server/notify"
 	"runtime/debug"
 
@@ -29,6 +30,34 @@ func (state *activeTellStreamState) handleUsageChunk(usage *openai.Usage) {
 	modelConfig := state.modelConfig
 	baseModelConfig := modelConfig.GetBaseModelConfig(state.authVars, state.settings, state.orgUserConfig)
 
+	didSendParams := &hooks.DidSendModelRequestParams{
+		InputTokens:    usage.PromptTokens,
+		OutputTokens:   usage.CompletionTokens,
+		CachedTokens:   cachedTokens,
+		ModelId:        baseModelConfig.ModelId,
+		ModelTag:       baseModelConfig.ModelTag,
+		ModelName:      baseModelConfig.ModelName,
+		ModelProvider:  baseModelConfig.Provider,
+		ModelPackName:  state.settings.GetModelPack().Name,
+		ModelRole:      modelConfig.Role,
+		Purpose:        "Response",
+		GenerationId:   generationId,
+		PlanId:         plan.Id,
+		ModelStreamId:  state.modelStreamId,
+		ConvoMessageId: state.replyId,
+
+		RequestStartedAt: state.requestStartedAt,
+		Streaming:        true,
+		FirstTokenAt:     state.firstTokenAt,
+		Req:              state.originalReq,
+		StreamResult:     state.activePlan.CurrentReplyContent,
+		ModelConfig:      state.modelConfig,
+
+		SessionId: sessionId,
+	}
+
+	go model.RecordModelUsage(state.currentOrgId, state.currentUserId, baseModelConfig, didSendParams)
+
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -38,33 +67,9 @@ func (state *activeTellStreamState) handleUsageChunk(usage *openai.Usage) {
 		}()
 
 		_, apiErr := hooks.ExecHook(hooks.DidSendModelRequest, hooks.HookParams{
-			Auth: auth,
-			Plan: plan,
-			DidSendModelRequestParams: &hooks.DidSendModelRequestParams{
-				InputTokens:    usage.PromptTokens,
-				OutputTokens:   usage.CompletionTokens,
-				CachedTokens:   cachedTokens,
-				ModelId:        baseModelConfig.ModelId,
-				ModelTag:       baseModelConfig.ModelTag,
-				ModelName:      baseModelConfig.ModelName,
-				ModelProvider:  baseModelConfig.Provider,
-				ModelPackName:  state.settings.GetModelPack().Name,
-				ModelRole:      modelConfig.Role,
-				Purpose:        "Response",
-				GenerationId:   generationId,
-				PlanId:         plan.Id,
-				ModelStreamId:  state.modelStreamId,
-				ConvoMessageId: state.replyId,
-
-				RequestStartedAt: state.requestStartedAt,
-				Streaming:        true,
-				FirstTokenAt:     state.firstTokenAt,
-				Req:              state.originalReq,
-				StreamResult:     state.activePlan.CurrentReplyContent,
-				ModelConfig:      state.modelConfig,
-
-				SessionId: sessionId,
-			},
+			Auth:                      auth,
+			Plan:                      plan,
+			DidSendModelRequestParams: didSendParams,
 		})
 
 		if apiErr != nil {
@@ -92,6 +97,38 @@ func (state *activeTellStreamState) execHookOnStop(sendStreamErr bool) {
 	modelConfig := state.modelConfig
 	baseModelConfig := modelConfig.GetBaseModelConfig(state.authVars, state.settings, state.orgUserConfig)
 
+	// no usage is reported for a stopped stream, so estimates are recorded
+	didSendParams := &hooks.DidSendModelRequestParams{
+		InputTokens:     state.totalRequestTokens,
+		OutputTokens:    active.NumTokens,
+		ModelId:         baseModelConfig.ModelId,
+		ModelTag:        baseModelConfig.ModelTag,
+		ModelName:       baseModelConfig.ModelName,
+		ModelProvider:   baseModelConfig.Provider,
+		ModelPackName:   state.settings.GetModelPack().Name,
+		ModelRole:       modelConfig.Role,
+		Purpose:         "Response",
+		GenerationId:    generationId,
+		PlanId:          plan.Id,
+		ModelStreamId:   state.modelStreamId,
+		ConvoMessageId:  state.replyId,
+		StoppedEarly:    true,
+		UserCancelled:   !sendStreamErr,
+		HadError:        sendStreamErr,
+		NoReportedUsage: true,
+
+		RequestStartedAt: state.requestStartedAt,
+		Streaming:        true,
+		FirstTokenAt:     state.firstTokenAt,
+		Req:              state.originalReq,
+		StreamResult:     state.activePlan.CurrentReplyContent,
+		ModelConfig:      state.modelConfig,
+
+		SessionId: active.SessionId,
+	}
+
+	go model.RecordModelUsage(state.currentOrgId, state.currentUserId, baseModelConfig, didSendParams)
+
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -101,36 +138,9 @@ func (state *activeTellStreamState) execHookOnStop(sendStreamErr bool) {
 		}()
 
 		_, apiErr := hooks.ExecHook(hooks.DidSendModelRequest, hooks.HookParams{
-			Auth: auth,
-			Plan: plan,
-			DidSendModelRequestParams: &hooks.DidSendModelRequestParams{
-				InputTokens:     state.totalRequestTokens,
-				OutputTokens:    active.NumTokens,
-				ModelId:         baseModelConfig.ModelId,
-				ModelTag:        baseModelConfig.ModelTag,
-				ModelName:       baseModelConfig.ModelName,
-				ModelProvider:   baseModelConfig.Provider,
-				ModelPackName:   state.settings.GetModelPack().Name,
-				ModelRole:       modelConfig.Role,
-				Purpose:         "Response",
-				GenerationId:    generationId,
-				PlanId:          plan.Id,
-				ModelStreamId:   state.modelStreamId,
-				ConvoMessageId:  state.replyId,
-				StoppedEarly:    true,
-				UserCancelled:   !sendStreamErr,
-				HadError:        sendStreamErr,
-				NoReportedUsage: true,
-
-				RequestStartedAt: state.requestStartedAt,
-				Streaming:        true,
-				FirstTokenAt:     state.firstTokenAt,
-				Req:              state.originalReq,
-				StreamResult:     state.activePlan.CurrentReplyContent,
-				ModelConfig:      state.modelConfig,
-
-				SessionId: active.SessionId,
-			},
+			Auth:                      auth,
+			Plan:                      plan,
+			DidSendModelRequestParams: didSendParams,
 		})
 
 		if apiErr != nil {
@@ -10,6 +10,7 @@ import (
 	"plandex-server/model"
 	"plandex-server/model/prompts"
 	"plandex-server/notify"
+	"plandex-server/tracing"
 	"plandex-server/types"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func (state *activeTellStreamState) addConversationMessages() bool {
@@ -249,6 +251,9 @@ func summarizeConvo(clients map[string]model.ClientInfo, authVars map[string]str
 	log.Printf("summarizeConvo: Called for plan ID %s on branch %s\n", planId, params.branch)
 	log.Printf("summarizeConvo: Starting summarizeConvo for planId: %s\n", planId)
 
+	ctx, span := tracing.Start(ctx, "summarizeConvo", attribute.String("plan.id", planId))
+	defer span.End()
+
 	branch := params.branch
 	convo := params.convo
 	summaries := params.summaries
@@ -1,7 +1,9 @@
 package plan
 
 import (
+	"plandex-server/model"
 	"plandex-server/types"
+	shared "plandex-shared"
 	"strings"
 )
 
@@ -24,3 +26,31 @@ func StripBackticksWrapper(s string) string {
 
 	return s
 }
+
+// streamModelStatus shows a model request's status (like waiting on a provider rate limit) to the plan's subscribers
+func streamModelStatus(planId, branch string) model.OnModelStatusFn {
+	return func(status string) {
+		active := GetActivePlan(planId, branch)
+		if active == nil {
+			return
+		}
+		active.Stream(shared.StreamMessage{
+			Type:        shared.StreamMessageModelStatus,
+			ModelStatus: status,
+		})
+	}
+}
+
+// streamWarning shows a warning (like approaching a spending limit) to the plan's subscribers
+func streamWarning(planId, branch string) model.OnModelWarningFn {
+	return func(warning string) {
+		active := GetActivePlan(planId, branch)
+		if active == nil {
+			return
+		}
+		active.Stream(shared.StreamMessage{
+			Type:    shared.StreamMessageWarning,
+			Warning: warning,
+		})
+	}
+}
@@ -6,6 +6,9 @@ import (
 
 	"plandex-server/syntax"
 	shared "plandex-shared"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
 )
 
 type ValidationPromptParams struct {
@@ -16,10 +19,12 @@ type ValidationPromptParams struct {
 	Diff                 string
 	Reasons              []syntax.NeedsVerifyReason
 	SyntaxErrors         []string
+	// only used by the JSON prompt--the XML prompt's output is cut off with stop sequences instead
+	ValidateOnly bool
 }
 
-// GetValidationReplacementsXmlPrompt constructs the complete prompt string for XML responses.
-func GetValidationReplacementsXmlPrompt(params ValidationPromptParams) (string, int) {
+// getValidationPromptHead describes the file, the applied changes, and what to validate--it's shared by the XML and JSON prompts
+func getValidationPromptHead(params ValidationPromptParams) (string, int) {
 	reasons := params.Reasons
 	syntaxErrs := params.SyntaxErrors
 	path := params.Path
@@ -89,7 +94,16 @@ e. Any syntax errors that have been previously specified
 Line numbers prefixed with 'pdx-' are included in the original file. Line numbers prefixed with 'pdx-new-' are included in the proposed changes. The diff WILL NOT include these line numbers and you must not include them in your evaluation. You must ignore them completely.
 
 --
+`
 
+	return s, headNumTokens
+}
+
+// GetValidationReplacementsXmlPrompt constructs the complete prompt string for XML responses.
+func GetValidationReplacementsXmlPrompt(params ValidationPromptParams) (string, int) {
+	s, headNumTokens := getValidationPromptHead(params)
+
+	s += `
 First, briefly reason through and assess whether the changes were applied *correctly*.
 You MUST include reasoning–do not skip this step.
 
@@ -242,3 +256,104 @@ Proposed changes (with line nums prefixed with 'pdx-new-'):
 		proposedWithLineNums,
 	)
 }
+
+// GetValidationReplacementsJsonPrompt constructs the complete prompt string for models that respond with a 'validateChanges' function call.
+func GetValidationReplacementsJsonPrompt(params ValidationPromptParams) (string, int) {
+	s, headNumTokens := getValidationPromptHead(params)
+
+	s += `
+You MUST respond by calling the 'validateChanges' function. Don't call any other function.
+
+Set 'reasoning' to a brief assessment of whether the changes were applied *correctly*. If they were applied *incorrectly*, explain what went wrong and briefly strategize on how these issues can be avoided when you generate replacements. You MUST include reasoning–do not skip this step.
+
+Set 'correct' to true if the changes were applied correctly, or false if there are ANY issues. If 'correct' is true, do NOT include 'comments' or 'replacements'.
+`
+
+	if params.ValidateOnly {
+		s += `
+Only assess whether the changes were applied correctly. Do NOT include 'comments' or 'replacements', even if the changes were applied incorrectly.
+`
+		return s, headNumTokens
+	}
+
+	s += `
+If 'correct' is false:
+
+1. Set 'comments' to an evaluation of *EVERY* comment in the *proposed updates*. For each comment, include its line number prefixed by 'pdx-new-', then evaluate whether it's a reference comment that refers to code in the *original file* that was left out of the *proposed updates*, or a comment that explains a change or was carried over from the *original file*. End each with 'Reference: true' or 'Reference: false'. Only include valid comments for the language.
+
+` + ExampleReferences + `
+
+2. Set 'replacements' to a list of replacements that apply the changes described in the *proposed updates* to the *original file* in order to produce a final, valid resulting file with all changes correctly applied. It MUST contain at least one replacement.
+
+Each replacement has an 'old' and a 'new' string.
+
+'old' must contain the *exact* original code that will be replaced. You MUST include line numbers prefixed with 'pdx-' in 'old' (NOT with 'pdx-new-'). Every line in 'old' must exactly match a line in the original file, including spacing, indentation, and the 'pdx-' line number. 'old' MUST NOT contain any partial lines, only complete lines.
+
+'new' must contain ALL the new code that will replace the code in 'old'. It must contain complete lines only. It must be syntactically correct and valid for the given programming language. It MUST NOT contain any line numbers. It MUST NOT contain any reference comments—ALL reference comments ABSOLUTELY MUST be replaced with the actual code they refer to in the *original file*.
+
+Apply changes intelligently *in order* to avoid syntax errors, breaking code, or removing code from the original file that should not be removed. Pay *EXTREMELY close attention* to opening and closing brackets, parentheses, and braces, and to newlines and indentation.
+
+Replacements must be ordered according to their position in the file, and MUST NOT overlap. If replacements depend on or intersect with each other, combine them into a single replacement.
+
+You ABSOLUTELY MUST NOT overwrite or delete code from the original file unless the plan *clearly intends* for the code to be overwritten or removed. Merge the original code and the proposed updates together intelligently according to the intention of the plan.
+
+Example 'replacements' value:
+
+[
+  {
+    "old": "pdx-42: func someFunction() {\npdx-43:   connectToDatabase()\npdx-44: }",
+    "new": "func someFunction() {\n  err := connectToDatabase()\n  if err != nil {\n    log.Printf(\"error: %v\", err)\n    return\n  }\n  processData()\n}"
+  }
+]
+
+DO NOT FORGET TO INCLUDE THE ***'pdx-' PREFIXED*** LINE NUMBERS IN EACH 'old' VALUE.
+`
+
+	return s, headNumTokens
+}
+
+type ValidationReplacement struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+type ValidateChangesRes struct {
+	Reasoning    string                  `json:"reasoning"`
+	Correct      bool                    `json:"correct"`
+	Comments     string                  `json:"comments"`
+	Replacements []ValidationReplacement `json:"replacements"`
+}
+
+var ValidateChangesFn = openai.FunctionDefinition{
+	Name: "validateChanges",
+	Parameters: &jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"reasoning": {
+				Type: jsonschema.String,
+			},
+			"correct": {
+				Type: jsonschema.Boolean,
+			},
+			"comments": {
+				Type: jsonschema.String,
+			},
+			"replacements": {
+				Type: jsonschema.Array,
+				Items: &jsonschema.Definition{
+					Type: jsonschema.Object,
+					Properties: map[string]jsonschema.Definition{
+						"old": {
+							Type: jsonschema.String,
+						},
+						"new": {
+							Type: jsonschema.String,
+						},
+					},
+					Required: []string{"old", "new"},
+				},
+			},
+		},
+		Required: []string{"reasoning", "correct"},
+	},
+}
@@ -0,0 +1,215 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	shared "plandex-shared"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Provider health is tracked in memory per provider with a circuit breaker. After repeated errors, a high error rate,
+// or very slow responses, a provider's circuit opens and provider lookup skips it for a cooldown, so roles move on to
+// the next provider in their failover chain (or the next provider that serves the model). Once the cooldown is up, the
+// circuit is half-open: requests go through again, and the first result either closes it or opens it for another
+// cooldown.
+//
+// Only errors on the provider's side count against it. Rate limits are per account and are handled by the rate
+// limiter and retries, and errors like context length or cancellation say nothing about the provider.
+//
+// The cooldown defaults to 60 seconds and can be set with PLANDEX_PROVIDER_COOLDOWN_SECONDS.
+
+const (
+	providerHealthWindow          = 20
+	providerHealthMinSamples      = 10
+	providerHealthMaxErrorRate    = 0.5
+	providerHealthMaxConsecutive  = 3
+	providerSlowResponseThreshold = 45 * time.Second
+	providerCooldownDefault       = 60 * time.Second
+)
+
+type circuitState string
+
+const (
+	circuitClosed   circuitState = "closed"
+	circuitOpen     circuitState = "open"
+	circuitHalfOpen circuitState = "half-open"
+)
+
+type providerHealth struct {
+	mu        sync.Mutex
+	composite string
+	cooldown  time.Duration
+
+	state    circuitState
+	openedAt time.Time
+
+	// most recent results, true for a failure
+	results             []bool
+	consecutiveFailures int
+	latencyAvg          time.Duration
+}
+
+var (
+	providerHealthsMu sync.Mutex
+	providerHealths   = map[string]*providerHealth{}
+
+	providerCooldownOnce sync.Once
+	providerCooldown     time.Duration
+)
+
+func init() {
+	shared.RegisterProviderHealthCheck(isProviderHealthy)
+}
+
+func getProviderCooldown() time.Duration {
+	providerCooldownOnce.Do(func() {
+		providerCooldown = providerCooldownDefault
+		raw := os.Getenv("PLANDEX_PROVIDER_COOLDOWN_SECONDS")
+		if raw == "" {
+			return
+		}
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 {
+			log.Printf("Invalid PLANDEX_PROVIDER_COOLDOWN_SECONDS %q, using default", raw)
+			return
+		}
+		providerCooldown = time.Duration(seconds) * time.Second
+	})
+	return providerCooldown
+}
+
+func getProviderHealth(composite string) *providerHealth {
+	providerHealthsMu.Lock()
+	defer providerHealthsMu.Unlock()
+
+	health, ok := providerHealths[composite]
+	if !ok {
+		health = &providerHealth{
+			composite: composite,
+			cooldown:  getProviderCooldown(),
+			state:     circuitClosed,
+		}
+		providerHealths[composite] = health
+	}
+	return health
+}
+
+func isProviderHealthy(composite string) bool {
+	providerHealthsMu.Lock()
+	health, ok := providerHealths[composite]
+	providerHealthsMu.Unlock()
+
+	if !ok {
+		return true
+	}
+	return health.isHealthy(time.Now())
+}
+
+// recordProviderResult is called once a request to a provider gets a response (or fails to), with the time it took
+func recordProviderResult(providerConfig shared.ModelProviderConfigSchema, latency time.Duration, err error) {
+	if err != nil && !isProviderHealthErr(err, providerConfig.HasClaudeMaxAuth) {
+		return
+	}
+	getProviderHealth(providerConfig.ToComposite()).record(err != nil, latency, time.Now())
+}
+
+func isProviderHealthErr(err error, isClaudeMax bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	modelErr := classifyBasicError(err, isClaudeMax)
+	switch modelErr.Kind {
+	case shared.ErrOverloaded:
+		return true
+	case shared.ErrOther:
+		return modelErr.Retriable
+	}
+	return false
+}
+
+func (h *providerHealth) isHealthy(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch h.state {
+	case circuitOpen:
+		if now.Sub(h.openedAt) < h.cooldown {
+			return false
+		}
+		h.state = circuitHalfOpen
+		log.Printf("[ProviderHealth] %s - cooldown over, circuit half-open", h.composite)
+	}
+	return true
+}
+
+func (h *providerHealth) record(failed bool, latency time.Duration, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if latency > 0 {
+		if h.latencyAvg == 0 {
+			h.latencyAvg = latency
+		} else {
+			h.latencyAvg = (h.latencyAvg*4 + latency) / 5
+		}
+	}
+
+	if latency > providerSlowResponseThreshold {
+		log.Printf("[ProviderHealth] %s - slow response (%v), counting as a failure", h.composite, latency.Round(time.Millisecond))
+		failed = true
+	}
+
+	h.results = append(h.results, failed)
+	if len(h.results) > providerHealthWindow {
+		h.results = h.results[len(h.results)-providerHealthWindow:]
+	}
+
+	if !failed {
+		h.consecutiveFailures = 0
+		if h.state == circuitHalfOpen {
+			h.state = circuitClosed
+			h.results = nil
+			log.Printf("[ProviderHealth] %s - recovered, circuit closed", h.composite)
+		}
+		return
+	}
+
+	h.consecutiveFailures++
+
+	if h.state == circuitOpen {
+		return
+	}
+
+	var reason string
+	if h.state == circuitHalfOpen {
+		reason = "failed after cooldown"
+	} else if h.consecutiveFailures >= providerHealthMaxConsecutive {
+		reason = strconv.Itoa(h.consecutiveFailures) + " consecutive failures"
+	} else if rate := h.errorRate(); len(h.results) >= providerHealthMinSamples && rate >= providerHealthMaxErrorRate {
+		reason = strconv.Itoa(int(rate*100)) + "% error rate"
+	}
+
+	if reason != "" {
+		h.state = circuitOpen
+		h.openedAt = now
+		log.Printf("[ProviderHealth] %s - circuit open for %v (%s, avg latency %v)", h.composite, h.cooldown, reason, h.latencyAvg.Round(time.Millisecond))
+	}
+}
+
+func (h *providerHealth) errorRate() float64 {
+	if len(h.results) == 0 {
+		return 0
+	}
+	failures := 0
+	for _, failed := range h.results {
+		if failed {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(h.results))
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProviderHealthCircuit(t *testing.T) {
+	h := &providerHealth{composite: "test", cooldown: time.Minute, state: circuitClosed}
+	now := time.Now()
+
+	h.record(true, time.Second, now)
+	h.record(true, time.Second, now)
+	if !h.isHealthy(now) {
+		t.Fatal("expected healthy before the consecutive failure limit")
+	}
+
+	h.record(true, time.Second, now)
+	if h.isHealthy(now) {
+		t.Fatal("expected circuit to open after consecutive failures")
+	}
+
+	// half-open after the cooldown, and a failure opens it again
+	later := now.Add(time.Minute + time.Second)
+	if !h.isHealthy(later) {
+		t.Fatal("expected half-open circuit after cooldown")
+	}
+	h.record(true, time.Second, later)
+	if h.isHealthy(later) {
+		t.Fatal("expected failure while half-open to reopen the circuit")
+	}
+
+	evenLater := later.Add(time.Minute + time.Second)
+	h.isHealthy(evenLater)
+	h.record(false, time.Second, evenLater)
+	if h.state != circuitClosed {
+		t.Fatalf("expected closed circuit after a success, got %s", h.state)
+	}
+}
+
+func TestProviderHealthErrorRateAndLatency(t *testing.T) {
+	h := &providerHealth{composite: "test", cooldown: time.Minute, state: circuitClosed}
+	now := time.Now()
+
+	// alternating failures never hit the consecutive limit, but the error rate does
+	for i := 0; i < providerHealthMinSamples; i++ {
+		h.record(i%2 == 1, time.Second, now)
+	}
+	if h.isHealthy(now) {
+		t.Fatal("expected circuit to open on error rate")
+	}
+
+	slow := &providerHealth{composite: "slow", cooldown: time.Minute, state: circuitClosed}
+	for i := 0; i < providerHealthMaxConsecutive; i++ {
+		slow.record(false, providerSlowResponseThreshold+time.Second, now)
+	}
+	if slow.isHealthy(now) {
+		t.Fatal("expected slow responses to count as failures")
+	}
+}
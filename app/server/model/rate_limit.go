@@ -0,0 +1,237 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	shared "plandex-shared"
+	"sync"
+	"time"
+)
+
+// Build races and parallel file builds can fire many requests at one provider at once. Providers with rate limits
+// get a server-wide limiter per model so those requests queue up here instead of piling into 429s and retries.
+//
+// Limits come from the provider config's 'rateLimits' (which custom providers can set), and can be set or overridden
+// for any provider with PLANDEX_PROVIDER_RATE_LIMITS, a JSON object keyed by provider id or custom provider name:
+//
+//	{"openai": {"requestsPerMinute": 500, "tokensPerMinute": 200000, "maxConcurrent": 10}}
+
+// OnModelStatusFn surfaces a status (like waiting on a rate limit) while a request is held up. An empty status
+// clears it.
+type OnModelStatusFn func(status string)
+
+const providerPausedDefault = 5 * time.Second
+
+type providerLimiter struct {
+	mu     sync.Mutex
+	name   string
+	limits shared.ModelProviderRateLimits
+
+	// token buckets -- capacity is the per-minute limit, refilled continuously
+	requests   float64
+	tokens     float64
+	lastRefill time.Time
+
+	inFlight    int
+	pausedUntil time.Time
+
+	// closed and replaced whenever a slot frees up, to wake queued requests
+	released chan struct{}
+}
+
+var (
+	providerLimitersMu sync.Mutex
+	providerLimiters   = map[string]*providerLimiter{}
+
+	envRateLimitsOnce sync.Once
+	envRateLimits     map[string]shared.ModelProviderRateLimits
+)
+
+func getProviderRateLimits(providerConfig shared.ModelProviderConfigSchema) *shared.ModelProviderRateLimits {
+	envRateLimitsOnce.Do(func() {
+		raw := os.Getenv("PLANDEX_PROVIDER_RATE_LIMITS")
+		if raw == "" {
+			return
+		}
+		err := json.Unmarshal([]byte(raw), &envRateLimits)
+		if err != nil {
+			log.Printf("Error parsing PLANDEX_PROVIDER_RATE_LIMITS, ignoring: %v", err)
+			envRateLimits = nil
+		}
+	})
+
+	key := string(providerConfig.Provider)
+	if providerConfig.CustomProvider != nil {
+		key = *providerConfig.CustomProvider
+	}
+
+	if limits, ok := envRateLimits[key]; ok {
+		return &limits
+	}
+
+	return providerConfig.RateLimits
+}
+
+// getProviderLimiter returns nil if the provider has no limits
+func getProviderLimiter(providerConfig shared.ModelProviderConfigSchema, modelName shared.ModelName) *providerLimiter {
+	limits := getProviderRateLimits(providerConfig)
+	if limits.IsZero() {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s/%s", providerConfig.ToComposite(), modelName)
+
+	providerLimitersMu.Lock()
+	defer providerLimitersMu.Unlock()
+
+	limiter, ok := providerLimiters[name]
+	if !ok {
+		limiter = &providerLimiter{
+			name:       name,
+			limits:     *limits,
+			requests:   float64(limits.RequestsPerMinute),
+			tokens:     float64(limits.TokensPerMinute),
+			lastRefill: time.Now(),
+			released:   make(chan struct{}),
+		}
+		providerLimiters[name] = limiter
+		return limiter
+	}
+
+	// pick up changes to a custom provider's limits
+	limiter.mu.Lock()
+	if limiter.limits != *limits {
+		limiter.limits = *limits
+		limiter.requests = min(limiter.requests, float64(limits.RequestsPerMinute))
+		limiter.tokens = min(limiter.tokens, float64(limits.TokensPerMinute))
+	}
+	limiter.mu.Unlock()
+
+	return limiter
+}
+
+// acquire waits until a request estimated at numTokens can be sent, then returns a release func that must be called
+// once the request is done with the tokens it actually used (or 0 if unknown)
+func (l *providerLimiter) acquire(ctx context.Context, numTokens int, onStatus OnModelStatusFn) (release func(usedTokens int), err error) {
+	var waitingOn string
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.refill(now)
+
+		reserved := float64(numTokens)
+		if l.limits.TokensPerMinute > 0 {
+			// a request bigger than the whole budget still goes through once the bucket is full
+			reserved = min(reserved, float64(l.limits.TokensPerMinute))
+		}
+
+		var wait time.Duration
+		var reason string
+
+		if now.Before(l.pausedUntil) {
+			wait = l.pausedUntil.Sub(now)
+			reason = "provider rate limited"
+		} else if l.limits.MaxConcurrent > 0 && l.inFlight >= l.limits.MaxConcurrent {
+			// woken by a release rather than a timer
+			wait = time.Minute
+			reason = fmt.Sprintf("max %d concurrent requests", l.limits.MaxConcurrent)
+		} else if l.limits.RequestsPerMinute > 0 && l.requests < 1 {
+			wait = bucketWait(1-l.requests, l.limits.RequestsPerMinute)
+			reason = fmt.Sprintf("%d requests per minute", l.limits.RequestsPerMinute)
+		} else if l.limits.TokensPerMinute > 0 && l.tokens < reserved {
+			wait = bucketWait(reserved-l.tokens, l.limits.TokensPerMinute)
+			reason = fmt.Sprintf("%d tokens per minute", l.limits.TokensPerMinute)
+		}
+
+		if wait == 0 {
+			if l.limits.RequestsPerMinute > 0 {
+				l.requests--
+			}
+			if l.limits.TokensPerMinute > 0 {
+				l.tokens -= reserved
+			}
+			l.inFlight++
+			l.mu.Unlock()
+
+			if waitingOn != "" && onStatus != nil {
+				onStatus("")
+			}
+
+			var once sync.Once
+			return func(usedTokens int) {
+				once.Do(func() { l.release(reserved, usedTokens) })
+			}, nil
+		}
+
+		released := l.released
+		l.mu.Unlock()
+
+		if reason != waitingOn {
+			waitingOn = reason
+			log.Printf("[RateLimit] %s - queued for %v (%s)", l.name, wait.Round(time.Millisecond), reason)
+			if onStatus != nil {
+				onStatus(fmt.Sprintf("Waiting for %s rate limit (%s)", l.name, reason))
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-released:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *providerLimiter) release(reserved float64, usedTokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+
+	// settle up the estimate against actual usage when we have it
+	if l.limits.TokensPerMinute > 0 && usedTokens > 0 {
+		l.tokens = min(l.tokens+reserved-float64(usedTokens), float64(l.limits.TokensPerMinute))
+	}
+
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// pause holds all requests to the provider after it returns a 429 anyway, so queued requests don't hammer it
+func (l *providerLimiter) pause(d time.Duration) {
+	if d <= 0 {
+		d = providerPausedDefault
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+func (l *providerLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.lastRefill).Minutes()
+	l.lastRefill = now
+
+	if l.limits.RequestsPerMinute > 0 {
+		l.requests = min(l.requests+elapsed*float64(l.limits.RequestsPerMinute), float64(l.limits.RequestsPerMinute))
+	}
+	if l.limits.TokensPerMinute > 0 {
+		l.tokens = min(l.tokens+elapsed*float64(l.limits.TokensPerMinute), float64(l.limits.TokensPerMinute))
+	}
+}
+
+func bucketWait(deficit float64, perMinute int) time.Duration {
+	return max(time.Duration(deficit/float64(perMinute)*float64(time.Minute)), time.Millisecond)
+}
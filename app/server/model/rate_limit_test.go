@@ -0,0 +1,86 @@
+package model
+
+import (
+	"context"
+	shared "plandex-shared"
+	"testing"
+	"time"
+)
+
+func TestProviderLimiterMaxConcurrent(t *testing.T) {
+	name := "limited"
+	limiter := getProviderLimiter(shared.ModelProviderConfigSchema{
+		Provider:       shared.ModelProviderCustom,
+		CustomProvider: &name,
+		RateLimits:     &shared.ModelProviderRateLimits{MaxConcurrent: 1},
+	}, "model")
+	if limiter == nil {
+		t.Fatal("expected a limiter")
+	}
+
+	ctx := context.Background()
+	release, err := limiter.acquire(ctx, 100, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statuses []string
+	acquired := make(chan struct{})
+	go func() {
+		release2, err := limiter.acquire(ctx, 100, func(status string) {
+			statuses = append(statuses, status)
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		release2(0)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second request should be queued")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release(0)
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second request should run once the first is released")
+	}
+
+	if len(statuses) != 2 || statuses[0] == "" || statuses[1] != "" {
+		t.Errorf("unexpected statuses: %q", statuses)
+	}
+}
+
+func TestProviderLimiterTokensPerMinute(t *testing.T) {
+	limiter := &providerLimiter{
+		limits:     shared.ModelProviderRateLimits{TokensPerMinute: 6000},
+		tokens:     6000,
+		lastRefill: time.Now(),
+		released:   make(chan struct{}),
+	}
+
+	release, err := limiter.acquire(context.Background(), 5000, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// actual usage was higher than estimated, so the bucket goes further down
+	release(5990)
+
+	// 6000/min refills 100 tokens a second, so this has to wait
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = limiter.acquire(ctx, 1000, nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected to wait for tokens, got %v", err)
+	}
+
+	// no limits, no limiter
+	if getProviderLimiter(shared.ModelProviderConfigSchema{Provider: shared.ModelProviderOpenAI}, "gpt-4.1") != nil {
+		t.Error("expected no limiter without limits")
+	}
+}
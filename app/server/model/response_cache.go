@@ -0,0 +1,203 @@
+package model
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"plandex-server/types"
+	shared "plandex-shared"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Roles like names, commit messages, auto-continue checks and the builders often get exactly the same input when a
+// plan is retried or rebuilt. With the response cache enabled, a response for one of these roles is kept in memory and
+// reused for an identical request (same org, model and normalized request) rather than paying for it again.
+//
+// It's opt-in with PLANDEX_RESPONSE_CACHE: '1' caches the default roles below, or it can be a comma-separated list of
+// roles. Entries expire after PLANDEX_RESPONSE_CACHE_TTL_SECONDS (default 1 hour), and the least recently used entries
+// are evicted once the cache is over PLANDEX_RESPONSE_CACHE_MAX_MB (default 50).
+
+const (
+	responseCacheDefaultTTL      = time.Hour
+	responseCacheDefaultMaxBytes = 50 * 1024 * 1024
+)
+
+var responseCacheDefaultRoles = []shared.ModelRole{
+	shared.ModelRoleName,
+	shared.ModelRoleCommitMsg,
+	shared.ModelRoleExecStatus,
+	shared.ModelRoleBuilder,
+	shared.ModelRoleWholeFileBuilder,
+}
+
+type responseCache struct {
+	mu       sync.Mutex
+	roles    map[shared.ModelRole]bool
+	ttl      time.Duration
+	maxBytes int
+
+	numBytes int
+	entries  map[string]*list.Element
+	// most recently used at the front
+	lru *list.List
+}
+
+type responseCacheEntry struct {
+	key       string
+	content   string
+	expiresAt time.Time
+}
+
+var (
+	activeResponseCacheOnce sync.Once
+	activeResponseCache     *responseCache
+)
+
+// getResponseCache returns nil if the cache isn't enabled
+func getResponseCache() *responseCache {
+	activeResponseCacheOnce.Do(func() {
+		raw := strings.TrimSpace(os.Getenv("PLANDEX_RESPONSE_CACHE"))
+		if raw == "" || raw == "0" {
+			return
+		}
+
+		roles := responseCacheDefaultRoles
+		if raw != "1" {
+			roles = nil
+			for _, role := range strings.Split(raw, ",") {
+				roles = append(roles, shared.ModelRole(strings.TrimSpace(role)))
+			}
+		}
+
+		ttl := responseCacheDefaultTTL
+		if s := os.Getenv("PLANDEX_RESPONSE_CACHE_TTL_SECONDS"); s != "" {
+			seconds, err := strconv.Atoi(s)
+			if err != nil || seconds <= 0 {
+				log.Printf("Invalid PLANDEX_RESPONSE_CACHE_TTL_SECONDS %q, using default", s)
+			} else {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+
+		maxBytes := responseCacheDefaultMaxBytes
+		if s := os.Getenv("PLANDEX_RESPONSE_CACHE_MAX_MB"); s != "" {
+			mb, err := strconv.Atoi(s)
+			if err != nil || mb <= 0 {
+				log.Printf("Invalid PLANDEX_RESPONSE_CACHE_MAX_MB %q, using default", s)
+			} else {
+				maxBytes = mb * 1024 * 1024
+			}
+		}
+
+		activeResponseCache = newResponseCache(roles, ttl, maxBytes)
+		log.Printf("[ResponseCache] Enabled for roles %v (ttl %v, max %d MB)", roles, ttl, maxBytes/1024/1024)
+	})
+
+	return activeResponseCache
+}
+
+func newResponseCache(roles []shared.ModelRole, ttl time.Duration, maxBytes int) *responseCache {
+	c := &responseCache{
+		roles:    map[shared.ModelRole]bool{},
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+	}
+	for _, role := range roles {
+		c.roles[role] = true
+	}
+	return c
+}
+
+func (c *responseCache) enabledForRole(role shared.ModelRole) bool {
+	return c.roles[role]
+}
+
+// responseCacheKey hashes the parts of a request that determine the response. Streaming options and prompt caching
+// markers don't affect the output, so they're left out.
+func responseCacheKey(orgId string, modelId shared.ModelId, req types.ExtendedChatCompletionRequest) (string, error) {
+	req.Stream = false
+	req.StreamOptions = nil
+
+	messages := make([]types.ExtendedChatMessage, len(req.Messages))
+	for i, msg := range req.Messages {
+		parts := make([]types.ExtendedChatMessagePart, len(msg.Content))
+		for j, part := range msg.Content {
+			part.CacheControl = nil
+			parts[j] = part
+		}
+		messages[i] = types.ExtendedChatMessage{Role: msg.Role, Content: parts}
+	}
+	req.Messages = messages
+
+	bytes, err := json.Marshal(struct {
+		OrgId   string                              `json:"orgId"`
+		ModelId shared.ModelId                      `json:"modelId"`
+		Req     types.ExtendedChatCompletionRequest `json:"req"`
+	}{orgId, modelId, req})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (c *responseCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+
+	entry := el.Value.(*responseCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(el)
+		return "", false
+	}
+
+	c.lru.MoveToFront(el)
+	return entry.content, true
+}
+
+func (c *responseCache) set(key, content string) {
+	// a single response bigger than the whole cache isn't worth keeping
+	if len(content) > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+
+	el := c.lru.PushFront(&responseCacheEntry{
+		key:       key,
+		content:   content,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+	c.entries[key] = el
+	c.numBytes += len(content)
+
+	for c.numBytes > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *responseCache) remove(el *list.Element) {
+	entry := el.Value.(*responseCacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, entry.key)
+	c.numBytes -= len(entry.content)
+}
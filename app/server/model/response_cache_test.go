@@ -0,0 +1,70 @@
+package model
+
+import (
+	"plandex-server/types"
+	shared "plandex-shared"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	msg := func(cacheControl *types.CacheControlSpec) []types.ExtendedChatMessage {
+		return []types.ExtendedChatMessage{{
+			Role: openai.ChatMessageRoleSystem,
+			Content: []types.ExtendedChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "name this plan", CacheControl: cacheControl},
+			},
+		}}
+	}
+
+	req := types.ExtendedChatCompletionRequest{Model: "openai/gpt-4.1-mini", Messages: msg(nil)}
+	key, err := responseCacheKey("org", "openai/gpt-4.1-mini", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// streaming options and cache markers don't change the key
+	streamReq := req
+	streamReq.Stream = true
+	streamReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	streamReq.Messages = msg(&types.CacheControlSpec{Type: types.CacheControlTypeEphemeral})
+	streamKey, _ := responseCacheKey("org", "openai/gpt-4.1-mini", streamReq)
+	if streamKey != key {
+		t.Error("expected streaming options and cache control to be ignored")
+	}
+	if streamReq.Messages[0].Content[0].CacheControl == nil {
+		t.Error("building the key shouldn't modify the request's messages")
+	}
+
+	otherOrgKey, _ := responseCacheKey("other-org", "openai/gpt-4.1-mini", req)
+	if otherOrgKey == key {
+		t.Error("expected keys to be scoped to the org")
+	}
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	c := newResponseCache([]shared.ModelRole{shared.ModelRoleName}, time.Hour, 10)
+	if !c.enabledForRole(shared.ModelRoleName) || c.enabledForRole(shared.ModelRolePlanner) {
+		t.Fatal("unexpected enabled roles")
+	}
+
+	c.set("a", "12345")
+	c.set("b", "12345")
+	c.get("a") // a is now the most recently used
+	c.set("c", "12345")
+
+	if _, ok := c.get("b"); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if content, ok := c.get("a"); !ok || content != "12345" {
+		t.Error("expected recently used entry to be kept")
+	}
+
+	c.ttl = -time.Second
+	c.set("d", "1")
+	if _, ok := c.get("d"); ok {
+		t.Error("expected expired entry to be missed")
+	}
+}
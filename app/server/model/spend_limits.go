@@ -0,0 +1,182 @@
+package model
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"plandex-server/db"
+	shared "plandex-shared"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Spending limits are checked before each model request against what's already been spent plus an estimate of the
+// request's cost from the model's pricing. Plans can set a total and a daily limit in their config ('plan-spend-limit'
+// and 'daily-spend-limit'), and the server can set daily limits per user and per org with
+// PLANDEX_USER_DAILY_SPEND_LIMIT and PLANDEX_ORG_DAILY_SPEND_LIMIT (in USD). Days are UTC.
+//
+// Crossing a warning threshold streams a warning to the plan. A request that would go over a limit isn't sent, and the
+// plan stops with an error rather than continuing, so it can be picked up again with 'plandex continue' once the
+// limit is raised.
+
+// OnModelWarningFn surfaces a warning (like approaching a spending limit) to the plan's subscribers
+type OnModelWarningFn func(warning string)
+
+var spendWarningThresholds = []float64{0.5, 0.8, 0.9}
+
+type spendLimit struct {
+	label string
+	// what's spent against it is tracked under this key, so each threshold is only warned about once
+	key   string
+	limit float64
+	spent float64
+	// how to raise the limit
+	hint string
+}
+
+var (
+	serverSpendLimitsOnce sync.Once
+	userDailySpendLimit   float64
+	orgDailySpendLimit    float64
+
+	spendWarningsMu sync.Mutex
+	// highest threshold warned about by limit key
+	spendWarnings = map[string]float64{}
+)
+
+func getServerSpendLimits() (userDaily, orgDaily float64) {
+	serverSpendLimitsOnce.Do(func() {
+		parse := func(name string) float64 {
+			raw := os.Getenv(name)
+			if raw == "" {
+				return 0
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil || v < 0 {
+				log.Printf("Invalid %s %q, ignoring", name, raw)
+				return 0
+			}
+			return v
+		}
+		userDailySpendLimit = parse("PLANDEX_USER_DAILY_SPEND_LIMIT")
+		orgDailySpendLimit = parse("PLANDEX_ORG_DAILY_SPEND_LIMIT")
+	})
+	return userDailySpendLimit, orgDailySpendLimit
+}
+
+type CheckSpendLimitsParams struct {
+	OrgId           string
+	UserId          string
+	Plan            *db.Plan
+	BaseModelConfig *shared.BaseModelConfig
+	InputTokens     int
+	OutputTokens    int
+	OnWarning       OnModelWarningFn
+}
+
+// CheckSpendLimits returns an error if the request would put the plan, user, or org over a spending limit
+func CheckSpendLimits(params CheckSpendLimitsParams) *shared.ApiError {
+	plan := params.Plan
+
+	var planLimit, planDailyLimit float64
+	if plan.PlanConfig != nil {
+		planLimit = plan.PlanConfig.PlanSpendLimit
+		planDailyLimit = plan.PlanConfig.DailySpendLimit
+	}
+	userDailyLimit, orgDailyLimit := getServerSpendLimits()
+
+	if planLimit == 0 && planDailyLimit == 0 && userDailyLimit == 0 && orgDailyLimit == 0 {
+		return nil
+	}
+
+	totals, err := db.GetModelSpendTotals(params.OrgId, params.UserId, plan.Id)
+	if err != nil {
+		// don't block requests on a failed lookup
+		log.Printf("[SpendLimits] Error getting spend totals: %v", err)
+		return nil
+	}
+
+	day := time.Now().UTC().Format("2006-01-02")
+	setConfigHint := "Raise it with 'plandex set-config %s', then 'plandex continue'."
+
+	limits := []spendLimit{
+		{
+			label: "plan spending limit",
+			key:   "plan|" + plan.Id,
+			limit: planLimit,
+			spent: totals.PlanTotal,
+			hint:  fmt.Sprintf(setConfigHint, "plan-spend-limit"),
+		},
+		{
+			label: "daily plan spending limit",
+			key:   "plan-day|" + plan.Id + "|" + day,
+			limit: planDailyLimit,
+			spent: totals.PlanDay,
+			hint:  fmt.Sprintf(setConfigHint, "daily-spend-limit"),
+		},
+		{
+			label: "daily user spending limit",
+			key:   "user-day|" + params.UserId + "|" + day,
+			limit: userDailyLimit,
+			spent: totals.UserDay,
+			hint:  "It resets at midnight UTC, or your server admin can raise it.",
+		},
+		{
+			label: "daily org spending limit",
+			key:   "org-day|" + params.OrgId + "|" + day,
+			limit: orgDailyLimit,
+			spent: totals.OrgDay,
+			hint:  "It resets at midnight UTC, or your server admin can raise it.",
+		},
+	}
+
+	estimate := params.BaseModelConfig.Pricing.GetCost(params.InputTokens, params.OutputTokens, 0)
+
+	for _, l := range limits {
+		if l.limit == 0 {
+			continue
+		}
+
+		projected := l.spent + estimate
+		if projected > l.limit {
+			log.Printf("[SpendLimits] %s reached for %s - spent $%.4f of $%.2f, request estimated at $%.4f", l.label, l.key, l.spent, l.limit, estimate)
+			return &shared.ApiError{
+				Type:   shared.ApiErrorTypeSpendLimitReached,
+				Status: http.StatusPaymentRequired,
+				Msg: fmt.Sprintf("Reached the %s of $%.2f ($%.2f spent, next request estimated at $%.2f). The plan has been paused. %s",
+					l.label, l.limit, l.spent, estimate, l.hint),
+			}
+		}
+
+		threshold := crossedSpendWarningThreshold(l.key, projected/l.limit)
+		if threshold > 0 && params.OnWarning != nil {
+			params.OnWarning(fmt.Sprintf("%d%% of the $%.2f %s used ($%.2f spent)", int(projected/l.limit*100), l.limit, l.label, l.spent))
+		}
+	}
+
+	return nil
+}
+
+// crossedSpendWarningThreshold returns the highest threshold crossed that hasn't been warned about yet, or 0
+func crossedSpendWarningThreshold(key string, fraction float64) float64 {
+	var crossed float64
+	for _, threshold := range spendWarningThresholds {
+		if fraction >= threshold {
+			crossed = threshold
+		}
+	}
+	if crossed == 0 {
+		return 0
+	}
+
+	spendWarningsMu.Lock()
+	defer spendWarningsMu.Unlock()
+
+	if spendWarnings[key] >= crossed {
+		return 0
+	}
+	spendWarnings[key] = crossed
+	return crossed
+}
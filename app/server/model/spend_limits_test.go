@@ -0,0 +1,84 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+	shared "plandex-shared"
+	"testing"
+)
+
+func TestModelPricingGetCost(t *testing.T) {
+	pricing := &shared.ModelPricing{InputPerMillion: 3, OutputPerMillion: 15, CachedInputPerMillion: 0.3}
+
+	cost := pricing.GetCost(1_000_000, 100_000, 0)
+	if math.Abs(cost-4.5) > 1e-9 {
+		t.Errorf("expected $4.50, got $%f", cost)
+	}
+
+	// cached tokens are part of the input tokens and billed at the cached price
+	cost = pricing.GetCost(1_000_000, 0, 500_000)
+	if math.Abs(cost-1.65) > 1e-9 {
+		t.Errorf("expected $1.65, got $%f", cost)
+	}
+
+	noCachedPrice := &shared.ModelPricing{InputPerMillion: 2, OutputPerMillion: 8}
+	cost = noCachedPrice.GetCost(1_000_000, 0, 1_000_000)
+	if math.Abs(cost-2) > 1e-9 {
+		t.Errorf("expected cached tokens at the input price, got $%f", cost)
+	}
+
+	var unpriced *shared.ModelPricing
+	if unpriced.GetCost(1_000_000, 1_000_000, 0) != 0 {
+		t.Error("expected models without pricing to cost nothing")
+	}
+}
+
+func TestCrossedSpendWarningThreshold(t *testing.T) {
+	key := "plan|test-crossed-threshold"
+
+	if got := crossedSpendWarningThreshold(key, 0.3); got != 0 {
+		t.Errorf("expected no threshold below 50%%, got %v", got)
+	}
+	if got := crossedSpendWarningThreshold(key, 0.55); got != 0.5 {
+		t.Errorf("expected 0.5, got %v", got)
+	}
+	if got := crossedSpendWarningThreshold(key, 0.6); got != 0 {
+		t.Errorf("expected a threshold to only be warned about once, got %v", got)
+	}
+	// jumping past several thresholds warns once for the highest
+	if got := crossedSpendWarningThreshold(key, 0.95); got != 0.9 {
+		t.Errorf("expected 0.9, got %v", got)
+	}
+	if got := crossedSpendWarningThreshold(key, 0.85); got != 0 {
+		t.Errorf("expected no warning for a lower threshold, got %v", got)
+	}
+}
+
+func TestPlanSpendLimitConfig(t *testing.T) {
+	var config shared.PlanConfig
+
+	setting := shared.ConfigSettingsByKey["planspendlimit"]
+	setting.FloatSetter(&config, 2.5)
+	if config.PlanSpendLimit != 2.5 || setting.Getter(&config) != "2.5" {
+		t.Errorf("expected a $2.50 plan limit, got %v (%s)", config.PlanSpendLimit, setting.Getter(&config))
+	}
+
+	daily := shared.ConfigSettingsByKey["dailyspendlimit"]
+	daily.FloatSetter(&config, 0.5)
+	if config.DailySpendLimit != 0.5 {
+		t.Errorf("expected a $0.50 daily limit, got %v", config.DailySpendLimit)
+	}
+	daily.FloatSetter(&config, -1)
+	if config.DailySpendLimit != 0 {
+		t.Errorf("expected a negative limit to disable it, got %v", config.DailySpendLimit)
+	}
+
+	// limits saved as whole dollars still load
+	err := json.Unmarshal([]byte(`{"planSpendLimit": 3, "dailySpendLimit": 1}`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.PlanSpendLimit != 3 || config.DailySpendLimit != 1 {
+		t.Errorf("expected whole-dollar limits to load, got %v and %v", config.PlanSpendLimit, config.DailySpendLimit)
+	}
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"log"
+	"plandex-server/db"
+	"plandex-server/hooks"
+	"plandex-server/metrics"
+	shared "plandex-shared"
+)
+
+// RecordModelUsage stores a model request's tokens and cost so usage can be reported on any deployment, and adds the
+// cost to the totals that spending limits are checked against. Responses served from the response cache are stored
+// with no cost.
+func RecordModelUsage(orgId, userId string, baseModelConfig *shared.BaseModelConfig, params *hooks.DidSendModelRequestParams) {
+	var cost float64
+	if !params.ResponseCacheHit {
+		cost = baseModelConfig.Pricing.GetCost(params.InputTokens, params.OutputTokens, params.CachedTokens)
+
+		provider, role := string(params.ModelProvider), string(params.ModelRole)
+		metrics.ObserveModelTokens(provider, role, params.InputTokens, params.OutputTokens, params.CachedTokens)
+		if !params.FirstTokenAt.IsZero() && !params.RequestStartedAt.IsZero() {
+			metrics.ObserveTimeToFirstToken(provider, role, params.FirstTokenAt.Sub(params.RequestStartedAt))
+		}
+	}
+
+	var sessionId *string
+	if params.SessionId != "" {
+		sessionId = &params.SessionId
+	}
+
+	err := db.InsertModelUsage(&db.ModelUsage{
+		OrgId:         orgId,
+		UserId:        userId,
+		PlanId:        params.PlanId,
+		SessionId:     sessionId,
+		ModelId:       params.ModelId,
+		ModelName:     params.ModelName,
+		ModelProvider: params.ModelProvider,
+		ModelRole:     params.ModelRole,
+		Purpose:       params.Purpose,
+		InputTokens:   params.InputTokens,
+		OutputTokens:  params.OutputTokens,
+		CachedTokens:  params.CachedTokens,
+		Cost:          cost,
+		Priced:        baseModelConfig.Pricing != nil,
+		CacheHit:      params.ResponseCacheHit,
+	})
+	if err != nil {
+		log.Printf("[Usage] Error recording model usage: %v", err)
+	}
+
+	if cost == 0 {
+		return
+	}
+
+	err = db.AddModelSpend(orgId, userId, params.PlanId, cost)
+	if err != nil {
+		log.Printf("[SpendLimits] Error recording spend: %v", err)
+	}
+}
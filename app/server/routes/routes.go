@@ -104,6 +104,9 @@ func addApiRoutes(r *mux.Router, prefix string) {
 	HandlePlandexFn(r, prefix+"/orgs/users/{userId}", false, handlers.DeleteOrgUserHandler).Methods("DELETE")
 	HandlePlandexFn(r, prefix+"/orgs/roles", false, handlers.ListOrgRolesHandler).Methods("GET")
 
+	HandlePlandexFn(r, prefix+"/admin/locks", false, handlers.ListRepoLocksHandler).Methods("GET")
+	HandlePlandexFn(r, prefix+"/admin/locks/{lockId}", false, handlers.ForceReleaseRepoLockHandler).Methods("DELETE")
+
 	HandlePlandexFn(r, prefix+"/invites", false, handlers.InviteUserHandler).Methods("POST")
 	HandlePlandexFn(r, prefix+"/invites/pending", false, handlers.ListPendingInvitesHandler).Methods("GET")
 	HandlePlandexFn(r, prefix+"/invites/accepted", false, handlers.ListAcceptedInvitesHandler).Methods("GET")
@@ -145,6 +148,7 @@ func addApiRoutes(r *mux.Router, prefix string) {
 	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/context/{contextId}/body", false, handlers.GetContextBodyHandler).Methods("GET")
 	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/context", false, handlers.UpdateContextHandler).Methods("PUT")
 	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/context", false, handlers.DeleteContextHandler).Methods("DELETE")
+	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/context/pin", false, handlers.PinContextHandler).Methods("PATCH")
 
 	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/convo", false, handlers.ListConvoHandler).Methods("GET")
 	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/rewind", false, handlers.RewindPlanHandler).Methods("PATCH")
@@ -161,6 +165,12 @@ func addApiRoutes(r *mux.Router, prefix string) {
 
 	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/tell", true, handlers.TellPlanHandler).Methods("POST")
 	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/build", true, handlers.BuildPlanHandler).Methods("PATCH")
+	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/resume", true, handlers.ResumePlanHandler).Methods("PATCH")
+	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/queue", false, handlers.QueuePromptHandler).Methods("POST")
+	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/queue", false, handlers.ListQueuedPromptsHandler).Methods("GET")
+	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/queue/{queuedPromptId}", false, handlers.DeleteQueuedPromptHandler).Methods("DELETE")
+	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/tell/ws", true, handlers.TellPlanWsHandler).Methods("GET")
+	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/build/ws", true, handlers.BuildPlanWsHandler).Methods("GET")
 
 	HandlePlandexFn(r, prefix+"/custom_models", false, handlers.ListCustomModelsHandler).Methods("GET")
 	HandlePlandexFn(r, prefix+"/custom_models", false, handlers.UpsertCustomModelsHandler).Methods("POST")
@@ -187,12 +197,16 @@ func addApiRoutes(r *mux.Router, prefix string) {
 
 	HandlePlandexFn(r, prefix+"/org_user_config", false, handlers.GetOrgUserConfigHandler).Methods("GET")
 	HandlePlandexFn(r, prefix+"/org_user_config", false, handlers.UpdateOrgUserConfigHandler).Methods("PUT")
+
+	HandlePlandexFn(r, prefix+"/usage/summary", false, handlers.GetUsageSummaryHandler).Methods("POST")
 }
 
 func addProxyableApiRoutes(r *mux.Router, prefix string) {
 	EnsureHandlePlandex()
 
-	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/connect", true, handlers.ConnectPlanHandler).Methods("PATCH")
+	// GET allows connecting with EventSource
+	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/connect", true, handlers.ConnectPlanHandler).Methods("PATCH", "GET")
+	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/connect/ws", true, handlers.ConnectPlanWsHandler).Methods("GET")
 	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/stop", false, handlers.StopPlanHandler).Methods("DELETE")
 
 	HandlePlandexFn(r, prefix+"/plans/{planId}/{branch}/respond_missing_file", false, handlers.RespondMissingFileHandler).Methods("POST")
@@ -0,0 +1,66 @@
+package setup
+
+import (
+	"net/http"
+	"os"
+	"plandex-server/db"
+	"plandex-server/metrics"
+	"plandex-server/model/plan"
+	"strings"
+	"time"
+)
+
+// newMetricsServer serves /metrics on its own listener instead of the public API port, since metrics aren't authenticated. Metrics are off unless PLANDEX_METRICS_PORT is set, either to a port (listening on all interfaces) or to a host and port like '127.0.0.1:9099'. Returns nil when metrics are off.
+func newMetricsServer() *http.Server {
+	addr := os.Getenv("PLANDEX_METRICS_PORT")
+	if addr == "" {
+		return nil
+	}
+
+	if !strings.Contains(addr, ":") {
+		addr = ":" + addr
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+func registerMetrics() {
+	metrics.RegisterGaugeFunc("active_plans", "Plans currently streaming on this instance.", func() float64 {
+		return float64(plan.NumActivePlans())
+	})
+
+	metrics.RegisterGaugeFunc("repo_queue_depth", "Repo operations waiting in this instance's queues for a lock.", func() float64 {
+		return float64(db.RepoQueueDepth())
+	})
+
+	metrics.RegisterCounterFunc("plan_maintenance_runs_total", "Plan maintenance runs completed by this instance.", func() float64 {
+		return float64(db.GetPlanMaintenanceStats().Runs)
+	})
+
+	metrics.RegisterCounterFunc("plan_maintenance_plans_compacted_total", "Plan repos compacted by plan maintenance on this instance.", func() float64 {
+		return float64(db.GetPlanMaintenanceStats().PlansCompacted)
+	})
+
+	metrics.RegisterCounterFunc("plan_maintenance_commits_squashed_total", "Plan history commits squashed by plan maintenance on this instance.", func() float64 {
+		return float64(db.GetPlanMaintenanceStats().CommitsSquashed)
+	})
+
+	metrics.RegisterCounterFunc("plan_maintenance_plans_purged_total", "Archived plans deleted by plan maintenance on this instance.", func() float64 {
+		return float64(db.GetPlanMaintenanceStats().PlansPurged)
+	})
+
+	metrics.RegisterCounterFunc("plan_maintenance_orphaned_dirs_removed_total", "Local dirs of deleted plans removed by plan maintenance on this instance.", func() float64 {
+		return float64(db.GetPlanMaintenanceStats().OrphanedDirsRemoved)
+	})
+
+	metrics.RegisterCounterFunc("plan_maintenance_bytes_reclaimed_total", "Disk space reclaimed by plan maintenance on this instance, in bytes.", func() float64 {
+		return float64(db.GetPlanMaintenanceStats().BytesReclaimed)
+	})
+}
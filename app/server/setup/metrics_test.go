@@ -0,0 +1,38 @@
+package setup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewMetricsServer(t *testing.T) {
+	t.Setenv("PLANDEX_METRICS_PORT", "")
+	if server := newMetricsServer(); server != nil {
+		t.Fatalf("expected metrics to be off by default, got %+v", server)
+	}
+
+	t.Setenv("PLANDEX_METRICS_PORT", "9100")
+	server := newMetricsServer()
+	if server == nil || server.Addr != ":9100" {
+		t.Fatalf("expected metrics on port 9100, got %+v", server)
+	}
+
+	rec := httptest.NewRecorder()
+	server.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected /metrics to be served, got status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	server.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected only /metrics to be served, got status %d for /health", rec.Code)
+	}
+
+	t.Setenv("PLANDEX_METRICS_PORT", "127.0.0.1:9100")
+	server = newMetricsServer()
+	if server == nil || server.Addr != "127.0.0.1:9100" {
+		t.Errorf("expected metrics on 127.0.0.1:9100, got %+v", server)
+	}
+}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"plandex-server/db"
+	"plandex-server/handlers"
 	"plandex-server/host"
 	"plandex-server/model/plan"
 	"plandex-server/notify"
 	"plandex-server/shutdown"
+	"plandex-server/tracing"
 	"runtime/debug"
 	"syscall"
 	"time"
@@ -39,6 +42,11 @@ func MustInitDb() {
 	if err != nil {
 		log.Fatal("Error caching org role ids: ", err)
 	}
+
+	err = db.InitPlanStorage()
+	if err != nil {
+		log.Fatal("Error initializing plan storage: ", err)
+	}
 }
 
 var shutdownHooks []func()
@@ -57,7 +65,11 @@ func loggingMiddleware(next http.Handler) http.Handler {
 
 		start := time.Now()
 
-		log.Printf("\n\nRequest: %s %s\n\n", r.Method, r.URL.Path)
+		if traceId := tracing.TraceId(r.Context()); traceId != "" {
+			log.Printf("\n\nRequest: %s %s (trace %s)\n\n", r.Method, r.URL.Path, traceId)
+		} else {
+			log.Printf("\n\nRequest: %s %s\n\n", r.Method, r.URL.Path)
+		}
 		next.ServeHTTP(w, r)
 		log.Printf("\n\nCompleted: %s %s in %v\n\n", r.Method, r.URL.Path, time.Since(start))
 	})
@@ -71,6 +83,30 @@ func StartServer(handler http.Handler, configureFn func(handler http.Handler) ht
 	shutdown.ShutdownCtx, shutdown.ShutdownCancel = context.WithCancel(context.Background())
 	defer shutdown.ShutdownCancel()
 
+	shutdownTracing, err := tracing.Init(shutdown.ShutdownCtx)
+	if err != nil {
+		log.Fatal("Error initializing tracing: ", err)
+	}
+	RegisterShutdownHook(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := shutdownTracing(ctx)
+		if err != nil {
+			log.Printf("Error flushing traces: %v", err)
+		}
+	})
+
+	err = handlers.StartPlanBus()
+	if err != nil {
+		log.Fatal("Error starting plan bus: ", err)
+	}
+
+	plan.StartCheckpointRecovery()
+	plan.StartQueuedPromptDispatch()
+	db.StartPlanMaintenance()
+
+	registerMetrics()
+
 	// Ensure database connection is closed
 	defer func() {
 		log.Println("Closing database connection...")
@@ -93,6 +129,9 @@ func StartServer(handler http.Handler, configureFn func(handler http.Handler) ht
 	// Apply the maxBytesMiddleware to limit request size to 1 GB
 	handler = maxBytesMiddleware(handler, 1000<<20) // 1 GB limit
 
+	// outermost so request logs and everything downstream can see the request's trace
+	handler = tracing.Middleware(handler)
+
 	if configureFn != nil {
 		handler = configureFn(handler)
 	}
@@ -112,6 +151,25 @@ func StartServer(handler http.Handler, configureFn func(handler http.Handler) ht
 
 	log.Println("Started Plandex server on port " + externalPort)
 
+	metricsServer := newMetricsServer()
+	if metricsServer != nil {
+		_, metricsPort, err := net.SplitHostPort(metricsServer.Addr)
+		if err != nil {
+			log.Fatalf("Invalid PLANDEX_METRICS_PORT %q: %v", metricsServer.Addr, err)
+		}
+		if metricsPort == externalPort {
+			log.Fatal("PLANDEX_METRICS_PORT must be different from PORT")
+		}
+
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Failed to start metrics server: %v", err)
+			}
+		}()
+
+		log.Println("Serving metrics on " + metricsServer.Addr)
+	}
+
 	if afterStart != nil {
 		afterStart()
 	}
@@ -175,6 +233,12 @@ func StartServer(handler http.Handler, configureFn func(handler http.Handler) ht
 		log.Printf("Http server forced to shutdown: %v", err)
 	}
 
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(httpCtx); err != nil {
+			log.Printf("Metrics server forced to shutdown: %v", err)
+		}
+	}
+
 	// Execute shutdown hooks
 	log.Println("Executing shutdown hooks...")
 	for _, hook := range shutdownHooks {
@@ -0,0 +1,149 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenTelemetry tracing, exported over OTLP/HTTP. It's configured entirely with the standard OTEL_* env vars, and is enabled when an OTLP endpoint is set. Until Init enables it, the global tracer provider is a no-op, so spans cost next to nothing.
+//
+// Much of a plan's work runs in goroutines that outlive the request that started it, under contexts that are canceled with the active plan rather than the request. ContextWithSpanFrom lets that work keep the active plan's cancellation while still nesting its spans under the right parent.
+
+const tracerName = "plandex-server"
+
+var tracer = otel.Tracer(tracerName)
+
+// Init sets up the global tracer provider and returns a func that flushes and stops it on shutdown
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	// trace context from the CLI (or anything else) is always accepted, even when spans aren't exported, so trace ids still show up in logs
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP trace exporter: %v", err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", tracerName)),
+		resource.WithHost(),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace resource: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	log.Println("Tracing enabled -- exporting spans via OTLP")
+
+	return provider.Shutdown, nil
+}
+
+func Enabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span, if there is one, and ends it
+func End(span trace.Span, err error) {
+	recordError(span, err)
+	span.End()
+}
+
+// RecordError marks the current span of ctx as failed with err
+func RecordError(ctx context.Context, err error) {
+	recordError(trace.SpanFromContext(ctx), err)
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+func AddEvent(ctx context.Context, name string, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).AddEvent(name, trace.WithAttributes(attrs...))
+}
+
+// ContextWithSpanFrom returns ctx (with its cancellation and values) carrying the current span of spanCtx
+func ContextWithSpanFrom(ctx, spanCtx context.Context) context.Context {
+	if spanCtx == nil {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, trace.SpanFromContext(spanCtx))
+}
+
+// TraceId returns the id of the trace ctx belongs to, or an empty string if there isn't one
+func TraceId(ctx context.Context) string {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.HasTraceID() {
+		return ""
+	}
+	return spanCtx.TraceID().String()
+}
+
+// Middleware starts a server span for each request, continuing the caller's trace if the request has a traceparent header. Spans are named by method until the router names them by route.
+func Middleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "plandex-server",
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !isMonitoringPath(r.URL.Path)
+		}),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method
+		}),
+	)
+}
+
+// NameSpanByRoute is router middleware that names the request's server span by its route template, e.g. 'POST /plans/{planId}/{branch}/tell', rather than by path, which would make every plan its own span name
+func NameSpanByRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				span := trace.SpanFromContext(r.Context())
+				span.SetName(r.Method + " " + tmpl)
+				span.SetAttributes(attribute.String("http.route", tmpl))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isMonitoringPath(path string) bool {
+	return path == "/health" || path == "/version" || path == "/metrics"
+}
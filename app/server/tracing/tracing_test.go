@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is a local stand-in for an OTLP/HTTP collector
+type collector struct {
+	mu    sync.Mutex
+	spans []*tracepb.Span
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	c.mu.Unlock()
+
+	b, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(b)
+}
+
+func (c *collector) span(t *testing.T, name string) *tracepb.Span {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	names := []string{}
+	for _, s := range c.spans {
+		names = append(names, s.Name)
+	}
+	t.Fatalf("expected a span named %q, got %v", name, names)
+	return nil
+}
+
+func TestRequestTraceIsPropagatedAndExported(t *testing.T) {
+	col := &collector{}
+	srv := httptest.NewServer(col)
+	defer srv.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", srv.URL)
+	t.Setenv("OTEL_SDK_DISABLED", "")
+
+	shutdown, err := Init(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// work that outlives the request runs under its own context, like an active plan
+	backgroundCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+
+	r := mux.NewRouter()
+	r.Use(NameSpanByRoute)
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {})
+	r.HandleFunc("/plans/{planId}/tell", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := Start(r.Context(), "execTellPlan")
+
+		go func() {
+			defer close(done)
+			_, child := Start(ContextWithSpanFrom(backgroundCtx, ctx), "ExecRepoOperation")
+			End(child, io.ErrUnexpectedEOF)
+		}()
+		<-done
+
+		span.End()
+	}).Methods(http.MethodPost)
+
+	handler := Middleware(r)
+
+	const traceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentSpanId = "00f067aa0ba902b7"
+
+	req := httptest.NewRequest(http.MethodPost, "/plans/abc/tell", nil)
+	req.Header.Set("traceparent", "00-"+traceId+"-"+parentSpanId+"-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	server := col.span(t, "POST /plans/{planId}/tell")
+	if got := hex.EncodeToString(server.TraceId); got != traceId {
+		t.Errorf("expected server span to continue trace %s, got %s", traceId, got)
+	}
+	if got := hex.EncodeToString(server.ParentSpanId); got != parentSpanId {
+		t.Errorf("expected server span's parent to be %s, got %s", parentSpanId, got)
+	}
+
+	exec := col.span(t, "execTellPlan")
+	if hex.EncodeToString(exec.ParentSpanId) != hex.EncodeToString(server.SpanId) {
+		t.Error("expected execTellPlan to be a child of the server span")
+	}
+
+	op := col.span(t, "ExecRepoOperation")
+	if hex.EncodeToString(op.ParentSpanId) != hex.EncodeToString(exec.SpanId) {
+		t.Error("expected ExecRepoOperation to be a child of execTellPlan")
+	}
+	if hex.EncodeToString(op.TraceId) != traceId {
+		t.Error("expected ExecRepoOperation to be in the request's trace")
+	}
+	if op.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR {
+		t.Errorf("expected ExecRepoOperation to have an error status, got %v", op.Status.GetCode())
+	}
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+	if len(col.spans) != 3 {
+		t.Errorf("expected 3 spans with /health filtered out, got %d", len(col.spans))
+	}
+}
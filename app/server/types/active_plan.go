@@ -9,6 +9,7 @@ import (
 	"plandex-server/db"
 	"plandex-server/notify"
 	"plandex-server/shutdown"
+	"plandex-server/tracing"
 	"sync"
 	"time"
 
@@ -82,23 +83,35 @@ type ActivePlan struct {
 	StoredReplyIds        []string
 	DidEditFiles          bool
 	SessionId             string
+	StartedAt             time.Time
+
+	// the tell request and auto-continue iteration being executed, checkpointed so the plan can be resumed after a restart
+	TellReq   *shared.TellPlanRequest
+	Iteration int
 
 	subscriptions  map[string]*subscription
 	subscriptionMu sync.Mutex
 
-	streamCh              chan string
+	streamCh              chan streamEvent
 	streamMu              sync.Mutex
 	lastStreamMessageSent time.Time
 	streamMessageBuffer   []shared.StreamMessage
+	lastStreamEventId     int64
+
+	// recently published messages for replay on reconnect -- protected by subscriptionMu
+	streamEvents *streamEventBuffer
 }
 
-func NewActivePlan(orgId, userId, planId, branch, prompt string, buildOnly, autoContext bool, sessionId string) *ActivePlan {
-	ctx, cancel := context.WithTimeout(shutdown.ShutdownCtx, ActivePlanTimeout)
+func NewActivePlan(traceCtx context.Context, orgId, userId, planId, branch, prompt string, buildOnly, autoContext bool, sessionId string) *ActivePlan {
+	// the plan's contexts are canceled on shutdown, not when the request that started the plan ends, but they carry its span so everything the plan does is traced under it
+	baseCtx := tracing.ContextWithSpanFrom(shutdown.ShutdownCtx, traceCtx)
+
+	ctx, cancel := context.WithTimeout(baseCtx, ActivePlanTimeout)
 	// child context for model stream so we can cancel it separately if needed
 	modelStreamCtx, cancelModelStream := context.WithCancel(ctx)
 
 	// we don't want to cancel summaries unless the whole plan is stopped or there's an error -- if the active plan finishes, we want summaries to continue -- so they get their own context
-	summaryCtx, cancelSummary := context.WithCancel(shutdown.ShutdownCtx)
+	summaryCtx, cancelSummary := context.WithCancel(baseCtx)
 
 	active := ActivePlan{
 		Id:                    planId,
@@ -126,9 +139,11 @@ func NewActivePlan(orgId, userId, planId, branch, prompt string, buildOnly, auto
 		AllowOverwritePaths:   map[string]bool{},
 		SkippedPaths:          map[string]bool{},
 		SessionId:             sessionId,
-		streamCh:              make(chan string),
+		StartedAt:             time.Now(),
+		streamCh:              make(chan streamEvent),
 		subscriptions:         map[string]*subscription{},
 		subscriptionMu:        sync.Mutex{},
+		streamEvents:          newStreamEventBuffer(StreamReplayBufferSize),
 	}
 
 	go func() {
@@ -142,15 +157,14 @@ func NewActivePlan(orgId, userId, planId, branch, prompt string, buildOnly, auto
 			select {
 			case <-active.Ctx.Done():
 				return
-			case msg := <-active.streamCh:
-				var subscriptions map[string]*subscription
+			case ev := <-active.streamCh:
+				// buffer and fan out under the same lock so a subscriber replaying from the buffer can't miss or duplicate a message
 				active.subscriptionMu.Lock()
-				subscriptions = active.subscriptions
-				active.subscriptionMu.Unlock()
-				for _, sub := range subscriptions {
-					sub.enqueueMessage(msg)
+				active.streamEvents.add(ev)
+				for _, sub := range active.subscriptions {
+					sub.enqueueMessage(ev.msg)
 				}
-
+				active.subscriptionMu.Unlock()
 			}
 		}
 	}()
@@ -238,20 +252,6 @@ func (ap *ActivePlan) Stream(msg shared.StreamMessage) {
 		}
 	}
 
-	// Direct send path
-	msgJson, err := json.Marshal(msg)
-	if err != nil {
-		ap.streamMu.Unlock()
-		go notify.NotifyErr(notify.SeverityError, fmt.Errorf("error marshalling stream message: %v", err))
-
-		ap.StreamDoneCh <- &shared.ApiError{
-			Type:   shared.ApiErrorTypeOther,
-			Status: http.StatusInternalServerError,
-			Msg:    "Error marshalling stream message: " + err.Error(),
-		}
-		return
-	}
-
 	if skipBuffer && len(ap.streamMessageBuffer) > 0 {
 		// Handle any remaining buffered messages before sending the message
 		// log.Println("ActivePlan.Stream: message is a skip buffer type and there are buffered messages")
@@ -287,14 +287,34 @@ func (ap *ActivePlan) Stream(msg shared.StreamMessage) {
 			ap.StreamDoneCh <- nil
 			return
 		}
+
+		// the message was already sent by the call above
+		ap.streamMu.Unlock()
+		return
 	}
 
+	// Direct send path
+	msg.Id = ap.lastStreamEventId + 1
+	msgJson, err := json.Marshal(msg)
+	if err != nil {
+		ap.streamMu.Unlock()
+		go notify.NotifyErr(notify.SeverityError, fmt.Errorf("error marshalling stream message: %v", err))
+
+		ap.StreamDoneCh <- &shared.ApiError{
+			Type:   shared.ApiErrorTypeOther,
+			Status: http.StatusInternalServerError,
+			Msg:    "Error marshalling stream message: " + err.Error(),
+		}
+		return
+	}
+	ap.lastStreamEventId = msg.Id
+
 	if verboseStreamLogging {
 		log.Println("ActivePlan.Stream: sending direct message")
 		log.Println(string(msgJson))
 	}
 
-	ap.streamCh <- string(msgJson)
+	ap.streamCh <- streamEvent{id: msg.Id, msg: string(msgJson)}
 
 	now := time.Now()
 	if now.After(ap.lastStreamMessageSent) {
@@ -339,6 +359,32 @@ func (ap *ActivePlan) PathQueueEmpty(path string) bool {
 func (ap *ActivePlan) Subscribe(reqCtx context.Context) (string, chan string) {
 	ap.subscriptionMu.Lock()
 	defer ap.subscriptionMu.Unlock()
+	id, sub := ap.initSubscription(reqCtx)
+	ap.subscriptions[id] = sub
+	return id, sub.ch
+}
+
+// SubscribeFrom subscribes to the plan stream, first replaying every buffered message published after lastEventId. If the buffer no longer covers everything after lastEventId, nothing is replayed and replayed is false, so the caller should fall back to sending the current state of the plan.
+func (ap *ActivePlan) SubscribeFrom(reqCtx context.Context, lastEventId int64) (id string, ch chan string, replayed bool) {
+	ap.subscriptionMu.Lock()
+	defer ap.subscriptionMu.Unlock()
+	id, sub := ap.initSubscription(reqCtx)
+
+	events, ok := ap.streamEvents.since(lastEventId)
+	if ok {
+		log.Printf("ActivePlan: replaying %d stream messages after event %d\n", len(events), lastEventId)
+		for _, ev := range events {
+			sub.enqueueMessage(ev.msg)
+		}
+	} else {
+		log.Printf("ActivePlan: can't replay stream messages after event %d\n", lastEventId)
+	}
+
+	ap.subscriptions[id] = sub
+	return id, sub.ch, ok
+}
+
+func (ap *ActivePlan) initSubscription(reqCtx context.Context) (string, *subscription) {
 	id := uuid.New().String()
 
 	planCtx := ap.Ctx // from the plan
@@ -358,10 +404,7 @@ func (ap *ActivePlan) Subscribe(reqCtx context.Context) (string, chan string) {
 		subCancel()
 	}()
 
-	sub := newSubscription(subCtx)
-
-	ap.subscriptions[id] = sub
-	return id, sub.ch
+	return id, newSubscription(subCtx)
 }
 
 func (ap *ActivePlan) Unsubscribe(id string) {
@@ -260,11 +260,14 @@ type ModelResponse struct {
 	Error        string        `json:"error,omitempty"`
 	GenerationId string        `json:"generation_id,omitempty"`
 	FirstTokenAt time.Time     `json:"first_token_at,omitempty"`
+	CacheHit     bool          `json:"cache_hit,omitempty"`
 }
 
 // StreamCompletionAccumulator accumulates content and tracks usage from streaming chunks
 type StreamCompletionAccumulator struct {
-	content      strings.Builder
+	content strings.Builder
+	// text the model output instead of calling a requested function--only used if no function call arrives
+	textFallback strings.Builder
 	usage        *openai.Usage
 	generationId string
 	firstTokenAt time.Time
@@ -283,6 +286,11 @@ func (a *StreamCompletionAccumulator) AddContent(content string) {
 	a.content.WriteString(content)
 }
 
+// AddTextFallback appends text content received when a function call was expected
+func (a *StreamCompletionAccumulator) AddTextFallback(content string) {
+	a.textFallback.WriteString(content)
+}
+
 // SetUsage sets the usage information, typically from the final chunk
 func (a *StreamCompletionAccumulator) SetUsage(usage *openai.Usage) {
 	a.usage = usage
@@ -307,8 +315,13 @@ func (a *StreamCompletionAccumulator) Result(stopped bool, err error) *ModelResp
 		errStr = err.Error()
 	}
 
+	content := a.content.String()
+	if content == "" {
+		content = a.textFallback.String()
+	}
+
 	return &ModelResponse{
-		Content:      a.content.String(),
+		Content:      content,
 		Usage:        a.usage,
 		Stopped:      stopped,
 		Error:        errStr,
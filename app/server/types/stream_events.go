@@ -0,0 +1,61 @@
+package types
+
+// Number of published stream messages kept per active plan so a reconnecting client can replay what it missed. Messages are batched at MaxStreamRate, so this covers a couple of minutes of continuous streaming.
+const StreamReplayBufferSize = 2000
+
+type streamEvent struct {
+	id  int64
+	msg string
+}
+
+// streamEventBuffer is a fixed-size ring buffer of the most recently published stream messages
+type streamEventBuffer struct {
+	events []streamEvent
+	start  int
+	size   int
+}
+
+func newStreamEventBuffer(capacity int) *streamEventBuffer {
+	return &streamEventBuffer{
+		events: make([]streamEvent, capacity),
+	}
+}
+
+func (b *streamEventBuffer) add(ev streamEvent) {
+	if len(b.events) == 0 {
+		return
+	}
+
+	if b.size < len(b.events) {
+		b.events[(b.start+b.size)%len(b.events)] = ev
+		b.size++
+		return
+	}
+
+	// buffer is full -- overwrite the oldest event
+	b.events[b.start] = ev
+	b.start = (b.start + 1) % len(b.events)
+}
+
+// since returns every buffered event with an id greater than lastId. ok is false if the buffer can't account for everything after lastId, either because older events were already dropped or because lastId is from a different stream.
+func (b *streamEventBuffer) since(lastId int64) (res []streamEvent, ok bool) {
+	if b.size == 0 {
+		return nil, lastId == 0
+	}
+
+	oldest := b.events[b.start]
+	newest := b.events[(b.start+b.size-1)%len(b.events)]
+
+	if lastId > newest.id || lastId < oldest.id-1 {
+		return nil, false
+	}
+
+	for i := 0; i < b.size; i++ {
+		ev := b.events[(b.start+i)%len(b.events)]
+		if ev.id > lastId {
+			res = append(res, ev)
+		}
+	}
+
+	return res, true
+}
@@ -0,0 +1,42 @@
+package types
+
+import "testing"
+
+func TestStreamEventBufferSince(t *testing.T) {
+	b := newStreamEventBuffer(3)
+
+	if _, ok := b.since(0); !ok {
+		t.Error("expected an empty buffer to cover a fresh stream")
+	}
+	if _, ok := b.since(5); ok {
+		t.Error("expected an empty buffer not to cover a later event")
+	}
+
+	for i := int64(1); i <= 5; i++ {
+		b.add(streamEvent{id: i, msg: "msg"})
+	}
+
+	// only events 3-5 are still buffered
+	events, ok := b.since(2)
+	if !ok || len(events) != 3 || events[0].id != 3 || events[2].id != 5 {
+		t.Errorf("expected events 3-5, got %v (ok=%t)", events, ok)
+	}
+
+	events, ok = b.since(4)
+	if !ok || len(events) != 1 || events[0].id != 5 {
+		t.Errorf("expected event 5, got %v (ok=%t)", events, ok)
+	}
+
+	events, ok = b.since(5)
+	if !ok || len(events) != 0 {
+		t.Errorf("expected nothing to replay, got %v (ok=%t)", events, ok)
+	}
+
+	if _, ok := b.since(1); ok {
+		t.Error("expected a gap to be reported once events were dropped")
+	}
+
+	if _, ok := b.since(9); ok {
+		t.Error("expected an id from a different stream not to be covered")
+	}
+}
@@ -56,6 +56,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			ReservedOutputTokens: 40000, ModelCompatibility: FullCompatibility,
 			PreferredOutputFormat: ModelOutputFormatXml, SystemPromptDisabled: true,
 			RoleParamsDisabled: true, ReasoningEffortEnabled: true, StopDisabled: true,
+			Pricing: &ModelPricing{InputPerMillion: 2, OutputPerMillion: 8, CachedInputPerMillion: 0.5},
 		},
 		RequiresVariantOverrides: []string{"ReasoningEffort"},
 		Variants: []BaseModelConfigVariant{
@@ -79,6 +80,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			PreferredOutputFormat: ModelOutputFormatToolCallJson, SystemPromptDisabled: true,
 			RoleParamsDisabled: true, ReasoningEffortEnabled: true, ReasoningEffort: ReasoningEffortHigh,
 			StopDisabled: true,
+			Pricing:      &ModelPricing{InputPerMillion: 1.1, OutputPerMillion: 4.4, CachedInputPerMillion: 0.275},
 		},
 		RequiresVariantOverrides: []string{"ReasoningEffort"},
 		Variants: []BaseModelConfigVariant{
@@ -100,6 +102,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			DefaultMaxConvoTokens: 75000, MaxTokens: 1047576,
 			MaxOutputTokens: 32768, ReservedOutputTokens: 32768,
 			ModelCompatibility: FullCompatibility, PreferredOutputFormat: ModelOutputFormatToolCallJson,
+			Pricing: &ModelPricing{InputPerMillion: 2, OutputPerMillion: 8, CachedInputPerMillion: 0.5},
 		},
 		Providers: []BaseModelUsesProvider{
 			{Provider: ModelProviderOpenAI, ModelName: "gpt-4.1"},
@@ -115,6 +118,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			DefaultMaxConvoTokens: 75000, MaxTokens: 1047576,
 			MaxOutputTokens: 32768, ReservedOutputTokens: 32768,
 			ModelCompatibility: FullCompatibility, PreferredOutputFormat: ModelOutputFormatToolCallJson,
+			Pricing: &ModelPricing{InputPerMillion: 0.4, OutputPerMillion: 1.6, CachedInputPerMillion: 0.1},
 		},
 		Providers: []BaseModelUsesProvider{
 			{Provider: ModelProviderOpenAI, ModelName: "gpt-4.1-mini"},
@@ -130,6 +134,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			DefaultMaxConvoTokens: 75000, MaxTokens: 1047576,
 			MaxOutputTokens: 32768, ReservedOutputTokens: 32768,
 			ModelCompatibility: FullCompatibility, PreferredOutputFormat: ModelOutputFormatToolCallJson,
+			Pricing: &ModelPricing{InputPerMillion: 0.1, OutputPerMillion: 0.4, CachedInputPerMillion: 0.025},
 		},
 		Providers: []BaseModelUsesProvider{
 			{Provider: ModelProviderOpenAI, ModelName: "gpt-4.1-nano"},
@@ -146,6 +151,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			ReservedOutputTokens: 20000, SupportsCacheControl: true,
 			PreferredOutputFormat: ModelOutputFormatXml, SingleMessageNoSystemPrompt: true,
 			TokenEstimatePaddingPct: 0.10,
+			Pricing:                 &ModelPricing{InputPerMillion: 15, OutputPerMillion: 75, CachedInputPerMillion: 1.5},
 		},
 		Variants: []BaseModelConfigVariant{
 			{IsBaseVariant: true},
@@ -175,6 +181,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			ReservedOutputTokens: 40000, SupportsCacheControl: true,
 			PreferredOutputFormat: ModelOutputFormatXml, SingleMessageNoSystemPrompt: true,
 			TokenEstimatePaddingPct: 0.10,
+			Pricing:                 &ModelPricing{InputPerMillion: 3, OutputPerMillion: 15, CachedInputPerMillion: 0.3},
 		},
 		Variants: []BaseModelConfigVariant{
 			{IsBaseVariant: true},
@@ -203,6 +210,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			ReservedOutputTokens: 20000, SupportsCacheControl: true,
 			PreferredOutputFormat: ModelOutputFormatXml, SingleMessageNoSystemPrompt: true,
 			TokenEstimatePaddingPct: 0.10,
+			Pricing:                 &ModelPricing{InputPerMillion: 3, OutputPerMillion: 15, CachedInputPerMillion: 0.3},
 		},
 		Variants: []BaseModelConfigVariant{
 			{IsBaseVariant: true},
@@ -231,6 +239,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			ReservedOutputTokens: 20000, SupportsCacheControl: true,
 			PreferredOutputFormat: ModelOutputFormatXml, SingleMessageNoSystemPrompt: true,
 			TokenEstimatePaddingPct: 0.10,
+			Pricing:                 &ModelPricing{InputPerMillion: 3, OutputPerMillion: 15, CachedInputPerMillion: 0.3},
 		},
 		Providers: []BaseModelUsesProvider{
 			{Provider: ModelProviderAnthropic, ModelName: "anthropic/claude-3-5-sonnet-latest"},
@@ -248,6 +257,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			ReservedOutputTokens: 8192, SupportsCacheControl: true,
 			PreferredOutputFormat: ModelOutputFormatXml, SingleMessageNoSystemPrompt: true,
 			TokenEstimatePaddingPct: 0.10,
+			Pricing:                 &ModelPricing{InputPerMillion: 0.8, OutputPerMillion: 4, CachedInputPerMillion: 0.08},
 		},
 		Providers: []BaseModelUsesProvider{
 			{Provider: ModelProviderAnthropic, ModelName: "anthropic/claude-3-5-haiku-latest"},
@@ -264,6 +274,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			DefaultMaxConvoTokens: 75000, MaxTokens: 2000000,
 			MaxOutputTokens: 8192, ReservedOutputTokens: 8192,
 			PreferredOutputFormat: ModelOutputFormatXml,
+			Pricing:               &ModelPricing{InputPerMillion: 1.25, OutputPerMillion: 5},
 		},
 		Providers: []BaseModelUsesProvider{
 			{Provider: ModelProviderGoogleAIStudio, ModelName: "gemini/gemini-1.5-pro"},
@@ -279,6 +290,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			DefaultMaxConvoTokens: 75000, MaxTokens: 1048576,
 			MaxOutputTokens: 65535, ReservedOutputTokens: 65535,
 			PreferredOutputFormat: ModelOutputFormatXml,
+			Pricing:               &ModelPricing{InputPerMillion: 1.25, OutputPerMillion: 10},
 		},
 		Providers: []BaseModelUsesProvider{
 			{Provider: ModelProviderGoogleAIStudio, ModelName: "gemini/gemini-2.5-pro"},
@@ -294,6 +306,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			DefaultMaxConvoTokens: 75000, MaxTokens: 1048576,
 			MaxOutputTokens: 65535, ReservedOutputTokens: 65535,
 			PreferredOutputFormat: ModelOutputFormatXml,
+			Pricing:               &ModelPricing{InputPerMillion: 0.3, OutputPerMillion: 2.5},
 		},
 		Variants: []BaseModelConfigVariant{
 			{IsBaseVariant: true},
@@ -321,6 +334,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			DefaultMaxConvoTokens: 7500, MaxTokens: 64000,
 			MaxOutputTokens: 8192, ReservedOutputTokens: 8192,
 			PreferredOutputFormat: ModelOutputFormatXml,
+			Pricing:               &ModelPricing{InputPerMillion: 0.27, OutputPerMillion: 1.1, CachedInputPerMillion: 0.07},
 		},
 		Providers: []BaseModelUsesProvider{
 			{Provider: ModelProviderDeepSeek, ModelName: "deepseek/deepseek-chat"},
@@ -335,6 +349,7 @@ var BuiltInModels = []*BaseModelConfigSchema{
 			DefaultMaxConvoTokens: 7500, MaxTokens: 164000,
 			MaxOutputTokens: 33000, ReservedOutputTokens: 20000,
 			PreferredOutputFormat: ModelOutputFormatXml,
+			Pricing:               &ModelPricing{InputPerMillion: 0.55, OutputPerMillion: 2.19, CachedInputPerMillion: 0.14},
 		},
 		Variants: []BaseModelConfigVariant{
 			{VariantTag: "visible", IsDefaultVariant: true, Description: "(reasoning visible)", Overrides: BaseModelShared{IncludeReasoning: true}},
@@ -50,6 +50,12 @@ type CustomProvider struct {
 	ApiKeyEnvVar  string                       `json:"apiKeyEnvVar,omitempty"`
 	ExtraAuthVars []ModelProviderExtraAuthVars `json:"extraAuthVars,omitempty"`
 
+	// for providers that don't use OpenAI-compatible chat completions
+	ApiType ModelProviderApiType `json:"apiType,omitempty"`
+
+	// server-side limits on requests to this provider
+	RateLimits *ModelProviderRateLimits `json:"rateLimits,omitempty"`
+
 	CreatedAt *time.Time `json:"createdAt,omitempty"`
 	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
 }
@@ -269,6 +275,8 @@ func (cp *CustomProvider) ToModelProviderConfigSchema() ModelProviderConfigSchem
 		SkipAuth:       cp.SkipAuth,
 		ApiKeyEnvVar:   cp.ApiKeyEnvVar,
 		ExtraAuthVars:  cp.ExtraAuthVars,
+		ApiType:        cp.ApiType,
+		RateLimits:     cp.RateLimits,
 	}
 }
 
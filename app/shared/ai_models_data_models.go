@@ -46,6 +46,7 @@ type BaseModelShared struct {
 	SupportsCacheControl        bool              `json:"supportsCacheControl,omitempty"`
 	SingleMessageNoSystemPrompt bool              `json:"singleMessageNoSystemPrompt,omitempty"`
 	TokenEstimatePaddingPct     float64           `json:"tokenEstimatePaddingPct,omitempty"`
+	Pricing                     *ModelPricing     `json:"pricing,omitempty"`
 	ModelCompatibility
 }
 
@@ -299,6 +300,10 @@ type ModelRoleConfig struct {
 	// MissingKeyFallback   *ModelRoleConfig `json:"missingKeyFallback"` // removed in 2.2.0 refactor —
 	StrongModel *ModelRoleConfig `json:"strongModel"`
 
+	// ProviderFailover is an ordered chain of providers (provider ids or custom provider names) to try for this role's
+	// model, e.g. ["anthropic", "aws-bedrock", "openrouter"]. Unhealthy providers are skipped.
+	ProviderFailover []string `json:"providerFailover,omitempty"`
+
 	LocalProvider ModelProvider `json:"localProvider,omitempty"`
 }
 
@@ -320,6 +325,8 @@ type ModelRoleConfigSchema struct {
 	LargeOutputFallback  *ModelRoleConfigSchema `json:"largeOutputFallback,omitempty"`
 	ErrorFallback        *ModelRoleConfigSchema `json:"errorFallback,omitempty"`
 	StrongModel          *ModelRoleConfigSchema `json:"strongModel,omitempty"`
+
+	ProviderFailover []string `json:"providerFailover,omitempty"`
 }
 
 // ToClientVal returns either:
@@ -365,6 +372,9 @@ func (m *ModelRoleConfigSchema) ToClientVal() RoleJSON {
 	if m.StrongModel != nil {
 		out["strongModel"] = m.StrongModel.ToClientVal()
 	}
+	if len(m.ProviderFailover) > 0 {
+		out["providerFailover"] = m.ProviderFailover
+	}
 
 	return out
 }
@@ -482,6 +492,8 @@ func (m *ModelRoleConfigSchema) toModelRoleConfig(role ModelRole) ModelRoleConfi
 		LargeOutputFallback:  largeOutputFallback,
 		ErrorFallback:        errorFallback,
 		StrongModel:          strongModel,
+
+		ProviderFailover: m.ProviderFailover,
 	}
 }
 
@@ -560,6 +572,7 @@ func (m *ModelRoleConfig) ToModelRoleConfigSchema() ModelRoleConfigSchema {
 		LargeOutputFallback:  largeOutputFallback,
 		ErrorFallback:        errorFallback,
 		StrongModel:          strongModel,
+		ProviderFailover:     m.ProviderFailover,
 	}
 }
 
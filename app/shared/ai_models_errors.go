@@ -108,9 +108,14 @@ func (m *ModelRoleConfig) GetFallbackForModelError(
 // if we've got openrouter credentials in the stack, we always use OpenRouter as the fallback since it has its own routing/fallback routing to maximize resilience
 // otherwise we just use the second provider in the stack
 // if we're using the claude subscription, we also go to second provider in the stack rather than openrouter
+// if the role has a failover chain, we go to the next healthy provider in the chain instead
 func (m ModelRoleConfig) GetProviderFallback(authVars map[string]string, settings *PlanSettings, orgUserConfig *OrgUserConfig) *ModelRoleConfig {
 	providers := m.GetProvidersForAuthVars(authVars, settings, orgUserConfig)
 
+	if len(m.ProviderFailover) > 0 {
+		return m.getProviderFailoverFallback(providers, authVars, settings)
+	}
+
 	if len(providers) < 2 {
 		return nil
 	}
@@ -147,3 +152,42 @@ func (m ModelRoleConfig) GetProviderFallback(authVars map[string]string, setting
 
 	return &res
 }
+
+func (m ModelRoleConfig) getProviderFailoverFallback(providers []ModelProviderConfigSchema, authVars map[string]string, settings *PlanSettings) *ModelRoleConfig {
+	if len(providers) == 0 {
+		return nil
+	}
+
+	var currentComposite string
+	if m.BaseModelConfig != nil {
+		currentComposite = m.BaseModelConfig.ToComposite()
+	} else {
+		currentComposite = providers[0].ToComposite()
+	}
+
+	// the current provider may already have been dropped from the list as unhealthy, in which case the first provider is next
+	next := -1
+	for i, p := range providers {
+		if p.ToComposite() == currentComposite {
+			next = i + 1
+			break
+		}
+	}
+	if next == -1 {
+		next = 0
+	}
+	if next >= len(providers) {
+		return nil
+	}
+
+	res := ModelRoleConfig{}
+	copier.Copy(&res, m)
+	res.BaseModelConfig = nil // otherwise it's returned as is whatever the provider
+	res.BaseModelConfig = res.GetBaseModelConfigForProvider(authVars, settings, &providers[next])
+
+	if res.BaseModelConfig == nil {
+		return nil
+	}
+
+	return &res
+}
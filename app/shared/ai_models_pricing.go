@@ -0,0 +1,29 @@
+package shared
+
+// ModelPricing is a model's price in USD per million tokens. It's used to estimate spend for spending limits, so
+// models without pricing (like local models) don't count toward them.
+type ModelPricing struct {
+	InputPerMillion  float64 `json:"inputPerMillion"`
+	OutputPerMillion float64 `json:"outputPerMillion"`
+
+	// price of input tokens read from the provider's prompt cache--the input price is used if not set
+	CachedInputPerMillion float64 `json:"cachedInputPerMillion,omitempty"`
+}
+
+// GetCost returns the cost in USD of a request--cachedTokens are included in inputTokens, as providers report them
+func (p *ModelPricing) GetCost(inputTokens, outputTokens, cachedTokens int) float64 {
+	if p == nil {
+		return 0
+	}
+
+	cachedPrice := p.CachedInputPerMillion
+	if cachedPrice == 0 {
+		cachedPrice = p.InputPerMillion
+	}
+
+	cachedTokens = min(cachedTokens, inputTokens)
+
+	return (float64(inputTokens-cachedTokens)*p.InputPerMillion +
+		float64(cachedTokens)*cachedPrice +
+		float64(outputTokens)*p.OutputPerMillion) / 1_000_000
+}
@@ -0,0 +1,62 @@
+package shared
+
+// The server tracks provider health (error rate and latency) and registers a check here so that provider lookup skips
+// providers that are cooling down after repeated failures. With no check registered (e.g. in the CLI), every provider
+// is considered healthy.
+
+type ProviderHealthCheckFn func(providerComposite string) bool
+
+var providerHealthCheck ProviderHealthCheckFn
+
+func RegisterProviderHealthCheck(fn ProviderHealthCheckFn) {
+	providerHealthCheck = fn
+}
+
+func IsProviderHealthy(providerComposite string) bool {
+	if providerHealthCheck == nil {
+		return true
+	}
+	return providerHealthCheck(providerComposite)
+}
+
+// filterHealthyProviders drops unhealthy providers, keeping the order--if every provider is unhealthy, they're all
+// returned so there's still something to try
+func filterHealthyProviders(providers []ModelProviderConfigSchema) []ModelProviderConfigSchema {
+	res := []ModelProviderConfigSchema{}
+	for _, provider := range providers {
+		if IsProviderHealthy(provider.ToComposite()) {
+			res = append(res, provider)
+		}
+	}
+	if len(res) == 0 {
+		return providers
+	}
+	return res
+}
+
+// orderByProviderFailover puts providers in the order of a role's failover chain, leaving out any that aren't in it--if
+// none of the chain's providers are available, providers are returned as is
+func orderByProviderFailover(providers []ModelProviderConfigSchema, chain []string) []ModelProviderConfigSchema {
+	res := []ModelProviderConfigSchema{}
+	for _, name := range chain {
+		for _, provider := range providers {
+			if provider.MatchesFailoverName(name) {
+				res = append(res, provider)
+				break
+			}
+		}
+	}
+	if len(res) == 0 {
+		return providers
+	}
+	return res
+}
+
+// MatchesFailoverName checks a provider against a failover chain entry, which is either a built-in provider id or a
+// custom provider's name
+func (m *ModelProviderConfigSchema) MatchesFailoverName(name string) bool {
+	if m.CustomProvider != nil {
+		return *m.CustomProvider == name
+	}
+	return string(m.Provider) == name
+}
@@ -20,6 +20,8 @@ const PerplexityApiKeyEnvVar = "PERPLEXITY_API_KEY"
 const AnthropicClaudeMaxTokenEnvVar = "ANTHROPIC_CLAUDE_MAX_TOKEN"
 const AnthropicClaudeMaxBetaHeader = "oauth-2025-04-20"
 const LiteLLMAnthropicBaseUrl = "https://api.anthropic.com"
+const AnthropicV1BaseUrl = "https://api.anthropic.com/v1"
+const GeminiV1BetaBaseUrl = "https://generativelanguage.googleapis.com/v1beta"
 
 type ModelPublisher string
 
@@ -76,6 +78,34 @@ var AllModelProviders = []ModelProvider{
 	ModelProviderCustom,
 }
 
+// ModelProviderApiType is the wire format a provider speaks. Providers default to OpenAI-compatible chat completions
+// (either directly or through the LiteLLM proxy); native api types are called directly by the server.
+type ModelProviderApiType string
+
+const (
+	ModelProviderApiTypeOpenAI    ModelProviderApiType = "openai"
+	ModelProviderApiTypeAnthropic ModelProviderApiType = "anthropic"
+	ModelProviderApiTypeGemini    ModelProviderApiType = "gemini"
+)
+
+var AllModelProviderApiTypes = []ModelProviderApiType{
+	ModelProviderApiTypeOpenAI,
+	ModelProviderApiTypeAnthropic,
+	ModelProviderApiTypeGemini,
+}
+
+// ModelProviderRateLimits are enforced by the server across all requests to a provider, separately for each model.
+// Zero means no limit.
+type ModelProviderRateLimits struct {
+	RequestsPerMinute int `json:"requestsPerMinute,omitempty"`
+	TokensPerMinute   int `json:"tokensPerMinute,omitempty"`
+	MaxConcurrent     int `json:"maxConcurrent,omitempty"`
+}
+
+func (l *ModelProviderRateLimits) IsZero() bool {
+	return l == nil || (l.RequestsPerMinute == 0 && l.TokensPerMinute == 0 && l.MaxConcurrent == 0)
+}
+
 type ModelProviderExtraAuthVars struct {
 	Var               string `json:"var"`
 	MaybeJSONFilePath bool   `json:"maybeJSONFilePath,omitempty"`
@@ -100,6 +130,11 @@ type ModelProviderConfigSchema struct {
 
 	ApiKeyEnvVar  string                       `json:"apiKeyEnvVar,omitempty"`
 	ExtraAuthVars []ModelProviderExtraAuthVars `json:"extraAuthVars,omitempty"`
+
+	// empty means OpenAI-compatible
+	ApiType ModelProviderApiType `json:"apiType,omitempty"`
+
+	RateLimits *ModelProviderRateLimits `json:"rateLimits,omitempty"`
 }
 
 func (m *ModelProviderConfigSchema) ToComposite() string {
@@ -288,6 +323,10 @@ func GetProvidersForAuthVars(authVars map[string]string, settings *PlanSettings,
 }
 
 func GetProvidersForAuthVarsWithModelId(authVars map[string]string, settings *PlanSettings, modelId ModelId, orgUserConfig *OrgUserConfig) []ModelProviderConfigSchema {
+	return filterHealthyProviders(getProvidersForAuthVarsWithModelId(authVars, settings, modelId, orgUserConfig))
+}
+
+func getProvidersForAuthVarsWithModelId(authVars map[string]string, settings *PlanSettings, modelId ModelId, orgUserConfig *OrgUserConfig) []ModelProviderConfigSchema {
 	var localProvider ModelProvider
 	if settings != nil {
 		modelPack := settings.GetModelPack()
@@ -335,7 +374,13 @@ func GetProvidersForAuthVarsWithModelId(authVars map[string]string, settings *Pl
 }
 
 func (m ModelRoleConfig) GetProvidersForAuthVars(authVars map[string]string, settings *PlanSettings, orgUserConfig *OrgUserConfig) []ModelProviderConfigSchema {
-	return GetProvidersForAuthVarsWithModelId(authVars, settings, m.ModelId, orgUserConfig)
+	if len(m.ProviderFailover) == 0 {
+		return GetProvidersForAuthVarsWithModelId(authVars, settings, m.ModelId, orgUserConfig)
+	}
+
+	// apply the failover chain before filtering on health so an unhealthy chain doesn't fall through to providers outside it
+	providers := getProvidersForAuthVarsWithModelId(authVars, settings, m.ModelId, orgUserConfig)
+	return filterHealthyProviders(orderByProviderFailover(providers, m.ProviderFailover))
 }
 
 func (m ModelRoleConfig) GetFirstProviderForAuthVars(authVars map[string]string, settings *PlanSettings, orgUserConfig *OrgUserConfig) *ModelProviderConfigSchema {
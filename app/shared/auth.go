@@ -30,6 +30,8 @@ const (
 	ApiErrorTypeCloudSubscriptionPaused  ApiErrorType = "cloud_subscription_paused"
 	ApiErrorTypeCloudSubscriptionOverdue ApiErrorType = "cloud_subscription_overdue"
 
+	ApiErrorTypeSpendLimitReached ApiErrorType = "spend_limit_reached"
+
 	ApiErrorTypeOther ApiErrorType = "other"
 )
 
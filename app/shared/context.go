@@ -59,6 +59,9 @@ func (c *Context) TypeAndIcon() (string, string) {
 	case ContextMapType:
 		icon = "🗺️ "
 		t = "map"
+	case ContextDocumentType:
+		icon = "📑"
+		t = "doc"
 	}
 
 	return t, icon
@@ -120,7 +123,7 @@ func SummaryForLoadContext(contexts []*Context, tokensAdded, totalTokens int) st
 
 	for _, context := range contexts {
 		switch context.ContextType {
-		case ContextFileType:
+		case ContextFileType, ContextDocumentType:
 			numFiles++
 		case ContextURLType:
 			numUrls++
@@ -234,6 +237,30 @@ func SummaryForRemoveContext(contexts []*Context, previousTotalTokens int) strin
 	return fmt.Sprintf("Removed %d piece%s of context | removed → %d 🪙 | total → %d 🪙", len(contexts), suffix, removedTokens, totalTokens)
 }
 
+func SummaryForPinContext(contexts []*Context, pinned bool) string {
+	action := "Pinned"
+	if !pinned {
+		action = "Unpinned"
+	}
+
+	if len(contexts) == 0 {
+		return fmt.Sprintf("%s 0 pieces of context", action)
+	}
+
+	suffix := ""
+	if len(contexts) > 1 {
+		suffix = "s"
+	}
+
+	var names []string
+	for _, context := range contexts {
+		_, icon := context.TypeAndIcon()
+		names = append(names, fmt.Sprintf("  • %s %s", icon, context.Name))
+	}
+
+	return fmt.Sprintf("%s %d piece%s of context\n\n%s", action, len(contexts), suffix, strings.Join(names, "\n"))
+}
+
 type SummaryForUpdateContextParams struct {
 	NumFiles    int
 	NumTrees    int
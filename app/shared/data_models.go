@@ -92,6 +92,7 @@ const (
 	ContextPipedDataType     ContextType = "piped data"
 	ContextImageType         ContextType = "image"
 	ContextMapType           ContextType = "map"
+	ContextDocumentType      ContextType = "document"
 )
 
 type FileMapBodies map[string]string
@@ -114,6 +115,7 @@ type Context struct {
 	MapTokens       map[string]int        `json:"mapTokens,omitempty"`
 	MapSizes        map[string]int64      `json:"mapSizes,omitempty"`
 	AutoLoaded      bool                  `json:"autoLoaded"`
+	Pinned          bool                  `json:"pinned"`
 	CreatedAt       time.Time             `json:"createdAt"`
 	UpdatedAt       time.Time             `json:"updatedAt"`
 }
@@ -398,3 +400,49 @@ func (t *CreditsTransaction) ModelString() string {
 
 	return s
 }
+
+type QueuedPrompt struct {
+	Id          string    `json:"id"`
+	PlanId      string    `json:"planId"`
+	Branch      string    `json:"branch"`
+	UserId      string    `json:"userId"`
+	Prompt      string    `json:"prompt"`
+	Interrupted bool      `json:"interrupted"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type RepoLockScope string
+
+const (
+	RepoLockScopeRead  RepoLockScope = "read"
+	RepoLockScopeWrite RepoLockScope = "write"
+)
+
+type RepoLock struct {
+	Id              string        `json:"id"`
+	PlanId          string        `json:"planId"`
+	Branch          string        `json:"branch"`
+	Scope           RepoLockScope `json:"scope"`
+	Reason          string        `json:"reason"`
+	UserId          string        `json:"userId"`
+	InstanceId      string        `json:"instanceId"`
+	GoroutineId     int64         `json:"goroutineId"`
+	HeartbeatAge    time.Duration `json:"heartbeatAge"`
+	Expired         bool          `json:"expired"`
+	LastHeartbeatAt time.Time     `json:"lastHeartbeatAt"`
+	CreatedAt       time.Time     `json:"createdAt"`
+}
+
+// RepoOperation is an operation waiting on or holding a plan's repo lock
+type RepoOperation struct {
+	Id         string        `json:"id"`
+	PlanId     string        `json:"planId"`
+	Branch     string        `json:"branch"`
+	Scope      RepoLockScope `json:"scope"`
+	Reason     string        `json:"reason"`
+	UserId     string        `json:"userId"`
+	Running    bool          `json:"running"`
+	LockId     string        `json:"lockId,omitempty"`
+	EnqueuedAt time.Time     `json:"enqueuedAt"`
+	StartedAt  *time.Time    `json:"startedAt,omitempty"`
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -66,6 +67,13 @@ type PlanConfig struct {
 	AutoLoadContext   bool `json:"autoContext"`
 	SmartContext      bool `json:"smartContext"`
 
+	// max tokens of loaded context per request - 0 means the model's limit
+	ContextBudget int `json:"contextBudget"`
+
+	// max USD spent on model requests for the plan, in total and per day (UTC), before it's paused - 0 means no limit
+	PlanSpendLimit  float64 `json:"planSpendLimit"`
+	DailySpendLimit float64 `json:"dailySpendLimit"`
+
 	// AutoApproveContext bool `json:"autoApproveContext"`
 	// QuietContext       bool `json:"quietContext"`
 
@@ -208,6 +216,7 @@ type ConfigSetting struct {
 	Visible         func(p *PlanConfig) bool
 	BoolSetter      func(p *PlanConfig, enabled bool)
 	IntSetter       func(p *PlanConfig, value int)
+	FloatSetter     func(p *PlanConfig, value float64)
 	StringSetter    func(p *PlanConfig, value string)
 	EditorSetter    func(p *PlanConfig, label, command string, args []string)
 	Getter          func(p *PlanConfig) string
@@ -334,6 +343,45 @@ var ConfigSettingsByKey = map[string]ConfigSetting{
 			return fmt.Sprintf("%t", p.SmartContext)
 		},
 	},
+	"contextbudget": {
+		Name: "context-budget",
+		Desc: "Max tokens of context per request before low-priority files are reduced to maps (0 for model limit)",
+		IntSetter: func(p *PlanConfig, value int) {
+			if value < 0 {
+				value = 0
+			}
+			p.ContextBudget = value
+		},
+		Getter: func(p *PlanConfig) string {
+			return fmt.Sprintf("%d", p.ContextBudget)
+		},
+	},
+	"planspendlimit": {
+		Name: "plan-spend-limit",
+		Desc: "Max USD to spend on model requests for the plan before it's paused (0 for no limit)",
+		FloatSetter: func(p *PlanConfig, value float64) {
+			if value < 0 {
+				value = 0
+			}
+			p.PlanSpendLimit = value
+		},
+		Getter: func(p *PlanConfig) string {
+			return strconv.FormatFloat(p.PlanSpendLimit, 'f', -1, 64)
+		},
+	},
+	"dailyspendlimit": {
+		Name: "daily-spend-limit",
+		Desc: "Max USD to spend on model requests for the plan per day (UTC) before it's paused (0 for no limit)",
+		FloatSetter: func(p *PlanConfig, value float64) {
+			if value < 0 {
+				value = 0
+			}
+			p.DailySpendLimit = value
+		},
+		Getter: func(p *PlanConfig) string {
+			return strconv.FormatFloat(p.DailySpendLimit, 'f', -1, 64)
+		},
+	},
 	"autocommit": {
 		Name: "auto-commit",
 		Desc: "Automatically commit changes to git after apply",
@@ -11,4 +11,5 @@ const (
 	PlanStatusFinished    PlanStatus = "finished"
 	PlanStatusStopped     PlanStatus = "stopped"
 	PlanStatusError       PlanStatus = "error"
+	PlanStatusInterrupted PlanStatus = "interrupted"
 )
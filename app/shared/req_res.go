@@ -107,6 +107,7 @@ type ListPlansRunningResponse struct {
 	StreamStartedAtByBranchId  map[string]time.Time `json:"streamStartedAtByBranchId"`
 	StreamFinishedAtByBranchId map[string]time.Time `json:"streamFinishedAtByBranchId"`
 	StreamIdByBranchId         map[string]string    `json:"streamIdByBranchId"`
+	QueuedPromptsByBranchId    map[string]int       `json:"queuedPromptsByBranchId"`
 	PlansById                  map[string]*Plan     `json:"plansById"`
 }
 
@@ -156,6 +157,19 @@ type BuildPlanRequest struct {
 
 const NoBuildsErr string = "No builds"
 
+type ResumePlanRequest struct {
+	ConnectStream bool              `json:"connectStream"`
+	AuthVars      map[string]string `json:"authVars"`
+	SessionId     string            `json:"sessionId"`
+}
+
+const NothingToResumeErr string = "Nothing to resume"
+
+type QueuePromptResponse struct {
+	QueuedPrompt *QueuedPrompt `json:"queuedPrompt"`
+	Position     int           `json:"position"`
+}
+
 type RespondMissingFileChoice string
 
 const (
@@ -193,6 +207,7 @@ type LoadContextParams struct {
 	ForceSkipIgnore bool                  `json:"forceSkipIgnore"`
 	ImageDetail     openai.ImageURLDetail `json:"imageDetail"`
 	AutoLoaded      bool                  `json:"autoLoaded"`
+	Pinned          bool                  `json:"pinned"`
 
 	InputShas   map[string]string `json:"inputShas"`
 	InputTokens map[string]int    `json:"inputTokens"`
@@ -261,6 +276,15 @@ type DeleteContextRequest struct {
 	Ids map[string]bool `json:"ids"`
 }
 
+type PinContextRequest struct {
+	Ids    map[string]bool `json:"ids"`
+	Pinned bool            `json:"pinned"`
+}
+
+type PinContextResponse struct {
+	Msg string `json:"msg"`
+}
+
 type DeleteContextResponse struct {
 	TokensRemoved int    `json:"tokensRemoved"`
 	TotalTokens   int    `json:"totalTokens"`
@@ -342,6 +366,38 @@ type GetBuildStatusResponse struct {
 	IsBuildingByPath map[string]bool `json:"isBuildingByPath"`
 }
 
+type UsageSummaryRequest struct {
+	PlanId    string     `json:"planId"`
+	SessionId string     `json:"sessionId"`
+	Since     *time.Time `json:"since"`
+	// include every user in the org rather than just the current user--requires billing permission
+	AllUsers bool `json:"allUsers"`
+}
+
+type UsageBreakdown struct {
+	Key          string  `json:"key"`
+	NumRequests  int     `json:"numRequests"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	CachedTokens int     `json:"cachedTokens"`
+	Cost         float64 `json:"cost"`
+}
+
+type UsageSummaryResponse struct {
+	Total UsageBreakdown `json:"total"`
+
+	ByPlanId      []UsageBreakdown  `json:"byPlanId"`
+	PlanNamesById map[string]string `json:"planNamesById"`
+
+	ByRole    []UsageBreakdown `json:"byRole"`
+	ByModel   []UsageBreakdown `json:"byModel"`
+	ByPurpose []UsageBreakdown `json:"byPurpose"`
+	ByDay     []UsageBreakdown `json:"byDay"`
+
+	// models without pricing are counted in tokens but not cost
+	UnpricedModels []string `json:"unpricedModels"`
+}
+
 // Cloud requests and responses
 type CreditsLogRequest struct {
 	TransactionType CreditsTransactionType `json:"transactionType"`
@@ -378,3 +434,14 @@ type CreditsSummaryResponse struct {
 type GetBalanceResponse struct {
 	Balance decimal.Decimal `json:"balance"`
 }
+
+type ListRepoLocksResponse struct {
+	Locks         []*RepoLock       `json:"locks"`
+	Operations    []*RepoOperation  `json:"operations"`
+	InstanceId    string            `json:"instanceId"`
+	PlanNamesById map[string]string `json:"planNamesById"`
+}
+
+type ForceReleaseRepoLockRequest struct {
+	Note string `json:"note"`
+}
@@ -24,11 +24,15 @@ const (
 	StreamMessageAborted           StreamMessageType = "aborted"
 	StreamMessageFinished          StreamMessageType = "finished"
 	StreamMessageError             StreamMessageType = "error"
+	StreamMessageModelStatus       StreamMessageType = "modelStatus"
+	StreamMessageWarning           StreamMessageType = "warning"
 
 	StreamMessageMulti StreamMessageType = "multi"
 )
 
 type StreamMessage struct {
+	// Sequence number assigned when a message is published for an active plan -- used to resume a dropped stream with Last-Event-Id
+	Id   int64             `json:"id,omitempty"`
 	Type StreamMessageType `json:"type"`
 
 	ReplyChunk string `json:"replyChunk,omitempty"`
@@ -43,6 +47,24 @@ type StreamMessage struct {
 	InitPrompt             string                   `json:"initPrompt,omitempty"`
 	InitReplies            []string                 `json:"initReplies,omitempty"`
 	InitBuildOnly          bool                     `json:"initBuildOnly,omitempty"`
+	ModelStatus            string                   `json:"modelStatus,omitempty"`
+	Warning                string                   `json:"warning,omitempty"`
 
 	StreamMessages []StreamMessage `json:"streamMessages,omitempty"`
 }
+
+type StreamClientMessageType string
+
+const (
+	StreamClientMessageStop               StreamClientMessageType = "stop"
+	StreamClientMessageRespondMissingFile StreamClientMessageType = "respondMissingFile"
+	StreamClientMessageAutoLoadContext    StreamClientMessageType = "autoLoadContext"
+)
+
+// StreamClientMessage is sent by a client to the server over a websocket plan stream
+type StreamClientMessage struct {
+	Type StreamClientMessageType `json:"type"`
+
+	RespondMissingFile *RespondMissingFileRequest `json:"respondMissingFile,omitempty"`
+	LoadContext        LoadContextRequest         `json:"loadContext,omitempty"`
+}
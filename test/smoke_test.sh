@@ -2,6 +2,8 @@
 # Plandex Smoke Test Script
 # Tests core functionality in a linear flow mimicking real usage
 # Assumes: Already signed in to Plandex Cloud (dev or staging account)
+# Set PLANDEX_MOCK_MODELS=1 to run against scripted replies from the mock model server instead of real providers
+# (requires a local server, with app/server/plandex-server built or PLANDEX_SERVER_BIN set)
 
 set -e  # Exit on error
 
@@ -31,20 +33,33 @@ This is a test project for Plandex smoke testing.
 EOF
 }
 
+cleanup() {
+    stop_mock_model_server
+    cleanup_test_dir
+}
+
 # Set trap for cleanup on exit
-trap cleanup_test_dir EXIT
+trap cleanup EXIT
 
 # Main test flow
 main() {
     log "=== Plandex Smoke Test Started at $(date) ==="
     
     setup
+
+    if [ -n "$PLANDEX_MOCK_MODELS" ]; then
+        start_mock_model_server
+    fi
     
     # 1. PLAN MANAGEMENT
     log "\n=== Testing Plan Management ==="
     
     # Create new plan with name
     run_plandex_cmd "new -n smoke-test-plan" "Create named plan"
+
+    if [ -n "$PLANDEX_MOCK_MODELS" ]; then
+        use_mock_models
+    fi
     
     # Check current plan
     run_plandex_cmd "current"
@@ -118,7 +118,10 @@ check_file() {
 
 # Setup test environment
 setup_test_dir() {
-    source ../.env.client-keys
+    # api keys aren't needed when running against the mock model server
+    if [ -z "$PLANDEX_MOCK_MODELS" ] || [ -f ../.env.client-keys ]; then
+        source ../.env.client-keys
+    fi
 
     local test_name="$1"
     TEST_DIR="/tmp/plandex-${test_name}-$$"
@@ -137,4 +140,39 @@ cleanup_test_dir() {
     cd /
     rm -rf "$TEST_DIR"
     success "Cleanup complete"
-}
\ No newline at end of file
+}
+# Mock model server -- lets tests run against scripted model replies instead of real providers.
+# Needs a local plandex server, since the mock has to be reachable from wherever the server runs.
+MOCK_MODEL_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)/mock-model"
+export PLANDEX_SERVER_BIN="${PLANDEX_SERVER_BIN:-$MOCK_MODEL_DIR/../../app/server/plandex-server}"
+
+start_mock_model_server() {
+    local scenarios="${1:-$MOCK_MODEL_DIR/scenarios.yaml}"
+
+    info "Starting mock model server with $scenarios"
+    "$PLANDEX_SERVER_BIN" mock-model --port 8199 --scenarios "$scenarios" > /tmp/plandex-mock-model-$$.log 2>&1 &
+    MOCK_MODEL_PID=$!
+
+    for i in $(seq 1 50); do
+        if curl -s http://localhost:8199/health > /dev/null; then
+            success "Mock model server running (pid $MOCK_MODEL_PID)"
+            return 0
+        fi
+        sleep 0.1
+    done
+
+    error "Mock model server failed to start -- see /tmp/plandex-mock-model-$$.log"
+}
+
+stop_mock_model_server() {
+    if [ -n "$MOCK_MODEL_PID" ]; then
+        kill "$MOCK_MODEL_PID" 2>/dev/null || true
+        MOCK_MODEL_PID=""
+    fi
+}
+
+# Import the mock provider, model, and model pack, then switch the current plan to it
+use_mock_models() {
+    run_plandex_cmd "models custom --file $MOCK_MODEL_DIR/models.json --save" "Import mock models"
+    run_plandex_cmd "set-model mock-pack" "Set mock model pack"
+}